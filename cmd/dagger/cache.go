@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/juju/ansiterm/tabwriter"
+	"github.com/spf13/cobra"
+
+	"github.com/dagger/dagger/engine/client"
+)
+
+func init() {
+	cacheCmd.AddCommand(cacheExplainCmd)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:    "cache",
+	Short:  "Inspect the engine's cache",
+	Hidden: true,
+	Annotations: map[string]string{
+		"experimental": "true",
+	},
+}
+
+var cacheExplainCmd = &cobra.Command{
+	Use:   "explain [options] <id>",
+	Short: "Explain why a call did not reuse the cached result of an earlier call",
+	Long: `Explain why a call did not reuse the cached result of an earlier call.
+
+The argument is an encoded ID, as returned by the "id" field of any object.
+It is compared to the closest earlier call of the same pipeline step recorded
+by the engine. The inputs that differ between the two calls are printed from
+the root of the call DAG to its tip, so the first one is typically the root
+cause. When the content of a host directory changed, the paths that were
+added, removed or changed in it are listed.
+
+Calls are only recorded by engines with "recordCalls" set in their config,
+and are only compared to earlier calls of the same client.`,
+	Example: `dagger cache explain "$(cat after.id)"`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withEngine(cmd.Context(), client.Params{}, func(ctx context.Context, engineClient *client.Client) error {
+			var res struct {
+				Engine struct {
+					ExplainCacheMiss []struct {
+						Path     string
+						Reason   string
+						Previous string
+						Current  string
+					}
+				}
+			}
+			err := engineClient.Do(ctx, `query ExplainCacheMiss($call: String!) {
+				engine {
+					explainCacheMiss(call: $call) {
+						path
+						reason
+						previous
+						current
+					}
+				}
+			}`, "ExplainCacheMiss", map[string]any{
+				"call": args[0],
+			}, &res)
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			for _, cause := range res.Engine.ExplainCacheMiss {
+				fmt.Fprintf(tw, "%s\n", cause.Path)
+				fmt.Fprintf(tw, "  %s\n", cause.Reason)
+				if cause.Previous != "" {
+					fmt.Fprintf(tw, "  - previous:\t%s\n", cause.Previous)
+				}
+				if cause.Current != "" {
+					fmt.Fprintf(tw, "  + current:\t%s\n", cause.Current)
+				}
+			}
+			return tw.Flush()
+		})
+	},
+}
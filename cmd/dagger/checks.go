@@ -24,6 +24,7 @@ var (
 
 func init() {
 	checksCmd.Flags().BoolVarP(&checksListMode, "list", "l", false, "List available checks")
+	checksCmd.Flags().BoolVar(&watchMode, "watch", false, "Re-run checks whenever the host files they read change")
 }
 
 var checksCmd = &cobra.Command{
@@ -37,6 +38,7 @@ Examples:
   dagger check                    # Run all checks
   dagger check -l                 # List all available checks
   dagger check go:lint            # Run the go:lint check and any subchecks
+  dagger check --watch            # Re-run all checks on every change
 `,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
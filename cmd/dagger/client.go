@@ -80,10 +80,7 @@ var clientInstallCmd = &cobra.Command{
 				return fmt.Errorf("failed to get local context directory path: %w", err)
 			}
 
-			_, err = mod.Source.
-				WithClient(generator, outputPath).
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, mod.Source.WithClient(generator, outputPath), contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to export client: %w", err)
 			}
@@ -135,10 +132,7 @@ var clientUninstallCmd = &cobra.Command{
 				return fmt.Errorf("failed to get local context directory path: %w", err)
 			}
 
-			_, err = mod.Source.
-				WithoutClient(path).
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, mod.Source.WithoutClient(path), contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to remove client from module: %w", err)
 			}
@@ -236,10 +230,7 @@ var clientUpdateCmd = &cobra.Command{
 				return fmt.Errorf("failed to get local context directory path: %w", err)
 			}
 
-			_, err = mod.Source.
-				WithUpdatedClients(args).
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, mod.Source.WithUpdatedClients(args), contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to update clients: %w", err)
 			}
@@ -9,6 +9,7 @@ import (
 	"github.com/dagger/dagger/dagql/dagui"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/client"
+	"github.com/dagger/dagger/engine/client/drivers"
 	"github.com/dagger/dagger/engine/client/imageload"
 	"github.com/dagger/dagger/engine/distconsts"
 	"github.com/dagger/dagger/engine/slog"
@@ -97,6 +98,10 @@ func withEngine(
 			params.RunnerHost = RunnerHost
 		}
 
+		if params.RunnerTLS == (drivers.TLSConfig{}) {
+			params.RunnerTLS = runnerTLS
+		}
+
 		if RunnerImageLoader != "" {
 			backend, err := imageload.GetBackend(RunnerImageLoader)
 			if err != nil {
@@ -107,6 +112,7 @@ func withEngine(
 
 		params.DisableHostRW = disableHostRW
 		params.AllowedLLMModules = allowedLLMModules
+		params.Offline = offline
 
 		params.CloudURLCallback = Frontend.SetCloudURL
 
@@ -133,6 +139,10 @@ func withEngine(
 		}
 		params.CloudAuth = ca
 
+		if watchMode {
+			return cleanup.Run, watchEngine(ctx, params, fn)
+		}
+
 		// Connect to and run with the engine
 		sess, err := client.Connect(ctx, params)
 		if err != nil {
@@ -146,6 +156,54 @@ func withEngine(
 	})
 }
 
+// watchEngine runs fn in a new session each time any of the host paths read
+// by the engine during the previous run change, until ctx is canceled.
+//
+// Each run gets a fresh session so host paths are synced again, while the
+// engine's cache ensures only the affected parts are rebuilt.
+func watchEngine(ctx context.Context, params client.Params, fn runClientCallback) error {
+	for run := 1; ; run++ {
+		watcher := newHostWatcher()
+		params.HostPathSyncCallback = watcher.Add
+
+		err := func() (rerr error) {
+			ctx, span := Tracer().Start(ctx, fmt.Sprintf("run #%d", run))
+			defer telemetry.EndWithCause(span, &rerr)
+
+			sess, err := client.Connect(ctx, params)
+			if err != nil {
+				return err
+			}
+			defer sess.Close()
+
+			Frontend.SetClient(sess.Dagger())
+
+			return fn(ctx, sess)
+		}()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if watcher.Len() == 0 {
+			if err == nil {
+				slog.Warn("no host paths were read, nothing to watch")
+			}
+			return err
+		}
+		if err != nil {
+			// keep watching, the next change may fix it
+			slog.Error("run failed", "run", run, "error", err)
+		}
+
+		slog.Info("watching for changes", "paths", watcher.Len())
+		if err := watcher.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return fmt.Errorf("watch host paths: %w", err)
+		}
+	}
+}
+
 func initEngineTelemetry(ctx context.Context) (context.Context, func(error)) {
 	// Setup telemetry config
 	telemetryCfg := telemetry.Config{
@@ -156,11 +214,26 @@ func initEngineTelemetry(ctx context.Context) (context.Context, func(error)) {
 		LiveLogExporters:    []sdklog.Exporter{Frontend.LogExporter()},
 		LiveMetricExporters: []sdkmetric.Exporter{Frontend.MetricExporter()},
 	}
-	if spans, logs, metrics, ok := enginetel.ConfiguredCloudExporters(ctx); ok {
+	if spans, logs, metrics, ok := enginetel.ConfiguredCloudExporters(ctx); ok && !offline {
 		telemetryCfg.LiveTraceExporters = append(telemetryCfg.LiveTraceExporters, spans)
 		telemetryCfg.LiveLogExporters = append(telemetryCfg.LiveLogExporters, logs)
 		telemetryCfg.LiveMetricExporters = append(telemetryCfg.LiveMetricExporters, metrics)
 	}
+	recorder, err := startRunRecorder(ctx)
+	if err != nil {
+		slog.Warn("failed to record run", "error", err)
+	}
+	if recorder != nil {
+		telemetryCfg.LiveTraceExporters = append(telemetryCfg.LiveTraceExporters, recorder.SpanExporter())
+		telemetryCfg.LiveLogExporters = append(telemetryCfg.LiveLogExporters, recorder.LogExporter())
+		telemetryCfg.LiveMetricExporters = append(telemetryCfg.LiveMetricExporters, recorder.MetricExporter())
+	}
+	var report *dagui.HTMLReport
+	if reportHTMLPath != "" {
+		report = dagui.NewHTMLReport()
+		telemetryCfg.LiveTraceExporters = append(telemetryCfg.LiveTraceExporters, report.SpanExporter())
+		telemetryCfg.LiveLogExporters = append(telemetryCfg.LiveLogExporters, report.LogExporter())
+	}
 	ctx = telemetry.Init(ctx, telemetryCfg)
 
 	// Set the full command string as the name of the root span.
@@ -173,6 +246,11 @@ func initEngineTelemetry(ctx context.Context) (context.Context, func(error)) {
 		name = os.Getenv(TraceNameEnv)
 	}
 	ctx, span := Tracer().Start(ctx, name)
+	if recorder != nil {
+		if err := recorder.Started(name, span); err != nil {
+			slog.Warn("failed to record run", "error", err)
+		}
+	}
 
 	// Set up global slog to log to the primary span output.
 	slog.SetDefault(slog.SpanLogger(ctx, InstrumentationLibrary))
@@ -182,6 +260,9 @@ func initEngineTelemetry(ctx context.Context) (context.Context, func(error)) {
 
 	// Set the span as the primary span for the frontend.
 	Frontend.SetPrimary(dagui.SpanID{SpanID: span.SpanContext().SpanID()})
+	if report != nil {
+		report.SetPrimary(dagui.SpanID{SpanID: span.SpanContext().SpanID()})
+	}
 
 	// Direct command stdout/stderr to span stdio via OpenTelemetry.
 	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary)
@@ -192,5 +273,15 @@ func initEngineTelemetry(ctx context.Context) (context.Context, func(error)) {
 		stdio.Close()
 		telemetry.EndWithCause(span, &rerr)
 		telemetry.Close()
+		if recorder != nil {
+			if err := recorder.Finish(rerr); err != nil {
+				slog.Warn("failed to record run", "error", err)
+			}
+		}
+		if report != nil {
+			if err := report.WriteFile(reportHTMLPath); err != nil {
+				slog.Warn("failed to write HTML report", "error", err)
+			}
+		}
 	}
 }
@@ -107,6 +107,10 @@ type FuncCommand struct {
 	// arguments rather than a debug level log.
 	warnSkipped bool
 
+	// runs is the number of times the command was executed, which can be
+	// more than once in watch mode.
+	runs int
+
 	q   *querybuilder.Selection
 	c   *client.Client
 	ctx context.Context
@@ -173,6 +177,13 @@ func (fc *FuncCommand) Command() *cobra.Command {
 				}
 
 				return withEngine(c.Context(), initModuleParams(a), func(ctx context.Context, engineClient *client.Client) (rerr error) {
+					if fc.runs > 0 {
+						// In watch mode, start from a clean command tree
+						// since it's built again from the reloaded module.
+						fc.resetCommand(c)
+					}
+					fc.runs++
+
 					fc.c = engineClient
 					fc.q = querybuilder.Query().Client(engineClient.Dagger().GraphQLClient())
 
@@ -226,10 +237,23 @@ func (fc *FuncCommand) Command() *cobra.Command {
 		fc.cmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "Save the result to a local file or directory")
 
 		fc.cmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Present result as JSON")
+
+		fc.cmd.PersistentFlags().BoolVar(&watchMode, "watch", false, "Re-run whenever the host files read by the call change")
 	}
 	return fc.cmd
 }
 
+// resetCommand removes the flags and sub-commands that were added to the
+// command tree from a previously loaded module.
+func (fc *FuncCommand) resetCommand(c *cobra.Command) {
+	c.RemoveCommand(c.Commands()...)
+	pflags := c.PersistentFlags()
+	c.ResetFlags()
+	c.PersistentFlags().AddFlagSet(pflags)
+	delete(c.Annotations, skippedOptsAnnotation)
+	delete(c.Annotations, skippedCmdsAnnotation)
+}
+
 func (fc *FuncCommand) Help(cmd *cobra.Command) error {
 	var args []any
 	// We need to store these in annotations because during traversal all
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/patternmatcher"
+
+	"github.com/dagger/dagger/engine/client"
+	"github.com/dagger/dagger/engine/slog"
+)
+
+const (
+	// hostWatchDebounce is how long host paths must be quiet after a change
+	// before it's reported, so that e.g. saving many files at once or a
+	// formatter rewriting them results in a single re-run.
+	hostWatchDebounce = 200 * time.Millisecond
+
+	// hostWatchPollInterval is how often host paths are scanned for changes
+	// when native file system events are unavailable.
+	hostWatchPollInterval = 500 * time.Millisecond
+)
+
+// hostWatcher records the host paths read by the engine during a session,
+// so it can wait for any of them to change.
+type hostWatcher struct {
+	mu    sync.Mutex
+	paths []*watchedHostPath
+	seen  map[string]struct{}
+
+	debounce     time.Duration
+	pollInterval time.Duration
+
+	// watching, if set, is called once changes are being watched for.
+	watching func()
+}
+
+type watchedHostPath struct {
+	client.HostPathSync
+
+	includes *patternmatcher.PatternMatcher
+	excludes *patternmatcher.PatternMatcher
+}
+
+func newHostWatcher() *hostWatcher {
+	return &hostWatcher{
+		seen:         map[string]struct{}{},
+		debounce:     hostWatchDebounce,
+		pollInterval: hostWatchPollInterval,
+	}
+}
+
+// Add records a host path read by the engine. It's safe to call concurrently,
+// and is meant to be used as a client.Params.HostPathSyncCallback.
+func (w *hostWatcher) Add(sync client.HostPathSync) {
+	key := sync.Path + "\x00" +
+		strings.Join(sync.IncludePatterns, "\x00") + "\x00\x00" +
+		strings.Join(sync.ExcludePatterns, "\x00")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.seen[key]; ok {
+		return
+	}
+	w.seen[key] = struct{}{}
+
+	p := &watchedHostPath{HostPathSync: sync}
+	var err error
+	if len(sync.IncludePatterns) > 0 {
+		p.includes, err = patternmatcher.New(sync.IncludePatterns)
+		if err != nil {
+			slog.Warn("invalid include patterns, watching all files", "path", sync.Path, "error", err)
+		}
+	}
+	if len(sync.ExcludePatterns) > 0 {
+		p.excludes, err = patternmatcher.New(sync.ExcludePatterns)
+		if err != nil {
+			slog.Warn("invalid exclude patterns, watching all files", "path", sync.Path, "error", err)
+		}
+	}
+	w.paths = append(w.paths, p)
+}
+
+// Len returns the number of host paths being watched.
+func (w *hostWatcher) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.paths)
+}
+
+// Wait blocks until any of the watched host paths change, or ctx is done.
+func (w *hostWatcher) Wait(ctx context.Context) error {
+	if err := w.waitForEvents(ctx); !errors.Is(err, errHostEventsUnsupported) {
+		return err
+	}
+	return w.poll(ctx)
+}
+
+// errHostEventsUnsupported is returned when native file system events can't
+// be used, in which case host paths are polled instead.
+var errHostEventsUnsupported = errors.New("file system events unsupported")
+
+// relevant returns whether a change to the given host path may affect any of
+// the contents read by the engine.
+func (w *hostWatcher) relevant(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range w.paths {
+		rel, err := filepath.Rel(p.Path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return true
+		}
+		if !p.ignored(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignored returns whether the given path, relative to the watched path, was
+// filtered out of the sync.
+func (p *watchedHostPath) ignored(rel string) bool {
+	if p.excludes != nil {
+		if excluded, _ := p.excludes.MatchesOrParentMatches(rel); excluded {
+			return true
+		}
+	}
+	if p.includes != nil {
+		if included, _ := p.includes.MatchesOrParentMatches(rel); !included {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn for every file and directory under the watched host paths
+// that wasn't excluded from the sync. Directories are always visited, since
+// they may contain included files.
+func (w *hostWatcher) walk(fn func(path string, d fs.DirEntry) error) error {
+	w.mu.Lock()
+	paths := append([]*watchedHostPath(nil), w.paths...)
+	w.mu.Unlock()
+
+	for _, p := range paths {
+		err := filepath.WalkDir(p.Path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					// deleted while walking; the change will be picked up anyway
+					return nil
+				}
+				return err
+			}
+			if path != p.Path {
+				rel, err := filepath.Rel(p.Path, path)
+				if err != nil {
+					return err
+				}
+				if p.excludes != nil {
+					if excluded, _ := p.excludes.MatchesOrParentMatches(rel); excluded {
+						if d.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+				}
+				if !d.IsDir() && p.ignored(rel) {
+					return nil
+				}
+			}
+			return fn(path, d)
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+type hostFileState struct {
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (w *hostWatcher) snapshot() (map[string]hostFileState, error) {
+	states := map[string]hostFileState{}
+	err := w.walk(func(path string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		states[path] = hostFileState{
+			size:    info.Size(),
+			mode:    info.Mode(),
+			modTime: info.ModTime(),
+		}
+		return nil
+	})
+	return states, err
+}
+
+// poll waits for changes by periodically scanning the watched host paths.
+func (w *hostWatcher) poll(ctx context.Context) error {
+	prev, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	if w.watching != nil {
+		w.watching()
+	}
+
+	changed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		}
+		cur, err := w.snapshot()
+		if err != nil {
+			return err
+		}
+		if !hostSnapshotsEqual(prev, cur) {
+			changed = true
+			prev = cur
+			continue
+		}
+		if changed {
+			// quiet for a full interval since the last change
+			return nil
+		}
+	}
+}
+
+func hostSnapshotsEqual(a, b map[string]hostFileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, st := range a {
+		other, ok := b[path]
+		if !ok || other.size != st.size || other.mode != st.mode || !other.modTime.Equal(st.modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/dagger/dagger/engine/slog"
+)
+
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// waitForEvents waits for changes using inotify, watching every directory
+// under the watched host paths.
+func (w *hostWatcher) waitForEvents(ctx context.Context) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		slog.Debug("inotify unavailable, polling for changes", "error", err)
+		return errHostEventsUnsupported
+	}
+	defer unix.Close(fd)
+
+	dirs := map[int]string{}
+	watched := map[string]struct{}{}
+	err = w.walk(func(path string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			// watch the parent, which also catches files being replaced
+			path = filepath.Dir(path)
+		}
+		if _, ok := watched[path]; ok {
+			return nil
+		}
+		watched[path] = struct{}{}
+		wd, err := unix.InotifyAddWatch(fd, path, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		dirs[wd] = path
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, unix.ENOSPC) {
+			slog.Warn("too many directories for inotify (see fs.inotify.max_user_watches), polling for changes")
+			return errHostEventsUnsupported
+		}
+		return err
+	}
+
+	if w.watching != nil {
+		w.watching()
+	}
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	pollTimeout := max(1, int(w.debounce/time.Millisecond/2))
+	var lastChange time.Time
+	for {
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, pollTimeout)
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return fmt.Errorf("poll inotify: %w", err)
+		}
+		if n == 0 {
+			if !lastChange.IsZero() && time.Since(lastChange) >= w.debounce {
+				return nil
+			}
+			continue
+		}
+
+		size, err := unix.Read(fd, buf)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return fmt.Errorf("read inotify: %w", err)
+		}
+		for offset := 0; offset+unix.SizeofInotifyEvent <= size; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// events were dropped, so assume something relevant changed
+				lastChange = time.Now()
+				continue
+			}
+			dir, ok := dirs[int(event.Wd)]
+			if !ok {
+				continue
+			}
+			path := dir
+			if event.Len > 0 {
+				path = filepath.Join(dir, strings.TrimRight(string(buf[nameStart:offset]), "\x00"))
+			}
+			if w.relevant(path) {
+				lastChange = time.Now()
+			}
+		}
+	}
+}
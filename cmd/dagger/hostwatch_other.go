@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "context"
+
+func (w *hostWatcher) waitForEvents(context.Context) error {
+	return errHostEventsUnsupported
+}
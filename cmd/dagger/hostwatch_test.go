@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine/client"
+)
+
+func TestHostWatcherRelevant(t *testing.T) {
+	dir := t.TempDir()
+	w := newHostWatcher()
+	w.Add(client.HostPathSync{
+		Path:            filepath.Join(dir, "src"),
+		IncludePatterns: []string{"*.go", "go.mod", "pkg"},
+		ExcludePatterns: []string{"pkg/testdata"},
+	})
+	w.Add(client.HostPathSync{Path: filepath.Join(dir, "dagger.json")})
+	// duplicate syncs are only recorded once
+	w.Add(client.HostPathSync{Path: filepath.Join(dir, "dagger.json")})
+	require.Equal(t, 2, w.Len())
+
+	for path, relevant := range map[string]bool{
+		"src":                          true,
+		"src/main.go":                  true,
+		"src/go.mod":                   true,
+		"src/README.md":                false,
+		"src/pkg/foo/foo.txt":          true,
+		"src/pkg/testdata/golden.json": false,
+		"dagger.json":                  true,
+		"README.md":                    false,
+		"../elsewhere/main.go":         false,
+	} {
+		require.Equal(t, relevant, w.relevant(filepath.Join(dir, path)), path)
+	}
+}
+
+func TestHostWatcherWait(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules"), 0o755))
+
+	w := newHostWatcher()
+	w.debounce = 10 * time.Millisecond
+	w.pollInterval = 10 * time.Millisecond
+	watching := make(chan struct{}, 1)
+	w.watching = func() { watching <- struct{}{} }
+	w.Add(client.HostPathSync{
+		Path:            dir,
+		ExcludePatterns: []string{"node_modules"},
+	})
+
+	// start waits for changes in the background, once the watcher is ready
+	start := func(t *testing.T, wait func(*hostWatcher, context.Context) error, ctx context.Context) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- wait(w, ctx) }()
+		select {
+		case <-watching:
+		case err := <-done:
+			t.Fatalf("stopped before watching: %v", err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the watcher to start")
+		}
+		return done
+	}
+	result := func(t *testing.T, done <-chan error) error {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return nil
+		}
+	}
+
+	for name, wait := range map[string]func(*hostWatcher, context.Context) error{
+		"events": (*hostWatcher).Wait,
+		"poll":   (*hostWatcher).poll,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// excluded files are ignored, long enough to have been picked up
+			ctx, cancel := context.WithTimeout(t.Context(), 20*w.pollInterval)
+			done := start(t, wait, ctx)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "dep.js"), []byte("x"), 0o600))
+			require.ErrorIs(t, result(t, done), context.DeadlineExceeded)
+			cancel()
+
+			done = start(t, wait, t.Context())
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "main.go"), []byte("package main"), 0o600))
+			require.NoError(t, result(t, done))
+
+			require.NoError(t, os.Remove(filepath.Join(dir, "sub", "main.go")))
+			require.NoError(t, os.Remove(filepath.Join(dir, "node_modules", "dep.js")))
+		})
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/dagger/dagger/dagql/dagui"
 	"github.com/dagger/dagger/dagql/idtui"
 	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/client/drivers"
 	"github.com/dagger/dagger/engine/client/pathutil"
 	"github.com/dagger/dagger/engine/slog"
 	enginetel "github.com/dagger/dagger/engine/telemetry"
@@ -70,11 +71,20 @@ var (
 	noExit                   bool
 	_, useCloudEngine        = os.LookupEnv("DAGGER_CLOUD_ENGINE")
 	enableScaleOut           bool
+	watchMode                bool
+	offline                  bool
 
 	dotOutputFilePath string
 	dotFocusField     string
 	dotShowInternal   bool
 
+	reportHTMLPath string
+
+	analyzeFlag               bool
+	chromeTraceOutputFilePath string
+
+	runnerTLS drivers.TLSConfig
+
 	stdoutIsTTY = isatty.IsTerminal(os.Stdout.Fd())
 	stderrIsTTY = isatty.IsTerminal(os.Stderr.Fd())
 
@@ -135,6 +145,7 @@ func init() {
 
 	rootCmd.AddCommand(
 		listenCmd,
+		serveCmd,
 		versionCmd(),
 		queryCmd,
 		runCmd,
@@ -149,6 +160,7 @@ func init() {
 		moduleDevelopCmd,
 		modulePublishCmd,
 		toolchainCmd,
+		moduleCmd,
 		funcListCmd,
 		callCoreCmd.Command(),
 		callModCmd.Command(),
@@ -156,6 +168,8 @@ func init() {
 		newGenCmd(),
 		shellCmd,
 		clientCmd,
+		cacheCmd,
+		runsCmd,
 		mcpCmd,
 	)
 
@@ -232,16 +246,20 @@ var rootCmd = &cobra.Command{
 		}
 
 		labels := enginetel.LoadDefaultLabels(workdir, engine.Version)
-		t := analytics.New(analytics.DefaultConfig(labels))
+		analyticsCfg := analytics.DefaultConfig(labels)
+		analyticsCfg.DoNotTrack = analyticsCfg.DoNotTrack || offline
+		t := analytics.New(analyticsCfg)
 		cmd.SetContext(analytics.WithContext(cmd.Context(), t))
 		cobra.OnFinalize(func() {
 			t.Close()
 		})
 
-		checkForUpdates(cmd.Context(), cmd.ErrOrStderr())
+		if !offline {
+			checkForUpdates(cmd.Context(), cmd.ErrOrStderr())
 
-		if err := checkCloudToken(cmd.Context(), cmd.OutOrStdout()); err != nil {
-			return err
+			if err := checkCloudToken(cmd.Context(), cmd.OutOrStdout()); err != nil {
+				return err
+			}
 		}
 
 		t.Capture(cmd.Context(), "cli_command", map[string]string{
@@ -332,17 +350,23 @@ func installGlobalFlags(flags *pflag.FlagSet) {
 	flags.CountVarP(&quiet, "quiet", "q", "Reduce verbosity (show progress, but clean up at the end)")
 	flags.BoolVarP(&silent, "silent", "s", silent, "Do not show progress at all")
 	flags.BoolVarP(&debugFlag, "debug", "d", debugFlag, "Show debug logs and full verbosity")
-	flags.StringVar(&progress, "progress", "auto", "Progress output format (auto, plain, tty, dots)")
+	flags.StringVar(&progress, "progress", "auto", "Progress output format (auto, plain, tty, dots, json)")
 	flags.BoolVarP(&interactive, "interactive", "i", false, "Spawn a terminal on container exec failure")
 	flags.StringVar(&interactiveCommand, "interactive-command", "/bin/sh", "Change the default command for interactive mode")
 	flags.BoolVarP(&web, "web", "w", false, "Open trace URL in a web browser")
 	flags.BoolVarP(&noExit, "no-exit", "E", false, "Leave the TUI running after completion")
 	flags.BoolVarP(&autoApply, "auto-apply", "y", false, "Automatically apply changes when a changeset is returned")
+	flags.BoolVar(&offline, "offline", false, "Serve images, git repositories, HTTP resources and modules from the cache, failing on anything uncached")
 
 	flags.StringVar(&dotOutputFilePath, "dot-output", "", "If set, write the calls made during execution to a dot file at the given path before exiting")
 	flags.StringVar(&dotFocusField, "dot-focus-field", "", "In dot output, filter out vertices that aren't this field or descendents of this field")
 	flags.BoolVar(&dotShowInternal, "dot-show-internal", false, "In dot output, if true then include calls and spans marked as internal")
 
+	flags.StringVar(&reportHTMLPath, "report-html", "", "If set, write a self-contained HTML report of the run to the given path before exiting")
+
+	flags.BoolVar(&analyzeFlag, "analyze", false, "Print the critical path, time spent waiting on dependencies, cache hit ratios and idle engine time after execution (always printed with --progress=report)")
+	flags.StringVar(&chromeTraceOutputFilePath, "chrome-trace-output", "", "If set, write the spans of the run to a Chrome trace file (for Perfetto or chrome://tracing) at the given path before exiting")
+
 	// this flag changes the behaviour of a few commands, e.g. call, functions, core, shell, etc.
 	// all those functions will run in a remote cloud engine which gets created at execution time
 	flags.BoolVar(&useCloudEngine, "cloud", useCloudEngine, "Run in a Dagger Cloud Engine")
@@ -352,6 +376,12 @@ func installGlobalFlags(flags *pflag.FlagSet) {
 	flags.BoolVar(&enableScaleOut, "scale-out", false, "Enable scale-out to cloud engines for each check or generate executed")
 	flags.Lookup("scale-out").Hidden = true
 
+	// these flags configure the tcp+tls:// driver, falling back to
+	// _EXPERIMENTAL_DAGGER_RUNNER_TLS{CACERT,CERT,KEY} when unset
+	flags.StringVar(&runnerTLS.CACert, "tlscacert", "", "Verify a tcp+tls:// engine's certificate with this CA certificate")
+	flags.StringVar(&runnerTLS.Cert, "tlscert", "", "Client certificate to present to a tcp+tls:// engine")
+	flags.StringVar(&runnerTLS.Key, "tlskey", "", "Client key to present to a tcp+tls:// engine")
+
 	for _, fl := range []string{
 		"workdir",
 		"dot-output",
@@ -429,6 +459,8 @@ func main() {
 	opts.DotOutputFilePath = dotOutputFilePath
 	opts.DotFocusField = dotFocusField
 	opts.DotShowInternal = dotShowInternal
+	opts.Analyze = analyzeFlag
+	opts.ChromeTraceOutputFilePath = chromeTraceOutputFilePath
 	opts.UsingCloudEngine = useCloudEngine || strings.HasPrefix(RunnerHost, engine.CloudRunnerHostPrefix)
 	if progress == "auto" {
 		if env := os.Getenv("DAGGER_PROGRESS"); env != "" {
@@ -458,6 +490,8 @@ func main() {
 		Frontend = idtui.NewDots(stderr)
 	case "report":
 		Frontend = idtui.NewReporter(stderr)
+	case "json":
+		Frontend = idtui.NewJSON(stderr)
 	default:
 		fmt.Fprintf(stderr, "unknown progress type %q\n", progress)
 		os.Exit(1)
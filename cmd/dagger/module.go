@@ -155,6 +155,7 @@ func init() {
 
 	modulePublishCmd.Flags().BoolVarP(&force, "force", "f", false, "Force publish even if the git repository is not clean")
 	modulePublishCmd.Flags().StringVarP(&moduleURL, "mod", "m", "", "Module reference to publish, remote git repo (defaults to current directory)")
+	modulePublishCmd.Flags().StringVar(&publishOCIAddress, "oci", "", "Publish the module as an OCI artifact to the given registry address instead of the Daggerverse")
 
 	moduleInstallCmd.Flags().StringVarP(&installName, "name", "n", "", "Name to use for the dependency in the module. Defaults to the name of the module being installed.")
 
@@ -334,7 +335,7 @@ dagger init --sdk=go
 			}
 
 			// Export generated files, including dagger.json
-			_, err = modSrc.GeneratedContextDirectory().Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to generate code: %w", err)
 			}
@@ -363,8 +364,8 @@ var moduleInstallCmd = &cobra.Command{
 	Use:     "install [options] <module>",
 	Aliases: []string{"use"},
 	Short:   "Install a dependency",
-	Long:    "Install another module as a dependency to the current module.",
-	Example: "dagger install github.com/shykes/daggerverse/hello@v0.3.0",
+	Long:    "Install another module as a dependency to the current module.\n\nThe version may be a semver constraint (e.g. ^0.3), resolved to the highest matching tag.\n\nModules published as OCI artifacts are installed by their registry ref, and pinned by digest.",
+	Example: `"dagger install github.com/shykes/daggerverse/hello@v0.3.0" or "dagger install github.com/shykes/daggerverse/hello@^0.3" or "dagger install registry.example.com/mods/hello:0.3.0"`,
 	GroupID: moduleGroup.ID,
 	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, extraArgs []string) (rerr error) {
@@ -412,9 +413,7 @@ var moduleInstallCmd = &cobra.Command{
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to update dependencies: %w", err)
 			}
@@ -483,6 +482,9 @@ var moduleUpdateCmd = &cobra.Command{
 
 To update only specific dependencies, specify their short names or a complete address.
 
+Dependencies installed with a semver constraint (e.g. github.com/org/mod@^1.4) are
+updated to the highest version satisfying it.
+
 If no dependency is specified, all dependencies are updated, as well as the module's blueprint, if it exists.
 `,
 	Example: `"dagger update" or "dagger update hello" "dagger update github.com/shykes/daggerverse/hello@v0.3.0"`,
@@ -523,9 +525,7 @@ If no dependency is specified, all dependencies are updated, as well as the modu
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to update dependencies: %w", err)
 			}
@@ -573,9 +573,7 @@ var moduleUnInstallCmd = &cobra.Command{
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to update dependencies: %w", err)
 			}
@@ -738,9 +736,7 @@ This command is idempotent: you can run it at any time, any number of times. It
 					if err != nil {
 						return fmt.Errorf("failed to get local context directory path: %w", err)
 					}
-					_, err = modSrc.
-						GeneratedContextDirectory().
-						Export(ctx, contextDirPath)
+					err = exportModuleSource(ctx, modSrc, contextDirPath)
 					if err != nil {
 						return fmt.Errorf("failed to generate code: %w", err)
 					}
@@ -816,9 +812,7 @@ var toolchainInstallCmd = &cobra.Command{
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to install toolchain: %w", err)
 			}
@@ -866,9 +860,7 @@ var toolchainUpdateCmd = &cobra.Command{
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to update toolchains: %w", err)
 			}
@@ -919,9 +911,7 @@ var toolchainUninstallCmd = &cobra.Command{
 				modSrc = modSrc.WithEngineVersion(engineVersion)
 			}
 
-			_, err = modSrc.
-				GeneratedContextDirectory().
-				Export(ctx, contextDirPath)
+			err = exportModuleSource(ctx, modSrc, contextDirPath)
 			if err != nil {
 				return fmt.Errorf("failed to uninstall toolchain: %w", err)
 			}
@@ -1063,6 +1053,10 @@ var modulePublishCmd = &cobra.Command{
 The module needs to be committed to a git repository and have a remote
 configured with name "origin". The git repository must be clean (unless
 forced), to avoid mistakenly depending on uncommitted files.
+
+With --oci, the module source and its resolved dagger.json are instead pushed
+as an OCI artifact to the given registry address, and the published ref is
+printed pinned by digest. The module's dependencies must all be remote.
 `,
 		daDaggerverse,
 	),
@@ -1091,6 +1085,10 @@ forced), to avoid mistakenly depending on uncommitted files.
 				return fmt.Errorf("module must be fully initialized")
 			}
 
+			if publishOCIAddress != "" {
+				return publishModuleOCI(ctx, cmd, dag, modSrc, publishOCIAddress)
+			}
+
 			contextDirPath, err := modSrc.LocalContextDirectoryPath(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to get local context directory path: %w", err)
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/juju/ansiterm/tabwriter"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/dagql/idtui"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/dagger/dagger/util/parallel"
+)
+
+//go:embed modapidiff.graphql
+var moduleAPIQuery string
+
+var moduleAPIDiffCmd = &cobra.Command{
+	Use:   "api-diff <old-ref> <new-ref>",
+	Short: "Compare the API of two versions of a module",
+	Long: `Compare the objects, interfaces and enums of two versions of a module, and
+classify each change as breaking or compatible for callers of the module.
+
+Removed types, functions, fields, arguments and enum values are breaking, as
+are new required arguments, changed types, and new functions on interfaces.
+
+Exits with a non-zero status if any change is breaking.
+`,
+	Example: `"dagger module api-diff github.com/org/mods/foo@v1.2.0 ./foo"`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngine(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+
+			var oldAPI, newAPI *moduleAPI
+			err = parallel.New().
+				WithJob("load "+args[0], func(ctx context.Context) (err error) {
+					oldAPI, err = loadModuleAPI(ctx, dag, args[0])
+					return err
+				}).
+				WithJob("load "+args[1], func(ctx context.Context) (err error) {
+					newAPI, err = loadModuleAPI(ctx, dag, args[1])
+					return err
+				}).
+				Run(ctx)
+			if err != nil {
+				return err
+			}
+
+			changes := diffModuleAPI(oldAPI, newAPI)
+			if err := printAPIChanges(cmd.OutOrStdout(), changes); err != nil {
+				return err
+			}
+
+			var breaking int
+			for _, change := range changes {
+				if change.Breaking {
+					breaking++
+				}
+			}
+			if breaking > 0 {
+				return idtui.ExitError{Code: 1, Original: fmt.Errorf("%d breaking changes", breaking)}
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	moduleCmd.AddCommand(moduleAPIDiffCmd)
+}
+
+// moduleAPI is the set of type definitions a module exposes to its callers.
+type moduleAPI struct {
+	Name       string
+	Objects    []*modTypeDef
+	Interfaces []*modTypeDef
+	Enums      []*modTypeDef
+}
+
+func loadModuleAPI(ctx context.Context, dag *dagger.Client, ref string) (*moduleAPI, error) {
+	var res struct {
+		Source struct {
+			Module *moduleAPI
+		}
+	}
+	err := dag.Do(ctx, &dagger.Request{
+		Query: moduleAPIQuery,
+		Variables: map[string]any{
+			"ref": ref,
+		},
+	}, &dagger.Response{Data: &res})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load module %q: %w", ref, err)
+	}
+	return res.Source.Module, nil
+}
+
+// apiChange is a single difference between two versions of a module's API.
+type apiChange struct {
+	// Path locates the change, e.g. Foo.bar(baz) for the baz argument of
+	// function bar on object Foo
+	Path     string
+	Message  string
+	Breaking bool
+}
+
+func diffModuleAPI(oldAPI, newAPI *moduleAPI) []apiChange {
+	d := &apiDiff{}
+
+	oldObjects := typeDefsByName(oldAPI.Objects)
+	newObjects := typeDefsByName(newAPI.Objects)
+	for name, oldDef := range oldObjects {
+		newDef, ok := newObjects[name]
+		if !ok {
+			d.breaking(name, "object removed")
+			continue
+		}
+		d.diffObject(oldDef.AsObject, newDef.AsObject)
+	}
+	for name := range newObjects {
+		if _, ok := oldObjects[name]; !ok {
+			d.compatible(name, "object added")
+		}
+	}
+
+	oldIfaces := typeDefsByName(oldAPI.Interfaces)
+	newIfaces := typeDefsByName(newAPI.Interfaces)
+	for name, oldDef := range oldIfaces {
+		newDef, ok := newIfaces[name]
+		if !ok {
+			d.breaking(name, "interface removed")
+			continue
+		}
+		d.diffFunctions(name, oldDef.AsInterface.Functions, newDef.AsInterface.Functions, true)
+	}
+	for name := range newIfaces {
+		if _, ok := oldIfaces[name]; !ok {
+			d.compatible(name, "interface added")
+		}
+	}
+
+	oldEnums := typeDefsByName(oldAPI.Enums)
+	newEnums := typeDefsByName(newAPI.Enums)
+	for name, oldDef := range oldEnums {
+		newDef, ok := newEnums[name]
+		if !ok {
+			d.breaking(name, "enum removed")
+			continue
+		}
+		d.diffEnum(oldDef.AsEnum, newDef.AsEnum)
+	}
+	for name := range newEnums {
+		if _, ok := oldEnums[name]; !ok {
+			d.compatible(name, "enum added")
+		}
+	}
+
+	slices.SortFunc(d.changes, func(a, b apiChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return d.changes
+}
+
+type apiDiff struct {
+	changes []apiChange
+}
+
+func (d *apiDiff) breaking(path, msg string, args ...any) {
+	d.changes = append(d.changes, apiChange{Path: path, Message: fmt.Sprintf(msg, args...), Breaking: true})
+}
+
+func (d *apiDiff) compatible(path, msg string, args ...any) {
+	d.changes = append(d.changes, apiChange{Path: path, Message: fmt.Sprintf(msg, args...)})
+}
+
+func (d *apiDiff) diffObject(oldObj, newObj *modObject) {
+	switch {
+	case oldObj.Constructor != nil && newObj.Constructor == nil:
+		d.breaking(oldObj.Name, "constructor removed")
+	case oldObj.Constructor == nil && newObj.Constructor != nil:
+		d.compatible(oldObj.Name, "constructor added")
+	case oldObj.Constructor != nil:
+		d.diffArgs(oldObj.Name, oldObj.Constructor.Args, newObj.Constructor.Args)
+	}
+	// fields are called like functions without arguments, so a field can
+	// become a function (and vice versa) without breaking callers
+	d.diffFunctions(oldObj.Name, oldObj.GetFunctions(), newObj.GetFunctions(), false)
+}
+
+// diffFunctions compares the functions of an object or interface. New
+// functions on an interface break the types implementing it.
+func (d *apiDiff) diffFunctions(parent string, oldFns, newFns []*modFunction, iface bool) {
+	oldByName := functionsByName(oldFns)
+	newByName := functionsByName(newFns)
+	for name, oldFn := range oldByName {
+		path := parent + "." + name
+		newFn, ok := newByName[name]
+		if !ok {
+			d.breaking(path, "function removed")
+			continue
+		}
+		d.diffReturnType(path, oldFn.ReturnType, newFn.ReturnType)
+		d.diffArgs(path, oldFn.Args, newFn.Args)
+	}
+	for name := range newByName {
+		if _, ok := oldByName[name]; ok {
+			continue
+		}
+		if iface {
+			d.breaking(parent+"."+name, "function added to interface")
+		} else {
+			d.compatible(parent+"."+name, "function added")
+		}
+	}
+}
+
+func (d *apiDiff) diffReturnType(path string, oldType, newType *modTypeDef) {
+	if oldType.String() != newType.String() {
+		d.breaking(path, "return type changed from %s to %s", oldType, newType)
+		return
+	}
+	switch {
+	case !oldType.Optional && newType.Optional:
+		d.breaking(path, "return type %s is now optional", newType)
+	case oldType.Optional && !newType.Optional:
+		d.compatible(path, "return type %s is no longer optional", newType)
+	}
+	if oldType.AsList == nil || newType.AsList == nil {
+		return
+	}
+	oldElem, newElem := oldType.AsList.ElementTypeDef, newType.AsList.ElementTypeDef
+	switch {
+	case !oldElem.Optional && newElem.Optional:
+		d.breaking(path, "elements of return type %s are now optional", newType)
+	case oldElem.Optional && !newElem.Optional:
+		d.compatible(path, "elements of return type %s are no longer optional", newType)
+	}
+}
+
+func (d *apiDiff) diffArgs(parent string, oldArgs, newArgs []*modFunctionArg) {
+	oldByName := argsByName(oldArgs)
+	newByName := argsByName(newArgs)
+	for name, oldArg := range oldByName {
+		path := parent + "(" + name + ")"
+		newArg, ok := newByName[name]
+		if !ok {
+			d.breaking(path, "argument removed")
+			continue
+		}
+		if oldArg.TypeDef.String() != newArg.TypeDef.String() {
+			d.breaking(path, "argument type changed from %s to %s", oldArg.TypeDef, newArg.TypeDef)
+			continue
+		}
+		switch {
+		case !oldArg.IsRequired() && newArg.IsRequired():
+			d.breaking(path, "argument is now required")
+		case oldArg.IsRequired() && !newArg.IsRequired():
+			d.compatible(path, "argument is now optional")
+		}
+		if oldArg.TypeDef.AsList == nil || newArg.TypeDef.AsList == nil {
+			continue
+		}
+		oldElem, newElem := oldArg.TypeDef.AsList.ElementTypeDef, newArg.TypeDef.AsList.ElementTypeDef
+		switch {
+		case oldElem.Optional && !newElem.Optional:
+			d.breaking(path, "argument elements are no longer optional")
+		case !oldElem.Optional && newElem.Optional:
+			d.compatible(path, "argument elements are now optional")
+		}
+	}
+	for name, newArg := range newByName {
+		if _, ok := oldByName[name]; ok {
+			continue
+		}
+		path := parent + "(" + name + ")"
+		if newArg.IsRequired() {
+			d.breaking(path, "required argument added")
+		} else {
+			d.compatible(path, "optional argument added")
+		}
+	}
+}
+
+func (d *apiDiff) diffEnum(oldEnum, newEnum *modEnum) {
+	oldValues := oldEnum.ValueNames()
+	newValues := newEnum.ValueNames()
+	for _, value := range oldValues {
+		if !slices.Contains(newValues, value) {
+			d.breaking(oldEnum.Name+"."+value, "enum value removed")
+		}
+	}
+	for _, value := range newValues {
+		if !slices.Contains(oldValues, value) {
+			d.compatible(oldEnum.Name+"."+value, "enum value added")
+		}
+	}
+}
+
+func typeDefsByName(defs []*modTypeDef) map[string]*modTypeDef {
+	byName := make(map[string]*modTypeDef, len(defs))
+	for _, def := range defs {
+		switch {
+		case def.AsObject != nil:
+			byName[def.AsObject.Name] = def
+		case def.AsInterface != nil:
+			byName[def.AsInterface.Name] = def
+		case def.AsEnum != nil:
+			byName[def.AsEnum.Name] = def
+		}
+	}
+	return byName
+}
+
+func functionsByName(fns []*modFunction) map[string]*modFunction {
+	byName := make(map[string]*modFunction, len(fns))
+	for _, fn := range fns {
+		byName[fn.Name] = fn
+	}
+	return byName
+}
+
+func argsByName(args []*modFunctionArg) map[string]*modFunctionArg {
+	byName := make(map[string]*modFunctionArg, len(args))
+	for _, arg := range args {
+		byName[arg.Name] = arg
+	}
+	return byName
+}
+
+func printAPIChanges(w io.Writer, changes []apiChange) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No API changes")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', tabwriter.DiscardEmptyColumns)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n",
+		termenv.String("Change").Bold(),
+		termenv.String("Path").Bold(),
+		termenv.String("Description").Bold(),
+	)
+	for _, change := range changes {
+		kind := termenv.String("compatible").Foreground(termenv.ANSIGreen)
+		if change.Breaking {
+			kind = termenv.String("breaking").Foreground(termenv.ANSIRed)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", kind, change.Path, change.Message)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"dagger.io/dagger"
+)
+
+func TestDiffModuleAPI(t *testing.T) {
+	str := func() *modTypeDef { return &modTypeDef{Kind: dagger.TypeDefKindStringKind} }
+	optStr := func() *modTypeDef { return &modTypeDef{Kind: dagger.TypeDefKindStringKind, Optional: true} }
+	integer := func() *modTypeDef { return &modTypeDef{Kind: dagger.TypeDefKindIntegerKind} }
+	list := func(elem *modTypeDef) *modTypeDef {
+		return &modTypeDef{Kind: dagger.TypeDefKindListKind, AsList: &modList{ElementTypeDef: elem}}
+	}
+	enum := func(name string, values ...string) *modTypeDef {
+		members := make([]*modEnumMember, 0, len(values))
+		for _, v := range values {
+			members = append(members, &modEnumMember{Name: v})
+		}
+		return &modTypeDef{Kind: dagger.TypeDefKindEnumKind, AsEnum: &modEnum{Name: name, Members: members}}
+	}
+
+	oldAPI := &moduleAPI{
+		Name: "foo",
+		Objects: []*modTypeDef{{
+			Kind: dagger.TypeDefKindObjectKind,
+			AsObject: &modObject{
+				Name: "Foo",
+				Constructor: &modFunction{
+					Args: []*modFunctionArg{{Name: "source", TypeDef: str()}},
+				},
+				Fields: []*modField{{Name: "version", TypeDef: str()}},
+				Functions: []*modFunction{
+					{Name: "build", ReturnType: str(), Args: []*modFunctionArg{
+						{Name: "target", TypeDef: str()},
+						{Name: "jobs", TypeDef: optStr()},
+						{Name: "tags", TypeDef: list(optStr())},
+					}},
+					{Name: "test", ReturnType: str()},
+					{Name: "files", ReturnType: list(str())},
+					{Name: "lint", ReturnType: optStr()},
+					{Name: "count", ReturnType: integer()},
+				},
+			},
+		}},
+		Interfaces: []*modTypeDef{{
+			Kind: dagger.TypeDefKindInterfaceKind,
+			AsInterface: &modInterface{
+				Name:      "FooBuilder",
+				Functions: []*modFunction{{Name: "build", ReturnType: str()}},
+			},
+		}},
+		Enums: []*modTypeDef{enum("FooLevel", "LOW", "HIGH")},
+	}
+
+	newAPI := &moduleAPI{
+		Name: "foo",
+		Objects: []*modTypeDef{{
+			Kind: dagger.TypeDefKindObjectKind,
+			AsObject: &modObject{
+				Name: "Foo",
+				Constructor: &modFunction{
+					Args: []*modFunctionArg{
+						{Name: "source", TypeDef: optStr()},
+						{Name: "platform", TypeDef: str()},
+					},
+				},
+				Functions: []*modFunction{
+					{Name: "version", ReturnType: str()},
+					{Name: "build", ReturnType: str(), Args: []*modFunctionArg{
+						{Name: "jobs", TypeDef: str()},
+						{Name: "verbose", TypeDef: optStr()},
+						{Name: "tags", TypeDef: list(str())},
+						{Name: "context", TypeDef: str(), DefaultPath: "."},
+					}},
+					{Name: "files", ReturnType: list(optStr())},
+					{Name: "lint", ReturnType: str()},
+					{Name: "count", ReturnType: str()},
+					{Name: "publish", ReturnType: str()},
+				},
+			},
+		}},
+		Interfaces: []*modTypeDef{{
+			Kind: dagger.TypeDefKindInterfaceKind,
+			AsInterface: &modInterface{
+				Name: "FooBuilder",
+				Functions: []*modFunction{
+					{Name: "build", ReturnType: str()},
+					{Name: "clean", ReturnType: str()},
+				},
+			},
+		}},
+		Enums: []*modTypeDef{enum("FooLevel", "LOW", "MEDIUM"), enum("FooMode", "FAST")},
+	}
+
+	require.Equal(t, []apiChange{
+		{Path: "Foo(platform)", Message: "required argument added", Breaking: true},
+		{Path: "Foo(source)", Message: "argument is now optional"},
+		{Path: "Foo.build(context)", Message: "optional argument added"},
+		{Path: "Foo.build(jobs)", Message: "argument is now required", Breaking: true},
+		{Path: "Foo.build(tags)", Message: "argument elements are no longer optional", Breaking: true},
+		{Path: "Foo.build(target)", Message: "argument removed", Breaking: true},
+		{Path: "Foo.build(verbose)", Message: "optional argument added"},
+		{Path: "Foo.count", Message: "return type changed from int to string", Breaking: true},
+		{Path: "Foo.files", Message: "elements of return type []string are now optional", Breaking: true},
+		{Path: "Foo.lint", Message: "return type string is no longer optional"},
+		{Path: "Foo.publish", Message: "function added"},
+		{Path: "Foo.test", Message: "function removed", Breaking: true},
+		{Path: "FooBuilder.clean", Message: "function added to interface", Breaking: true},
+		{Path: "FooLevel.HIGH", Message: "enum value removed", Breaking: true},
+		{Path: "FooLevel.MEDIUM", Message: "enum value added"},
+		{Path: "FooMode", Message: "enum added"},
+	}, diffModuleAPI(oldAPI, newAPI))
+
+	require.Empty(t, diffModuleAPI(oldAPI, oldAPI))
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/juju/ansiterm/tabwriter"
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/dagger/dagger/engine/slog"
+	"github.com/dagger/dagger/util/parallel"
+)
+
+var depsTree bool
+
+var moduleCmd = &cobra.Command{
+	Use:     "module",
+	Short:   "Inspect and manage a module",
+	GroupID: moduleGroup.ID,
+	Annotations: map[string]string{
+		"experimental": "true",
+	},
+}
+
+var moduleDepsCmd = &cobra.Command{
+	Use:   "deps [options]",
+	Short: "List a module's dependencies",
+	Long: `List the transitive dependencies of a module, as they are resolved.
+
+Git modules required at different commits by different dependents are marked
+as conflicting.
+`,
+	Example: `"dagger module deps" or "dagger module deps --tree"`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, extraArgs []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngine(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			modRef, err := getModuleSourceRefWithDefault()
+			if err != nil {
+				return err
+			}
+			modSrc := dag.ModuleSource(modRef)
+
+			var root *moduleDep
+			err = parallel.Run(ctx, "load dependencies", func(ctx context.Context) error {
+				alreadyExists, err := modSrc.ConfigExists(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to check if module already exists: %w", err)
+				}
+				if !alreadyExists {
+					return fmt.Errorf("module must be fully initialized")
+				}
+				root, err = loadModuleDep(ctx, modSrc, map[string]*moduleDep{})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if depsTree {
+				return printModuleDepsTree(cmd.OutOrStdout(), root)
+			}
+			return printModuleDepsList(cmd.OutOrStdout(), root)
+		})
+	},
+}
+
+func init() {
+	moduleDepsCmd.Flags().BoolVar(&depsTree, "tree", false, "Show dependencies as a tree")
+	moduleAddFlags(moduleDepsCmd, moduleDepsCmd.Flags(), false)
+
+	moduleCmd.AddCommand(moduleDepsCmd)
+}
+
+// moduleDep is a module in a dependency graph.
+type moduleDep struct {
+	name string
+	// source is the module's ref without any version
+	source  string
+	version string
+	commit  string
+	local   bool
+	deps    []*moduleDep
+}
+
+func (dep *moduleDep) String() string {
+	s := dep.name
+	if dep.local {
+		return s + " " + dep.source
+	}
+	s += " " + dep.source
+	if dep.version != "" {
+		s += "@" + dep.version
+	}
+	if dep.commit != "" && dep.commit != dep.version {
+		s += " (" + shortCommit(dep.commit) + ")"
+	}
+	return s
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// loadModuleDep loads the dependencies and toolchains of a module source
+// recursively. Git modules are only loaded once per commit.
+func loadModuleDep(ctx context.Context, src *dagger.ModuleSource, loaded map[string]*moduleDep) (*moduleDep, error) {
+	kind, err := src.Kind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, err := src.ModuleName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subpath, err := src.SourceRootSubpath(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dep := &moduleDep{name: name}
+	switch kind {
+	case dagger.ModuleSourceKindGitSource:
+		dep.source, err = src.CloneRef(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if subpath != "" && subpath != "." {
+			dep.source += "/" + strings.TrimPrefix(subpath, "/")
+		}
+		dep.version, err = src.Version(ctx)
+		if err != nil {
+			return nil, err
+		}
+		dep.commit, err = src.Commit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		key := dep.source + "@" + dep.commit
+		if existing, ok := loaded[key]; ok {
+			return existing, nil
+		}
+		loaded[key] = dep
+	default:
+		dep.local = true
+		dep.source = subpath
+	}
+
+	deps, err := src.Dependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	toolchains, err := src.Toolchains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, depSrc := range slices.Concat(deps, toolchains) {
+		child, err := loadModuleDep(ctx, &depSrc, loaded)
+		if err != nil {
+			return nil, err
+		}
+		dep.deps = append(dep.deps, child)
+	}
+	return dep, nil
+}
+
+// moduleDepConflicts returns the git module sources that are required at
+// different commits in the dependency graph.
+func moduleDepConflicts(root *moduleDep) map[string]bool {
+	lock := &modules.ModuleLock{}
+	visited := map[*moduleDep]bool{}
+	var walk func(parent *moduleDep, requiredBy string)
+	walk = func(parent *moduleDep, requiredBy string) {
+		if visited[parent] {
+			return
+		}
+		visited[parent] = true
+		for _, dep := range parent.deps {
+			if dep.local {
+				// local dependencies are part of the module itself
+				walk(dep, requiredBy)
+				continue
+			}
+			lock.Add(dep.source, dep.version, dep.commit, "", requiredBy)
+			walk(dep, dep.source)
+		}
+	}
+	walk(root, ".")
+
+	conflicts := map[string]bool{}
+	for _, conflict := range lock.Conflicts() {
+		conflicts[conflict.Source] = true
+	}
+	return conflicts
+}
+
+// exportModuleSource writes the module's generated files, including dagger.json
+// and dagger.lock, to its context directory on the host, and warns about any
+// conflicts between its dependencies recorded in the lock.
+func exportModuleSource(ctx context.Context, modSrc *dagger.ModuleSource, contextDirPath string) error {
+	if _, err := modSrc.GeneratedContextDirectory().Export(ctx, contextDirPath); err != nil {
+		return err
+	}
+	return warnModuleLockConflicts(ctx, modSrc, contextDirPath)
+}
+
+// warnModuleLockConflicts reports the conflicts recorded in the dagger.lock
+// exported for a module, i.e. git modules required at different commits.
+func warnModuleLockConflicts(ctx context.Context, modSrc *dagger.ModuleSource, contextDirPath string) error {
+	sourceRootSubpath, err := modSrc.SourceRootSubpath(ctx)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(filepath.Join(contextDirPath, sourceRootSubpath, modules.LockFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read module lock: %w", err)
+	}
+	lock, err := modules.ParseModuleLock(contents)
+	if err != nil {
+		return err
+	}
+	for _, conflict := range lock.Conflicts() {
+		slog.Warn("module dependency conflict: " + conflict.String())
+	}
+	return nil
+}
+
+func printModuleDepsTree(w io.Writer, root *moduleDep) error {
+	conflicts := moduleDepConflicts(root)
+	conflictMark := termenv.String(" [conflict]").Foreground(termenv.ANSIRed).String()
+
+	fmt.Fprintln(w, root.name)
+	var printDeps func(deps []*moduleDep, prefix string)
+	printDeps = func(deps []*moduleDep, prefix string) {
+		for i, dep := range deps {
+			branch, indent := "├── ", "│   "
+			if i == len(deps)-1 {
+				branch, indent = "└── ", "    "
+			}
+			line := prefix + branch + dep.String()
+			if conflicts[dep.source] {
+				line += conflictMark
+			}
+			fmt.Fprintln(w, line)
+			printDeps(dep.deps, prefix+indent)
+		}
+	}
+	printDeps(root.deps, "")
+	return nil
+}
+
+func printModuleDepsList(w io.Writer, root *moduleDep) error {
+	conflicts := moduleDepConflicts(root)
+
+	type entry struct {
+		dep        *moduleDep
+		requiredBy []string
+	}
+	var entries []*entry
+	byDep := map[*moduleDep]*entry{}
+	var walk func(parent *moduleDep, requiredBy string)
+	walk = func(parent *moduleDep, requiredBy string) {
+		for _, dep := range parent.deps {
+			if dep.local {
+				walk(dep, requiredBy)
+				continue
+			}
+			e, ok := byDep[dep]
+			if !ok {
+				e = &entry{dep: dep}
+				byDep[dep] = e
+				entries = append(entries, e)
+			}
+			if slices.Contains(e.requiredBy, requiredBy) {
+				continue
+			}
+			e.requiredBy = append(e.requiredBy, requiredBy)
+			walk(dep, dep.name)
+		}
+	}
+	walk(root, root.name)
+	slices.SortFunc(entries, func(a, b *entry) int {
+		return strings.Compare(a.dep.source+"@"+a.dep.version, b.dep.source+"@"+b.dep.version)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', tabwriter.DiscardEmptyColumns)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+		termenv.String("Source").Bold(),
+		termenv.String("Version").Bold(),
+		termenv.String("Commit").Bold(),
+		termenv.String("Required by").Bold(),
+	)
+	for _, e := range entries {
+		source := e.dep.source
+		if conflicts[source] {
+			source += termenv.String(" [conflict]").Foreground(termenv.ANSIRed).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+			source,
+			e.dep.version,
+			shortCommit(e.dep.commit),
+			strings.Join(e.requiredBy, ", "),
+		)
+	}
+	return tw.Flush()
+}
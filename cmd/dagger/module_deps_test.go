@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func TestPrintModuleDepsTree(t *testing.T) {
+	sharedOld := &moduleDep{name: "shared", source: "github.com/org/shared", version: "v1.4.0", commit: "1111111111111111"}
+	sharedNew := &moduleDep{name: "shared", source: "github.com/org/shared", version: "v1.6.0", commit: "2222222222222222"}
+	util := &moduleDep{name: "util", source: "github.com/org/util", version: "main", commit: "3333333333333333"}
+	root := &moduleDep{
+		name: "app",
+		deps: []*moduleDep{
+			{name: "a", source: "github.com/org/a", version: "v1.0.0", commit: "aaaa", deps: []*moduleDep{sharedOld, util}},
+			{name: "b", source: "github.com/org/b", version: "v2.0.0", commit: "bbbb", deps: []*moduleDep{sharedNew, util}},
+			{name: "lib", source: "lib", local: true},
+		},
+	}
+
+	var out strings.Builder
+	require.NoError(t, printModuleDepsTree(&out, root))
+	require.Equal(t, `app
+├── a github.com/org/a@v1.0.0 (aaaa)
+│   ├── shared github.com/org/shared@v1.4.0 (111111111111) [conflict]
+│   └── util github.com/org/util@main (333333333333)
+├── b github.com/org/b@v2.0.0 (bbbb)
+│   ├── shared github.com/org/shared@v1.6.0 (222222222222) [conflict]
+│   └── util github.com/org/util@main (333333333333)
+└── lib lib
+`, ansiEscape.ReplaceAllString(out.String(), ""))
+
+	out.Reset()
+	require.NoError(t, printModuleDepsList(&out, root))
+	lines := strings.Split(strings.TrimSpace(ansiEscape.ReplaceAllString(out.String(), "")), "\n")
+	require.Len(t, lines, 6)
+	require.Contains(t, lines[3], "github.com/org/shared [conflict]")
+	require.Contains(t, lines[3], "v1.4.0")
+	require.Contains(t, lines[4], "v1.6.0")
+	require.Contains(t, lines[5], "github.com/org/util")
+	require.Contains(t, lines[5], "a, b")
+}
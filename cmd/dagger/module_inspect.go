@@ -1045,7 +1045,7 @@ func (r *modFunctionArg) Long() string {
 }
 
 func (r *modFunctionArg) IsRequired() bool {
-	return !r.TypeDef.Optional && r.DefaultValue == ""
+	return !r.TypeDef.Optional && r.DefaultValue == "" && r.DefaultPath == ""
 }
 
 func (r *modFunctionArg) IsUnsupportedFlag() bool {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"dagger.io/dagger"
+)
+
+var publishOCIAddress string
+
+//go:embed modpublish.graphql
+var modulePublishQuery string
+
+// publishModuleOCI pushes the module source, with its resolved dagger.json, as
+// an OCI artifact and prints the published ref pinned by digest.
+func publishModuleOCI(ctx context.Context, cmd *cobra.Command, dag *dagger.Client, modSrc *dagger.ModuleSource, address string) error {
+	srcID, err := modSrc.ID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get module source id: %w", err)
+	}
+
+	var res struct {
+		Source struct {
+			Publish string
+		}
+	}
+	err = dag.Do(ctx, &dagger.Request{
+		Query: modulePublishQuery,
+		Variables: map[string]any{
+			"source":  srcID,
+			"address": address,
+		},
+	}, &dagger.Response{Data: &res})
+	if err != nil {
+		return fmt.Errorf("failed to publish module: %w", err)
+	}
+
+	cmd.Println(res.Source.Publish)
+	return nil
+}
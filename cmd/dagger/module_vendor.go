@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/engine/client"
+)
+
+var vendorPath string
+
+//go:embed modvendor.graphql
+var moduleVendorQuery string
+
+var moduleVendorCmd = &cobra.Command{
+	Use:   "vendor [options]",
+	Short: "Vendor a module's dependencies for offline use",
+	Long: `Copy the git dependencies, toolchains, blueprint and SDK of a module into a
+local directory, so that the module can be loaded without network access.
+
+The vendor directory is recorded in dagger.json, and vendored copies are then
+preferred over fetching, as long as they match the pinned commits. Builtin SDK
+images are exported as OCI tarballs.
+`,
+	Example: `"dagger module vendor" or "dagger module vendor --path third_party/dagger"`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, extraArgs []string) (rerr error) {
+		ctx := cmd.Context()
+		return withEngine(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) (err error) {
+			dag := engineClient.Dagger()
+			modRef, err := getModuleSourceRefWithDefault()
+			if err != nil {
+				return err
+			}
+			modSrc := dag.ModuleSource(modRef, dagger.ModuleSourceOpts{
+				// We can only vendor a local module
+				RequireKind: dagger.ModuleSourceKindLocalSource,
+			})
+
+			alreadyExists, err := modSrc.ConfigExists(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to check if module already exists: %w", err)
+			}
+			if !alreadyExists {
+				return fmt.Errorf("module must be fully initialized")
+			}
+			contextDirPath, err := modSrc.LocalContextDirectoryPath(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get local context directory path: %w", err)
+			}
+			srcRootSubpath, err := modSrc.SourceRootSubpath(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get source root subpath: %w", err)
+			}
+			srcRootPath := filepath.Join(contextDirPath, srcRootSubpath)
+
+			path := vendorPath
+			if path == "" {
+				cfgBytes, err := os.ReadFile(filepath.Join(srcRootPath, modules.Filename))
+				if err != nil {
+					return fmt.Errorf("failed to read module config: %w", err)
+				}
+				cfg, err := modules.ParseModuleConfig(cfgBytes)
+				if err != nil {
+					return err
+				}
+				path = cfg.Vendor
+			}
+			if path == "" {
+				path = modules.DefaultVendorDir
+			}
+
+			srcID, err := modSrc.ID(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get module source id: %w", err)
+			}
+			err = dag.Do(ctx, &dagger.Request{
+				Query: moduleVendorQuery,
+				Variables: map[string]any{
+					"source":         srcID,
+					"path":           path,
+					"contextDirPath": contextDirPath,
+				},
+			}, &dagger.Response{})
+			if err != nil {
+				return fmt.Errorf("failed to vendor module: %w", err)
+			}
+
+			// drop anything left over from previous runs
+			vendorDir := filepath.Join(srcRootPath, path)
+			manifestBytes, err := os.ReadFile(filepath.Join(vendorDir, modules.VendorManifestFilename))
+			if err != nil {
+				return fmt.Errorf("failed to read vendor manifest: %w", err)
+			}
+			manifest, err := modules.ParseVendorManifest(manifestBytes)
+			if err != nil {
+				return err
+			}
+			if err := pruneVendorDir(vendorDir, manifest); err != nil {
+				return fmt.Errorf("failed to prune vendor directory: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "vendored %d modules and %d SDKs in %s\n",
+				len(manifest.Modules), len(manifest.SDKs), filepath.Join(srcRootSubpath, path))
+			return nil
+		})
+	},
+}
+
+func init() {
+	moduleVendorCmd.Flags().StringVar(&vendorPath, "path", "", "Path of the vendor directory, relative to the module's dagger.json (default: the configured one, or "+modules.DefaultVendorDir+")")
+	moduleAddFlags(moduleVendorCmd, moduleVendorCmd.Flags(), false)
+
+	moduleCmd.AddCommand(moduleVendorCmd)
+}
+
+// pruneVendorDir removes everything from a vendor directory that isn't listed
+// in its manifest.
+func pruneVendorDir(dir string, manifest *modules.VendorManifest) error {
+	keep := []string{modules.VendorManifestFilename}
+	for _, mod := range manifest.Modules {
+		keep = append(keep, mod.Path)
+	}
+	for _, sdk := range manifest.SDKs {
+		keep = append(keep, sdk.Path)
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		for _, p := range keep {
+			switch {
+			case rel == p:
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			case strings.HasPrefix(p, rel+"/"):
+				// a parent of something to keep
+				return nil
+			}
+		}
+		if err := os.RemoveAll(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
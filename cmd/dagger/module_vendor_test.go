@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/core/modules"
+)
+
+func TestPruneVendorDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{
+		"modules.json",
+		"git/github.com/foo/bar/aaa/dagger.json",
+		"git/github.com/foo/bar/bbb/dagger.json",
+		"git/github.com/foo/baz/ccc/main.go",
+		"sdk/go.tar",
+		"sdk/python.tar",
+		"stray.txt",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, p), nil, 0o644))
+	}
+
+	err := pruneVendorDir(dir, &modules.VendorManifest{
+		Modules: []*modules.VendoredModule{
+			{Path: "git/github.com/foo/bar/aaa"},
+		},
+		SDKs: []*modules.VendoredSDK{
+			{Name: "go", Path: "sdk/go.tar"},
+		},
+	})
+	require.NoError(t, err)
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		"modules.json",
+		"git/github.com/foo/bar/aaa/dagger.json",
+		"sdk/go.tar",
+	}, files)
+	require.NoDirExists(t, filepath.Join(dir, "git/github.com/foo/baz"))
+}
@@ -0,0 +1,511 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"dagger.io/dagger/telemetry"
+	"github.com/adrg/xdg"
+	"github.com/juju/ansiterm/tabwriter"
+	"github.com/spf13/cobra"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/dagger/dagger/dagql/dagui"
+	"github.com/dagger/dagger/engine/clientdb"
+	"github.com/dagger/dagger/engine/slog"
+	enginetel "github.com/dagger/dagger/engine/telemetry"
+	"github.com/dagger/dagger/util/cleanups"
+)
+
+const (
+	RunsRetentionEnv = "DAGGER_RUNS_RETENTION"
+
+	defaultRunsRetention = 7 * 24 * time.Hour
+	runsReplayBatchSize  = 1000
+)
+
+// runsDir is where the telemetry of each run is recorded
+var runsDir = filepath.Join(xdg.StateHome, "dagger", "runs")
+
+var runsListLimit int
+
+func init() {
+	runsListCmd.Flags().IntVarP(&runsListLimit, "limit", "n", 20, "Maximum number of runs to list (0 for all)")
+	runsCmd.AddCommand(runsListCmd, runsShowCmd, runsExportCmd)
+}
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Browse the history of local runs",
+	Long: `Browse the history of local runs.
+
+The spans, logs and metrics of every command run against an engine are
+recorded locally, so a run can be revisited after its terminal is closed,
+without Dagger Cloud.
+
+Runs are kept for a week. Set ` + RunsRetentionEnv + ` to a duration (e.g. "72h")
+to keep them for longer or shorter, or to "0" to disable recording.`,
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list [options]",
+	Short: "List recent runs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runs, err := loadRuns()
+		if err != nil {
+			return err
+		}
+		if runsListLimit > 0 && len(runs) > runsListLimit {
+			runs = runs[:runsListLimit]
+		}
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATUS\tDURATION\tSTARTED\tCOMMAND")
+		for _, run := range runs {
+			duration := "-"
+			if !run.EndTime.IsZero() {
+				duration = dagui.FormatDuration(run.EndTime.Sub(run.StartTime))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				run.ID,
+				run.Status(),
+				duration,
+				run.StartTime.Local().Format(time.DateTime),
+				run.Command,
+			)
+		}
+		return tw.Flush()
+	},
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:     "show [options] <id>",
+	Aliases: []string{"replay"},
+	Short:   "Replay a recorded run",
+	Long: `Replay a recorded run in the progress output, from its recorded spans and logs.
+
+The ID may be abbreviated to any unique prefix.`,
+	Example: "dagger runs show 20250102-150405-a1b2",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		run, err := findRun(args[0])
+		if err != nil {
+			return err
+		}
+		return Frontend.Run(cmd.Context(), opts, func(ctx context.Context) (cleanups.CleanupF, error) {
+			return nil, replayRun(ctx, run, Frontend.SetPrimary, Frontend.SpanExporter(), Frontend.LogExporter(), Frontend.MetricExporter())
+		})
+	},
+}
+
+var runsExportCmd = &cobra.Command{
+	Use:   "export [options] <id> <file.html>",
+	Short: "Export a recorded run as a self-contained HTML report",
+	Long: `Export a recorded run as a self-contained HTML report, with its span tree,
+logs, durations, cache hits and errors, and a timeline.
+
+The report has no external dependencies, so it can be shared or uploaded as a
+CI artifact. The ID may be abbreviated to any unique prefix.`,
+	Example: "dagger runs export 20250102-150405-a1b2 report.html",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		run, err := findRun(args[0])
+		if err != nil {
+			return err
+		}
+		report := dagui.NewHTMLReport()
+		if err := replayRun(cmd.Context(), run, report.SetPrimary, report.SpanExporter(), report.LogExporter(), nil); err != nil {
+			return err
+		}
+		return report.WriteFile(args[1])
+	},
+}
+
+// runSummary describes a recorded run. It's stored next to the run's
+// telemetry database.
+type runSummary struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Workdir string `json:"workdir"`
+
+	// The root span of the run
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (run runSummary) Status() string {
+	switch {
+	case run.EndTime.IsZero():
+		// still running, or the CLI exited without finishing the run
+		return "incomplete"
+	case run.Error != "":
+		return "failed"
+	default:
+		return "succeeded"
+	}
+}
+
+func runSummaryPath(id string) string {
+	return filepath.Join(runsDir, id+".json")
+}
+
+func runsRetention() (time.Duration, error) {
+	v := os.Getenv(RunsRetentionEnv)
+	if v == "" {
+		return defaultRunsRetention, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	retention, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", RunsRetentionEnv, err)
+	}
+	return retention, nil
+}
+
+// newRunID returns an ID for a run started at the given time. IDs sort in
+// the order runs were started.
+func newRunID(start time.Time) string {
+	var suffix [2]byte
+	rand.Read(suffix[:])
+	return start.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// loadRuns returns the recorded runs, most recent first.
+func loadRuns() ([]runSummary, error) {
+	ents, err := os.ReadDir(runsDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var runs []runSummary
+	for _, ent := range ents {
+		id, ok := strings.CutSuffix(ent.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(runsDir, id+".db")); err != nil {
+			// telemetry was garbage collected
+			removeRunSummary(id)
+			continue
+		}
+		bs, err := os.ReadFile(filepath.Join(runsDir, ent.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var run runSummary
+		if err := json.Unmarshal(bs, &run); err != nil {
+			slog.Warn("skipping invalid run summary", "path", ent.Name(), "error", err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	slices.SortFunc(runs, func(a, b runSummary) int {
+		return b.StartTime.Compare(a.StartTime)
+	})
+	return runs, nil
+}
+
+// removeRunSummaries removes the summaries of runs whose telemetry was
+// garbage collected.
+func removeRunSummaries() {
+	ents, err := os.ReadDir(runsDir)
+	if err != nil {
+		return
+	}
+	for _, ent := range ents {
+		id, ok := strings.CutSuffix(ent.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(runsDir, id+".db")); errors.Is(err, os.ErrNotExist) {
+			removeRunSummary(id)
+		}
+	}
+}
+
+func removeRunSummary(id string) {
+	if err := os.Remove(runSummaryPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Debug("failed to remove run summary", "id", id, "error", err)
+	}
+}
+
+// findRun returns the run with the given ID or unique ID prefix.
+func findRun(id string) (runSummary, error) {
+	runs, err := loadRuns()
+	if err != nil {
+		return runSummary{}, err
+	}
+	var matches []runSummary
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+		if strings.HasPrefix(run.ID, id) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return runSummary{}, fmt.Errorf("no run matching %q; see 'dagger runs list'", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return runSummary{}, fmt.Errorf("%q matches %d runs; use a longer prefix", id, len(matches))
+	}
+}
+
+// replayRun exports the recorded telemetry of a run to the given exporters,
+// e.g. the frontend's. Metrics are skipped if metricExp is nil.
+func replayRun(
+	ctx context.Context,
+	run runSummary,
+	setPrimary func(dagui.SpanID),
+	spanExp sdktrace.SpanExporter,
+	logExp sdklog.Exporter,
+	metricExp sdkmetric.Exporter,
+) error {
+	db, err := clientdb.NewDBs(runsDir).Open(ctx, run.ID)
+	if err != nil {
+		return fmt.Errorf("open run %s: %w", run.ID, err)
+	}
+	defer db.Close()
+
+	if spanID, err := trace.SpanIDFromHex(run.SpanID); err == nil {
+		setPrimary(dagui.SpanID{SpanID: spanID})
+	}
+
+	for since := int64(0); ; {
+		spans, err := db.SelectSpansSince(ctx, clientdb.SelectSpansSinceParams{
+			ID:    since,
+			Limit: runsReplayBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("select spans: %w", err)
+		}
+		if len(spans) == 0 {
+			break
+		}
+		since = spans[len(spans)-1].ID
+		ros := make([]sdktrace.ReadOnlySpan, len(spans))
+		for i := range spans {
+			ros[i] = spans[i].ReadOnly()
+		}
+		if err := spanExp.ExportSpans(ctx, ros); err != nil {
+			return fmt.Errorf("export spans: %w", err)
+		}
+	}
+
+	for since := int64(0); ; {
+		logs, err := db.SelectLogsSince(ctx, clientdb.SelectLogsSinceParams{
+			ID:    since,
+			Limit: runsReplayBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("select logs: %w", err)
+		}
+		if len(logs) == 0 {
+			break
+		}
+		since = logs[len(logs)-1].ID
+		if err := telemetry.ReexportLogsFromPB(ctx, logExp, &collogspb.ExportLogsServiceRequest{
+			ResourceLogs: clientdb.LogsToPB(logs),
+		}); err != nil {
+			return fmt.Errorf("export logs: %w", err)
+		}
+	}
+
+	for since := int64(0); metricExp != nil; {
+		metrics, err := db.SelectMetricsSince(ctx, clientdb.SelectMetricsSinceParams{
+			ID:    since,
+			Limit: runsReplayBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("select metrics: %w", err)
+		}
+		if len(metrics) == 0 {
+			break
+		}
+		since = metrics[len(metrics)-1].ID
+		if err := enginetel.ReexportMetricsFromPB(ctx, []sdkmetric.Exporter{metricExp}, &colmetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: clientdb.MetricsToPB(metrics),
+		}); err != nil {
+			return fmt.Errorf("export metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runRecorder records the telemetry of a run to the run history.
+type runRecorder struct {
+	db  *clientdb.DB
+	run runSummary
+}
+
+// startRunRecorder starts recording a run, returning nil if recording is
+// disabled.
+func startRunRecorder(ctx context.Context) (*runRecorder, error) {
+	retention, err := runsRetention()
+	if err != nil || retention == 0 {
+		return nil, err
+	}
+	dbs := clientdb.NewDBs(runsDir)
+	dbs.Retention = retention
+	if err := dbs.GC(nil); err != nil {
+		slog.Debug("failed to collect old runs", "error", err)
+	}
+	removeRunSummaries()
+
+	now := time.Now()
+	id := newRunID(now)
+	db, err := dbs.Open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	workdir, _ := os.Getwd()
+	return &runRecorder{
+		db: db,
+		run: runSummary{
+			ID:        id,
+			Workdir:   workdir,
+			StartTime: now,
+		},
+	}, nil
+}
+
+// Started records the command and root span of the run.
+func (rec *runRecorder) Started(command string, span trace.Span) error {
+	rec.run.Command = command
+	rec.run.TraceID = span.SpanContext().TraceID().String()
+	rec.run.SpanID = span.SpanContext().SpanID().String()
+	return rec.writeSummary()
+}
+
+// Finish records the outcome of the run, once its telemetry has been flushed.
+func (rec *runRecorder) Finish(rerr error) error {
+	rec.run.EndTime = time.Now()
+	if rerr != nil {
+		rec.run.Error = rerr.Error()
+	}
+	return errors.Join(rec.writeSummary(), rec.db.Close())
+}
+
+func (rec *runRecorder) writeSummary() error {
+	bs, err := json.MarshalIndent(rec.run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(runSummaryPath(rec.run.ID), bs, 0o600)
+}
+
+func (rec *runRecorder) SpanExporter() sdktrace.SpanExporter {
+	return runSpans{rec}
+}
+
+func (rec *runRecorder) LogExporter() sdklog.Exporter {
+	return runLogs{rec}
+}
+
+func (rec *runRecorder) MetricExporter() sdkmetric.Exporter {
+	return runMetrics{rec}
+}
+
+type runSpans struct {
+	*runRecorder
+}
+
+func (rec runSpans) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	ctx = context.WithoutCancel(ctx)
+	for _, span := range spans {
+		insert, err := clientdb.InsertSpanParamsFromSpan(span)
+		if err != nil {
+			slog.Debug("failed to prepare span", "error", err)
+			continue
+		}
+		if _, err := rec.db.InsertSpan(ctx, *insert); err != nil {
+			return fmt.Errorf("insert span: %w", err)
+		}
+	}
+	return nil
+}
+
+func (rec runSpans) Shutdown(context.Context) error { return nil }
+
+type runLogs struct {
+	*runRecorder
+}
+
+func (rec runLogs) Export(ctx context.Context, logs []sdklog.Record) error {
+	ctx = context.WithoutCancel(ctx)
+	for _, log := range logs {
+		insert, err := clientdb.InsertLogParamsFromRecord(&log)
+		if err != nil {
+			slog.Debug("failed to prepare log record", "error", err)
+			continue
+		}
+		if _, err := rec.db.InsertLog(ctx, *insert); err != nil {
+			return fmt.Errorf("insert log: %w", err)
+		}
+	}
+	return nil
+}
+
+func (rec runLogs) ForceFlush(context.Context) error { return nil }
+func (rec runLogs) Shutdown(context.Context) error   { return nil }
+
+type runMetrics struct {
+	*runRecorder
+}
+
+func (rec runMetrics) Export(ctx context.Context, metrics *metricdata.ResourceMetrics) error {
+	if len(metrics.ScopeMetrics) == 0 {
+		return nil
+	}
+	pbMetrics, err := telemetry.ResourceMetricsToPB(metrics)
+	if err != nil {
+		return fmt.Errorf("convert metrics to pb: %w", err)
+	}
+	payload, err := protojson.Marshal(pbMetrics)
+	if err != nil {
+		return fmt.Errorf("marshal metrics: %w", err)
+	}
+	if _, err := rec.db.InsertMetric(context.WithoutCancel(ctx), payload); err != nil {
+		return fmt.Errorf("insert metrics: %w", err)
+	}
+	return nil
+}
+
+func (rec runMetrics) Temporality(sdkmetric.InstrumentKind) metricdata.Temporality {
+	return metricdata.DeltaTemporality
+}
+
+func (rec runMetrics) Aggregation(sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.AggregationDefault{}
+}
+
+func (rec runMetrics) ForceFlush(context.Context) error { return nil }
+func (rec runMetrics) Shutdown(context.Context) error   { return nil }
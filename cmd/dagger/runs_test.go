@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/dagger/dagger/dagql/dagui"
+	"github.com/dagger/dagger/engine/clientdb"
+)
+
+func TestRunRecorder(t *testing.T) {
+	runsDir = t.TempDir()
+	t.Setenv(RunsRetentionEnv, "")
+	ctx := t.Context()
+
+	record := func(command string, rerr error) runSummary {
+		rec, err := startRunRecorder(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, rec)
+
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(rec.SpanExporter()))
+		_, span := tp.Tracer("test").Start(ctx, command)
+		require.NoError(t, rec.Started(command, span))
+		span.End()
+		require.NoError(t, tp.Shutdown(ctx))
+		require.NoError(t, rec.Finish(rerr))
+		return rec.run
+	}
+	first := record("dagger call build", nil)
+	// IDs only have second precision
+	time.Sleep(time.Second)
+	second := record("dagger call test", errors.New("tests failed"))
+
+	runs, err := loadRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, second.ID, runs[0].ID)
+	require.Equal(t, "dagger call test", runs[0].Command)
+	require.Equal(t, "failed", runs[0].Status())
+	require.Equal(t, first.ID, runs[1].ID)
+	require.Equal(t, "succeeded", runs[1].Status())
+
+	run, err := findRun(first.ID[:len(first.ID)-2])
+	require.NoError(t, err)
+	require.Equal(t, first.ID, run.ID)
+	_, err = findRun("20")
+	require.ErrorContains(t, err, "matches 2 runs")
+	_, err = findRun("nope")
+	require.ErrorContains(t, err, "no run matching")
+
+	db, err := clientdb.NewDBs(runsDir).Open(ctx, first.ID)
+	require.NoError(t, err)
+	defer db.Close()
+	spans, err := db.SelectSpansSince(ctx, clientdb.SelectSpansSinceParams{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	require.Equal(t, "dagger call build", spans[0].Name)
+	require.Equal(t, first.SpanID, spans[0].SpanID)
+
+	report := dagui.NewHTMLReport()
+	require.NoError(t, replayRun(ctx, second, report.SetPrimary, report.SpanExporter(), report.LogExporter(), nil))
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, report.WriteFile(reportPath))
+	html, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	require.Contains(t, string(html), "<title>dagger call test</title>")
+}
+
+func TestRunRecorderDisabled(t *testing.T) {
+	runsDir = t.TempDir()
+	t.Setenv(RunsRetentionEnv, "0")
+	rec, err := startRunRecorder(t.Context())
+	require.NoError(t, err)
+	require.Nil(t, rec)
+
+	t.Setenv(RunsRetentionEnv, "forever")
+	_, err = startRunRecorder(t.Context())
+	require.ErrorContains(t, err, "invalid "+RunsRetentionEnv)
+}
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	require.Regexp(t, regexp.MustCompile(`^20250102-150405-[0-9a-f]{4}$`), newRunID(start))
+	require.Less(t, newRunID(start), newRunID(start.Add(time.Second)))
+}
+
+func TestRunRecorderGC(t *testing.T) {
+	runsDir = t.TempDir()
+	t.Setenv(RunsRetentionEnv, "1h")
+	ctx := t.Context()
+
+	rec, err := startRunRecorder(ctx)
+	require.NoError(t, err)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(rec.SpanExporter()))
+	_, span := tp.Tracer("test").Start(ctx, "dagger call build")
+	require.NoError(t, rec.Started("dagger call build", span))
+	span.End()
+	require.NoError(t, tp.Shutdown(ctx))
+	require.NoError(t, rec.Finish(nil))
+
+	// expire the telemetry, but not the summary
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(runsDir, rec.run.ID+".db"), old, old))
+
+	next, err := startRunRecorder(ctx)
+	require.NoError(t, err)
+	require.NoError(t, next.Finish(nil))
+
+	_, err = os.Stat(runSummaryPath(rec.run.ID))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	runs, err := loadRuns()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, next.run.ID, runs[0].ID)
+}
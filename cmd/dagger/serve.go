@@ -0,0 +1,541 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger"
+	"dagger.io/dagger/querybuilder"
+	"dagger.io/dagger/telemetry"
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine/client"
+	"github.com/dagger/dagger/util/gitutil"
+)
+
+var (
+	serveAddress         string
+	serveREST            bool
+	serveAllowHostInputs bool
+)
+
+// serveTokenEnv is the environment variable holding the token that requests
+// must present when host inputs are allowed.
+const serveTokenEnv = "DAGGER_SERVE_TOKEN"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [options]",
+	Short: "Serve the functions of a module over HTTP",
+	Long: `Serve the functions of a module over HTTP.
+
+With --rest, each function of the module's main object is exposed as
+POST /<object>/<function>, taking its arguments as a JSON object in the request
+body, and constructor arguments as query parameters. Object arguments, like
+Directory or Container, are accepted as addresses that don't reach into the
+host: image refs, and http(s) git URLs for directories, files, git repositories
+and modules.
+
+With --allow-host-inputs, object arguments may also be IDs or any address,
+including host paths, secrets, sockets and services. Every request must then
+present the token from $DAGGER_SERVE_TOKEN as "Authorization: Bearer <token>".
+
+The result is returned as {"result": ...}, where objects are returned as IDs.
+Requests accepting text/event-stream instead stream the function's logs as
+"log" events, followed by a "result" or "error" event.
+
+An OpenAPI 3 document describing the API is served at /openapi.json.
+`,
+	Example: `"dagger serve --rest" or "dagger serve --rest -m github.com/org/mods/foo --listen 0.0.0.0:8080"`,
+	GroupID: moduleGroup.ID,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !serveREST {
+			return errors.New("no API to serve: only --rest is supported")
+		}
+		token := os.Getenv(serveTokenEnv)
+		if serveAllowHostInputs && token == "" {
+			return fmt.Errorf("--allow-host-inputs requires $%s to be set, to authenticate requests", serveTokenEnv)
+		}
+
+		// route engine logs to the requests that emitted them; this must be
+		// registered before the engine session picks up the log processors
+		logs := newRESTLogRouter()
+		telemetry.LogProcessors = append(telemetry.LogProcessors, logs)
+
+		ctx := cmd.Context()
+		return withEngine(ctx, client.Params{}, func(ctx context.Context, engineClient *client.Client) error {
+			dag := engineClient.Dagger()
+			mod, err := initializeDefaultModule(ctx, dag)
+			if err != nil {
+				return err
+			}
+			gw, err := newRESTGateway(dag, mod, logs)
+			if err != nil {
+				return err
+			}
+			if serveAllowHostInputs {
+				gw.token = token
+			}
+			return gw.Serve(ctx, cmd)
+		})
+	},
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveREST, "rest", false, "Serve the module's functions as a REST API, with an OpenAPI document")
+	serveCmd.Flags().StringVar(&serveAddress, "listen", "127.0.0.1:8080", "Listen on network address ADDR")
+	serveCmd.Flags().BoolVar(&serveAllowHostInputs, "allow-host-inputs", false, "Accept IDs and host-scoped addresses (host paths, secrets, sockets, services) as arguments, from requests authenticated with $"+serveTokenEnv)
+	moduleAddFlags(serveCmd, serveCmd.Flags(), false)
+}
+
+// restGateway maps the functions of a module's main object to REST endpoints.
+type restGateway struct {
+	dag  *dagger.Client
+	mod  *moduleDef
+	logs *restLogRouter
+
+	// object is the path segment of the main object, e.g. "my-mod"
+	object      string
+	constructor *modFunction
+	functions   map[string]*modFunction
+
+	// token, if set, must be presented by every call, which may then pass
+	// IDs and host-scoped addresses as arguments
+	token string
+}
+
+func newRESTGateway(dag *dagger.Client, mod *moduleDef, logs *restLogRouter) (*restGateway, error) {
+	obj := mod.MainObject.AsObject
+	if obj == nil || obj.Constructor == nil {
+		return nil, fmt.Errorf("module %q has no main object to serve", mod.Name)
+	}
+	gw := &restGateway{
+		dag:         dag,
+		mod:         mod,
+		logs:        logs,
+		object:      cliName(obj.Constructor.Name),
+		constructor: obj.Constructor,
+		functions:   map[string]*modFunction{},
+	}
+	for _, fn := range obj.GetFunctions() {
+		gw.functions[fn.CmdName()] = fn
+	}
+	return gw, nil
+}
+
+func (gw *restGateway) Serve(ctx context.Context, cmd *cobra.Command) error {
+	stderr := cmd.ErrOrStderr()
+
+	l, err := net.Listen("tcp", serveAddress)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	defer l.Close()
+
+	srv := &http.Server{
+		Handler: gw,
+		// Gosec G112: prevent slowloris attacks
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		fmt.Fprintln(stderr, "==> server shutting down")
+		srv.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(stderr, "==> serving %s on http://%s/%s/\n", gw.mod.Name, serveAddress, gw.object)
+	fmt.Fprintf(stderr, "==> OpenAPI document at http://%s/openapi.json\n", serveAddress)
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (gw *restGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/openapi.json" {
+		if r.Method != http.MethodGet {
+			writeRESTError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gw.OpenAPI())
+		return
+	}
+
+	object, function, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	fn, ok := gw.functions[function]
+	if object != gw.object || !ok {
+		writeRESTError(w, http.StatusNotFound, fmt.Errorf("no function at %s", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeRESTError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	if gw.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(gw.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeRESTError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+	}
+
+	// run each call in its own trace, so its logs can be told apart
+	ctx, span := Tracer().Start(r.Context(), "POST "+r.URL.Path,
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(r.Context())))
+	defer span.End()
+
+	q, err := gw.query(ctx, fn, r)
+	if err != nil {
+		writeRESTError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		result, err := gw.execute(ctx, q, fn)
+		if err != nil {
+			writeRESTError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"result": result})
+		return
+	}
+
+	logs := gw.logs.Subscribe(span.SpanContext().TraceID())
+	defer gw.logs.Unsubscribe(span.SpanContext().TraceID())
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	writeEvent := func(event string, data string) {
+		fmt.Fprintf(w, "event: %s\n", event)
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(w, "data: %s\n", line)
+		}
+		fmt.Fprint(w, "\n")
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := gw.execute(ctx, q, fn)
+		done <- outcome{result, err}
+	}()
+	for {
+		select {
+		case line := <-logs:
+			writeEvent("log", line)
+		case res := <-done:
+			// drain the logs that arrived before the result
+		drain:
+			for {
+				select {
+				case line := <-logs:
+					writeEvent("log", line)
+				default:
+					break drain
+				}
+			}
+			if res.err != nil {
+				data, _ := json.Marshal(map[string]any{"error": res.err.Error()})
+				writeEvent("error", string(data))
+				return
+			}
+			data, _ := json.Marshal(map[string]any{"result": res.result})
+			writeEvent("result", string(data))
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// query builds the API query for a call to fn, from the request's query
+// parameters (constructor arguments) and JSON body (function arguments).
+func (gw *restGateway) query(ctx context.Context, fn *modFunction, r *http.Request) (*querybuilder.Selection, error) {
+	q := querybuilder.Query().Select(gw.constructor.Name)
+
+	params := r.URL.Query()
+	for _, arg := range gw.constructor.Args {
+		if !params.Has(arg.Name) {
+			if arg.IsRequired() {
+				return nil, fmt.Errorf("missing required query parameter %q", arg.Name)
+			}
+			continue
+		}
+		v, err := gw.paramValue(ctx, arg, params.Get(arg.Name))
+		if err != nil {
+			return nil, err
+		}
+		q = q.Arg(arg.Name, v)
+	}
+
+	body := map[string]json.RawMessage{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	}
+	q = q.Select(fn.Name)
+	for _, arg := range fn.Args {
+		raw, ok := body[arg.Name]
+		if !ok || string(raw) == "null" {
+			if arg.IsRequired() {
+				return nil, fmt.Errorf("missing required argument %q", arg.Name)
+			}
+			continue
+		}
+		delete(body, arg.Name)
+		v, err := gw.argValue(ctx, arg, raw)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Arg(arg.Name, v)
+	}
+	if len(body) > 0 {
+		return nil, fmt.Errorf("unknown arguments: %s", strings.Join(slices.Sorted(maps.Keys(body)), ", "))
+	}
+
+	return handleObjectLeaf(q, fn.ReturnType), nil
+}
+
+func (gw *restGateway) execute(ctx context.Context, q *querybuilder.Selection, fn *modFunction) (any, error) {
+	var response any
+	if err := makeRequest(ctx, q.Client(gw.dag.GraphQLClient()), &response); err != nil {
+		return nil, err
+	}
+	if fn.ReturnType.Kind == dagger.TypeDefKindVoidKind {
+		return nil, nil
+	}
+	return response, nil
+}
+
+// paramValue converts a query parameter to a value for arg.
+func (gw *restGateway) paramValue(ctx context.Context, arg *modFunctionArg, param string) (any, error) {
+	switch arg.TypeDef.Kind {
+	case dagger.TypeDefKindIntegerKind:
+		v, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", arg.Name, err)
+		}
+		return v, nil
+	case dagger.TypeDefKindFloatKind:
+		v, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", arg.Name, err)
+		}
+		return v, nil
+	case dagger.TypeDefKindBooleanKind:
+		v, err := strconv.ParseBool(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", arg.Name, err)
+		}
+		return v, nil
+	case dagger.TypeDefKindObjectKind, dagger.TypeDefKindInterfaceKind:
+		return gw.objectValue(ctx, arg, param)
+	case dagger.TypeDefKindStringKind, dagger.TypeDefKindEnumKind, dagger.TypeDefKindScalarKind:
+		return param, nil
+	default:
+		// lists and inputs don't fit in a query parameter
+		return gw.argValue(ctx, arg, json.RawMessage(param))
+	}
+}
+
+// argValue converts a JSON value to a value for arg.
+func (gw *restGateway) argValue(ctx context.Context, arg *modFunctionArg, raw json.RawMessage) (any, error) {
+	switch arg.TypeDef.Kind {
+	case dagger.TypeDefKindStringKind, dagger.TypeDefKindEnumKind, dagger.TypeDefKindScalarKind:
+		return decodeRESTArg[string](arg, raw)
+	case dagger.TypeDefKindIntegerKind:
+		return decodeRESTArg[int](arg, raw)
+	case dagger.TypeDefKindFloatKind:
+		return decodeRESTArg[float64](arg, raw)
+	case dagger.TypeDefKindBooleanKind:
+		return decodeRESTArg[bool](arg, raw)
+	case dagger.TypeDefKindInputKind:
+		return decodeRESTArg[map[string]any](arg, raw)
+	case dagger.TypeDefKindObjectKind, dagger.TypeDefKindInterfaceKind:
+		s, err := decodeRESTArg[string](arg, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected an address", err)
+		}
+		return gw.objectValue(ctx, arg, s)
+	case dagger.TypeDefKindListKind:
+		elems, err := decodeRESTArg[[]json.RawMessage](arg, raw)
+		if err != nil {
+			return nil, err
+		}
+		elemArg := &modFunctionArg{
+			Name:    arg.Name,
+			TypeDef: arg.TypeDef.AsList.ElementTypeDef,
+			Ignore:  arg.Ignore,
+		}
+		vals := make([]any, 0, len(elems))
+		for _, elem := range elems {
+			val, err := gw.argValue(ctx, elemArg, elem)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return vals, nil
+	default:
+		return nil, fmt.Errorf("unsupported type for argument %q: %s", arg.Name, arg.TypeDef)
+	}
+}
+
+func decodeRESTArg[T any](arg *modFunctionArg, raw json.RawMessage) (T, error) {
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, fmt.Errorf("invalid argument %q: %w", arg.Name, err)
+	}
+	return v, nil
+}
+
+// objectValue loads an object argument from an address, like a git URL or
+// image ref. IDs and host-scoped addresses are only accepted when the
+// gateway authenticates its callers.
+func (gw *restGateway) objectValue(ctx context.Context, arg *modFunctionArg, s string) (any, error) {
+	var id call.ID
+	if err := id.Decode(s); err == nil {
+		// an ID may select anything, including from the host
+		if gw.token == "" {
+			return nil, fmt.Errorf("invalid argument %q: IDs are not accepted without --allow-host-inputs", arg.Name)
+		}
+		if arg.TypeDef.Kind == dagger.TypeDefKindObjectKind && id.Type().NamedType() != arg.TypeDef.String() {
+			return nil, fmt.Errorf("invalid argument %q: expected a %s ID, got a %s ID", arg.Name, arg.TypeDef, id.Type().NamedType())
+		}
+		return s, nil
+	}
+	flags := pflag.NewFlagSet(arg.Name, pflag.ContinueOnError)
+	if err := arg.AddFlag(flags); err != nil {
+		return nil, err
+	}
+	flag, err := arg.GetFlag(flags)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := flag.Value.(DaggerValue); !ok {
+		return nil, fmt.Errorf("invalid argument %q: %s can only be passed by ID", arg.Name, arg.TypeDef)
+	}
+	if gw.token == "" && !hostFreeAddress(flag.Value.Type(), s) {
+		return nil, fmt.Errorf("invalid argument %q: %s address %q may reach into the host, which requires --allow-host-inputs", arg.Name, arg.TypeDef, s)
+	}
+	if err := flag.Value.Set(s); err != nil {
+		return nil, fmt.Errorf("invalid argument %q: %w", arg.Name, err)
+	}
+	return arg.GetFlagValue(ctx, flag, gw.dag, gw.mod)
+}
+
+// hostFreeAddress returns whether loading an object of the given type from
+// addr can't read anything from the host running the gateway.
+func hostFreeAddress(typ string, addr string) bool {
+	switch typ {
+	case Container, CacheVolume:
+		return true
+	case Directory, File, GitRepository, GitRef, Module, ModuleSource:
+		// anything but a remote git URL is a host path; ssh would use the
+		// host's agent
+		gitURL, err := gitutil.ParseURL(addr)
+		if err != nil {
+			return false
+		}
+		return gitURL.Scheme == gitutil.HTTPSProtocol || gitURL.Scheme == gitutil.HTTPProtocol
+	default:
+		// secrets, sockets and services are all resolved on the host
+		return false
+	}
+}
+
+func writeRESTError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+}
+
+// restLogRouter is a log processor that forwards the logs of each trace to
+// its subscriber, if any.
+type restLogRouter struct {
+	mu   sync.Mutex
+	subs map[trace.TraceID]chan string
+}
+
+var _ sdklog.Processor = (*restLogRouter)(nil)
+
+func newRESTLogRouter() *restLogRouter {
+	return &restLogRouter{subs: map[trace.TraceID]chan string{}}
+}
+
+func (lr *restLogRouter) Subscribe(traceID trace.TraceID) <-chan string {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	ch := make(chan string, 1024)
+	lr.subs[traceID] = ch
+	return ch
+}
+
+func (lr *restLogRouter) Unsubscribe(traceID trace.TraceID) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	delete(lr.subs, traceID)
+}
+
+func (lr *restLogRouter) OnEmit(_ context.Context, rec *sdklog.Record) error {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	ch, ok := lr.subs[rec.TraceID()]
+	if !ok {
+		return nil
+	}
+	body := rec.Body()
+	line := body.String()
+	if body.Kind() == log.KindString {
+		line = body.AsString()
+	}
+	select {
+	case ch <- strings.TrimSuffix(line, "\n"):
+	default:
+		// never block the telemetry pipeline on a slow client
+	}
+	return nil
+}
+
+func (lr *restLogRouter) Shutdown(context.Context) error {
+	return nil
+}
+
+func (lr *restLogRouter) ForceFlush(context.Context) error {
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+
+	"dagger.io/dagger"
+)
+
+// OpenAPI returns an OpenAPI 3 document describing the gateway's endpoints.
+func (gw *restGateway) OpenAPI() map[string]any {
+	var params []any
+	for _, arg := range gw.constructor.Args {
+		params = append(params, map[string]any{
+			"name":        arg.Name,
+			"in":          "query",
+			"description": arg.Description,
+			"required":    arg.IsRequired(),
+			"schema":      gw.schema(arg.TypeDef, true),
+		})
+	}
+
+	paths := map[string]any{}
+	for name, fn := range gw.functions {
+		props := map[string]any{}
+		required := []string{}
+		for _, arg := range fn.Args {
+			schema := gw.schema(arg.TypeDef, true)
+			if arg.Description != "" {
+				schema["description"] = arg.Description
+			}
+			props[arg.Name] = schema
+			if arg.IsRequired() {
+				required = append(required, arg.Name)
+			}
+		}
+
+		result := map[string]any{}
+		if fn.ReturnType.Kind != dagger.TypeDefKindVoidKind {
+			result["result"] = gw.schema(fn.ReturnType, false)
+		}
+
+		op := map[string]any{
+			"operationId": gw.object + "/" + name,
+			"summary":     fn.Short(),
+			"description": fn.Description,
+			"requestBody": map[string]any{
+				"required": len(required) > 0,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{
+							"type":       "object",
+							"properties": props,
+							"required":   required,
+						},
+					},
+				},
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The result of the function, or its logs followed by its result when streaming.",
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": map[string]any{
+								"type":       "object",
+								"properties": result,
+							},
+						},
+						"text/event-stream": map[string]any{
+							"schema": map[string]any{"type": "string"},
+						},
+					},
+				},
+				"400": errorResponse("The arguments are invalid."),
+				"500": errorResponse("The function failed."),
+			},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		paths["/"+gw.object+"/"+name] = map[string]any{"post": op}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       gw.mod.Name,
+			"description": gw.mod.Description,
+			"version":     cmp.Or(gw.mod.SourceVersion, "0.0.0"),
+		},
+		"paths": paths,
+	}
+}
+
+// schema returns the JSON schema of a type. Objects are passed as IDs, or
+// as addresses when they are arguments.
+func (gw *restGateway) schema(t *modTypeDef, isArg bool) map[string]any {
+	var schema map[string]any
+	switch t.Kind {
+	case dagger.TypeDefKindStringKind:
+		schema = map[string]any{"type": "string"}
+	case dagger.TypeDefKindIntegerKind:
+		schema = map[string]any{"type": "integer"}
+	case dagger.TypeDefKindFloatKind:
+		schema = map[string]any{"type": "number"}
+	case dagger.TypeDefKindBooleanKind:
+		schema = map[string]any{"type": "boolean"}
+	case dagger.TypeDefKindScalarKind:
+		schema = map[string]any{"type": "string", "format": t.AsScalar.Name}
+	case dagger.TypeDefKindEnumKind:
+		schema = map[string]any{"type": "string"}
+		if enum := gw.mod.GetEnum(t.AsEnum.Name); enum != nil {
+			schema["enum"] = enum.ValueNames()
+		}
+	case dagger.TypeDefKindInputKind:
+		schema = map[string]any{"type": "object"}
+	case dagger.TypeDefKindObjectKind, dagger.TypeDefKindInterfaceKind:
+		desc := "ID of a " + t.String()
+		if isArg {
+			desc = "Address of a " + t.String()
+			if gw.token != "" {
+				desc = "ID or address of a " + t.String()
+			}
+		}
+		schema = map[string]any{"type": "string", "format": t.String() + "ID", "description": desc}
+	case dagger.TypeDefKindListKind:
+		schema = map[string]any{"type": "array", "items": gw.schema(t.AsList.ElementTypeDef, isArg)}
+	default:
+		schema = map[string]any{}
+	}
+	if t.Optional {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+func errorResponse(desc string) map[string]any {
+	return map[string]any{
+		"description": desc,
+		"content": map[string]any{
+			"application/json": map[string]any{
+				"schema": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"error": map[string]any{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
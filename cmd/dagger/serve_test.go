@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger"
+	"github.com/dagger/dagger/dagql/call"
+)
+
+func testRESTGateway(t *testing.T) *restGateway {
+	t.Helper()
+	str := &modTypeDef{Kind: dagger.TypeDefKindStringKind}
+	mainObj := &modTypeDef{
+		Kind: dagger.TypeDefKindObjectKind,
+		AsObject: &modObject{
+			Name: "MyMod",
+			Functions: []*modFunction{
+				{
+					Name:        "buildImage",
+					Description: "Build the image",
+					ReturnType:  &modTypeDef{Kind: dagger.TypeDefKindObjectKind, AsObject: &modObject{Name: "Container"}},
+					Args: []*modFunctionArg{
+						{Name: "source", TypeDef: &modTypeDef{Kind: dagger.TypeDefKindObjectKind, AsObject: &modObject{Name: "Directory"}}},
+						{Name: "tags", TypeDef: &modTypeDef{Kind: dagger.TypeDefKindListKind, Optional: true, AsList: &modList{ElementTypeDef: str}}},
+						{Name: "level", TypeDef: &modTypeDef{Kind: dagger.TypeDefKindEnumKind, Optional: true, AsEnum: &modEnum{Name: "MyModLevel"}}},
+					},
+				},
+				{Name: "version", ReturnType: str},
+			},
+		},
+	}
+	mainObj.AsObject.Constructor = &modFunction{
+		Name:       "myMod",
+		ReturnType: mainObj,
+		Args: []*modFunctionArg{
+			{Name: "verbose", TypeDef: &modTypeDef{Kind: dagger.TypeDefKindBooleanKind, Optional: true}},
+		},
+	}
+	mod := &moduleDef{
+		Name:       "my-mod",
+		MainObject: mainObj,
+		Enums: []*modTypeDef{{
+			Kind:   dagger.TypeDefKindEnumKind,
+			AsEnum: &modEnum{Name: "MyModLevel", Members: []*modEnumMember{{Name: "LOW"}, {Name: "HIGH"}}},
+		}},
+	}
+	gw, err := newRESTGateway(nil, mod, newRESTLogRouter())
+	require.NoError(t, err)
+	return gw
+}
+
+func TestRESTGatewayOpenAPI(t *testing.T) {
+	gw := testRESTGateway(t)
+	require.Equal(t, "my-mod", gw.object)
+
+	doc := gw.OpenAPI()
+	paths := doc["paths"].(map[string]any)
+	require.Len(t, paths, 2)
+	require.Contains(t, paths, "/my-mod/version")
+
+	op := paths["/my-mod/build-image"].(map[string]any)["post"].(map[string]any)
+	require.Equal(t, "Build the image", op["summary"])
+	params := op["parameters"].([]any)
+	require.Len(t, params, 1)
+	require.Equal(t, "verbose", params[0].(map[string]any)["name"])
+	require.Equal(t, false, params[0].(map[string]any)["required"])
+
+	schema := op["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	require.Equal(t, []string{"source"}, schema["required"])
+	props := schema["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "string", "format": "DirectoryID", "description": "Address of a Directory"}, props["source"])
+	require.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "nullable": true}, props["tags"])
+	require.Equal(t, map[string]any{"type": "string", "enum": []string{"LOW", "HIGH"}, "nullable": true}, props["level"])
+}
+
+func TestRESTGatewayRouting(t *testing.T) {
+	gw := testRESTGateway(t)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/openapi.json", 200},
+		{"POST", "/openapi.json", 405},
+		{"POST", "/other/version", 404},
+		{"POST", "/my-mod/missing", 404},
+		{"GET", "/my-mod/version", 405},
+	} {
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		require.Equal(t, tc.status, rec.Code, tc.method+" "+tc.path)
+	}
+
+	// argument errors are reported before calling the engine
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("POST", "/my-mod/build-image", strings.NewReader(`{"tags": ["a"]}`)))
+	require.Equal(t, 400, rec.Code)
+	require.Contains(t, rec.Body.String(), `missing required argument \"source\"`)
+
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("POST", "/my-mod/version?verbose=maybe", nil))
+	require.Equal(t, 400, rec.Code)
+	require.Contains(t, rec.Body.String(), `invalid query parameter \"verbose\"`)
+
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("POST", "/my-mod/version", strings.NewReader(`{"nope": 1}`)))
+	require.Equal(t, 400, rec.Code)
+	require.Contains(t, rec.Body.String(), "unknown arguments: nope")
+}
+
+func TestRESTGatewayHostInputs(t *testing.T) {
+	ctx := context.Background()
+	gw := testRESTGateway(t)
+
+	objArg := func(name string) *modFunctionArg {
+		return &modFunctionArg{
+			Name:    "arg",
+			TypeDef: &modTypeDef{Kind: dagger.TypeDefKindObjectKind, AsObject: &modObject{Name: name}},
+		}
+	}
+	for _, tc := range []struct {
+		typ  string
+		addr string
+	}{
+		{Secret, "env://HOME"},
+		{Secret, "file:///etc/passwd"},
+		{Secret, "cmd://cat /etc/passwd"},
+		{Directory, "/home"},
+		{Directory, "file:///home"},
+		{Directory, "git@github.com:dagger/dagger.git"},
+		{File, "./go.mod"},
+		{GitRepository, "."},
+		{ModuleSource, "../other"},
+		{Socket, "unix:///var/run/docker.sock"},
+		{Service, "tcp://localhost:22"},
+	} {
+		_, err := gw.objectValue(ctx, objArg(tc.typ), tc.addr)
+		require.ErrorContains(t, err, "requires --allow-host-inputs", tc.typ+" "+tc.addr)
+	}
+	require.True(t, hostFreeAddress(Container, "alpine:latest"))
+	require.True(t, hostFreeAddress(Directory, "https://github.com/dagger/dagger#main:docs"))
+	require.True(t, hostFreeAddress(ModuleSource, "https://github.com/dagger/dagger/modules/go@main"))
+
+	// IDs may encode host selections
+	id, err := call.New().
+		Append(&ast.Type{NamedType: "Host", NonNull: true}, "host").
+		Append(&ast.Type{NamedType: "Directory", NonNull: true}, "directory", call.WithArgs(
+			call.NewArgument("path", call.NewLiteralString("/"), false),
+		)).
+		Encode()
+	require.NoError(t, err)
+	_, err = gw.objectValue(ctx, objArg(Directory), id)
+	require.ErrorContains(t, err, "IDs are not accepted")
+}
+
+func TestRESTGatewayAuth(t *testing.T) {
+	gw := testRESTGateway(t)
+	gw.token = "s3cret"
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("POST", "/my-mod/version", nil))
+	require.Equal(t, 401, rec.Code)
+
+	req := httptest.NewRequest("POST", "/my-mod/version", nil)
+	req.Header.Set("Authorization", "Bearer nope")
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	require.Equal(t, 401, rec.Code)
+
+	// the OpenAPI document stays public
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	require.Equal(t, 200, rec.Code)
+}
+
+func TestRESTLogRouter(t *testing.T) {
+	ctx := context.Background()
+	lr := newRESTLogRouter()
+
+	traceID := trace.TraceID{1}
+	logs := lr.Subscribe(traceID)
+
+	emit := func(traceID trace.TraceID, body string) {
+		var rec sdklog.Record
+		rec.SetTraceID(traceID)
+		rec.SetBody(log.StringValue(body))
+		require.NoError(t, lr.OnEmit(ctx, &rec))
+	}
+	emit(traceID, "hello\n")
+	emit(trace.TraceID{2}, "someone else")
+	require.Equal(t, "hello", <-logs)
+	require.Empty(t, logs)
+
+	lr.Unsubscribe(traceID)
+	emit(traceID, "dropped")
+	require.Empty(t, logs)
+}
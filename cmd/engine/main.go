@@ -504,6 +504,7 @@ func main() { //nolint:gocyclo
 		http2Server := &http2.Server{}
 		httpServer := &http.Server{
 			ReadHeaderTimeout: 30 * time.Second,
+			ConnContext:       server.ConnContext,
 			Handler: h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("content-type"), "application/grpc") {
 					// The docs on grpcServer.ServeHTTP warn that some features are missing vs. serving fully "native" gRPC,
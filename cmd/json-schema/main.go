@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/dagql/idtui"
 	"github.com/dagger/dagger/engine/config"
 )
 
@@ -60,6 +61,11 @@ var targets = []target{
 		path:  "./engine/config",
 		value: &config.Config{},
 	},
+	{
+		id:    "progress.json",
+		path:  "./dagql/idtui",
+		value: &idtui.JSONEvent{},
+	},
 }
 
 type target struct {
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -361,7 +362,10 @@ func (ch *Changeset) AsPatch(ctx context.Context) (*File, error) {
 	}, nil
 }
 
-func (ch *Changeset) Export(ctx context.Context, destPath string) (rerr error) {
+// Export applies the changeset to a path on the host. Unless onConflict is
+// OverwriteOnExportConflict, host paths that changed since the before snapshot
+// are detected first and handled according to onConflict.
+func (ch *Changeset) Export(ctx context.Context, destPath string, onConflict ChangesetExportConflict) (rerr error) {
 	paths, err := ch.ComputePaths(ctx)
 	if err != nil {
 		return fmt.Errorf("compute paths: %w", err)
@@ -384,6 +388,57 @@ func (ch *Changeset) Export(ctx context.Context, destPath string) (rerr error) {
 	ctx, span := Tracer(ctx).Start(ctx, fmt.Sprintf("export changeset to host %s", destPath))
 	defer telemetry.EndWithCause(span, &rerr)
 
+	if onConflict == OverwriteOnExportConflict {
+		return exportDiff(ctx, bk, dir, destPath, paths.Removed)
+	}
+
+	// the host directory must stay mounted until backups are exported
+	return ch.checkExportConflicts(ctx, destPath, paths, func(hostDir string, conflicts []string) error {
+		removed := paths.Removed
+		if len(conflicts) > 0 {
+			stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary)
+			defer stdio.Close()
+
+			// the diff is cached and mounted read-only, so skip or back up
+			// conflicts in a new snapshot on top of it
+			switch onConflict {
+			case SkipOnExportConflict:
+				dir, err = execInMount(ctx, dir.Clone(), func(root string) error {
+					root, err := containerdfs.RootPath(root, dir.Dir)
+					if err != nil {
+						return err
+					}
+					removed, err = skipExportConflicts(root, paths, conflicts)
+					return err
+				}, withSavedSnapshot("skip export conflicts %s", strings.Join(conflicts, ",")))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(stdio.Stderr, "skipped conflicting paths: %s\n", strings.Join(conflicts, ", "))
+			case BackupOnExportConflict:
+				var backups []string
+				dir, err = execInMount(ctx, dir.Clone(), func(root string) error {
+					root, err := containerdfs.RootPath(root, dir.Dir)
+					if err != nil {
+						return err
+					}
+					backups, err = backupExportConflicts(ctx, root, hostDir, conflicts)
+					return err
+				}, withSavedSnapshot("back up export conflicts %s", strings.Join(conflicts, ",")))
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(stdio.Stderr, "backed up conflicting paths: %s\n", strings.Join(backups, ", "))
+			default:
+				return &ExportConflictsError{Path: destPath, Paths: conflicts}
+			}
+		}
+		return exportDiff(ctx, bk, dir, destPath, removed)
+	})
+}
+
+// exportDiff writes the diff to the host path and removes the given paths.
+func exportDiff(ctx context.Context, bk *buildkit.Client, dir *Directory, destPath string, removed []string) error {
 	root, closer, err := mountObj(ctx, dir)
 	if err != nil {
 		return fmt.Errorf("failed to mount directory: %w", err)
@@ -394,8 +449,7 @@ func (ch *Changeset) Export(ctx context.Context, destPath string) (rerr error) {
 	if err != nil {
 		return err
 	}
-
-	return bk.LocalDirExport(ctx, root, destPath, true, paths.Removed)
+	return bk.LocalDirExport(ctx, root, destPath, true, removed)
 }
 
 type ChangeType int
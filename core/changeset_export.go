@@ -0,0 +1,320 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/containerd/containerd/v2/core/mount"
+	containerdfs "github.com/containerd/continuity/fs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dagger/dagger/engine/buildkit"
+	fscopy "github.com/dagger/dagger/internal/fsutil/copy"
+)
+
+// ChangesetExportConflict specifies what to do when exporting a changeset to a
+// host path whose content changed since the changeset's before snapshot.
+type ChangesetExportConflict int
+
+const (
+	// OverwriteOnExportConflict overwrites host paths without checking them.
+	OverwriteOnExportConflict ChangesetExportConflict = iota
+	// FailOnExportConflict fails without writing anything if any conflict is detected.
+	FailOnExportConflict
+	// SkipOnExportConflict leaves conflicting host paths untouched, and applies
+	// all other changes.
+	SkipOnExportConflict
+	// BackupOnExportConflict copies conflicting host paths next to themselves
+	// with a ChangesetExportBackupSuffix before applying all changes.
+	BackupOnExportConflict
+)
+
+// ChangesetExportBackupSuffix is appended to the host paths backed up by
+// BackupOnExportConflict.
+const ChangesetExportBackupSuffix = ".orig"
+
+// ExportConflictsError is returned when exporting a changeset would overwrite
+// changes made on the host since the changeset's before snapshot.
+type ExportConflictsError struct {
+	Path  string
+	Paths []string
+}
+
+func (e *ExportConflictsError) Error() string {
+	return fmt.Sprintf("export to %s conflicts with changes on the host: %s",
+		e.Path, strings.Join(e.Paths, ", "))
+}
+
+// checkExportConflicts compares the current content of the host path with
+// the changeset's before and after snapshots, and returns the paths that
+// differ from both, i.e. those that were changed on the host since the
+// before snapshot was taken, and that would be clobbered by the export.
+func (ch *Changeset) checkExportConflicts(ctx context.Context, destPath string, paths *ChangesetPaths, fn func(hostDir string, conflicts []string) error) error {
+	touched := slices.Concat(paths.Added, paths.Modified, paths.AllRemoved)
+	if len(touched) == 0 {
+		return fn("", nil)
+	}
+
+	query, err := CurrentQuery(ctx)
+	if err != nil {
+		return err
+	}
+	bk, err := query.Buildkit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+	absPath, err := bk.AbsPath(ctx, destPath)
+	if err != nil {
+		return fmt.Errorf("failed to get host absolute path for %s: %w", destPath, err)
+	}
+	if _, err := bk.StatCallerHostPath(ctx, absPath, false); err != nil {
+		if status.Code(err) == codes.NotFound {
+			// nothing to conflict with
+			return fn("", nil)
+		}
+		return err
+	}
+
+	// only sync the paths touched by the changeset
+	include := make([]string, 0, len(touched))
+	for _, p := range touched {
+		include = append(include, strings.TrimSuffix(p, "/"))
+	}
+	hostDir, err := (&Host{}).Directory(ctx, absPath, CopyFilter{Include: include}, true, ".")
+	if err != nil {
+		return fmt.Errorf("load host directory %s: %w", destPath, err)
+	}
+	hostRef, err := getRefOrEvaluate(ctx, hostDir)
+	if err != nil {
+		return fmt.Errorf("evaluate host directory: %w", err)
+	}
+	bkSessionGroup, ok := buildkit.CurrentBuildkitSessionGroup(ctx)
+	if !ok {
+		return fmt.Errorf("no buildkit session group in context")
+	}
+
+	return ch.withMountedDirs(ctx, func(beforeDir, afterDir string) error {
+		return MountRef(ctx, hostRef, bkSessionGroup, func(hostMount string, _ *mount.Mount) error {
+			hostRoot, err := containerdfs.RootPath(hostMount, hostDir.Dir)
+			if err != nil {
+				return err
+			}
+			conflicts, err := exportConflicts(touched, beforeDir, afterDir, hostRoot)
+			if err != nil {
+				return err
+			}
+			return fn(hostRoot, conflicts)
+		}, mountRefAsReadOnly)
+	})
+}
+
+// exportConflicts returns the paths whose content in hostDir differs from
+// both beforeDir and afterDir. Directory paths end with a slash; a removed
+// directory conflicts if the host added anything underneath it.
+func exportConflicts(paths []string, beforeDir, afterDir, hostDir string) ([]string, error) {
+	var conflicts []string
+	for _, p := range paths {
+		rel := filepath.FromSlash(strings.TrimSuffix(p, "/"))
+		hostPath := filepath.Join(hostDir, rel)
+
+		sameAsBefore, err := sameHostContent(filepath.Join(beforeDir, rel), hostPath)
+		if err != nil {
+			return nil, err
+		}
+		if sameAsBefore && strings.HasSuffix(p, "/") {
+			// the directory itself is unchanged, but anything added under it
+			// on the host would be lost if it's removed
+			added, err := hasAddedEntries(filepath.Join(beforeDir, rel), hostPath)
+			if err != nil {
+				return nil, err
+			}
+			sameAsBefore = !added
+		}
+		if sameAsBefore {
+			continue
+		}
+		sameAsAfter, err := sameHostContent(filepath.Join(afterDir, rel), hostPath)
+		if err != nil {
+			return nil, err
+		}
+		if sameAsAfter {
+			// already applied
+			continue
+		}
+		conflicts = append(conflicts, p)
+	}
+	return conflicts, nil
+}
+
+// sameHostContent returns whether two paths have the same type and content.
+// Missing paths are equal to each other, and directories are compared by
+// type only.
+func sameHostContent(a, b string) (bool, error) {
+	aStat, aErr := os.Lstat(a)
+	bStat, bErr := os.Lstat(b)
+	switch {
+	case errors.Is(aErr, os.ErrNotExist) && errors.Is(bErr, os.ErrNotExist):
+		return true, nil
+	case errors.Is(aErr, os.ErrNotExist) || errors.Is(bErr, os.ErrNotExist):
+		return false, nil
+	case aErr != nil:
+		return false, aErr
+	case bErr != nil:
+		return false, bErr
+	}
+	if aStat.Mode().Type() != bStat.Mode().Type() {
+		return false, nil
+	}
+	switch aStat.Mode().Type() {
+	case fs.ModeDir:
+		return true, nil
+	case fs.ModeSymlink:
+		aTarget, err := os.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		bTarget, err := os.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return aTarget == bTarget, nil
+	case 0:
+		if aStat.Size() != bStat.Size() {
+			return false, nil
+		}
+		return sameFileContent(a, b)
+	default:
+		return true, nil
+	}
+}
+
+func sameFileContent(a, b string) (bool, error) {
+	aFile, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer aFile.Close()
+	bFile, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer bFile.Close()
+
+	aBuf := make([]byte, 32*1024)
+	bBuf := make([]byte, 32*1024)
+	for {
+		aN, aErr := io.ReadFull(aFile, aBuf)
+		bN, bErr := io.ReadFull(bFile, bBuf)
+		if !bytes.Equal(aBuf[:aN], bBuf[:bN]) {
+			return false, nil
+		}
+		aEOF := errors.Is(aErr, io.EOF) || errors.Is(aErr, io.ErrUnexpectedEOF)
+		bEOF := errors.Is(bErr, io.EOF) || errors.Is(bErr, io.ErrUnexpectedEOF)
+		switch {
+		case aEOF && bEOF:
+			return true, nil
+		case aEOF != bEOF:
+			return false, nil
+		case aErr != nil:
+			return false, aErr
+		case bErr != nil:
+			return false, bErr
+		}
+	}
+}
+
+// hasAddedEntries returns whether hostDir contains anything that's not in
+// beforeDir.
+func hasAddedEntries(beforeDir, hostDir string) (bool, error) {
+	if _, err := os.Lstat(hostDir); errors.Is(err, os.ErrNotExist) {
+		// e.g. a directory added by the changeset, which isn't on the host yet
+		return false, nil
+	}
+	var added bool
+	err := filepath.WalkDir(hostDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(hostDir, path)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(filepath.Join(beforeDir, rel)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				added = true
+				return filepath.SkipAll
+			}
+			return err
+		}
+		return nil
+	})
+	return added, err
+}
+
+// skipExportConflicts removes the conflicting paths from the diff to export,
+// and returns the paths to remove on the host without the conflicting ones.
+func skipExportConflicts(diffRoot string, paths *ChangesetPaths, conflicts []string) ([]string, error) {
+	for _, p := range conflicts {
+		if err := os.RemoveAll(filepath.Join(diffRoot, filepath.FromSlash(strings.TrimSuffix(p, "/")))); err != nil {
+			return nil, err
+		}
+	}
+
+	isConflict := func(p string) bool {
+		return slices.Contains(conflicts, p)
+	}
+	hasConflictUnder := func(dir string) bool {
+		return slices.ContainsFunc(conflicts, func(c string) bool {
+			return strings.HasPrefix(c, dir)
+		})
+	}
+
+	var removed []string
+	for _, p := range paths.Removed {
+		switch {
+		case strings.HasSuffix(p, "/") && hasConflictUnder(p):
+			// remove what's underneath individually, keeping the directory
+			// and anything added to it on the host
+			for _, child := range paths.AllRemoved {
+				if strings.HasPrefix(child, p) && !strings.HasSuffix(child, "/") && !isConflict(child) {
+					removed = append(removed, child)
+				}
+			}
+		case isConflict(p):
+		default:
+			removed = append(removed, p)
+		}
+	}
+	return removed, nil
+}
+
+// backupExportConflicts copies the conflicting paths from the host into the
+// diff to export, next to themselves with ChangesetExportBackupSuffix.
+func backupExportConflicts(ctx context.Context, diffRoot, hostDir string, conflicts []string) ([]string, error) {
+	var backups []string
+	for _, p := range conflicts {
+		rel := filepath.FromSlash(strings.TrimSuffix(p, "/"))
+		if _, err := os.Lstat(filepath.Join(hostDir, rel)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// removed on the host, nothing to back up
+				continue
+			}
+			return nil, err
+		}
+		backup := rel + ChangesetExportBackupSuffix
+		if err := fscopy.Copy(ctx, hostDir, rel, diffRoot, backup); err != nil {
+			return nil, fmt.Errorf("back up %s: %w", p, err)
+		}
+		backups = append(backups, filepath.ToSlash(backup))
+	}
+	return backups, nil
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -133,3 +135,81 @@ func TestChangesetConflicts(t *testing.T) {
 		})
 	}
 }
+
+func TestExportConflicts(t *testing.T) {
+	writeFiles := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for path, contents := range files {
+			path = filepath.Join(dir, path)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			if contents != "" {
+				require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+			}
+		}
+		return dir
+	}
+
+	before := writeFiles(t, map[string]string{
+		"modified.txt":          "before",
+		"modified-on-host.txt":  "before",
+		"removed.txt":           "before",
+		"removed-on-host.txt":   "before",
+		"applied.txt":           "before",
+		"removed-dir/file.txt":  "before",
+		"removed-dir2/file.txt": "before",
+	})
+	after := writeFiles(t, map[string]string{
+		"modified.txt":         "after",
+		"modified-on-host.txt": "after",
+		"applied.txt":          "after",
+		"added.txt":            "after",
+		"added-on-host.txt":    "after",
+		"added-dir/file.txt":   "after",
+	})
+	host := writeFiles(t, map[string]string{
+		"modified.txt":          "before",
+		"modified-on-host.txt":  "edited",
+		"removed.txt":           "before",
+		"removed-on-host.txt":   "edited",
+		"applied.txt":           "after",
+		"added-on-host.txt":     "edited",
+		"removed-dir/file.txt":  "before",
+		"removed-dir2/file.txt": "before",
+		"removed-dir2/new.txt":  "edited",
+	})
+
+	paths := &ChangesetPaths{
+		Added:    []string{"added.txt", "added-on-host.txt", "added-dir/", "added-dir/file.txt"},
+		Modified: []string{"modified.txt", "modified-on-host.txt", "applied.txt"},
+		Removed:  []string{"removed.txt", "removed-on-host.txt", "removed-dir/", "removed-dir2/"},
+		AllRemoved: []string{
+			"removed.txt", "removed-on-host.txt",
+			"removed-dir/file.txt", "removed-dir/",
+			"removed-dir2/file.txt", "removed-dir2/",
+		},
+	}
+	conflicts, err := exportConflicts(
+		append(append(paths.Added, paths.Modified...), paths.AllRemoved...),
+		before, after, host)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"added-on-host.txt",
+		"modified-on-host.txt",
+		"removed-on-host.txt",
+		"removed-dir2/",
+	}, conflicts)
+
+	diff := writeFiles(t, map[string]string{
+		"added.txt":            "after",
+		"added-on-host.txt":    "after",
+		"modified.txt":         "after",
+		"modified-on-host.txt": "after",
+		"applied.txt":          "after",
+	})
+	removed, err := skipExportConflicts(diff, paths, conflicts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"removed.txt", "removed-dir/", "removed-dir2/file.txt"}, removed)
+	require.NoFileExists(t, filepath.Join(diff, "added-on-host.txt"))
+	require.NoFileExists(t, filepath.Join(diff, "modified-on-host.txt"))
+	require.FileExists(t, filepath.Join(diff, "modified.txt"))
+}
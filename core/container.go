@@ -750,6 +750,18 @@ func (container *Container) Build(
 		return nil, err
 	}
 	if err := dag.Walk(func(dag *buildkit.OpDAG) error {
+		// the Dockerfile's base images are pulled by the frontend rather than
+		// Container.from, so check them against the security policy here
+		if img, ok := dag.AsImage(); ok {
+			ref, err := imageSourceRef(img.Identifier)
+			if err != nil {
+				return err
+			}
+			if err := query.CheckImagePolicy(ref); err != nil {
+				return err
+			}
+		}
+
 		// forcibly inject our trace context into each op, since st.Marshal
 		// isn't strong enough to do so
 		desc := dag.Metadata.Description
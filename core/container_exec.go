@@ -230,6 +230,10 @@ func (container *Container) WithExec(
 
 	secretEnvs := container.secretEnvs()
 
+	// module runtimes are run with execution metadata, and need the network
+	// to reach the engine
+	runtimeExec := execMD != nil
+
 	execMD, err = container.execMeta(ctx, opts, execMD)
 	if err != nil {
 		return nil, err
@@ -256,6 +260,18 @@ func (container *Container) WithExec(
 	if err != nil {
 		return nil, err
 	}
+	if !runtimeExec && !opts.ExperimentalPrivilegedNesting {
+		// dependencies that weren't granted network access run their
+		// containers with only a loopback interface; this isn't prompted
+		// for, since most execs never reach the network
+		allowed, err := query.ModuleGranted(ctx, ModulePermission{Kind: ModulePermissionNetwork})
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			metaSpec.NetMode = pb.NetMode_NONE
+		}
+	}
 
 	bkSessionGroup, ok := buildkit.CurrentBuildkitSessionGroup(ctx)
 	if !ok {
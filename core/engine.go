@@ -2,9 +2,22 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 
-	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/containerd/containerd/v2/core/mount"
+	containerdfs "github.com/containerd/continuity/fs"
+	"github.com/opencontainers/go-digest"
 	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+	"github.com/dagger/dagger/engine/buildkit"
 )
 
 type Engine struct {
@@ -22,6 +35,22 @@ func (*Engine) TypeDescription() string {
 	return "The Dagger engine configuration and state"
 }
 
+type EngineClient struct {
+	ClientID string `field:"true" name:"clientID" doc:"The ID of the client."`
+	Identity string `field:"true" doc:"The subject of the certificate the client presented when connecting over mutual TLS, or empty if it did not present one."`
+}
+
+func (*EngineClient) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "EngineClient",
+		NonNull:   true,
+	}
+}
+
+func (*EngineClient) TypeDescription() string {
+	return "A client connected to the Dagger engine"
+}
+
 type EngineCache struct {
 	MaxUsedSpace  int `field:"true" doc:"The maximum bytes to keep in the cache without pruning."`
 	TargetSpace   int `field:"true" doc:"The target number of bytes to keep when pruning."`
@@ -88,3 +117,219 @@ func (*EngineCacheEntry) Type() *ast.Type {
 func (*EngineCacheEntry) TypeDescription() string {
 	return "An individual cache entry in a cache entry set"
 }
+
+type EngineCacheMissCause struct {
+	Path     string `field:"true" doc:"The path of the call in which the differing input was found."`
+	Reason   string `field:"true" doc:"A description of how the input differs."`
+	Previous string `field:"true" doc:"The previous value of the input, if applicable."`
+	Current  string `field:"true" doc:"The current value of the input, if applicable."`
+}
+
+func (*EngineCacheMissCause) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "EngineCacheMissCause",
+		NonNull:   true,
+	}
+}
+
+func (*EngineCacheMissCause) TypeDescription() string {
+	return "An input that differs between a call and an earlier call it was expected to reuse"
+}
+
+// explainCacheMissCandidates is the number of recorded calls of the same
+// shape that are compared to the call that missed the cache.
+const explainCacheMissCandidates = 10
+
+// ExplainCacheMiss returns the inputs that differ between the given call and
+// the closest earlier call of the same shape recorded by the engine, from the
+// root of the call DAG to its tip.
+func ExplainCacheMiss(ctx context.Context, current *call.ID) ([]*EngineCacheMissCause, error) {
+	cache, err := CurrentDagqlCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cache.RecordsCalls() {
+		return nil, fmt.Errorf("the engine doesn't record calls: set recordCalls in the engine config")
+	}
+	candidates, err := cache.RecordedCalls(ctx, current, explainCacheMissCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("find earlier calls: %w", err)
+	}
+	if len(candidates) == 0 {
+		return []*EngineCacheMissCause{{
+			Path:   current.Path(),
+			Reason: "no earlier call of " + current.Shape() + " was recorded by the engine",
+		}}, nil
+	}
+
+	// the earlier call the current one was expected to reuse is assumed to be
+	// the one with the fewest differences, the most recent one on a tie
+	var diffs []*call.Difference
+	for i, candidate := range candidates {
+		candidateDiffs := call.Diff(candidate, current)
+		if i == 0 || len(candidateDiffs) < len(diffs) {
+			diffs = candidateDiffs
+		}
+	}
+	if len(diffs) == 0 {
+		return []*EngineCacheMissCause{{
+			Path:   current.Path(),
+			Reason: "no inputs differ; the earlier result may have expired, been pruned or not been safe to cache",
+		}}, nil
+	}
+	causes := make([]*EngineCacheMissCause, 0, len(diffs))
+	for _, diff := range diffs {
+		if diff.PreviousContent != "" && diff.CurrentContent != "" {
+			pathCauses, err := changedContentPaths(ctx, cache, diff)
+			if err != nil {
+				return nil, err
+			}
+			if len(pathCauses) > 0 {
+				causes = append(causes, pathCauses...)
+				continue
+			}
+		}
+		causes = append(causes, &EngineCacheMissCause{
+			Path:     diff.Path,
+			Reason:   diff.Reason,
+			Previous: diff.Previous,
+			Current:  diff.Current,
+		})
+	}
+	return causes, nil
+}
+
+// changedContentPaths returns a cause for each path that differs between the
+// previous and current content of a call, if the paths of both were recorded.
+func changedContentPaths(ctx context.Context, cache *dagql.SessionCache, diff *call.Difference) ([]*EngineCacheMissCause, error) {
+	previous, err := cache.RecordedContents(ctx, diff.PreviousContent)
+	if err != nil {
+		return nil, fmt.Errorf("find previous contents: %w", err)
+	}
+	current, err := cache.RecordedContents(ctx, diff.CurrentContent)
+	if err != nil {
+		return nil, fmt.Errorf("find current contents: %w", err)
+	}
+	if previous == nil || current == nil {
+		return nil, nil
+	}
+	return diffContentPaths(diff.Path, previous, current), nil
+}
+
+// diffContentPaths returns a cause for each path added, removed or changed
+// between two recordings of the content of the call at callPath.
+func diffContentPaths(callPath string, previous, current map[string]digest.Digest) []*EngineCacheMissCause {
+	paths := slices.Collect(maps.Keys(current))
+	for p := range previous {
+		if _, ok := current[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+
+	var causes []*EngineCacheMissCause
+	for _, p := range paths {
+		prev, cur := previous[p], current[p]
+		var reason string
+		switch {
+		case prev == cur:
+			continue
+		case prev == "":
+			reason = fmt.Sprintf("path %q added", p)
+		case cur == "":
+			reason = fmt.Sprintf("path %q removed", p)
+		default:
+			reason = fmt.Sprintf("path %q changed", p)
+		}
+		causes = append(causes, &EngineCacheMissCause{
+			Path:     callPath,
+			Reason:   reason,
+			Previous: prev.String(),
+			Current:  cur.String(),
+		})
+	}
+	return causes
+}
+
+// maxRecordedContentPaths is the most paths recorded for a directory; the
+// paths of bigger directories aren't recorded.
+const maxRecordedContentPaths = 100_000
+
+// RecordDirectoryContents records the digest of each file in a content
+// hashed directory, if the engine records calls, so that a later cache miss
+// caused by a change in its content can list the paths that changed.
+func RecordDirectoryContents(ctx context.Context, dir dagql.ObjectResult[*Directory]) error {
+	cache, err := CurrentDagqlCache(ctx)
+	if err != nil {
+		return err
+	}
+	contentDigest := dir.ID().ContentDigest()
+	if !cache.RecordsCalls() || contentDigest == "" {
+		return nil
+	}
+	ref, err := getRefOrEvaluate(ctx, dir.Self())
+	if err != nil {
+		return err
+	}
+	bkSessionGroup, ok := buildkit.CurrentBuildkitSessionGroup(ctx)
+	if !ok {
+		return fmt.Errorf("no buildkit session group in context")
+	}
+	paths := map[string]digest.Digest{}
+	err = MountRef(ctx, ref, bkSessionGroup, func(root string, _ *mount.Mount) error {
+		root, err := containerdfs.RootPath(root, dir.Self().Dir)
+		if err != nil {
+			return err
+		}
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if len(paths) == maxRecordedContentPaths {
+				return errTooManyContentPaths
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			dgst, err := pathDigest(path, d)
+			if err != nil {
+				return err
+			}
+			paths[filepath.ToSlash(rel)] = dgst
+			return nil
+		})
+	}, mountRefAsReadOnly)
+	if errors.Is(err, errTooManyContentPaths) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return cache.RecordContents(ctx, contentDigest, paths)
+}
+
+var errTooManyContentPaths = errors.New("too many paths to record")
+
+// pathDigest returns the digest of a file's content or a symlink's target.
+func pathDigest(path string, d fs.DirEntry) (digest.Digest, error) {
+	if d.Type()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return digest.FromString(target), nil
+	}
+	if !d.Type().IsRegular() {
+		return digest.FromString(d.Type().String()), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return digest.FromReader(f)
+}
@@ -0,0 +1,21 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffContentPaths(t *testing.T) {
+	a, b := digest.FromString("a"), digest.FromString("b")
+	causes := diffContentPaths(`host.directory(path: "/app")`,
+		map[string]digest.Digest{"go.mod": a, "main.go": a, "old.go": a},
+		map[string]digest.Digest{"go.mod": a, "main.go": b, "new.go": b},
+	)
+	require.Equal(t, []*EngineCacheMissCause{
+		{Path: `host.directory(path: "/app")`, Reason: `path "main.go" changed`, Previous: a.String(), Current: b.String()},
+		{Path: `host.directory(path: "/app")`, Reason: `path "new.go" added`, Current: b.String()},
+		{Path: `host.directory(path: "/app")`, Reason: `path "old.go" removed`, Previous: a.String()},
+	}, causes)
+}
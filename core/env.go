@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 
 	"github.com/dagger/dagger/dagql"
@@ -218,6 +219,296 @@ func (env *Env) Check(ctx context.Context, name string) (*Check, error) {
 	}
 }
 
+// envEncodingVersion is the version of the format written by Env.Encode.
+const envEncodingVersion = 1
+
+// envJSON is the format written by Env.Encode. Objects are referred to by
+// their encoded IDs.
+type envJSON struct {
+	Version    int           `json:"version"`
+	Workspace  string        `json:"workspace,omitempty"`
+	MainModule string        `json:"mainModule,omitempty"`
+	Modules    []string      `json:"modules,omitempty"`
+	Privileged bool          `json:"privileged,omitempty"`
+	Writable   bool          `json:"writable,omitempty"`
+	Inputs     []bindingJSON `json:"inputs"`
+	Outputs    []bindingJSON `json:"outputs"`
+}
+
+type bindingJSON struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	// The ID of an object value
+	ID string `json:"id,omitempty"`
+	// The value of a string value
+	Value *string `json:"value,omitempty"`
+	// Whether the value is a list of Type, whose elements are in IDs or Values
+	List bool `json:"list,omitempty"`
+	// The IDs of the elements of an object list
+	IDs []string `json:"ids,omitempty"`
+	// The elements of a string list
+	Values []string `json:"values,omitempty"`
+}
+
+// Encode serializes the environment and its bindings to JSON, so that it can
+// be restored with LoadEnv, e.g. in a later session.
+func (env *Env) Encode() ([]byte, error) {
+	enc := envJSON{
+		Version:    envEncodingVersion,
+		Privileged: env.privileged,
+		Writable:   env.writable,
+		Inputs:     []bindingJSON{},
+		Outputs:    []bindingJSON{},
+	}
+	if env.Workspace.Self() != nil {
+		id, err := env.Workspace.ID().Encode()
+		if err != nil {
+			return nil, fmt.Errorf("encode workspace: %w", err)
+		}
+		enc.Workspace = id
+	}
+	if env.MainModule != nil {
+		id, err := encodeModuleID(env.MainModule)
+		if err != nil {
+			return nil, err
+		}
+		enc.MainModule = id
+	}
+	for _, mod := range env.installedModules {
+		id, err := encodeModuleID(mod)
+		if err != nil {
+			return nil, err
+		}
+		enc.Modules = append(enc.Modules, id)
+	}
+	for _, input := range env.Inputs() {
+		bnd, err := input.encode()
+		if err != nil {
+			return nil, fmt.Errorf("encode input %q: %w", input.Key, err)
+		}
+		enc.Inputs = append(enc.Inputs, bnd)
+	}
+	for _, output := range env.Outputs() {
+		bnd, err := output.encode()
+		if err != nil {
+			return nil, fmt.Errorf("encode output %q: %w", output.Key, err)
+		}
+		enc.Outputs = append(enc.Outputs, bnd)
+	}
+	// sort bindings, so that the same environment always encodes the same way
+	for _, bnds := range [][]bindingJSON{enc.Inputs, enc.Outputs} {
+		slices.SortFunc(bnds, func(a, b bindingJSON) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+	return json.MarshalIndent(enc, "", "  ")
+}
+
+func encodeModuleID(mod *Module) (string, error) {
+	if mod.ResultID == nil {
+		return "", fmt.Errorf("encode module %q: module has no ID", mod.Name())
+	}
+	id, err := mod.ResultID.Encode()
+	if err != nil {
+		return "", fmt.Errorf("encode module %q: %w", mod.Name(), err)
+	}
+	return id, nil
+}
+
+func (b *Binding) encode() (bindingJSON, error) {
+	enc := bindingJSON{
+		Name:        b.Key,
+		Type:        b.ExpectedType,
+		Description: b.Description,
+	}
+	if b.Value == nil {
+		return enc, nil
+	}
+	enc.Type = b.Value.Type().Name()
+	if obj, ok := b.AsObject(); ok {
+		id, err := obj.ID().Encode()
+		if err != nil {
+			return enc, err
+		}
+		enc.ID = id
+		return enc, nil
+	}
+	if str, ok := b.AsString(); ok {
+		enc.Value = &str
+		return enc, nil
+	}
+	if list, ok := b.AsList(); ok {
+		return b.encodeList(enc, list)
+	}
+	return enc, fmt.Errorf("unsupported value of type %s", enc.Type)
+}
+
+func (b *Binding) encodeList(enc bindingJSON, list dagql.Enumerable) (bindingJSON, error) {
+	enc.Type = list.Element().Type().Name()
+	enc.List = true
+	// object elements are selected from the list's own ID, if it has one
+	var listID *call.ID
+	if res, ok := dagql.UnwrapAs[dagql.AnyResult](b.Value); ok {
+		listID = res.ID()
+	}
+	for i := 1; i <= list.Len(); i++ {
+		elem, err := list.Nth(i)
+		if err != nil {
+			return enc, err
+		}
+		if str, ok := dagql.UnwrapAs[dagql.String](elem); ok {
+			enc.Values = append(enc.Values, str.String())
+			continue
+		}
+		val, err := list.NthValue(i, listID)
+		if err != nil {
+			return enc, err
+		}
+		obj, ok := dagql.UnwrapAs[dagql.AnyObjectResult](val)
+		if !ok {
+			return enc, fmt.Errorf("unsupported list element of type %s", enc.Type)
+		}
+		id, err := obj.ID().Encode()
+		if err != nil {
+			return enc, fmt.Errorf("encode list element %d: %w", i, err)
+		}
+		enc.IDs = append(enc.IDs, id)
+	}
+	return enc, nil
+}
+
+// LoadEnv restores an environment serialized by Env.Encode, loading the
+// objects that it refers to.
+func LoadEnv(ctx context.Context, srv *dagql.Server, data []byte, deps *ModDeps) (*Env, error) {
+	var enc envJSON
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return nil, fmt.Errorf("decode env: %w", err)
+	}
+	if enc.Version != envEncodingVersion {
+		return nil, fmt.Errorf("unsupported env encoding version %d, expected %d", enc.Version, envEncodingVersion)
+	}
+
+	var workspace dagql.ObjectResult[*Directory]
+	if enc.Workspace != "" {
+		id, err := decodeEnvID(enc.Workspace)
+		if err != nil {
+			return nil, fmt.Errorf("decode workspace: %w", err)
+		}
+		workspace, err = dagql.NewID[*Directory](id).Load(ctx, srv)
+		if err != nil {
+			return nil, fmt.Errorf("load workspace: %w", err)
+		}
+	}
+	env := NewEnv(workspace, deps)
+	if enc.MainModule != "" {
+		mod, err := loadEnvModule(ctx, srv, enc.MainModule)
+		if err != nil {
+			return nil, err
+		}
+		env = env.WithMainModule(mod)
+	}
+	for _, modID := range enc.Modules {
+		mod, err := loadEnvModule(ctx, srv, modID)
+		if err != nil {
+			return nil, err
+		}
+		env = env.WithModule(mod)
+	}
+	env.privileged = enc.Privileged
+	env.writable = enc.Writable
+
+	for _, input := range enc.Inputs {
+		val, err := input.load(ctx, srv)
+		if err != nil {
+			return nil, fmt.Errorf("load input %q: %w", input.Name, err)
+		}
+		if val == nil {
+			return nil, fmt.Errorf("load input %q: missing value", input.Name)
+		}
+		env.inputsByName[input.Name] = &Binding{
+			Key:         input.Name,
+			Value:       val,
+			Description: input.Description,
+		}
+	}
+	for _, output := range enc.Outputs {
+		val, err := output.load(ctx, srv)
+		if err != nil {
+			return nil, fmt.Errorf("load output %q: %w", output.Name, err)
+		}
+		env.outputsByName[output.Name] = &Binding{
+			Key:          output.Name,
+			Value:        val,
+			ExpectedType: output.Type,
+			Description:  output.Description,
+		}
+	}
+	return env, nil
+}
+
+func decodeEnvID(str string) (*call.ID, error) {
+	var id call.ID
+	if err := id.Decode(str); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func loadEnvModule(ctx context.Context, srv *dagql.Server, str string) (*Module, error) {
+	id, err := decodeEnvID(str)
+	if err != nil {
+		return nil, fmt.Errorf("decode module: %w", err)
+	}
+	mod, err := dagql.NewID[*Module](id).Load(ctx, srv)
+	if err != nil {
+		return nil, fmt.Errorf("load module: %w", err)
+	}
+	return mod.Self(), nil
+}
+
+func (b bindingJSON) load(ctx context.Context, srv *dagql.Server) (dagql.Typed, error) {
+	switch {
+	case b.List && b.Type == "String":
+		return dagql.NewStringArray(b.Values...), nil
+	case b.List:
+		objType, ok := srv.ObjectType(b.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown list element type %s", b.Type)
+		}
+		list := dagql.DynamicResultArrayOutput{Elem: objType.Typed()}
+		for i, str := range b.IDs {
+			obj, err := b.loadObject(ctx, srv, str)
+			if err != nil {
+				return nil, fmt.Errorf("load list element %d: %w", i+1, err)
+			}
+			list.Values = append(list.Values, obj)
+		}
+		return list, nil
+	case b.ID != "":
+		return b.loadObject(ctx, srv, b.ID)
+	case b.Value != nil:
+		return dagql.NewString(*b.Value), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (b bindingJSON) loadObject(ctx context.Context, srv *dagql.Server, str string) (dagql.AnyObjectResult, error) {
+	id, err := decodeEnvID(str)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := srv.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type().Name() != b.Type {
+		return nil, fmt.Errorf("type mismatch: expected %s, got %s", b.Type, obj.Type().Name())
+	}
+	return obj, nil
+}
+
 type Binding struct {
 	Key         string
 	Value       dagql.Typed
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine/cache"
+)
+
+func TestEnvEncode(t *testing.T) {
+	ctx := context.Background()
+
+	env := NewEnv(dagql.ObjectResult[*Directory]{}, nil).
+		Writable().
+		WithInput("task", dagql.NewString("fix the tests"), "the task to complete").
+		WithOutput("verdict", dagql.String(""), "whether the change is ready").
+		WithOutput("summary", dagql.String(""), "what was changed")
+	verdict, ok := env.Output("verdict")
+	require.True(t, ok)
+	verdict.Value = dagql.NewString("ship it")
+
+	data, err := env.Encode()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": 1,
+		"writable": true,
+		"inputs": [
+			{"name": "task", "type": "String", "description": "the task to complete", "value": "fix the tests"}
+		],
+		"outputs": [
+			{"name": "summary", "type": "String", "description": "what was changed"},
+			{"name": "verdict", "type": "String", "description": "whether the change is ready", "value": "ship it"}
+		]
+	}`, string(data))
+
+	loaded, err := LoadEnv(ctx, nil, data, nil)
+	require.NoError(t, err)
+	assert.True(t, loaded.writable)
+	assert.False(t, loaded.IsPrivileged())
+
+	task, ok := loaded.Input("task")
+	require.True(t, ok)
+	assert.Equal(t, "fix the tests", task.String())
+	assert.Equal(t, "the task to complete", task.Description)
+
+	verdict, ok = loaded.Output("verdict")
+	require.True(t, ok)
+	assert.Equal(t, "ship it", verdict.String())
+	assert.Equal(t, "String", verdict.TypeName())
+
+	summary, ok := loaded.Output("summary")
+	require.True(t, ok)
+	assert.Nil(t, summary.Value)
+	assert.Equal(t, "what was changed", summary.Description)
+
+	// restoring the encoded environment encodes the same way
+	again, err := loaded.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+
+	_, err = LoadEnv(ctx, nil, []byte(`{"version": 2}`), nil)
+	assert.ErrorContains(t, err, "unsupported env encoding version 2")
+}
+
+func TestEnvEncodeList(t *testing.T) {
+	ctx := context.Background()
+
+	baseCache, err := cache.NewCache[string, dagql.AnyResult](ctx, "")
+	require.NoError(t, err)
+	srv := dagql.NewServer(LLMTestQuery{}, dagql.NewSessionCache(baseCache))
+	dagql.Fields[LLMTestQuery]{
+		dagql.Func("secret", func(ctx context.Context, self LLMTestQuery, args struct {
+			URI string
+		}) (mockSecret, error) {
+			return mockSecret{uri: args.URI}, nil
+		}),
+	}.Install(srv)
+	dagql.Fields[mockSecret]{}.Install(srv)
+
+	var secrets dagql.ObjectResultArray[mockSecret]
+	for _, uri := range []string{"env://A", "env://B"} {
+		var secret dagql.ObjectResult[mockSecret]
+		require.NoError(t, srv.Select(ctx, srv.Root(), &secret, dagql.Selector{
+			Field: "secret",
+			Args:  []dagql.NamedInput{{Name: "uri", Value: dagql.NewString(uri)}},
+		}))
+		secrets = append(secrets, secret)
+	}
+
+	env := NewEnv(dagql.ObjectResult[*Directory]{}, nil).
+		WithInput("files", dagql.NewStringArray("go.mod", "go.sum"), "the files to check").
+		WithInput("none", dagql.NewStringArray(), "").
+		WithInput("secrets", secrets, "the secrets to use")
+
+	data, err := env.Encode()
+	require.NoError(t, err)
+
+	loaded, err := LoadEnv(ctx, srv, data, nil)
+	require.NoError(t, err)
+
+	files, ok := loaded.Input("files")
+	require.True(t, ok)
+	list, ok := files.AsList()
+	require.True(t, ok)
+	require.Equal(t, 2, list.Len())
+	elem, err := list.Nth(2)
+	require.NoError(t, err)
+	assert.Equal(t, dagql.NewString("go.sum"), elem)
+
+	none, ok := loaded.Input("none")
+	require.True(t, ok)
+	list, ok = none.AsList()
+	require.True(t, ok)
+	assert.Equal(t, 0, list.Len())
+
+	loadedSecrets, ok := loaded.Input("secrets")
+	require.True(t, ok)
+	list, ok = loadedSecrets.AsList()
+	require.True(t, ok)
+	require.Equal(t, 2, list.Len())
+	for i, secret := range secrets {
+		val, err := list.NthValue(i+1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, secret.ID().Digest(), val.ID().Digest())
+	}
+
+	// restoring the encoded environment encodes the same way
+	again, err := loaded.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
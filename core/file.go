@@ -629,6 +629,24 @@ func (file *File) AsJSON(ctx context.Context) (JSON, error) {
 	return json, nil
 }
 
+// AsYAML parses the file contents as a YAML document
+func (file *File) AsYAML(ctx context.Context) (*JSONValue, error) {
+	contents, err := file.Contents(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONValueFromYAML(contents)
+}
+
+// AsTOML parses the file contents as a TOML document
+func (file *File) AsTOML(ctx context.Context) (*JSONValue, error) {
+	contents, err := file.Contents(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONValueFromTOML(contents)
+}
+
 // AsEnvFile converts a File to an EnvFile by parsing its contents
 func (file *File) AsEnvFile(ctx context.Context, expand bool) (*EnvFile, error) {
 	contents, err := file.Contents(ctx, nil, nil)
@@ -163,6 +163,23 @@ func (repo *RemoteGitRepository) runLsRemote(ctx context.Context) (*gitutil.Remo
 	if err != nil {
 		return nil, err
 	}
+	offline, err := query.Offline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		// serve the refs from the last time they were listed
+		payload, ok := query.Resolutions().Get(ctx, ResolutionGitRemote, repo.URL.Remote())
+		if !ok {
+			return nil, &OfflineError{Resource: repo.URL.Remote()}
+		}
+		var remote gitutil.Remote
+		if err := json.Unmarshal([]byte(payload), &remote); err != nil {
+			return nil, fmt.Errorf("decode recorded remote: %w", err)
+		}
+		return &remote, nil
+	}
+
 	svcs, err := query.Services(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get services: %w", err)
@@ -183,6 +200,11 @@ func (repo *RemoteGitRepository) runLsRemote(ctx context.Context) (*gitutil.Remo
 	if err != nil {
 		return nil, err
 	}
+	if payload, err := json.Marshal(remote); err == nil {
+		if err := query.Resolutions().Put(ctx, ResolutionGitRemote, repo.URL.Remote(), string(payload)); err != nil {
+			slog.Warn("failed to record git remote resolution", "remote", repo.URL.Remote(), "error", err)
+		}
+	}
 	return remote, nil
 }
 
@@ -336,6 +358,20 @@ func (repo *RemoteGitRepository) mount(ctx context.Context, depth int, refs []Gi
 			}
 		}
 
+		if len(fetchRefs) > 0 {
+			query, err := CurrentQuery(ctx)
+			if err != nil {
+				return err
+			}
+			offline, err := query.Offline(ctx)
+			if err != nil {
+				return err
+			}
+			if offline {
+				return &OfflineError{Resource: repo.URL.Remote() + "@" + fetchRefs[0].SHA}
+			}
+		}
+
 		err = repo.fetch(ctx, git, depth, fetchRefs)
 		if err != nil {
 			return err
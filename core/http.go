@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -70,6 +71,36 @@ func DoHTTPRequest(
 		}
 	}
 
+	offline, err := query.Offline(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if offline {
+		// serve the last download of the URL without revalidating it
+		for _, md := range latestHTTPDownloads(mds) {
+			dgst := md.getHTTPChecksum()
+			snap, err := cache.Get(ctx, md.ID(), nil)
+			if err != nil {
+				continue
+			}
+			resp := &http.Response{
+				Status:     http.StatusText(http.StatusOK),
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(bytes.NewReader(nil)),
+				Request:    req,
+			}
+			if etag := md.getETag(); etag != "" {
+				resp.Header.Set("ETag", etag)
+			}
+			if modTime := md.getHTTPModTime(); modTime != "" {
+				resp.Header.Set("Last-Modified", modTime)
+			}
+			return snap, dgst, resp, nil
+		}
+		return nil, "", nil, &OfflineError{Resource: url}
+	}
+
 	dns, err := DNSConfig(ctx)
 	if err != nil {
 		return nil, "", nil, err
@@ -178,9 +209,10 @@ func DoHTTPRequest(
 		if err := md.setETag(respETag); err != nil {
 			return nil, "", nil, err
 		}
-		if err := md.setHTTPChecksum(urlDigest, contentDgst); err != nil {
-			return nil, "", nil, err
-		}
+	}
+	// always index the download, so it can be served while offline
+	if err := md.setHTTPChecksum(urlDigest, contentDgst); err != nil {
+		return nil, "", nil, err
 	}
 	if modTime := resp.Header.Get("Last-Modified"); modTime != "" {
 		if err := md.setHTTPModTime(modTime); err != nil {
@@ -192,6 +224,21 @@ func DoHTTPRequest(
 	return snap, contentDgst, resp, nil
 }
 
+// latestHTTPDownloads returns the completed downloads of a URL, most recent
+// first.
+func latestHTTPDownloads(mds []cacheRefMetadata) []cacheRefMetadata {
+	var downloads []cacheRefMetadata
+	for _, md := range mds {
+		if md.getHTTPChecksum() != "" {
+			downloads = append(downloads, md)
+		}
+	}
+	slices.SortStableFunc(downloads, func(a, b cacheRefMetadata) int {
+		return b.GetCreatedAt().Compare(a.GetCreatedAt())
+	})
+	return downloads
+}
+
 func etagValue(v string) string {
 	// remove weak for direct comparison
 	return strings.TrimPrefix(v, "W/")
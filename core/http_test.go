@@ -0,0 +1,50 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	bkcache "github.com/dagger/dagger/internal/buildkit/cache"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHTTPRefMetadata struct {
+	bkcache.RefMetadata
+	id        string
+	checksum  string
+	createdAt time.Time
+}
+
+func (md fakeHTTPRefMetadata) ID() string {
+	return md.id
+}
+
+func (md fakeHTTPRefMetadata) GetCreatedAt() time.Time {
+	return md.createdAt
+}
+
+func (md fakeHTTPRefMetadata) GetString(key string) string {
+	if key == keyHTTPChecksum {
+		return md.checksum
+	}
+	return ""
+}
+
+func TestLatestHTTPDownloads(t *testing.T) {
+	now := time.Now()
+	mds := []cacheRefMetadata{
+		{fakeHTTPRefMetadata{id: "old", checksum: "sha256:aaa", createdAt: now.Add(-time.Hour)}},
+		{fakeHTTPRefMetadata{id: "incomplete", createdAt: now.Add(time.Hour)}},
+		{fakeHTTPRefMetadata{id: "new", checksum: "sha256:bbb", createdAt: now}},
+	}
+
+	downloads := latestHTTPDownloads(mds)
+	ids := make([]string, len(downloads))
+	for i, md := range downloads {
+		ids[i] = md.ID()
+	}
+	// offline mode serves the newest download, not whichever is indexed first
+	require.Equal(t, []string{"new", "old"}, ids)
+
+	require.Empty(t, latestHTTPDownloads(nil))
+}
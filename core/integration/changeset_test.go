@@ -584,6 +584,12 @@ func (t *Test) Update() *dagger.Changeset {
 func (t *Test) NoChanges() *dagger.Changeset {
 	return t.Dir.Changes(t.Dir)
 }
+
+func (t *Test) AddDir() *dagger.Changeset {
+	return t.Dir.
+		WithNewFile("newdir/new.txt", "im new here").
+		Changes(t.Dir)
+}
 `,
 		).
 		With(daggerCall("dir", "-o", "./outdir"))
@@ -639,6 +645,88 @@ func (t *Test) NoChanges() *dagger.Changeset {
 		require.NoError(t, err)
 		require.Contains(t, out, "no changes to apply")
 	})
+
+	t.Run("conflicts", func(ctx context.Context, t *testctx.T) {
+		// edit a file modified by the changeset and a file removed by it
+		edited := modGen.
+			WithNewFile("./outdir/foo.txt", "my local edit").
+			WithNewFile("./outdir/bar.txt", "my other local edit")
+
+		t.Run("fail", func(ctx context.Context, t *testctx.T) {
+			_, err := edited.With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "FAIL")).Sync(ctx)
+			requireErrOut(t, err, "conflicts with changes on the host: foo.txt, bar.txt")
+
+			contents, err := edited.File("./outdir/foo.txt").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "my local edit", contents)
+		})
+
+		t.Run("skip", func(ctx context.Context, t *testctx.T) {
+			ctr, err := edited.With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "SKIP")).Sync(ctx)
+			require.NoError(t, err)
+
+			entries, err := ctr.Directory("./outdir").Entries(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"bar.txt", "baz.txt", "foo.txt"}, entries)
+
+			contents, err := ctr.File("./outdir/foo.txt").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "my local edit", contents)
+		})
+
+		t.Run("backup", func(ctx context.Context, t *testctx.T) {
+			ctr, err := edited.With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "BACKUP")).Sync(ctx)
+			require.NoError(t, err)
+
+			entries, err := ctr.Directory("./outdir").Entries(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"bar.txt.orig", "baz.txt", "foo.txt", "foo.txt.orig"}, entries)
+
+			contents, err := ctr.File("./outdir/foo.txt").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "foo\nbaz", contents)
+
+			contents, err = ctr.File("./outdir/foo.txt.orig").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "my local edit", contents)
+		})
+
+		t.Run("diff intact", func(ctx context.Context, t *testctx.T) {
+			// skipping and backing up conflicts must not modify the cached diff
+			ctr, err := edited.
+				With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "SKIP")).
+				With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "BACKUP")).
+				With(daggerCall("update", "export", "--path", "./otherdir")).
+				Sync(ctx)
+			require.NoError(t, err)
+
+			entries, err := ctr.Directory("./otherdir").Entries(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"baz.txt", "foo.txt"}, entries)
+
+			contents, err := ctr.File("./otherdir/foo.txt").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "foo\nbaz", contents)
+		})
+
+		t.Run("unchanged", func(ctx context.Context, t *testctx.T) {
+			ctr, err := modGen.With(daggerCall("update", "export", "--path", "./outdir", "--on-conflict", "FAIL")).Sync(ctx)
+			require.NoError(t, err)
+
+			entries, err := ctr.Directory("./outdir").Entries(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"baz.txt", "foo.txt"}, entries)
+		})
+
+		t.Run("added directory", func(ctx context.Context, t *testctx.T) {
+			ctr, err := modGen.With(daggerCall("add-dir", "export", "--path", "./outdir", "--on-conflict", "FAIL")).Sync(ctx)
+			require.NoError(t, err)
+
+			contents, err := ctr.File("./outdir/newdir/new.txt").Contents(ctx)
+			require.NoError(t, err)
+			require.Equal(t, "im new here", contents)
+		})
+	})
 }
 
 func (s ChangesetSuite) TestWithChanges(ctx context.Context, t *testctx.T) {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"dagger.io/dagger"
+	"github.com/dagger/dagger/internal/testutil"
 	"github.com/dagger/testctx"
 	"github.com/stretchr/testify/require"
 )
@@ -219,3 +220,25 @@ func (JSONSuite) TestBytes(ctx context.Context, t *testctx.T) {
 	require.Contains(t, prettyStr, "\n")
 	require.Contains(t, prettyStr, "  ") // indentation
 }
+
+func (JSONSuite) TestYAMLAndTOML(ctx context.Context, t *testctx.T) {
+	res, err := testutil.Query[struct {
+		File struct {
+			AsYAML struct {
+				Contents string
+				AsTOML   string
+			}
+		}
+	}](t,
+		`{
+			file(name: "values.yaml", contents: "# comment\nimage:\n  tag: 1.2.3\n") {
+				asYAML {
+					contents
+					asTOML
+				}
+			}
+		}`, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"image":{"tag":"1.2.3"}}`, res.File.AsYAML.Contents)
+	require.Equal(t, "\n[image]\n  tag = \"1.2.3\"\n", res.File.AsYAML.AsTOML)
+}
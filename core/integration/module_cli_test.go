@@ -1578,6 +1578,18 @@ func (CLISuite) TestDaggerUpdate(ctx context.Context, t *testctx.T) {
 		]
 	}`
 
+	depHasConstraint := `{
+		"name": "foo",
+		"sdk": "go",
+		"dependencies": [
+			{
+				"name": "docker",
+				"source": "github.com/shykes/daggerverse/docker@>=0.4.1 <=0.4.2",
+				"pin": "` + v041DockerPin + `"
+			}
+		]
+	}`
+
 	testcases := []struct {
 		name          string
 		daggerjson    string
@@ -1586,6 +1598,27 @@ func (CLISuite) TestDaggerUpdate(ctx context.Context, t *testctx.T) {
 		notContains   []string
 		expectedError string
 	}{
+		{
+			name:        "existing dep has constraint, update cmd has no version",
+			daggerjson:  depHasConstraint,
+			updateCmd:   []string{"update", "docker"},
+			contains:    []string{`"github.com/shykes/daggerverse/docker@>=0.4.1 <=0.4.2"`, v042DockerPin},
+			notContains: []string{v041DockerPin},
+		},
+		{
+			name:        "existing dep has constraint, implicitly update all",
+			daggerjson:  depHasConstraint,
+			updateCmd:   []string{"update"},
+			contains:    []string{`"github.com/shykes/daggerverse/docker@>=0.4.1 <=0.4.2"`, v042DockerPin},
+			notContains: []string{v041DockerPin},
+		},
+		{
+			name:        "existing dep has version, update cmd has constraint",
+			daggerjson:  depHasOldVersion,
+			updateCmd:   []string{"update", "github.com/shykes/daggerverse/docker@~0.4.1"},
+			contains:    []string{`"github.com/shykes/daggerverse/docker@~0.4.1"`},
+			notContains: []string{randomMainPin},
+		},
 		{
 			name:        "existing dep has version, update cmd has version",
 			daggerjson:  depHasOldVersion,
@@ -1723,6 +1756,99 @@ func (CLISuite) TestDaggerUpdate(ctx context.Context, t *testctx.T) {
 	}
 }
 
+func (CLISuite) TestDaggerModuleDeps(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ctr := c.Container().
+		From("alpine:latest").
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work").
+		With(daggerExec("init", "--sdk=go", "--name=foo", "--source=.")).
+		With(daggerExec("install", "github.com/shykes/daggerverse/docker@^0.4.1"))
+
+	t.Run("constraint is kept in dagger.json", func(ctx context.Context, t *testctx.T) {
+		daggerjson, err := ctr.File("dagger.json").Contents(ctx)
+		require.NoError(t, err)
+		require.Contains(t, daggerjson, `"github.com/shykes/daggerverse/docker@^0.4.1"`)
+	})
+
+	t.Run("lock records transitive dependencies", func(ctx context.Context, t *testctx.T) {
+		lockContents, err := ctr.File("dagger.lock").Contents(ctx)
+		require.NoError(t, err)
+		lock, err := modules.ParseModuleLock([]byte(lockContents))
+		require.NoError(t, err)
+		require.Empty(t, lock.Conflicts())
+
+		var docker *modules.ModuleLockEntry
+		for _, entry := range lock.Modules {
+			if entry.Source == "github.com/shykes/daggerverse/docker" {
+				docker = entry
+			}
+		}
+		require.NotNil(t, docker)
+		require.Equal(t, []string{"^0.4.1"}, docker.Constraints)
+		require.Equal(t, []string{"."}, docker.RequiredBy)
+		require.True(t, strings.HasPrefix(docker.Version, "docker/v0.4."), docker.Version)
+	})
+
+	t.Run("deps tree", func(ctx context.Context, t *testctx.T) {
+		out, err := ctr.With(daggerExec("module", "deps", "--tree")).Stdout(ctx)
+		require.NoError(t, err)
+		require.Contains(t, out, "└── docker github.com/shykes/daggerverse/docker@docker/v0.4.")
+		require.NotContains(t, out, "[conflict]")
+	})
+}
+
+func (CLISuite) TestDaggerInstallOCI(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	ref := registryRef("oci-module")
+
+	published, err := goGitBase(t, c).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work/dep").
+		With(daggerExec("init", "--source=.", "--name=dep", "--sdk=go")).
+		WithNewFile("/work/dep/main.go", `package main
+
+type Dep struct {}
+
+func (m *Dep) Hello() string { return "hi from oci" }
+`,
+		).
+		With(daggerExec("publish", "--oci", ref)).
+		Stdout(ctx)
+	require.NoError(t, err)
+	require.Contains(t, published, "@sha256:")
+
+	ctr := goGitBase(t, c).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work/test").
+		With(daggerExec("init", "--source=.", "--name=test", "--sdk=go")).
+		With(daggerExec("install", ref)).
+		WithNewFile("/work/test/main.go", `package main
+
+import "context"
+
+type Test struct {}
+
+func (m *Test) Fn(ctx context.Context) (string, error) { return dag.Dep().Hello(ctx) }
+`,
+		)
+
+	t.Run("dep is pinned by digest", func(ctx context.Context, t *testctx.T) {
+		daggerjson, err := ctr.File("dagger.json").Contents(ctx)
+		require.NoError(t, err)
+		require.Contains(t, daggerjson, `"source": "`+ref+`"`)
+		require.Contains(t, daggerjson, `"pin": "sha256:`)
+	})
+
+	t.Run("call dep", func(ctx context.Context, t *testctx.T) {
+		out, err := ctr.With(daggerCall("fn")).Stdout(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "hi from oci", strings.TrimSpace(out))
+	})
+}
+
 func (CLISuite) TestInvalidModule(ctx context.Context, t *testctx.T) {
 	c := connect(ctx, t)
 
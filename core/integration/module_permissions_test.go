@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+
+	"github.com/dagger/testctx"
+	"github.com/stretchr/testify/require"
+)
+
+func (ModuleSuite) TestDependencyNetworkPermission(ctx context.Context, t *testctx.T) {
+	c := connect(ctx, t)
+
+	modGen := goGitBase(t, c).
+		WithMountedFile(testCLIBinPath, daggerCliFile(t, c)).
+		WithWorkdir("/work/dep").
+		With(daggerExec("init", "--name=dep", "--sdk=go", "--source=.")).
+		With(sdkSource("go", `package main
+
+import "context"
+
+type Dep struct{}
+
+func (m *Dep) FetchHTTP(ctx context.Context) (string, error) {
+	return dag.HTTP("https://dagger.io").Contents(ctx)
+}
+
+func (m *Dep) FetchGit(ctx context.Context) (string, error) {
+	return dag.Git("https://github.com/dagger/dagger").Head().Commit(ctx)
+}
+`)).
+		WithWorkdir("/work").
+		With(daggerExec("init", "--name=test", "--sdk=go", "--source=.")).
+		With(sdkSource("go", `package main
+
+import "context"
+
+type Test struct{}
+
+func (m *Test) FetchHTTP(ctx context.Context) (string, error) {
+	return dag.Dep().FetchHTTP(ctx)
+}
+
+func (m *Test) FetchGit(ctx context.Context) (string, error) {
+	return dag.Dep().FetchGit(ctx)
+}
+`)).
+		With(daggerExec("install", "./dep")).
+		WithNewFile("dagger.json", `{
+  "name": "test",
+  "sdk": {"source": "go"},
+  "source": ".",
+  "dependencies": [
+    {
+      "name": "dep",
+      "source": "dep",
+      "permissions": {"network": false}
+    }
+  ]
+}`)
+
+	for _, fn := range []string{"fetch-http", "fetch-git"} {
+		t.Run(fn, func(ctx context.Context, t *testctx.T) {
+			_, err := modGen.With(daggerCall(fn)).Stdout(ctx)
+			requireErrOut(t, err, "was denied network access")
+		})
+	}
+
+	t.Run("allowed", func(ctx context.Context, t *testctx.T) {
+		out, err := modGen.
+			WithNewFile("dagger.json", `{
+  "name": "test",
+  "sdk": {"source": "go"},
+  "source": ".",
+  "dependencies": [
+    {
+      "name": "dep",
+      "source": "dep",
+      "permissions": {"network": true}
+    }
+  ]
+}`).
+			With(daggerCall("fetch-git")).
+			Stdout(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, out)
+	})
+}
@@ -1,10 +1,27 @@
 package core
 
-import "github.com/vektah/gqlparser/v2/ast"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gopkg.in/yaml.v3"
+)
 
 // JSONValue is a simple state carrier for JSON-encoded bytes
 type JSONValue struct {
 	Data []byte
+
+	// YAML is the YAML document this value was parsed from, if any.
+	//
+	// It is kept alongside Data so that edits made with WithField can be
+	// serialized back to YAML without losing comments, key order or style.
+	YAML []byte
 }
 
 func (*JSONValue) Type() *ast.Type {
@@ -13,3 +30,277 @@ func (*JSONValue) Type() *ast.Type {
 		NonNull:   true,
 	}
 }
+
+// NewJSONValueFromYAML parses a single YAML document into a JSONValue. Streams
+// of several documents are rejected, rather than silently dropping all but the
+// first one.
+func NewJSONValueFromYAML(data []byte) (*JSONValue, error) {
+	var v any
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&v); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var next yaml.Node
+	switch err := dec.Decode(&next); {
+	case errors.Is(err, io.EOF):
+	case err != nil:
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	default:
+		return nil, fmt.Errorf("invalid YAML: expected a single document, found several")
+	}
+	v, err := normalizeYAMLValue(v)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONValue{Data: jsonData, YAML: data}, nil
+}
+
+// NewJSONValueFromTOML parses a TOML document into a JSONValue.
+func NewJSONValueFromTOML(data []byte) (*JSONValue, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+	jsonData, err := json.Marshal(tree.ToMap())
+	if err != nil {
+		return nil, err
+	}
+	return &JSONValue{Data: jsonData}, nil
+}
+
+// AsYAML encodes the value as a YAML document.
+//
+// If the value was parsed from YAML, the original document is re-encoded,
+// which preserves its comments and key order.
+func (v *JSONValue) AsYAML() ([]byte, error) {
+	if v.YAML != nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(v.YAML, &doc); err != nil {
+			return nil, err
+		}
+		return encodeYAML(&doc)
+	}
+	var val any
+	if err := json.Unmarshal(v.Data, &val); err != nil {
+		return nil, err
+	}
+	return encodeYAML(val)
+}
+
+// AsTOML encodes the value as a TOML document. Only objects can be encoded,
+// since TOML has no representation for a bare value.
+func (v *JSONValue) AsTOML() ([]byte, error) {
+	val, err := decodeJSONNumbers(v.Data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("only objects can be encoded as TOML")
+	}
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return nil, fmt.Errorf("encode TOML: %w", err)
+	}
+	return tree.Marshal()
+}
+
+// WithField returns a copy of the value with the field at the given path set
+// to the given JSON-encoded value. Intermediate objects are created as needed.
+func (v *JSONValue) WithField(path []string, value []byte) (*JSONValue, error) {
+	var root any
+	if err := json.Unmarshal(v.Data, &root); err != nil {
+		return nil, err
+	}
+	// Ensure root is an object
+	rootMap, ok := root.(map[string]any)
+	if !ok {
+		rootMap = make(map[string]any)
+	}
+
+	// Get the value to set
+	var setValue any
+	if err := json.Unmarshal(value, &setValue); err != nil {
+		return nil, err
+	}
+	// Navigate to the parent of the target field
+	current := rootMap
+	for i, key := range path {
+		if i == len(path)-1 {
+			// Set the final value
+			current[key] = setValue
+			break
+		}
+		// Navigate deeper, creating objects as needed
+		if m, ok := current[key].(map[string]any); ok {
+			current = m
+		} else {
+			// Replace missing or non-object value with new object
+			newMap := make(map[string]any)
+			current[key] = newMap
+			current = newMap
+		}
+	}
+	data, err := json.Marshal(rootMap)
+	if err != nil {
+		return nil, err
+	}
+	result := &JSONValue{Data: data}
+
+	if v.YAML != nil {
+		// Apply the same edit to the YAML document, so that it can be
+		// encoded again with its comments intact.
+		result.YAML, err = yamlWithField(v.YAML, path, setValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func yamlWithField(src []byte, path []string, value any) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return nil, err
+	}
+
+	current := doc.Content[0]
+	if current.Kind != yaml.MappingNode {
+		*current = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for i, key := range path {
+		var child *yaml.Node
+		for j := 0; j+1 < len(current.Content); j += 2 {
+			if current.Content[j].Value == key {
+				child = current.Content[j+1]
+				break
+			}
+		}
+		last := i == len(path)-1
+		switch {
+		case child == nil:
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = valueNode
+			}
+			current.Content = append(current.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				child,
+			)
+		case last:
+			// keep comments attached to the replaced value
+			valueNode.HeadComment = child.HeadComment
+			valueNode.LineComment = child.LineComment
+			valueNode.FootComment = child.FootComment
+			*child = *valueNode
+		case child.Kind != yaml.MappingNode:
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", LineComment: child.LineComment}
+		}
+		current = child
+	}
+	return encodeYAML(&doc)
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeYAMLValue converts maps with non-string keys, which YAML allows
+// but JSON does not, to maps keyed by the string form of each key.
+func normalizeYAMLValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			norm, err := normalizeYAMLValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = norm
+		}
+		return v, nil
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, elem := range v {
+			norm, err := normalizeYAMLValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = norm
+		}
+		return m, nil
+	case []any:
+		for i, elem := range v {
+			norm, err := normalizeYAMLValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = norm
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
+
+// decodeJSONNumbers decodes JSON, keeping integers as int64 rather than
+// float64 so that encoders with distinct integer types round-trip them.
+func decodeJSONNumbers(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid JSON: unexpected trailing data")
+	}
+	return convertJSONNumbers(v), nil
+}
+
+func convertJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, elem := range v {
+			v[k] = convertJSONNumbers(elem)
+		}
+		return v
+	case []any:
+		for i, elem := range v {
+			v[i] = convertJSONNumbers(elem)
+		}
+		return v
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
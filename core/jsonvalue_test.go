@@ -0,0 +1,95 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONValueFromYAML(t *testing.T) {
+	v, err := NewJSONValueFromYAML([]byte(`
+name: app
+replicas: 3
+ports:
+  - 80
+  - 443
+1: numeric-key
+`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"app","replicas":3,"ports":[80,443],"1":"numeric-key"}`, string(v.Data))
+
+	_, err = NewJSONValueFromYAML([]byte("a: [b"))
+	require.ErrorContains(t, err, "invalid YAML")
+
+	_, err = NewJSONValueFromYAML([]byte("a: 1\n---\nb: 2\n"))
+	require.ErrorContains(t, err, "expected a single document")
+
+	v, err = NewJSONValueFromYAML([]byte("---\na: 1\n"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a":1}`, string(v.Data))
+
+	v, err = NewJSONValueFromYAML(nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `null`, string(v.Data))
+}
+
+func TestJSONValueYAMLWithFieldKeepsComments(t *testing.T) {
+	v, err := NewJSONValueFromYAML([]byte(`# chart values
+image:
+  repository: example/app # upstream image
+  tag: 1.2.3 # bumped by CI
+replicas: 2
+`))
+	require.NoError(t, err)
+
+	v, err = v.WithField([]string{"image", "tag"}, []byte(`"1.3.0"`))
+	require.NoError(t, err)
+	v, err = v.WithField([]string{"resources", "limits", "cpu"}, []byte(`"500m"`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"image": {"repository": "example/app", "tag": "1.3.0"},
+		"replicas": 2,
+		"resources": {"limits": {"cpu": "500m"}}
+	}`, string(v.Data))
+
+	out, err := v.AsYAML()
+	require.NoError(t, err)
+	require.Equal(t, `# chart values
+image:
+  repository: example/app # upstream image
+  tag: 1.3.0 # bumped by CI
+replicas: 2
+resources:
+  limits:
+    cpu: 500m
+`, string(out))
+}
+
+func TestJSONValueTOML(t *testing.T) {
+	v, err := NewJSONValueFromTOML([]byte(`
+[package]
+name = "app"
+version = "0.1.0"
+edition = 2021
+`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"package":{"name":"app","version":"0.1.0","edition":2021}}`, string(v.Data))
+
+	v, err = v.WithField([]string{"package", "version"}, []byte(`"0.2.0"`))
+	require.NoError(t, err)
+
+	out, err := v.AsTOML()
+	require.NoError(t, err)
+	roundTrip, err := NewJSONValueFromTOML(out)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"package":{"name":"app","version":"0.2.0","edition":2021}}`, string(roundTrip.Data))
+
+	_, err = (&JSONValue{Data: []byte(`[1, 2]`)}).AsTOML()
+	require.ErrorContains(t, err, "only objects")
+}
+
+func TestJSONValueAsYAML(t *testing.T) {
+	out, err := (&JSONValue{Data: []byte(`{"b":[1,2],"a":"x"}`)}).AsYAML()
+	require.NoError(t, err)
+	require.Equal(t, "a: x\nb:\n  - 1\n  - 2\n", string(out))
+}
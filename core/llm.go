@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -81,6 +82,20 @@ type LLM struct {
 
 	// Whether to disable the default system prompt
 	disableDefaultSystemPrompt bool
+
+	// Path on the client's host to record a replay fixture to
+	recording string
+
+	// How to compact the history as it approaches the context window
+	historyStrategy  LLMHistoryStrategy
+	historyThreshold float64
+	// Compactions so far, the tokens they freed, and the usage of the
+	// messages that they dropped
+	compactions     int
+	compactedTokens int64
+	compactedUsage  LLMTokenUsage
+	// The last message at the time of the last compaction
+	compactedAt *ModelMessage
 }
 
 type LLMEndpoint struct {
@@ -89,6 +104,8 @@ type LLMEndpoint struct {
 	Key      string
 	Provider LLMProvider
 	Client   LLMClient
+	// The model's context window in tokens, or 0 if unknown
+	ContextWindow int
 }
 
 type LLMProvider string
@@ -111,6 +128,8 @@ type LLMTokenUsage struct {
 	CachedTokenReads  int64 `field:"true" json:"cached_token_reads"`
 	CachedTokenWrites int64 `field:"true" json:"cached_token_writes"`
 	TotalTokens       int64 `field:"true" json:"total_tokens"`
+	Compactions       int   `field:"true" json:"compactions,omitempty" doc:"The number of times the history was compacted to fit the context window"`
+	CompactedTokens   int64 `field:"true" json:"compacted_tokens,omitempty" doc:"The estimated number of tokens freed by compacting the history"`
 }
 
 func (*LLMTokenUsage) Type() *ast.Type {
@@ -147,6 +166,7 @@ const (
 	Google    LLMProvider = "google"
 	Meta      LLMProvider = "meta"
 	Mistral   LLMProvider = "mistral"
+	Ollama    LLMProvider = "ollama"
 	DeepSeek  LLMProvider = "deepseek"
 	Other     LLMProvider = "other"
 )
@@ -166,6 +186,24 @@ type LLMRouter struct {
 	GeminiAPIKey  string
 	GeminiBaseURL string
 	GeminiModel   string
+
+	OllamaHost          string
+	OllamaModel         string
+	OllamaContextLength int
+
+	// Context window to assume for every model, overriding the known defaults
+	ContextWindow int
+
+	// Directory on the client's host to record a replay fixture of every LLM
+	// session into
+	RecordDir string
+
+	// Access to the client's host, for loading and recording replay fixtures
+	readFile  func(ctx context.Context, path string) ([]byte, error)
+	writeFile func(ctx context.Context, path string, data []byte) error
+	// Plaintexts of the API keys and the client's secrets, to scrub from
+	// recorded fixtures
+	secrets func(ctx context.Context) ([]string, error)
 }
 
 func (r *LLMRouter) isAnthropicModel(model string) bool {
@@ -184,22 +222,51 @@ func (r *LLMRouter) isMistralModel(model string) bool {
 	return strings.HasPrefix(model, "mistral-") || strings.HasPrefix(model, "mistral/")
 }
 
+func (r *LLMRouter) isOllamaModel(model string) bool {
+	return model == "ollama" || strings.HasPrefix(model, "ollama/")
+}
+
 func (r *LLMRouter) isReplay(model string) bool {
 	return strings.HasPrefix(model, "replay-") || strings.HasPrefix(model, "replay/")
 }
 
-func (r *LLMRouter) getReplay(model string) (messages []*ModelMessage, _ error) {
-	model, ok := strings.CutPrefix(model, "replay-")
-	if !ok {
-		model, ok = strings.CutPrefix(model, "replay/")
+// llmReplayFilePrefix prefixes the path of a replay fixture on the client's
+// host, as opposed to inline base64 JSON.
+const llmReplayFilePrefix = "replay-file/"
+
+// IsLLMReplayFile returns whether the model replays a fixture from the
+// client's host.
+func IsLLMReplayFile(model string) bool {
+	return strings.HasPrefix(model, llmReplayFilePrefix)
+}
+
+// getReplay loads the messages to replay from either inline base64 JSON
+// (replay/<base64>) or a fixture file on the client's host
+// (replay-file/<path>).
+func (r *LLMRouter) getReplay(ctx context.Context, model string) (messages []*ModelMessage, _ error) {
+	var result []byte
+	if path, ok := strings.CutPrefix(model, llmReplayFilePrefix); ok {
+		if r.readFile == nil {
+			return nil, fmt.Errorf("cannot read replay fixture %q: no access to the client's host", path)
+		}
+		var err error
+		result, err = r.readFile(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("read replay fixture: %w", err)
+		}
+	} else {
+		model, ok := strings.CutPrefix(model, "replay-")
 		if !ok {
-			return nil, fmt.Errorf("model %q is not replayable", model)
+			model, ok = strings.CutPrefix(model, "replay/")
+			if !ok {
+				return nil, fmt.Errorf("model %q is not replayable", model)
+			}
+		}
+		var err error
+		result, err = base64.StdEncoding.DecodeString(model)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	result, err := base64.StdEncoding.DecodeString(model)
-	if err != nil {
-		return nil, err
 	}
 	if err := json.Unmarshal(result, &messages); err != nil {
 		return nil, err
@@ -244,6 +311,33 @@ func (r *LLMRouter) routeGoogleModel() (*LLMEndpoint, error) {
 	return endpoint, nil
 }
 
+// routeOllamaModel routes to an Ollama server. If no model is named, the most
+// recently modified model available on the server is used.
+func (r *LLMRouter) routeOllamaModel(ctx context.Context, model string) (*LLMEndpoint, error) {
+	baseURL, err := ollamaBaseURL(r.OllamaHost)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &LLMEndpoint{
+		Model:    model,
+		BaseURL:  baseURL,
+		Provider: Ollama,
+	}
+	client := newOllamaClient(endpoint)
+	endpoint.Client = client
+	if model == "ollama" {
+		models, err := client.ListModels(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("discover ollama models at %s: %w", baseURL, err)
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("no models available at %s: pull one with 'ollama pull' or set OLLAMA_MODEL", baseURL)
+		}
+		endpoint.Model = "ollama/" + models[0]
+	}
+	return endpoint, nil
+}
+
 func (r *LLMRouter) routeOtherModel() *LLMEndpoint {
 	// default to openAI compat from other providers
 	endpoint := &LLMEndpoint{
@@ -256,8 +350,8 @@ func (r *LLMRouter) routeOtherModel() *LLMEndpoint {
 	return endpoint
 }
 
-func (r *LLMRouter) routeReplayModel(model string) (*LLMEndpoint, error) {
-	replay, err := r.getReplay(model)
+func (r *LLMRouter) routeReplayModel(ctx context.Context, model string) (*LLMEndpoint, error) {
+	replay, err := r.getReplay(ctx, model)
 	if err != nil {
 		return nil, err
 	}
@@ -285,12 +379,20 @@ func (r *LLMRouter) DefaultModel() string {
 	if r.GeminiAPIKey != "" {
 		return modelDefaultGoogle
 	}
+	// local models only come after the hosted providers configured with a key
+	if r.OllamaModel != "" {
+		return "ollama/" + r.OllamaModel
+	}
+	if r.OllamaHost != "" {
+		// discover a model from the server
+		return "ollama"
+	}
 	return ""
 }
 
 // Return an endpoint for the requested model
 // If the model name is not set, a default will be selected.
-func (r *LLMRouter) Route(model string) (*LLMEndpoint, error) {
+func (r *LLMRouter) Route(ctx context.Context, model string) (*LLMEndpoint, error) {
 	if model == "" {
 		model = r.DefaultModel()
 	} else {
@@ -310,8 +412,15 @@ func (r *LLMRouter) Route(model string) (*LLMEndpoint, error) {
 		}
 	case r.isMistralModel(model):
 		return nil, fmt.Errorf("mistral models are not yet supported")
+	case r.isOllamaModel(model):
+		endpoint, err = r.routeOllamaModel(ctx, model)
+		if err != nil {
+			return nil, err
+		}
+		// the model may have been discovered
+		model = endpoint.Model
 	case r.isReplay(model):
-		endpoint, err = r.routeReplayModel(model)
+		endpoint, err = r.routeReplayModel(ctx, model)
 		if err != nil {
 			return nil, err
 		}
@@ -319,6 +428,7 @@ func (r *LLMRouter) Route(model string) (*LLMEndpoint, error) {
 		endpoint = r.routeOtherModel()
 	}
 	endpoint.Model = model
+	endpoint.ContextWindow = r.contextWindow(endpoint)
 	return endpoint, nil
 }
 
@@ -374,14 +484,37 @@ func (r *LLMRouter) LoadConfig(ctx context.Context, getenv func(context.Context,
 		return save("GEMINI_MODEL", &r.GeminiModel)
 	})
 
+	eg.Go(func() error {
+		return save("OLLAMA_HOST", &r.OllamaHost)
+	})
+	eg.Go(func() error {
+		return save("OLLAMA_MODEL", &r.OllamaModel)
+	})
+
+	eg.Go(func() error {
+		return save("DAGGER_LLM_RECORD", &r.RecordDir)
+	})
+
 	var (
 		openAIDisableStreaming string
+		ollamaContextLength    string
+		contextWindow          string
 	)
 	eg.Go(func() error {
 		var err error
 		openAIDisableStreaming, err = getenv(ctx, "OPENAI_DISABLE_STREAMING")
 		return err
 	})
+	eg.Go(func() error {
+		var err error
+		ollamaContextLength, err = getenv(ctx, "OLLAMA_CONTEXT_LENGTH")
+		return err
+	})
+	eg.Go(func() error {
+		var err error
+		contextWindow, err = getenv(ctx, "DAGGER_LLM_CONTEXT_WINDOW")
+		return err
+	})
 
 	if err := eg.Wait(); err != nil {
 		return err
@@ -394,6 +527,20 @@ func (r *LLMRouter) LoadConfig(ctx context.Context, getenv func(context.Context,
 		}
 		r.OpenAIDisableStreaming = v
 	}
+	if ollamaContextLength != "" {
+		v, err := strconv.Atoi(ollamaContextLength)
+		if err != nil {
+			return fmt.Errorf("OLLAMA_CONTEXT_LENGTH: %w", err)
+		}
+		r.OllamaContextLength = v
+	}
+	if contextWindow != "" {
+		v, err := strconv.Atoi(contextWindow)
+		if err != nil {
+			return fmt.Errorf("DAGGER_LLM_CONTEXT_WINDOW: %w", err)
+		}
+		r.ContextWindow = v
+	}
 
 	return nil
 }
@@ -482,7 +629,42 @@ func loadLLMRouter(ctx context.Context, query *Query) (*LLMRouter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewLLMRouter(ctx, mainSrv)
+	router, err := NewLLMRouter(ctx, mainSrv)
+	if err != nil {
+		return nil, err
+	}
+
+	// replay fixtures are read from and recorded to the root client's host
+	router.readFile = func(ctx context.Context, path string) ([]byte, error) {
+		ctx = engine.ContextWithClientMetadata(ctx, parentClient)
+		bk, err := query.Buildkit(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get buildkit client: %w", err)
+		}
+		return bk.ReadCallerHostFile(ctx, path)
+	}
+	router.writeFile = func(ctx context.Context, path string, data []byte) error {
+		ctx = engine.ContextWithClientMetadata(ctx, parentClient)
+		bk, err := query.Buildkit(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get buildkit client: %w", err)
+		}
+		return bk.IOReaderExport(ctx, bytes.NewReader(data), path, 0o644)
+	}
+	router.secrets = func(ctx context.Context) ([]string, error) {
+		// scrub every secret of the client, not only those the model was
+		// given: tool results can reveal secrets reached through any object
+		secretStore, err := query.Secrets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		secrets := []string{router.AnthropicAPIKey, router.OpenAIAPIKey, router.GeminiAPIKey}
+		for _, plaintext := range secretStore.Plaintexts(ctx) {
+			secrets = append(secrets, string(plaintext))
+		}
+		return secrets, nil
+	}
+	return router, nil
 }
 
 func (*LLM) Type() *ast.Type {
@@ -519,7 +701,7 @@ func (llm *LLM) Endpoint(ctx context.Context) (*LLMEndpoint, error) {
 	if err != nil {
 		return nil, err
 	}
-	endpoint, err := router.Route(llm.model)
+	endpoint, err := router.Route(ctx, llm.model)
 	if err != nil {
 		return nil, err
 	}
@@ -527,6 +709,14 @@ func (llm *LLM) Endpoint(ctx context.Context) (*LLMEndpoint, error) {
 		return nil, fmt.Errorf("no valid LLM endpoint configuration")
 	}
 
+	recording := llm.recording
+	if recording == "" && router.RecordDir != "" {
+		recording = llmRecordingPath(router.RecordDir, time.Now())
+	}
+	if recording != "" {
+		endpoint.Client = newLLMRecorder(endpoint.Client, recording, router.writeFile, router.secrets)
+	}
+
 	llm.endpoint = endpoint
 
 	return llm.endpoint, nil
@@ -560,6 +750,20 @@ func (llm *LLM) WithModel(model string) *LLM {
 	return llm
 }
 
+// WithRecording records the conversation to a replay fixture at the given path
+// on the client's host, which can be replayed with the "replay-file/<path>"
+// model.
+func (llm *LLM) WithRecording(path string) *LLM {
+	llm = llm.Clone()
+	llm.recording = path
+
+	llm.endpointMtx.Lock()
+	defer llm.endpointMtx.Unlock()
+	llm.endpoint = nil
+
+	return llm
+}
+
 // Append a user message (prompt) to the message history
 func (llm *LLM) WithPrompt(
 	// The prompt message.
@@ -820,10 +1024,13 @@ func (llm *LLM) loop(ctx context.Context) error {
 			return err
 		}
 
-		messagesToSend := llm.messagesWithSystemPrompt()
+		ep, err := llm.Endpoint(ctx)
+		if err != nil {
+			return err
+		}
 
 		var newMessages []*ModelMessage
-		for _, msg := range slices.Backward(messagesToSend) {
+		for _, msg := range slices.Backward(llm.messagesWithSystemPrompt()) {
 			if msg.Role == "assistant" || msg.ToolCallID != "" {
 				// only display messages appended since the last response
 				break
@@ -856,13 +1063,14 @@ func (llm *LLM) loop(ctx context.Context) error {
 			}()
 		}
 
+		if err := llm.compactHistory(ctx, ep); err != nil {
+			return fmt.Errorf("compact history: %w", err)
+		}
+		messagesToSend := llm.messagesWithSystemPrompt()
+
 		var res *LLMResponse
 
 		// Retry operation
-		ep, err := llm.Endpoint(ctx)
-		if err != nil {
-			return err
-		}
 		client := ep.Client
 		err = backoff.Retry(func() error {
 			var sendErr error
@@ -1103,7 +1311,10 @@ func (llm *LLM) TokenUsage(ctx context.Context, dag *dagql.Server) (*LLMTokenUsa
 	if err := llm.Sync(ctx); err != nil {
 		return nil, err
 	}
-	var res LLMTokenUsage
+	// account for messages dropped by compaction
+	res := llm.compactedUsage
+	res.Compactions = llm.compactions
+	res.CompactedTokens = llm.compactedTokens
 	for _, msg := range llm.messages {
 		res.InputTokens += msg.TokenUsage.InputTokens
 		res.OutputTokens += msg.TokenUsage.OutputTokens
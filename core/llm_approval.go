@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// toolCallApproved is prepended to the result of a call that the user
+// approved, so that the approval is recorded in the history.
+const toolCallApproved = "The user approved this call."
+
+// ToolCallDeniedError is returned for a call that the user denied, or
+// couldn't be asked to approve.
+type ToolCallDeniedError struct {
+	Function string
+	// Why the user couldn't be asked, if they weren't
+	Cause error
+}
+
+func (err *ToolCallDeniedError) Error() string {
+	if err.Cause != nil {
+		return fmt.Sprintf("%s requires the user's approval, but they could not be asked (%s); do not retry it", err.Function, err.Cause)
+	}
+	return fmt.Sprintf("the user denied the call to %s; do not retry it, and ask the user how to proceed instead", err.Function)
+}
+
+func (err *ToolCallDeniedError) Unwrap() error {
+	return err.Cause
+}
+
+// WithApprovalRequired requires the user's approval before the model calls the
+// function. Unlike WithBlockedFunction, the function is still exposed as a
+// tool.
+func (llm *LLM) WithApprovalRequired(ctx context.Context, typeName, funcName string) (*LLM, error) {
+	llm = llm.Clone()
+	if err := llm.mcp.RequireApproval(ctx, typeName, funcName); err != nil {
+		return nil, err
+	}
+	return llm, nil
+}
+
+func (m *MCP) RequireApproval(ctx context.Context, typeName, funcName string) error {
+	if err := m.checkFunction(ctx, typeName, funcName); err != nil {
+		return err
+	}
+	m.approvalMethods[typeName] = append(m.approvalMethods[typeName], funcName)
+	return nil
+}
+
+// requestApproval asks the user whether the model may call the function with
+// the given arguments. It fails closed: if the user can't be asked, e.g.
+// because the session isn't interactive, the call is denied.
+func (m *MCP) requestApproval(ctx context.Context, typeName, funcName string, args map[string]any) error {
+	function := typeName + "." + funcName
+	prompt, err := approvalPrompt(function, args)
+	if err != nil {
+		return err
+	}
+	approved, err := promptApproval(ctx, prompt)
+	if err != nil {
+		return &ToolCallDeniedError{Function: function, Cause: err}
+	}
+	if !approved {
+		return &ToolCallDeniedError{Function: function}
+	}
+	return nil
+}
+
+func approvalPrompt(function string, args map[string]any) (string, error) {
+	prompt := fmt.Sprintf("The LLM wants to call **%s**", function)
+	if len(args) > 0 {
+		args = maps.Clone(args)
+		if self, ok := args["self"]; ok {
+			delete(args, "self")
+			prompt += fmt.Sprintf(" on `%v`", self)
+		}
+	}
+	if len(args) > 0 {
+		pl, err := json.MarshalIndent(args, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		prompt += fmt.Sprintf(" with these arguments:\n\n```json\n%s\n```\n\n", pl)
+	} else {
+		prompt += ". "
+	}
+	return prompt + "Allow it?", nil
+}
+
+func promptApproval(ctx context.Context, prompt string) (bool, error) {
+	query, err := CurrentQuery(ctx)
+	if err != nil {
+		return false, err
+	}
+	bk, err := query.Buildkit(ctx)
+	if err != nil {
+		return false, err
+	}
+	return bk.PromptApproveToolCall(ctx, prompt)
+}
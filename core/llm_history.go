@@ -0,0 +1,314 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dagger.io/dagger/telemetry"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dagger/dagger/core/prompts"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+)
+
+type LLMHistoryStrategy string
+
+var LLMHistoryStrategies = dagql.NewEnum[LLMHistoryStrategy]()
+
+var (
+	LLMHistoryStrategyNone = LLMHistoryStrategies.Register("NONE",
+		"Never compact the history; requests fail once it outgrows the context window")
+	LLMHistoryStrategyTruncate = LLMHistoryStrategies.Register("TRUNCATE",
+		"Replace the results of the oldest tool calls with a placeholder")
+	LLMHistoryStrategySummarize = LLMHistoryStrategies.Register("SUMMARIZE",
+		"Replace the conversation so far with a summary written by the model, keeping the current turn")
+)
+
+func (strategy LLMHistoryStrategy) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "LLMHistoryStrategy",
+		NonNull:   true,
+	}
+}
+
+func (strategy LLMHistoryStrategy) TypeDescription() string {
+	return "How an LLM compacts its history when it approaches the model's context window."
+}
+
+func (strategy LLMHistoryStrategy) Decoder() dagql.InputDecoder {
+	return LLMHistoryStrategies
+}
+
+func (strategy LLMHistoryStrategy) ToLiteral() call.Literal {
+	return LLMHistoryStrategies.Literal(strategy)
+}
+
+// llmDefaultHistoryThreshold is the share of the context window that the
+// history may fill before it's compacted.
+const llmDefaultHistoryThreshold = 0.8
+
+// llmContextWindows lists the context window of each model family, in tokens.
+// The first matching prefix wins, so more specific prefixes come first.
+var llmContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude-", 200_000},
+	{"gpt-4.1", 1_047_576},
+	{"gpt-4o", 128_000},
+	{"gpt-5", 400_000},
+	{"o3", 200_000},
+	{"o4-mini", 200_000},
+	{"gemini-", 1_048_576},
+	{"llama-3.", 128_000},
+}
+
+// contextWindow returns the context window of the endpoint's model, in tokens,
+// or 0 if it's unknown.
+func (r *LLMRouter) contextWindow(endpoint *LLMEndpoint) int {
+	switch endpoint.Provider {
+	case "":
+		// replays don't have a model
+		return 0
+	case Ollama:
+		// Ollama serves a smaller window than models support, unless configured
+		if r.ContextWindow > 0 {
+			return r.ContextWindow
+		}
+		return r.OllamaContextLength
+	}
+	if r.ContextWindow > 0 {
+		return r.ContextWindow
+	}
+	model := endpoint.Model
+	if _, name, ok := strings.Cut(model, "/"); ok {
+		model = name
+	}
+	for _, window := range llmContextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return 0
+}
+
+// WithHistoryStrategy configures how the history is compacted when it
+// approaches the model's context window.
+func (llm *LLM) WithHistoryStrategy(strategy LLMHistoryStrategy, threshold float64) (*LLM, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("history threshold must be between 0 and 1, got %v", threshold)
+	}
+	llm = llm.Clone()
+	llm.historyStrategy = strategy
+	llm.historyThreshold = threshold
+	return llm, nil
+}
+
+// llmTruncatedToolResult replaces the results of tool calls truncated by the
+// TRUNCATE strategy.
+const llmTruncatedToolResult = "[truncated %d characters of tool output to fit the context window]"
+
+// estimateTokens estimates the size of a prompt made of the given messages,
+// based on the input tokens last reported by the provider, plus roughly four
+// characters per token for anything appended since. Usage reported up to the
+// stale message predates the last compaction, so it's disregarded.
+func estimateTokens(provider LLMProvider, messages []*ModelMessage, stale *ModelMessage) int64 {
+	var tokens int64
+	start := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i] == stale {
+			break
+		}
+		usage := messages[i].TokenUsage
+		if messages[i].Role != "assistant" || usage.InputTokens == 0 {
+			continue
+		}
+		tokens = usage.InputTokens + usage.OutputTokens
+		if provider == Anthropic {
+			// Anthropic doesn't count cached tokens as input tokens
+			tokens += usage.CachedTokenReads + usage.CachedTokenWrites
+		}
+		start = i + 1
+		break
+	}
+	for _, msg := range messages[start:] {
+		tokens += int64(messageChars(msg) / 4)
+	}
+	return tokens
+}
+
+func messageChars(msg *ModelMessage) int {
+	n := len(msg.Content)
+	for _, call := range msg.ToolCalls {
+		n += len(call.Function.Name)
+		if args, err := json.Marshal(call.Function.Arguments); err == nil {
+			n += len(args)
+		}
+	}
+	return n
+}
+
+// compactHistory compacts the history following the configured strategy, if
+// the next prompt is estimated to exceed the threshold of the context window.
+func (llm *LLM) compactHistory(ctx context.Context, ep *LLMEndpoint) (rerr error) {
+	strategy := llm.historyStrategy
+	if strategy == "" {
+		strategy = LLMHistoryStrategyTruncate
+	}
+	threshold := llm.historyThreshold
+	if threshold == 0 {
+		threshold = llmDefaultHistoryThreshold
+	}
+	if strategy == LLMHistoryStrategyNone || ep.ContextWindow <= 0 {
+		return nil
+	}
+	limit := int64(float64(ep.ContextWindow) * threshold)
+	estimate := estimateTokens(ep.Provider, llm.messagesWithSystemPrompt(), llm.compactedAt)
+	if estimate < limit {
+		return nil
+	}
+
+	ctx, span := Tracer(ctx).Start(ctx, "compact LLM history", telemetry.Reveal(), trace.WithAttributes(
+		attribute.String(telemetry.UIActorEmojiAttr, "🗜️"),
+	))
+	defer telemetry.EndWithCause(span, &rerr)
+	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary,
+		log.String(telemetry.ContentTypeAttr, "text/markdown"))
+	defer stdio.Close()
+
+	var freed int64
+	switch strategy {
+	case LLMHistoryStrategyTruncate:
+		// leave plenty of room, so we don't have to compact again right away
+		freed = llm.truncateToolResults(estimate, limit/2)
+	case LLMHistoryStrategySummarize:
+		var err error
+		freed, err = llm.summarizeHistory(ctx, ep)
+		if err != nil {
+			return fmt.Errorf("summarize history: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown history strategy %q", strategy)
+	}
+	if freed <= 0 {
+		fmt.Fprintf(stdio.Stdout, "History uses ~%d of %d tokens, but nothing could be compacted.\n", estimate, ep.ContextWindow)
+		return nil
+	}
+	llm.compactions++
+	llm.compactedTokens += freed
+	llm.compactedAt = llm.messages[len(llm.messages)-1]
+	fmt.Fprintf(stdio.Stdout, "History used ~%d of %d tokens; compacted ~%d tokens with the %s strategy.\n",
+		estimate, ep.ContextWindow, freed, strategy)
+	return nil
+}
+
+// truncateToolResults replaces the results of the oldest tool calls with a
+// placeholder until the estimate drops to the target, leaving the results of
+// the latest tool calls intact. It returns the estimated number of tokens
+// freed.
+func (llm *LLM) truncateToolResults(estimate, target int64) int64 {
+	current := len(llm.messages)
+	for current > 0 && llm.messages[current-1].Role != "assistant" {
+		current--
+	}
+	var freed int64
+	for i, msg := range llm.messages[:current] {
+		if estimate-freed <= target {
+			break
+		}
+		if msg.ToolCallID == "" || strings.HasPrefix(msg.Content, "[truncated ") {
+			continue
+		}
+		truncated := fmt.Sprintf(llmTruncatedToolResult, len(msg.Content))
+		if len(truncated) >= len(msg.Content) {
+			continue
+		}
+		// messages are shared with clones, so replace rather than modify
+		cp := *msg
+		cp.Content = truncated
+		llm.messages[i] = &cp
+		freed += int64((len(msg.Content) - len(truncated)) / 4)
+	}
+	return freed
+}
+
+// summarizeHistory asks the model to summarize the conversation so far, and
+// replaces it with the summary. The current turn is kept as-is, since tool
+// call results must follow the calls that they answer. It returns the
+// estimated number of tokens freed.
+func (llm *LLM) summarizeHistory(ctx context.Context, ep *LLMEndpoint) (int64, error) {
+	current := len(llm.messages)
+	for current > 0 && llm.messages[current-1].Role != "assistant" {
+		current--
+	}
+	if current > 0 && len(llm.messages[current-1].ToolCalls) > 0 {
+		current--
+	}
+
+	var system, old []*ModelMessage
+	for _, msg := range llm.messages[:current] {
+		if msg.Role == "system" {
+			system = append(system, msg)
+		} else {
+			old = append(old, msg)
+		}
+	}
+	if len(old) == 0 {
+		return 0, nil
+	}
+
+	prompt, err := prompts.FS.ReadFile("compact.md")
+	if err != nil {
+		return 0, err
+	}
+	// leave out the current turn, without clobbering it
+	req := llm.messagesWithSystemPrompt()
+	req = append(slices.Clone(req[:len(req)-(len(llm.messages)-current)]), &ModelMessage{
+		Role:    "user",
+		Content: string(prompt),
+	})
+	// no tools, the model must answer with the summary rather than call them
+	res, err := ep.Client.SendQuery(ctx, req, nil)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(res.Content) == "" {
+		// don't replace the history with nothing
+		return 0, fmt.Errorf("model returned an empty summary")
+	}
+
+	summary := &ModelMessage{
+		Role: "user",
+		Content: "This session is being continued from a previous conversation that ran out of context. " +
+			"The conversation is summarized below:\n\n" + res.Content,
+	}
+	// keep accounting for the tokens spent so far, and on summarizing
+	llm.compactedUsage = addTokenUsage(llm.compactedUsage, res.TokenUsage)
+	var freed int
+	for _, msg := range old {
+		freed += messageChars(msg)
+		llm.compactedUsage = addTokenUsage(llm.compactedUsage, msg.TokenUsage)
+	}
+	freed -= messageChars(summary)
+
+	messages := append(system, summary)
+	messages = append(messages, llm.messages[current:]...)
+	llm.messages = messages
+	return int64(max(freed, 0) / 4), nil
+}
+
+func addTokenUsage(a, b LLMTokenUsage) LLMTokenUsage {
+	a.InputTokens += b.InputTokens
+	a.OutputTokens += b.OutputTokens
+	a.CachedTokenReads += b.CachedTokenReads
+	a.CachedTokenWrites += b.CachedTokenWrites
+	a.TotalTokens += b.TotalTokens
+	return a
+}
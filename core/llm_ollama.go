@@ -0,0 +1,386 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"dagger.io/dagger/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OllamaClient talks to Ollama's native API, which unlike its OpenAI
+// compatible API supports tool calls while streaming, reports token usage, and
+// can pull models that aren't available locally yet.
+type OllamaClient struct {
+	endpoint *LLMEndpoint
+	http     *http.Client
+
+	// whether the model is known to be available locally
+	pulled   bool
+	pulledMu sync.Mutex
+}
+
+func newOllamaClient(endpoint *LLMEndpoint) *OllamaClient {
+	return &OllamaClient{
+		endpoint: endpoint,
+		http:     http.DefaultClient,
+	}
+}
+
+var _ LLMClient = (*OllamaClient)(nil)
+
+// ollamaBaseURL normalizes OLLAMA_HOST, which may omit the scheme or port.
+//
+// Ollama reads the same variable as the address to listen on, so it's commonly
+// set to an unspecified address like 0.0.0.0, which can't be connected to from
+// the engine; these are rejected rather than silently failing to connect. An
+// unset OLLAMA_HOST is rejected too: Ollama's default of 127.0.0.1 would
+// point at the engine container rather than the machine running Ollama.
+func ollamaBaseURL(host string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("OLLAMA_HOST is not set: set it to an address of the Ollama server that the engine can reach")
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid OLLAMA_HOST %q: %w", host, err)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && ip.IsUnspecified() {
+		return "", fmt.Errorf("OLLAMA_HOST %q is an address for Ollama to listen on: set it to an address of the Ollama server that the engine can reach", host)
+	}
+	if u.Port() == "" && u.Scheme == "http" {
+		u.Host += ":11434"
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// ollamaModelName strips the "ollama/" prefix from a model routed to Ollama.
+func ollamaModelName(model string) string {
+	return strings.TrimPrefix(model, "ollama/")
+}
+
+type ollamaError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *ollamaError) Error() string {
+	return fmt.Sprintf("ollama: %s (status %d)", err.Message, err.StatusCode)
+}
+
+func (c *OllamaClient) IsRetryable(err error) bool {
+	var ollamaErr *ollamaError
+	if !errors.As(err, &ollamaErr) {
+		return false
+	}
+	// Ollama responds with 503 while it's busy loading a model
+	return ollamaErr.StatusCode == http.StatusTooManyRequests ||
+		ollamaErr.StatusCode >= http.StatusInternalServerError
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int64         `json:"prompt_eval_count"`
+	EvalCount       int64         `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// ListModels returns the models available locally, in the order Ollama lists
+// them (most recently modified first).
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	var tags ollamaTagsResponse
+	res, err := c.do(ctx, http.MethodGet, "/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("ollama: decode models: %w", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
+// ensureModel pulls the model if it isn't available locally yet.
+func (c *OllamaClient) ensureModel(ctx context.Context) (rerr error) {
+	c.pulledMu.Lock()
+	defer c.pulledMu.Unlock()
+	if c.pulled {
+		return nil
+	}
+	model := ollamaModelName(c.endpoint.Model)
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	// Ollama implies the :latest tag
+	if slices.Contains(models, model) || slices.Contains(models, model+":latest") {
+		c.pulled = true
+		return nil
+	}
+
+	ctx, span := Tracer(ctx).Start(ctx, "pull ollama model "+model, telemetry.Reveal())
+	defer telemetry.EndWithCause(span, &rerr)
+	res, err := c.do(ctx, http.MethodPost, "/api/pull", map[string]any{
+		"model":  model,
+		"stream": false,
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&status); err != nil {
+		return fmt.Errorf("ollama: decode pull status: %w", err)
+	}
+	if status.Error != "" {
+		return fmt.Errorf("ollama: pull %s: %s", model, status.Error)
+	}
+	c.pulled = true
+	return nil
+}
+
+func (c *OllamaClient) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint.BaseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(res.Body)
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Error != "" {
+			msg = []byte(apiErr.Error)
+		}
+		return nil, &ollamaError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	return res, nil
+}
+
+func (c *OllamaClient) SendQuery(ctx context.Context, history []*ModelMessage, tools []LLMTool) (_ *LLMResponse, rerr error) {
+	if err := c.ensureModel(ctx); err != nil {
+		return nil, err
+	}
+
+	stdio := telemetry.SpanStdio(ctx, InstrumentationLibrary,
+		log.String(telemetry.ContentTypeAttr, "text/markdown"))
+	defer stdio.Close()
+
+	m := telemetry.Meter(ctx, InstrumentationLibrary)
+	spanCtx := trace.SpanContextFromContext(ctx)
+	attrs := []attribute.KeyValue{
+		attribute.String(telemetry.MetricsTraceIDAttr, spanCtx.TraceID().String()),
+		attribute.String(telemetry.MetricsSpanIDAttr, spanCtx.SpanID().String()),
+		attribute.String("model", c.endpoint.Model),
+		attribute.String("provider", string(c.endpoint.Provider)),
+	}
+	inputTokens, err := m.Int64Gauge(telemetry.LLMInputTokens)
+	if err != nil {
+		return nil, err
+	}
+	outputTokens, err := m.Int64Gauge(telemetry.LLMOutputTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ollamaChatRequest{
+		Model:  ollamaModelName(c.endpoint.Model),
+		Stream: true,
+	}
+	// Ollama identifies tool results by the name of the tool rather than an ID
+	toolNames := map[string]string{}
+	for _, msg := range history {
+		if msg.ToolCallID != "" {
+			content := msg.Content
+			if msg.ToolErrored {
+				content = "error: " + content
+			}
+			req.Messages = append(req.Messages, ollamaMessage{
+				Role:     "tool",
+				Content:  content,
+				ToolName: toolNames[msg.ToolCallID],
+			})
+			continue
+		}
+		ollamaMsg := ollamaMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			var ollamaCall ollamaToolCall
+			ollamaCall.Function.Name = call.Function.Name
+			ollamaCall.Function.Arguments = call.Function.Arguments
+			ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, ollamaCall)
+		}
+		req.Messages = append(req.Messages, ollamaMsg)
+	}
+	for _, tool := range tools {
+		var ollamaTool ollamaTool
+		ollamaTool.Type = "function"
+		ollamaTool.Function.Name = tool.Name
+		ollamaTool.Function.Description = tool.Description
+		ollamaTool.Function.Parameters = tool.Schema
+		req.Tools = append(req.Tools, ollamaTool)
+	}
+
+	res, err := c.do(ctx, http.MethodPost, "/api/chat", req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// The response is streamed as newline-delimited JSON. Tool calls arrive
+	// whole in a single chunk, and the final chunk reports token usage.
+	var content strings.Builder
+	var calls []ollamaToolCall
+	var final ollamaChatResponse
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("ollama: decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, &ollamaError{StatusCode: res.StatusCode, Message: chunk.Error}
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			fmt.Fprint(stdio.Stdout, chunk.Message.Content)
+		}
+		calls = append(calls, chunk.Message.ToolCalls...)
+		if chunk.Done {
+			final = chunk
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ollama: read response: %w", err)
+	}
+
+	if final.PromptEvalCount > 0 {
+		inputTokens.Record(ctx, final.PromptEvalCount, metric.WithAttributes(attrs...))
+	}
+	if final.EvalCount > 0 {
+		outputTokens.Record(ctx, final.EvalCount, metric.WithAttributes(attrs...))
+	}
+
+	var toolCalls []LLMToolCall
+	for i, call := range calls {
+		if call.Function.Name == "" {
+			continue
+		}
+		args := call.Function.Arguments
+		if args == nil {
+			args = map[string]any{}
+		}
+		toolCalls = append(toolCalls, LLMToolCall{
+			// Ollama doesn't assign IDs, so derive stable ones from the position
+			// in the history
+			ID:   fmt.Sprintf("call_%d_%d", len(history), i),
+			Type: "function",
+			Function: FuncCall{
+				Name:      call.Function.Name,
+				Arguments: args,
+			},
+		})
+	}
+
+	if content.Len() == 0 && len(toolCalls) == 0 {
+		return nil, &ModelFinishedError{
+			Reason: final.DoneReason,
+		}
+	}
+
+	return &LLMResponse{
+		Content:   content.String(),
+		ToolCalls: toolCalls,
+		TokenUsage: LLMTokenUsage{
+			InputTokens:  final.PromptEvalCount,
+			OutputTokens: final.EvalCount,
+			TotalTokens:  final.PromptEvalCount + final.EvalCount,
+		},
+	}, nil
+}
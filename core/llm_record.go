@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+	"time"
+)
+
+// llmScrubString replaces secrets in recorded fixtures, like secrets are
+// scrubbed from exec output.
+const llmScrubString = "***"
+
+// LLMRecorder wraps a LLMClient and records the conversation into a fixture
+// that can be replayed with the "replay-file/<path>" model.
+//
+// After every response, the whole history is written, including the response
+// and the tool call results that led to it, in the same format as
+// LLM.historyJSON.
+type LLMRecorder struct {
+	client LLMClient
+	path   string
+
+	// write writes the fixture to the client's host
+	write func(ctx context.Context, path string, data []byte) error
+	// secrets returns the plaintexts to scrub from the fixture
+	secrets func(ctx context.Context) ([]string, error)
+
+	mu sync.Mutex
+}
+
+func newLLMRecorder(
+	client LLMClient,
+	path string,
+	write func(context.Context, string, []byte) error,
+	secrets func(context.Context) ([]string, error),
+) *LLMRecorder {
+	return &LLMRecorder{
+		client:  client,
+		path:    path,
+		write:   write,
+		secrets: secrets,
+	}
+}
+
+// llmRecordingPath returns a unique path for a recording in the given
+// directory, as configured with DAGGER_LLM_RECORD.
+func llmRecordingPath(dir string, now time.Time) string {
+	return path.Join(dir, fmt.Sprintf("llm-%s.json", now.UTC().Format("20060102T150405.000000000")))
+}
+
+func (c *LLMRecorder) IsRetryable(err error) bool {
+	return c.client.IsRetryable(err)
+}
+
+func (c *LLMRecorder) SendQuery(ctx context.Context, history []*ModelMessage, tools []LLMTool) (*LLMResponse, error) {
+	res, err := c.client.SendQuery(ctx, history, tools)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 && history[0].Role == "system" {
+		// drop the default system prompt, like HistoryJSON does, since replays
+		// skip it too
+		history = history[1:]
+	}
+	messages := append(slices.Clone(history), &ModelMessage{
+		Role:       "assistant",
+		Content:    res.Content,
+		ToolCalls:  res.ToolCalls,
+		TokenUsage: res.TokenUsage,
+	})
+	if err := c.record(ctx, messages); err != nil {
+		return nil, fmt.Errorf("record LLM fixture %s: %w", c.path, err)
+	}
+	return res, nil
+}
+
+func (c *LLMRecorder) record(ctx context.Context, messages []*ModelMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fixture, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	secrets, err := c.secrets(ctx)
+	if err != nil {
+		return fmt.Errorf("load secrets to scrub: %w", err)
+	}
+	fixture = scrubLLMFixture(fixture, secrets)
+	return c.write(ctx, c.path, append(fixture, '\n'))
+}
+
+// scrubLLMFixture replaces every occurrence of the given secrets in the JSON
+// encoded fixture, whether or not they had to be escaped.
+func scrubLLMFixture(fixture []byte, secrets []string) []byte {
+	// replace longer secrets first, in case one contains another
+	secrets = slices.Clone(secrets)
+	slices.SortFunc(secrets, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		fixture = bytes.ReplaceAll(fixture, []byte(secret), []byte(llmScrubString))
+		escaped, err := json.Marshal(secret)
+		if err != nil {
+			continue
+		}
+		escaped = escaped[1 : len(escaped)-1] // trim quotes
+		if !bytes.Equal(escaped, []byte(secret)) {
+			fixture = bytes.ReplaceAll(fixture, escaped, []byte(llmScrubString))
+		}
+	}
+	return fixture
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/dagger/dagger/dagql"
+)
+
+var subAgentNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// WithSubAgent exposes another LLM as a tool, so that the model can delegate
+// tasks to it. The sub-agent keeps its own environment, tools and API call
+// budget, and the outputs it saves are handed back to the model as objects.
+func (llm *LLM) WithSubAgent(name string, agent *LLM) (*LLM, error) {
+	if !subAgentNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid sub-agent name %q: must match %s", name, subAgentNameRegex)
+	}
+	llm = llm.Clone()
+	llm.mcp = llm.mcp.WithSubAgent(name, agent)
+	return llm, nil
+}
+
+// WithToolConcurrency limits how many independent tool calls from a single
+// response run at once. Zero means no limit.
+func (llm *LLM) WithToolConcurrency(limit int) (*LLM, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("tool concurrency must not be negative, got %d", limit)
+	}
+	llm = llm.Clone()
+	llm.mcp = llm.mcp.WithToolConcurrency(limit)
+	return llm, nil
+}
+
+func subAgentToolName(name string) string {
+	return "Delegate" + strcase.ToCamel(name)
+}
+
+func (m *MCP) subAgentTool(srv *dagql.Server, name string, agent *LLM) LLMTool {
+	desc := fmt.Sprintf("Delegate a task to the %q agent, and wait for its reply.", name)
+	if outputs := agent.Env().Self().Outputs(); len(outputs) > 0 {
+		desc += "\n\nThe agent saves the following outputs, which are returned as objects:\n"
+		for _, output := range outputs {
+			desc += fmt.Sprintf("\n- %s (%s): %s", output.Key, output.ExpectedType, output.Description)
+		}
+	}
+	return LLMTool{
+		Name:        subAgentToolName(name),
+		Description: desc,
+		// The sub-agent works in its own environment, so delegations can run
+		// alongside each other and other read-only calls.
+		ReadOnly: true,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"task": map[string]any{
+					"type":        "string",
+					"description": "A complete description of the task, since the agent can't see this conversation.",
+				},
+			},
+			"required":             []string{"task"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+		Call: ToolFunc(srv, func(ctx context.Context, args struct {
+			Task string
+		}) (any, error) {
+			return m.delegate(ctx, agent, args.Task)
+		}),
+	}
+}
+
+// delegate runs a task with a fresh copy of the sub-agent and ingests the
+// outputs that it saved.
+func (m *MCP) delegate(ctx context.Context, agent *LLM, task string) (any, error) {
+	srv, err := CurrentDagqlServer(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get dagql server: %w", err)
+	}
+
+	sub := agent.Clone()
+	// Outputs are saved in place, so give each delegation its own copy of the
+	// environment; otherwise concurrent delegations would save over each other.
+	env := sub.mcp.env
+	sub.mcp.env, err = dagql.NewObjectResultForID(env.Self().Clone(), srv, env.ID())
+	if err != nil {
+		return nil, err
+	}
+	sub = sub.WithPrompt(task)
+	if err := sub.Sync(ctx); err != nil {
+		return nil, err
+	}
+	reply, err := sub.LastReply(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := map[string]string{}
+	for _, output := range sub.Env().Self().Outputs() {
+		if output.Value == nil {
+			continue
+		}
+		if obj, ok := output.AsObject(); ok {
+			outputs[output.Key] = m.Ingest(obj, output.Description)
+		} else {
+			outputs[output.Key] = output.String()
+		}
+	}
+	res := map[string]any{
+		"reply": reply,
+	}
+	if len(outputs) > 0 {
+		res["outputs"] = outputs
+	}
+	return toolStructuredResponse(res)
+}
@@ -2,7 +2,17 @@ package core
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vektah/gqlparser/v2/ast"
@@ -39,18 +49,23 @@ func TestLlmConfig(t *testing.T) {
 	srv := dagql.NewServer(q, dagql.NewSessionCache(baseCache))
 
 	vars := map[string]string{
-		"file://.env":                    "",
-		"env://ANTHROPIC_API_KEY":        "anthropic-api-key",
-		"env://ANTHROPIC_BASE_URL":       "anthropic-base-url",
-		"env://ANTHROPIC_MODEL":          "anthropic-model",
-		"env://OPENAI_API_KEY":           "openai-api-key",
-		"env://OPENAI_AZURE_VERSION":     "openai-azure-version",
-		"env://OPENAI_BASE_URL":          "openai-base-url",
-		"env://OPENAI_MODEL":             "openai-model",
-		"env://OPENAI_DISABLE_STREAMING": "t",
-		"env://GEMINI_API_KEY":           "gemini-api-key",
-		"env://GEMINI_BASE_URL":          "gemini-base-url",
-		"env://GEMINI_MODEL":             "gemini-model",
+		"file://.env":                     "",
+		"env://ANTHROPIC_API_KEY":         "anthropic-api-key",
+		"env://ANTHROPIC_BASE_URL":        "anthropic-base-url",
+		"env://ANTHROPIC_MODEL":           "anthropic-model",
+		"env://OPENAI_API_KEY":            "openai-api-key",
+		"env://OPENAI_AZURE_VERSION":      "openai-azure-version",
+		"env://OPENAI_BASE_URL":           "openai-base-url",
+		"env://OPENAI_MODEL":              "openai-model",
+		"env://OPENAI_DISABLE_STREAMING":  "t",
+		"env://GEMINI_API_KEY":            "gemini-api-key",
+		"env://GEMINI_BASE_URL":           "gemini-base-url",
+		"env://GEMINI_MODEL":              "gemini-model",
+		"env://OLLAMA_HOST":               "ollama-host",
+		"env://OLLAMA_MODEL":              "ollama-model",
+		"env://OLLAMA_CONTEXT_LENGTH":     "8192",
+		"env://DAGGER_LLM_RECORD":         "testdata/llm",
+		"env://DAGGER_LLM_CONTEXT_WINDOW": "100000",
 	}
 
 	dagql.Fields[LLMTestQuery]{
@@ -84,6 +99,11 @@ func TestLlmConfig(t *testing.T) {
 	assert.Equal(t, "gemini-api-key", r.GeminiAPIKey)
 	assert.Equal(t, "gemini-base-url", r.GeminiBaseURL)
 	assert.Equal(t, "gemini-model", r.GeminiModel)
+	assert.Equal(t, "ollama-host", r.OllamaHost)
+	assert.Equal(t, "ollama-model", r.OllamaModel)
+	assert.Equal(t, 8192, r.OllamaContextLength)
+	assert.Equal(t, "testdata/llm", r.RecordDir)
+	assert.Equal(t, 100000, r.ContextWindow)
 }
 
 func TestLlmConfigDisableStreaming(t *testing.T) {
@@ -203,3 +223,432 @@ GEMINI_MODEL=gemini-model`, nil
 	assert.Equal(t, "gemini-base-url", r.GeminiBaseURL)
 	assert.Equal(t, "gemini-model", r.GeminiModel)
 }
+
+type fakeLLMClient struct {
+	res *LLMResponse
+}
+
+func (c fakeLLMClient) SendQuery(context.Context, []*ModelMessage, []LLMTool) (*LLMResponse, error) {
+	return c.res, nil
+}
+
+func (fakeLLMClient) IsRetryable(error) bool {
+	return false
+}
+
+func TestLlmRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+
+	files := map[string][]byte{}
+	router := &LLMRouter{
+		readFile: func(_ context.Context, path string) ([]byte, error) {
+			return files[path], nil
+		},
+	}
+	recorder := newLLMRecorder(
+		fakeLLMClient{res: &LLMResponse{
+			Content: "the token is hunter2, or \"quoted\"",
+			ToolCalls: []LLMToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: FuncCall{Name: "login", Arguments: map[string]any{"password": "hunter2"}},
+			}},
+		}},
+		"testdata/session.json",
+		func(_ context.Context, path string, data []byte) error {
+			files[path] = data
+			return nil
+		},
+		func(context.Context) ([]string, error) {
+			return []string{"hunter2", `"quoted"`, ""}, nil
+		},
+	)
+
+	history := []*ModelMessage{
+		{Role: "system", Content: "default system prompt"},
+		{Role: "user", Content: "log in"},
+	}
+	res, err := recorder.SendQuery(ctx, history, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content, "hunter2", "responses are passed through unscrubbed")
+
+	fixture := string(files["testdata/session.json"])
+	assert.NotContains(t, fixture, "hunter2")
+	assert.NotContains(t, fixture, "quoted")
+	assert.NotContains(t, fixture, "default system prompt")
+
+	endpoint, err := router.Route(ctx, "replay-file/testdata/session.json")
+	assert.NoError(t, err)
+	replayed, err := endpoint.Client.SendQuery(ctx, history, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "the token is ***, or ***", replayed.Content)
+	assert.Equal(t, "***", replayed.ToolCalls[0].Function.Arguments["password"])
+
+	inline, err := router.getReplay(ctx, "replay/"+base64.StdEncoding.EncodeToString(files["testdata/session.json"]))
+	assert.NoError(t, err)
+	assert.Len(t, inline, 2)
+
+	// paths must be explicit, so inline fixtures are never read from the host
+	_, err = router.getReplay(ctx, "replay/testdata/session.json")
+	assert.Error(t, err)
+}
+
+func TestLlmCallBatchConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	tool := LLMTool{
+		Name:     "Sleep",
+		ReadOnly: true,
+		Call: func(ctx context.Context, args any) (any, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				prev := maxRunning.Load()
+				if n <= prev || maxRunning.CompareAndSwap(prev, n) {
+					break
+				}
+			}
+			// later calls finish first
+			ms := args.(map[string]any)["ms"].(int)
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+			return fmt.Sprint(ms), nil
+		},
+	}
+
+	var calls []LLMToolCall
+	for i := range 6 {
+		calls = append(calls, LLMToolCall{
+			ID:       fmt.Sprintf("call_%d", i),
+			Function: FuncCall{Name: "Sleep", Arguments: map[string]any{"ms": 60 - i*10}},
+		})
+	}
+
+	m := newMCP(dagql.ObjectResult[*Env]{}).WithToolConcurrency(2)
+	results := m.CallBatch(context.Background(), []LLMTool{tool}, calls)
+
+	assert.Equal(t, int32(2), maxRunning.Load())
+	assert.Len(t, results, len(calls))
+	for i, res := range results {
+		assert.Equal(t, calls[i].ID, res.ToolCallID)
+		assert.Equal(t, fmt.Sprint(60-i*10), res.Content)
+		assert.False(t, res.ToolErrored)
+	}
+}
+
+func TestLlmOllama(t *testing.T) {
+	ctx := context.Background()
+
+	models := []string{"qwen2.5-coder:14b"}
+	var chat ollamaChatRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		var tags ollamaTagsResponse
+		for _, name := range models {
+			tags.Models = append(tags.Models, struct {
+				Name string `json:"name"`
+			}{name})
+		}
+		json.NewEncoder(w).Encode(tags)
+	})
+	mux.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		models = append(models, req.Model+":latest")
+		fmt.Fprintln(w, `{"status":"success"}`)
+	})
+	mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+		chat = ollamaChatRequest{}
+		json.NewDecoder(r.Body).Decode(&chat)
+		if !slices.Contains(models, chat.Model+":latest") && !slices.Contains(models, chat.Model) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error":"model %q not found, try pulling it first"}`, chat.Model)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Reading "}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"the file."}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"ReadFile","arguments":{"path":"go.mod"}}}]}}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":42,"eval_count":7}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	router := &LLMRouter{OllamaHost: srv.URL}
+	assert.Equal(t, "ollama", router.DefaultModel())
+
+	endpoint, err := router.Route(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "ollama/qwen2.5-coder:14b", endpoint.Model)
+	assert.Equal(t, Ollama, endpoint.Provider)
+
+	endpoint, err = router.Route(ctx, "ollama/llama3.2")
+	assert.NoError(t, err)
+	res, err := endpoint.Client.SendQuery(ctx, []*ModelMessage{
+		{Role: "system", Content: "be helpful"},
+		{Role: "user", Content: "what's in go.mod?"},
+		{Role: "assistant", ToolCalls: []LLMToolCall{{ID: "call_1_0", Function: FuncCall{Name: "ListFiles", Arguments: map[string]any{}}}}},
+		{Role: "user", Content: "go.mod", ToolCallID: "call_1_0"},
+	}, []LLMTool{{Name: "ReadFile", Description: "Read a file", Schema: map[string]any{"type": "object"}}})
+	assert.NoError(t, err)
+	assert.Contains(t, models, "llama3.2:latest", "missing model should be pulled")
+
+	assert.Equal(t, "llama3.2", chat.Model)
+	assert.True(t, chat.Stream)
+	assert.Equal(t, "ReadFile", chat.Tools[0].Function.Name)
+	assert.Equal(t, ollamaMessage{Role: "tool", Content: "go.mod", ToolName: "ListFiles"}, chat.Messages[3])
+
+	assert.Equal(t, "Reading the file.", res.Content)
+	assert.Equal(t, []LLMToolCall{{
+		ID:       "call_4_0",
+		Type:     "function",
+		Function: FuncCall{Name: "ReadFile", Arguments: map[string]any{"path": "go.mod"}},
+	}}, res.ToolCalls)
+	assert.Equal(t, LLMTokenUsage{InputTokens: 42, OutputTokens: 7, TotalTokens: 49}, res.TokenUsage)
+}
+
+func TestLlmOllamaBaseURL(t *testing.T) {
+	for host, expected := range map[string]string{
+		"192.168.64.1:11434":      "http://192.168.64.1:11434",
+		"https://ollama.internal": "https://ollama.internal",
+		"http://ollama:8080/":     "http://ollama:8080",
+	} {
+		actual, err := ollamaBaseURL(host)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, host)
+	}
+	_, err := ollamaBaseURL("")
+	assert.ErrorContains(t, err, "OLLAMA_HOST is not set")
+	// addresses that `ollama serve` listens on can't be connected to
+	for _, host := range []string{"0.0.0.0", "0.0.0.0:11434", "http://[::]:11434"} {
+		_, err := ollamaBaseURL(host)
+		assert.ErrorContains(t, err, "listen on", host)
+	}
+}
+
+func TestLlmDefaultModelOllama(t *testing.T) {
+	// keys of hosted providers take precedence over local models
+	r := &LLMRouter{AnthropicAPIKey: "key", OllamaHost: "192.168.64.1", OllamaModel: "qwen3"}
+	assert.Equal(t, modelDefaultAnthropic, r.DefaultModel())
+
+	r = &LLMRouter{OllamaHost: "192.168.64.1", OllamaModel: "qwen3"}
+	assert.Equal(t, "ollama/qwen3", r.DefaultModel())
+
+	r = &LLMRouter{OllamaHost: "192.168.64.1"}
+	assert.Equal(t, "ollama", r.DefaultModel())
+
+	_, err := (&LLMRouter{OllamaHost: "0.0.0.0:11434"}).Route(t.Context(), "")
+	assert.ErrorContains(t, err, "OLLAMA_HOST")
+}
+
+func TestLlmContextWindow(t *testing.T) {
+	r := &LLMRouter{}
+	for model, expected := range map[string]int{
+		"claude-sonnet-4-5":        200_000,
+		"gpt-4.1-mini":             1_047_576,
+		"gpt-4o":                   128_000,
+		"openai/gpt-5":             400_000,
+		"gemini-2.5-pro":           1_048_576,
+		"meta-llama/llama-3.3-70b": 128_000,
+		"some-unknown-model":       0,
+	} {
+		assert.Equal(t, expected, r.contextWindow(&LLMEndpoint{Model: model, Provider: OpenAI}), model)
+	}
+	// replays have no context window
+	assert.Equal(t, 0, r.contextWindow(&LLMEndpoint{Model: "replay/abc"}))
+	assert.Equal(t, 0, r.contextWindow(&LLMEndpoint{Model: "ollama/qwen3", Provider: Ollama}))
+
+	r = &LLMRouter{ContextWindow: 50_000, OllamaContextLength: 8192}
+	assert.Equal(t, 50_000, r.contextWindow(&LLMEndpoint{Model: "claude-sonnet-4-5", Provider: Anthropic}))
+	assert.Equal(t, 50_000, r.contextWindow(&LLMEndpoint{Model: "ollama/qwen3", Provider: Ollama}))
+	r = &LLMRouter{OllamaContextLength: 8192}
+	assert.Equal(t, 8192, r.contextWindow(&LLMEndpoint{Model: "ollama/qwen3", Provider: Ollama}))
+}
+
+func TestLlmEstimateTokens(t *testing.T) {
+	messages := []*ModelMessage{
+		{Role: "user", Content: strings.Repeat("a", 400)},
+	}
+	// without reported usage, assume four characters per token
+	assert.Equal(t, int64(100), estimateTokens(OpenAI, messages, nil))
+
+	reply := &ModelMessage{Role: "assistant", Content: "ok", TokenUsage: LLMTokenUsage{
+		InputTokens:      1000,
+		OutputTokens:     10,
+		CachedTokenReads: 500,
+	}}
+	messages = append(messages, reply, &ModelMessage{
+		Role:       "user",
+		Content:    strings.Repeat("b", 40),
+		ToolCallID: "call_1",
+	})
+	assert.Equal(t, int64(1020), estimateTokens(OpenAI, messages, nil))
+	// Anthropic reports cached tokens separately
+	assert.Equal(t, int64(1520), estimateTokens(Anthropic, messages, nil))
+	// usage reported before a compaction is stale
+	assert.Equal(t, int64(110), estimateTokens(OpenAI, messages, reply))
+}
+
+func historyTestLLM(messages ...*ModelMessage) *LLM {
+	return &LLM{
+		mcp:                        newMCP(dagql.ObjectResult[*Env]{}),
+		messages:                   messages,
+		disableDefaultSystemPrompt: true,
+		endpointMtx:                &sync.Mutex{},
+		once:                       &sync.Once{},
+	}
+}
+
+func TestLlmCompactHistoryTruncate(t *testing.T) {
+	ctx := context.Background()
+	ep := &LLMEndpoint{Provider: OpenAI, ContextWindow: 1000}
+	oldResult := &ModelMessage{Role: "user", Content: strings.Repeat("x", 4000), ToolCallID: "call_1"}
+	newResult := &ModelMessage{Role: "user", Content: strings.Repeat("y", 400), ToolCallID: "call_2"}
+	llm := historyTestLLM(
+		&ModelMessage{Role: "user", Content: "do the thing"},
+		&ModelMessage{Role: "assistant", ToolCalls: []LLMToolCall{{ID: "call_1"}}},
+		oldResult,
+		&ModelMessage{Role: "assistant", ToolCalls: []LLMToolCall{{ID: "call_2"}}},
+		newResult,
+	)
+
+	// below the threshold, nothing happens
+	below, err := llm.WithHistoryStrategy(LLMHistoryStrategyTruncate, 1)
+	assert.NoError(t, err)
+	below.messages = below.messages[:2]
+	assert.NoError(t, below.compactHistory(ctx, ep))
+	assert.Equal(t, 0, below.compactions)
+
+	clone := llm.Clone()
+	assert.NoError(t, llm.compactHistory(ctx, ep))
+	assert.Equal(t, 1, llm.compactions)
+	assert.Positive(t, llm.compactedTokens)
+	assert.Equal(t, "[truncated 4000 characters of tool output to fit the context window]", llm.messages[2].Content)
+	// the latest tool results are kept
+	assert.Same(t, newResult, llm.messages[4])
+	// clones share messages, so they must not be modified
+	assert.Same(t, oldResult, clone.messages[2])
+	assert.Len(t, oldResult.Content, 4000)
+
+	// nothing is compacted when the strategy is NONE
+	clone, err = clone.WithHistoryStrategy(LLMHistoryStrategyNone, 0.5)
+	assert.NoError(t, err)
+	assert.NoError(t, clone.compactHistory(ctx, ep))
+	assert.Equal(t, 0, clone.compactions)
+
+	_, err = clone.WithHistoryStrategy(LLMHistoryStrategySummarize, 1.5)
+	assert.Error(t, err)
+}
+
+type summarizingLLMClient struct {
+	summary string
+	history []*ModelMessage
+	tools   []LLMTool
+}
+
+func (c *summarizingLLMClient) SendQuery(_ context.Context, history []*ModelMessage, tools []LLMTool) (*LLMResponse, error) {
+	c.history = history
+	c.tools = tools
+	return &LLMResponse{
+		Content:    c.summary,
+		TokenUsage: LLMTokenUsage{InputTokens: 900, OutputTokens: 5, TotalTokens: 905},
+	}, nil
+}
+
+func (*summarizingLLMClient) IsRetryable(error) bool {
+	return false
+}
+
+func TestLlmCompactHistorySummarize(t *testing.T) {
+	ctx := context.Background()
+	client := &summarizingLLMClient{summary: "we did the thing"}
+	ep := &LLMEndpoint{Provider: OpenAI, ContextWindow: 1000, Client: client}
+	system := &ModelMessage{Role: "system", Content: "be helpful"}
+	call := &ModelMessage{
+		Role:       "assistant",
+		ToolCalls:  []LLMToolCall{{ID: "call_2"}},
+		TokenUsage: LLMTokenUsage{InputTokens: 1000, OutputTokens: 10, TotalTokens: 1010},
+	}
+	result := &ModelMessage{Role: "user", Content: "done", ToolCallID: "call_2"}
+	llm, err := historyTestLLM(
+		system,
+		&ModelMessage{Role: "user", Content: "do the thing"},
+		&ModelMessage{
+			Role:       "assistant",
+			ToolCalls:  []LLMToolCall{{ID: "call_1"}},
+			TokenUsage: LLMTokenUsage{InputTokens: 10, OutputTokens: 10, TotalTokens: 20},
+		},
+		&ModelMessage{Role: "user", Content: strings.Repeat("x", 4000), ToolCallID: "call_1"},
+		call,
+		result,
+	).WithHistoryStrategy(LLMHistoryStrategySummarize, 0.8)
+	assert.NoError(t, err)
+
+	assert.NoError(t, llm.compactHistory(ctx, ep))
+	assert.Equal(t, 1, llm.compactions)
+
+	// the summary is requested without the current turn, nor tools to call
+	assert.Nil(t, client.tools)
+	assert.Len(t, client.history, 5)
+	assert.Equal(t, "user", client.history[4].Role)
+	assert.Equal(t, "call_1", client.history[3].ToolCallID)
+
+	assert.Len(t, llm.messages, 4)
+	assert.Same(t, system, llm.messages[0])
+	assert.Contains(t, llm.messages[1].Content, "we did the thing")
+	assert.Same(t, call, llm.messages[2])
+	assert.Same(t, result, llm.messages[3])
+
+	// the usage of dropped messages and of the summary is still accounted for
+	assert.Equal(t, int64(910), llm.compactedUsage.InputTokens)
+	assert.Positive(t, llm.compactedTokens)
+
+	// the usage reported before summarizing is stale, so it doesn't compact again
+	assert.NoError(t, llm.compactHistory(ctx, ep))
+	assert.Equal(t, 1, llm.compactions)
+
+	// an empty summary leaves the history intact
+	emptyClient := &summarizingLLMClient{summary: " \n"}
+	ep = &LLMEndpoint{Provider: OpenAI, ContextWindow: 1000, Client: emptyClient}
+	llm, err = historyTestLLM(
+		system,
+		&ModelMessage{Role: "user", Content: strings.Repeat("x", 4000)},
+		call,
+		result,
+	).WithHistoryStrategy(LLMHistoryStrategySummarize, 0.8)
+	assert.NoError(t, err)
+	messages := slices.Clone(llm.messages)
+	assert.ErrorContains(t, llm.compactHistory(ctx, ep), "empty summary")
+	assert.Equal(t, messages, llm.messages)
+	assert.Equal(t, 0, llm.compactions)
+}
+
+func TestLlmApprovalPrompt(t *testing.T) {
+	prompt, err := approvalPrompt("Container.export", map[string]any{
+		"self": "Container#1",
+		"path": "./out",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "The LLM wants to call **Container.export** on `Container#1` with these arguments:\n\n```json\n{\n  \"path\": \"./out\"\n}\n```\n\nAllow it?", prompt)
+
+	prompt, err = approvalPrompt("Container.publish", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "The LLM wants to call **Container.publish**. Allow it?", prompt)
+}
+
+func TestLlmApprovalFailsClosed(t *testing.T) {
+	m := newMCP(dagql.ObjectResult[*Env]{})
+	m.approvalMethods["Container"] = []string{"export"}
+
+	// without a client to ask, the call is denied
+	err := m.requestApproval(context.Background(), "Container", "export", map[string]any{"path": "./out"})
+	var denied *ToolCallDeniedError
+	assert.ErrorAs(t, err, &denied)
+	assert.Equal(t, "Container.export", denied.Function)
+	assert.Error(t, denied.Cause)
+	assert.Contains(t, toolErrorMessage(err), "requires the user's approval, but they could not be asked")
+
+	// clones don't share approvals
+	clone := m.Clone()
+	clone.approvalMethods["Container"] = append(clone.approvalMethods["Container"], "publish")
+	assert.Equal(t, []string{"export"}, m.approvalMethods["Container"])
+}
@@ -85,6 +85,8 @@ type MCP struct {
 	selectedMethods map[string]bool
 	// Never show these functions, grouped by type
 	blockedMethods map[string][]string
+	// Ask the user before calling these functions, grouped by type
+	approvalMethods map[string][]string
 	// The last value returned by a function.
 	lastResult dagql.Typed
 	// Indicates that the model has returned
@@ -99,6 +101,10 @@ type MCP struct {
 	mcpServers map[string]*MCPServerConfig
 	// Persistent MCP sessions.
 	mcpSessions map[string]*mcp.ClientSession
+	// Other LLMs that tasks can be delegated to, by name.
+	subAgents map[string]*LLM
+	// Maximum number of tool calls to run at once, or 0 for no limit.
+	toolConcurrency int
 	// Synchronize any concurrent tool call results.
 	mu *sync.Mutex
 }
@@ -134,11 +140,13 @@ func newMCP(env dagql.ObjectResult[*Env]) *MCP {
 		env:             env,
 		selectedMethods: map[string]bool{},
 		blockedMethods:  blocked,
+		approvalMethods: map[string][]string{},
 		objsByID:        map[string]contextualBinding{},
 		typeCounts:      map[string]int{},
 		idByHash:        map[digest.Digest]string{},
 		mcpServers:      make(map[string]*MCPServerConfig),
 		mcpSessions:     map[string]*mcp.ClientSession{},
+		subAgents:       map[string]*LLM{},
 		mu:              &sync.Mutex{},
 	}
 }
@@ -192,11 +200,16 @@ func (m *MCP) Clone() *MCP {
 	for typeName, methods := range cp.blockedMethods {
 		cp.blockedMethods[typeName] = slices.Clone(methods)
 	}
+	cp.approvalMethods = maps.Clone(cp.approvalMethods)
+	for typeName, methods := range cp.approvalMethods {
+		cp.approvalMethods[typeName] = slices.Clone(methods)
+	}
 	cp.objsByID = maps.Clone(cp.objsByID)
 	cp.typeCounts = maps.Clone(cp.typeCounts)
 	cp.idByHash = maps.Clone(cp.idByHash)
 	cp.mcpServers = maps.Clone(cp.mcpServers)
 	cp.mcpSessions = maps.Clone(cp.mcpSessions)
+	cp.subAgents = maps.Clone(cp.subAgents)
 	cp.returned = false
 	cp.mu = &sync.Mutex{}
 	return &cp
@@ -260,6 +273,18 @@ func (m *MCP) WithMCPServer(srv *MCPServerConfig) *MCP {
 	return m
 }
 
+func (m *MCP) WithSubAgent(name string, agent *LLM) *MCP {
+	m = m.Clone()
+	m.subAgents[name] = agent
+	return m
+}
+
+func (m *MCP) WithToolConcurrency(limit int) *MCP {
+	m = m.Clone()
+	m.toolConcurrency = limit
+	return m
+}
+
 func (m *MCP) Tools(ctx context.Context) ([]LLMTool, error) {
 	srv, err := m.Server(ctx)
 	if err != nil {
@@ -745,6 +770,18 @@ func (m *MCP) call(ctx context.Context,
 		return val, nil
 	}
 
+	if slices.Contains(m.approvalMethods[selfType], fieldDef.Name) {
+		if err := m.requestApproval(ctx, selfType, fieldDef.Name, args); err != nil {
+			return "", err
+		}
+		defer func() {
+			if rerr == nil {
+				// record the approval in the history
+				res = strings.TrimSpace(toolCallApproved + "\n\n" + res)
+			}
+		}()
+	}
+
 	val, err := doSelect(ctx, m.env)
 	if err != nil {
 		return "", err
@@ -994,6 +1031,15 @@ func (m *MCP) toolCallToSelections(
 }
 
 func (m *MCP) BlockFunction(ctx context.Context, typeName, funcName string) error {
+	if err := m.checkFunction(ctx, typeName, funcName); err != nil {
+		return err
+	}
+	m.blockedMethods[typeName] = append(m.blockedMethods[typeName], funcName)
+	return nil
+}
+
+// checkFunction checks that the function exists in the schema.
+func (m *MCP) checkFunction(ctx context.Context, typeName, funcName string) error {
 	srv, err := m.Server(ctx)
 	if err != nil {
 		return fmt.Errorf("load schema: %w", err)
@@ -1006,7 +1052,6 @@ func (m *MCP) BlockFunction(ctx context.Context, typeName, funcName string) erro
 	if !ok {
 		return fmt.Errorf("function %q not found on type %q", funcName, typeName)
 	}
-	m.blockedMethods[typeName] = append(m.blockedMethods[typeName], funcName)
 	return nil
 }
 
@@ -1106,7 +1151,10 @@ func (m *MCP) Call(ctx context.Context, tools []LLMTool, toolCall LLMToolCall) (
 }
 
 // CallBatch executes a batch of tool calls, handling MCP server syncing efficiently by
-// grouping calls by destructiveness and server to avoid workspace conflicts
+// grouping calls by destructiveness and server to avoid workspace conflicts.
+//
+// Results are returned in the same order as the tool calls, regardless of the
+// order they completed in, so that the history is deterministic.
 func (m *MCP) CallBatch(ctx context.Context, tools []LLMTool, toolCalls []LLMToolCall) []*ModelMessage {
 	// Group tool calls by their characteristics
 	readOnlyMCPCalls := make(map[string][]LLMToolCall)    // server -> read-only calls
@@ -1175,6 +1223,13 @@ func (m *MCP) CallBatch(ctx context.Context, tools []LLMTool, toolCalls []LLMToo
 		allResults = append(allResults, m.callBatchRegular(ctx, tools, readOnlyToolCalls)...)
 	}
 
+	order := make(map[string]int, len(toolCalls))
+	for i, call := range toolCalls {
+		order[call.ID] = i
+	}
+	slices.SortStableFunc(allResults, func(a, b *ModelMessage) int {
+		return order[a.ToolCallID] - order[b.ToolCallID]
+	})
 	return allResults
 }
 
@@ -1230,6 +1285,9 @@ func (m *MCP) callBatchMCPServer(ctx context.Context, tools []LLMTool, toolCalls
 func (m *MCP) callBatchRegular(ctx context.Context, tools []LLMTool, toolCalls []LLMToolCall) []*ModelMessage {
 	// Run tool calls in parallel using the existing pool logic
 	toolCallsPool := pool.NewWithResults[*ModelMessage]()
+	if m.toolConcurrency > 0 {
+		toolCallsPool = toolCallsPool.WithMaxGoroutines(m.toolConcurrency)
+	}
 	for _, toolCall := range toolCalls {
 		toolCallsPool.Go(func() *ModelMessage {
 			content, isError := m.Call(ctx, tools, toolCall)
@@ -1653,6 +1711,10 @@ func (m *MCP) loadBuiltins(srv *dagql.Server, allTools, objectMethods *LLMToolSe
 		allTools.Add(m.saveTool(srv))
 	}
 
+	for _, name := range slices.Sorted(maps.Keys(m.subAgents)) {
+		allTools.Add(m.subAgentTool(srv, name, m.subAgents[name]))
+	}
+
 	if len(m.env.Self().inputsByName) > 0 {
 		allTools.Add(LLMTool{
 			Name:        "UserProvidedValues",
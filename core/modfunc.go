@@ -669,12 +669,34 @@ func (fn *ModuleFunction) Call(ctx context.Context, opts *CallOpts) (t dagql.Any
 		AllowedLLMModules: clientMetadata.AllowedLLMModules,
 	}
 
+	query, err := CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a dependency's functions are restricted to the permissions declared for
+	// it by the module depending on it
+	permissions, err := query.ModulePermissionsFor(ctx, mod)
+	if err != nil {
+		return nil, fmt.Errorf("get module permissions: %w", err)
+	}
+	if err := query.CheckModuleSecretsPermission(ctx, mod, permissions, callID); err != nil {
+		return nil, err
+	}
+
 	var cacheMixins []string
 	if opts.OverrideStorageKey != "" {
 		cacheMixins = append(cacheMixins, opts.OverrideStorageKey)
 	} else {
 		cacheMixins = append(cacheMixins, cache.CurrentStorageKey(ctx))
 	}
+	if permissions != nil {
+		permissionsJSON, err := json.Marshal(permissions)
+		if err != nil {
+			return nil, fmt.Errorf("marshal module permissions: %w", err)
+		}
+		cacheMixins = append(cacheMixins, string(permissionsJSON))
+	}
 
 	execMD.CacheMixin = hashutil.HashStrings(cacheMixins...)
 
@@ -726,6 +748,8 @@ func (fn *ModuleFunction) Call(ctx context.Context, opts *CallOpts) (t dagql.Any
 		Parent:    parentJSON,
 		ParentID:  callID.Receiver(),
 		InputArgs: callInputs,
+
+		Permissions: permissions,
 	}
 	if envID, ok := EnvIDFromContext(ctx); ok {
 		fnCall.EnvID = envID
@@ -787,10 +811,6 @@ func (fn *ModuleFunction) Call(ctx context.Context, opts *CallOpts) (t dagql.Any
 		return nil, fmt.Errorf("exec function: %w", err)
 	}
 
-	query, err := CurrentQuery(ctx)
-	if err != nil {
-		return nil, err
-	}
 	bk, err := query.Buildkit(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get buildkit client: %w", err)
@@ -970,6 +990,11 @@ func moduleAnalyticsProps(mod *Module, prefix string, props map[string]string) {
 		props[prefix+"git_version"] = git.Version
 		props[prefix+"git_commit"] = git.Commit
 		props[prefix+"git_html_repo_url"] = git.HTMLRepoURL
+	case ModuleSourceKindOCI:
+		props[prefix+"source_kind"] = "oci"
+		props[prefix+"oci_repository"] = source.OCI.Repository
+		props[prefix+"oci_tag"] = source.OCI.Tag
+		props[prefix+"oci_digest"] = source.OCI.Digest
 	}
 }
 
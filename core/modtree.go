@@ -279,7 +279,7 @@ func (node *ModTreeNode) tryRunGeneratorScaleOut(ctx context.Context) (_ bool, _
 }
 
 // buildScaleOutModuleQuery builds a query to load a module for scale-out execution.
-// It handles all module source kinds (Local, Git, Dir) and returns a query
+// It handles all module source kinds (Local, Git, Dir, OCI) and returns a query
 // positioned at the "asModule" selection, ready for check/generator-specific queries.
 func (node *ModTreeNode) buildScaleOutModuleQuery(query *querybuilder.Selection) (*querybuilder.Selection, error) {
 	modSrc := node.Module.Source.Value.Self()
@@ -295,6 +295,11 @@ func (node *ModTreeNode) buildScaleOutModuleQuery(query *querybuilder.Selection)
 			Arg("refString", modSrc.AsString()).
 			Arg("refPin", modSrc.Git.Commit).
 			Arg("requireKind", modSrc.Kind)
+	case ModuleSourceKindOCI:
+		query = query.Select("moduleSource").
+			Arg("refString", modSrc.AsString()).
+			Arg("refPin", modSrc.OCI.Digest).
+			Arg("requireKind", modSrc.Kind)
 	case ModuleSourceKindDir:
 		dirIDEnc, err := modSrc.DirSrc.OriginalContextDir.ID().Encode()
 		if err != nil {
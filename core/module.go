@@ -289,6 +289,10 @@ func (mod *Module) IDModule() *call.Module {
 		}
 		pin = src.Git.Commit
 
+	case ModuleSourceKindOCI:
+		ref = src.AsString()
+		pin = src.OCI.Digest
+
 	case ModuleSourceKindDir:
 		// FIXME: this is better than nothing, but no other code handles refs that
 		// are an encoded ID right now
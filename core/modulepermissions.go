@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
+)
+
+// ModulePermissionKind is a kind of access that may be granted to a
+// dependency in the permissions block of dagger.json.
+type ModulePermissionKind string
+
+const (
+	ModulePermissionHostPaths ModulePermissionKind = "hostPaths"
+	ModulePermissionNetwork   ModulePermissionKind = "network"
+	ModulePermissionSecrets   ModulePermissionKind = "secrets"
+	ModulePermissionNesting   ModulePermissionKind = "nesting"
+)
+
+// ModulePermission is access requested by a module function.
+type ModulePermission struct {
+	Kind ModulePermissionKind
+	// The host path or secret name, if any
+	Value string
+}
+
+func (perm ModulePermission) String() string {
+	switch perm.Kind {
+	case ModulePermissionHostPaths:
+		return "access to host path " + perm.Value
+	case ModulePermissionNetwork:
+		return "network access"
+	case ModulePermissionSecrets:
+		return "access to secret " + perm.Value
+	case ModulePermissionNesting:
+		return "privileged nesting"
+	default:
+		return string(perm.Kind)
+	}
+}
+
+func (perm ModulePermission) allowedBy(perms *modules.ModulePermissions) bool {
+	switch perm.Kind {
+	case ModulePermissionHostPaths:
+		return perms.AllowsHostPath(perm.Value)
+	case ModulePermissionNetwork:
+		return perms.AllowsNetwork()
+	case ModulePermissionSecrets:
+		return perms.AllowsSecret(perm.Value)
+	case ModulePermissionNesting:
+		return perms.AllowsNesting()
+	default:
+		return false
+	}
+}
+
+// ModulePermissionError is returned when a dependency's function is denied
+// access beyond the permissions declared for it.
+//
+// It supports being serialized/deserialized through graphql.
+type ModulePermissionError struct {
+	// Module is the source of the module that was denied
+	Module     string
+	Permission ModulePermission
+}
+
+func (e *ModulePermissionError) Error() string {
+	return fmt.Sprintf("module %s was denied %s; add it to the permissions of the dependency in dagger.json to allow it", e.Module, e.Permission)
+}
+
+func (e *ModulePermissionError) Extensions() map[string]any {
+	return map[string]any{
+		"_type":      "MODULE_PERMISSION_DENIED",
+		"module":     e.Module,
+		"permission": string(e.Permission.Kind),
+		"value":      e.Permission.Value,
+	}
+}
+
+// CheckModulePermission returns an error if the module function making the
+// call wasn't granted the given access by the module depending on it, and the
+// user doesn't allow it when prompted.
+func (q *Query) CheckModulePermission(ctx context.Context, perm ModulePermission) error {
+	allowed, mod, err := q.modulePermitted(ctx, perm)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &ModulePermissionError{Module: moduleSourceString(mod), Permission: perm}
+	}
+	return nil
+}
+
+// ModuleGranted reports whether the module function making the call was
+// granted the given access by the module depending on it. Unlike
+// CheckModulePermission, it never prompts the user, so it suits restrictions
+// that are applied rather than enforced with an error, e.g. running a
+// dependency's containers without a network.
+func (q *Query) ModuleGranted(ctx context.Context, perm ModulePermission) (bool, error) {
+	fnCall, err := q.CurrentFunctionCall(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCurrentModule) {
+			return true, nil
+		}
+		return false, err
+	}
+	if fnCall == nil || fnCall.Permissions == nil {
+		return true, nil
+	}
+	return perm.allowedBy(fnCall.Permissions), nil
+}
+
+func (q *Query) modulePermitted(ctx context.Context, perm ModulePermission) (bool, *Module, error) {
+	fnCall, err := q.CurrentFunctionCall(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCurrentModule) {
+			return true, nil, nil
+		}
+		return false, nil, err
+	}
+	if fnCall == nil || fnCall.Permissions == nil {
+		return true, nil, nil
+	}
+	mod, err := q.CurrentModule(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	allowed, err := q.promptModulePermission(ctx, mod, fnCall.Permissions, perm)
+	return allowed, mod, err
+}
+
+func (q *Query) promptModulePermission(ctx context.Context, mod *Module, perms *modules.ModulePermissions, perm ModulePermission) (bool, error) {
+	if perm.allowedBy(perms) {
+		return true, nil
+	}
+	if perm.Kind == ModulePermissionHostPaths {
+		// a local module can always read its own context directory
+		if src := mod.GetSource(); src != nil && src.Kind == ModuleSourceKindLocal && src.Local != nil &&
+			withinHostPath(perm.Value, src.Local.ContextDirectoryPath) {
+			return true, nil
+		}
+	}
+	bk, err := q.Buildkit(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+	return bk.PromptAllowModulePermission(ctx, moduleSourceString(mod), perm.String())
+}
+
+// ModulePermissionsFor returns the access granted to functions of the given
+// module when called from the current client: the permissions declared for it
+// in the calling module's dagger.json, restricted further by those the calling
+// module runs with. Calls from clients that aren't modules are unrestricted.
+func (q *Query) ModulePermissionsFor(ctx context.Context, callee *Module) (*modules.ModulePermissions, error) {
+	caller, err := q.CurrentModule(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCurrentModule) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var inherited *modules.ModulePermissions
+	fnCall, err := q.CurrentFunctionCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fnCall != nil {
+		inherited = fnCall.Permissions
+	}
+	if caller.Name() == callee.Name() {
+		return inherited, nil
+	}
+
+	var declared *modules.ModulePermissions
+	if src := caller.GetSource(); src != nil {
+		for _, dep := range src.ConfigDependencies {
+			if dep.Name != callee.Name() || dep.Permissions == nil {
+				continue
+			}
+			var rootDir string
+			if src.Kind == ModuleSourceKindLocal && src.Local != nil {
+				rootDir = filepath.Join(src.Local.ContextDirectoryPath, src.SourceRootSubpath)
+			}
+			declared = dep.Permissions.WithHostPathsRelativeTo(rootDir)
+			break
+		}
+	}
+	return declared.Restrict(inherited), nil
+}
+
+// CheckModuleSecretsPermission returns an error if any secrets in the given
+// call aren't allowed to be passed to a module with the given permissions, and
+// the user doesn't allow them when prompted.
+func (q *Query) CheckModuleSecretsPermission(ctx context.Context, callee *Module, perms *modules.ModulePermissions, id *call.ID) error {
+	if perms == nil || id == nil {
+		return nil
+	}
+	walked, err := dagql.WalkID(id, false)
+	if err != nil {
+		return fmt.Errorf("failed to walk ID: %w", err)
+	}
+	secretStore, err := q.Secrets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get secret store: %w", err)
+	}
+	for _, secretID := range dagql.WalkedIDs[*Secret](walked) {
+		name, ok := secretStore.GetSecretNameOrURI(SecretIDDigest(secretID.ID()))
+		if !ok {
+			continue
+		}
+		perm := ModulePermission{Kind: ModulePermissionSecrets, Value: name}
+		allowed, err := q.promptModulePermission(ctx, callee, perms, perm)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return &ModulePermissionError{Module: moduleSourceString(callee), Permission: perm}
+		}
+	}
+	return nil
+}
+
+func moduleSourceString(mod *Module) string {
+	if mod == nil {
+		return ""
+	}
+	if src := mod.GetSource(); src != nil {
+		return src.AsString()
+	}
+	return mod.Name()
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/core/modules"
+)
+
+func TestModulePermissionAllowedBy(t *testing.T) {
+	perms := &modules.ModulePermissions{
+		HostPaths: []string{"/work/app"},
+		Secrets:   []string{"env://GITHUB_TOKEN"},
+		Nesting:   true,
+	}
+
+	require.True(t, ModulePermission{Kind: ModulePermissionHostPaths, Value: "/work/app/src"}.allowedBy(perms))
+	require.False(t, ModulePermission{Kind: ModulePermissionHostPaths, Value: "/home/me/.ssh"}.allowedBy(perms))
+	require.True(t, ModulePermission{Kind: ModulePermissionSecrets, Value: "env://GITHUB_TOKEN"}.allowedBy(perms))
+	require.False(t, ModulePermission{Kind: ModulePermissionSecrets, Value: "env://AWS_SECRET_ACCESS_KEY"}.allowedBy(perms))
+	require.False(t, ModulePermission{Kind: ModulePermissionNetwork}.allowedBy(perms))
+	require.True(t, ModulePermission{Kind: ModulePermissionNesting}.allowedBy(perms))
+
+	// unrestricted modules are allowed everything
+	require.True(t, ModulePermission{Kind: ModulePermissionNetwork}.allowedBy(nil))
+}
+
+func TestModulePermissionError(t *testing.T) {
+	err := &ModulePermissionError{
+		Module:     "github.com/acme/linter@v1.0.0",
+		Permission: ModulePermission{Kind: ModulePermissionSecrets, Value: "env://AWS_SECRET_ACCESS_KEY"},
+	}
+	require.Equal(t, "module github.com/acme/linter@v1.0.0 was denied access to secret env://AWS_SECRET_ACCESS_KEY; add it to the permissions of the dependency in dagger.json to allow it", err.Error())
+	require.Equal(t, map[string]any{
+		"_type":      "MODULE_PERMISSION_DENIED",
+		"module":     "github.com/acme/linter@v1.0.0",
+		"permission": "secrets",
+		"value":      "env://AWS_SECRET_ACCESS_KEY",
+	}, err.Extensions())
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"golang.org/x/mod/semver"
 
 	"dagger.io/dagger/telemetry"
+	"github.com/dagger/dagger/core/modules"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/engine/slog"
 	"github.com/dagger/dagger/engine/vcs"
@@ -21,6 +23,8 @@ func fastModuleSourceKindCheck(
 	refPin string,
 ) ModuleSourceKind {
 	switch {
+	case isOCIRefString(refString):
+		return ModuleSourceKindOCI
 	case refPin != "":
 		return ModuleSourceKindGit
 	case len(refString) > 0 && (refString[0] == '/' || refString[0] == '.'):
@@ -47,6 +51,7 @@ type ParsedRefString struct {
 	Kind  ModuleSourceKind
 	Local *ParsedLocalRefString
 	Git   *ParsedGitRefString
+	OCI   *ParsedOCIRefString
 }
 
 func ParseRefString(
@@ -76,6 +81,15 @@ func ParseRefString(
 			Kind: kind,
 			Git:  &parsedGitRef,
 		}, nil
+	case ModuleSourceKindOCI:
+		parsedOCIRef, err := ParseOCIRefString(refString)
+		if err != nil {
+			return nil, err
+		}
+		return &ParsedRefString{
+			Kind: kind,
+			OCI:  &parsedOCIRef,
+		}, nil
 	}
 
 	// First, we stat ref in case the mod path github.com/username is a local directory
@@ -98,6 +112,8 @@ func ParseRefString(
 			Kind: ModuleSourceKindGit,
 			Git:  &parsedGitRef,
 		}, nil
+	case errors.As(err, new(*OfflineError)):
+		return nil, err
 	case errors.As(err, &gitEndpointError{}):
 		// couldn't connect to git endpoint, fallback to local
 		return &ParsedRefString{
@@ -111,6 +127,23 @@ func ParseRefString(
 	}
 }
 
+// lockSource returns the source a git or OCI ref is recorded as in a lock
+// file, along with the version it requests, if any.
+func (ref *ParsedRefString) lockSource() (source string, version string) {
+	switch ref.Kind {
+	case ModuleSourceKindGit:
+		source = ref.Git.SourceCloneRef
+		if subdir := strings.Trim(ref.Git.RepoRootSubdir, "/"); subdir != "" && subdir != "." {
+			source += "/" + subdir
+		}
+		return source, ref.Git.ModVersion
+	case ModuleSourceKindOCI:
+		return ref.OCI.Repository, cmp.Or(ref.OCI.Digest, ref.OCI.Tag)
+	default:
+		return "", ""
+	}
+}
+
 type ParsedLocalRefString struct {
 	ModPath string
 }
@@ -138,6 +171,64 @@ func ParseGitRefString(ctx context.Context, refString string) (_ ParsedGitRefStr
 	_, span := Tracer(ctx).Start(ctx, fmt.Sprintf("parseGitRefString: %s", refString), telemetry.Internal())
 	defer telemetry.EndWithCause(span, &rerr)
 
+	return parseGitRefString(refString, func(importPath string) (*vcs.RepoRoot, error) {
+		return repoRootForImportPath(ctx, importPath)
+	})
+}
+
+// repoRootForImportPath resolves the repo root of an import path, which for
+// custom domains requires a go-get style HTTP lookup. Those lookups are
+// recorded so they can be served while offline.
+func repoRootForImportPath(ctx context.Context, importPath string) (*vcs.RepoRoot, error) {
+	rr, err := vcs.RepoRootForImportPathStatic(importPath, "")
+	if err == nil {
+		return rr, nil
+	}
+	query, qerr := CurrentQuery(ctx)
+	if qerr != nil {
+		return vcs.RepoRootForImportPath(importPath, false)
+	}
+	offline, err := query.Offline(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		repo, ok := query.Resolutions().Get(ctx, ResolutionModuleRepoRoot, importPath)
+		if !ok {
+			return nil, &OfflineError{Resource: importPath}
+		}
+		root, repo, _ := strings.Cut(repo, " ")
+		return &vcs.RepoRoot{VCS: vcs.ByCmd("git"), Repo: repo, Root: root}, nil
+	}
+	rr, err = vcs.RepoRootForImportPath(importPath, false)
+	if err != nil {
+		return nil, err
+	}
+	if rr.VCS != nil && rr.VCS.Cmd == "git" {
+		if err := query.Resolutions().Put(ctx, ResolutionModuleRepoRoot, importPath, rr.Root+" "+rr.Repo); err != nil {
+			slog.Warn("failed to record module repo root resolution", "importPath", importPath, "error", err)
+		}
+	}
+	return rr, nil
+}
+
+// ParseVendoredGitRefString parses a git ref string whose repo root is
+// already known from a vendored copy, without discovering it over the
+// network.
+func ParseVendoredGitRefString(refString string, vendored *modules.VendoredModule) (ParsedGitRefString, error) {
+	return parseGitRefString(refString, func(importPath string) (*vcs.RepoRoot, error) {
+		if importPath != vendored.RepoRootPath && !strings.HasPrefix(importPath, vendored.RepoRootPath+"/") {
+			return nil, fmt.Errorf("import path %q is not in vendored repo %q", importPath, vendored.RepoRootPath)
+		}
+		return &vcs.RepoRoot{
+			VCS:  vcs.ByCmd("git"),
+			Repo: vendored.HTMLRepoURL,
+			Root: vendored.RepoRootPath,
+		}, nil
+	})
+}
+
+func parseGitRefString(refString string, repoRootForImportPath func(string) (*vcs.RepoRoot, error)) (ParsedGitRefString, error) {
 	scheme, schemelessRef := parseScheme(refString)
 
 	if scheme == NoScheme && isSCPLike(schemelessRef) {
@@ -173,7 +264,7 @@ func ParseGitRefString(ctx context.Context, refString string) (_ ParsedGitRefStr
 	// Try to isolate the root of the git repo
 	// RepoRootForImportPath does not support SCP-like ref style. In parseGitEndpoint, we made sure that all refs
 	// would be compatible with this function to benefit from the repo URL and root splitting
-	repoRoot, err := vcs.RepoRootForImportPath(gitParsed.modPath, false)
+	repoRoot, err := repoRootForImportPath(gitParsed.modPath)
 	if err != nil {
 		return ParsedGitRefString{}, gitEndpointError{fmt.Errorf("failed to get repo root for import path: %w", err)}
 	}
@@ -282,6 +373,12 @@ func (p *ParsedGitRefString) GitRef(
 			return inst, fmt.Errorf("matching version to tags: %w", err)
 		}
 		modTag = matched
+	} else if p.hasVersion && modules.IsVersionConstraint(p.ModVersion) {
+		matched, err := p.resolveVersionConstraint(ctx, dag, pinCommitRef)
+		if err != nil {
+			return inst, err
+		}
+		modTag = matched
 	}
 
 	repoSelector := dagql.Selector{
@@ -330,6 +427,81 @@ func (p *ParsedGitRefString) GitRef(
 	return gitRef, nil
 }
 
+// resolveVersionConstraint resolves the ref's version constraint to the tag
+// of the highest matching version. If the ref is pinned, the matching tag
+// pointing to the pinned commit is preferred, so that resolving a pinned
+// dependency is stable as new versions are released.
+func (p *ParsedGitRefString) resolveVersionConstraint(
+	ctx context.Context,
+	dag *dagql.Server,
+	pinCommitRef string, // "" if none
+) (string, error) {
+	constraint, err := modules.ParseVersionConstraint(p.ModVersion)
+	if err != nil {
+		return "", err
+	}
+
+	var repo dagql.ObjectResult[*GitRepository]
+	err = dag.Select(ctx, dag.Root(), &repo,
+		dagql.Selector{
+			Field: "git",
+			Args: []dagql.NamedInput{
+				{Name: "url", Value: dagql.String(p.cloneRef)},
+			},
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git tags: %w", err)
+	}
+	remote := repo.Self().Remote.Tags()
+
+	if pinCommitRef != "" {
+		var pinned []string
+		for _, tag := range remote.ShortNames() {
+			ref, err := remote.Lookup(tag)
+			if err == nil && ref.SHA == pinCommitRef {
+				pinned = append(pinned, tag)
+			}
+		}
+		if matched, err := matchVersionConstraint(pinned, constraint, p.RepoRootSubdir); err == nil {
+			return matched, nil
+		}
+	}
+
+	matched, err := matchVersionConstraint(remote.ShortNames(), constraint, p.RepoRootSubdir)
+	if err != nil {
+		return "", fmt.Errorf("matching version to tags: %w", err)
+	}
+	return matched, nil
+}
+
+// Match a version constraint in a list of versions with optional subPath,
+// returning the highest matching version.
+// e.g. github.com/foo/daggerverse/mod@^1.0 -> mod/v1.2.0
+// e.g. github.com/foo/mod@^1.0 -> v1.2.0
+func matchVersionConstraint(versions []string, constraint *modules.VersionConstraint, subPath string) (string, error) {
+	// If theres a subPath, first match on {subPath}/{version} for monorepo tags
+	if subPath != "/" {
+		prefix := strings.TrimPrefix(subPath, "/") + "/"
+		byVersion := map[string]string{}
+		var subVersions []string
+		for _, v := range versions {
+			if version, ok := strings.CutPrefix(v, prefix); ok {
+				byVersion[version] = v
+				subVersions = append(subVersions, version)
+			}
+		}
+		if latest, ok := constraint.Latest(subVersions); ok {
+			return byVersion[latest], nil
+		}
+	}
+
+	if latest, ok := constraint.Latest(versions); ok {
+		return latest, nil
+	}
+	return "", fmt.Errorf("unable to find version matching %s", constraint)
+}
+
 // Match a version string in a list of versions with optional subPath
 // e.g. github.com/foo/daggerverse/mod@mod/v1.0.0
 // e.g. github.com/foo/mod@v1.0.0
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// OCIRefPrefix can be used to explicitly mark a module ref as an OCI artifact,
+// e.g. oci://localhost:5000/mods/foo:1.2.0.
+const OCIRefPrefix = "oci://"
+
+// Image config labels recording how a module was published as an OCI
+// artifact.
+const (
+	// OCIModuleSourceRootLabel is the path, relative to the artifact root,
+	// of the directory containing the module's dagger.json.
+	OCIModuleSourceRootLabel = "io.dagger.module.source-root"
+	// OCIModuleNameLabel is the name of the published module.
+	OCIModuleNameLabel = "io.dagger.module.name"
+)
+
+type ParsedOCIRefString struct {
+	// Repository is the repository the artifact is pulled from, e.g.
+	// registry.example.com/mods/foo
+	Repository string
+	// Tag is the tag of the artifact, if any
+	Tag string
+	// Digest is the manifest digest of the artifact, if any
+	Digest string
+}
+
+// isOCIRefString returns whether a module ref names an OCI artifact, either
+// explicitly with an oci:// prefix, or by naming a registry host and a tag or
+// digest, e.g. registry.example.com/mods/foo:1.2.0. Git refs use @ for
+// versions, so they never match.
+func isOCIRefString(refString string) bool {
+	if strings.HasPrefix(refString, OCIRefPrefix) {
+		return true
+	}
+	if strings.Contains(refString, "://") {
+		return false
+	}
+	host, rest, ok := strings.Cut(refString, "/")
+	if !ok || strings.Contains(host, "@") {
+		// scp-like git refs have a user before the host
+		return false
+	}
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return false
+	}
+	if name := rest[strings.LastIndex(rest, "/")+1:]; !strings.Contains(name, ":") {
+		// neither a tag nor a digest
+		return false
+	}
+	_, err := reference.Parse(refString)
+	return err == nil
+}
+
+func ParseOCIRefString(refString string) (ParsedOCIRefString, error) {
+	ref, err := reference.Parse(strings.TrimPrefix(refString, OCIRefPrefix))
+	if err != nil {
+		return ParsedOCIRefString{}, fmt.Errorf("invalid OCI module ref %q: %w", refString, err)
+	}
+	named, ok := ref.(reference.Named)
+	if !ok {
+		return ParsedOCIRefString{}, fmt.Errorf("invalid OCI module ref %q: missing repository", refString)
+	}
+	parsed := ParsedOCIRefString{Repository: named.Name()}
+	if tagged, ok := ref.(reference.Tagged); ok {
+		parsed.Tag = tagged.Tag()
+	}
+	if digested, ok := ref.(reference.Digested); ok {
+		parsed.Digest = digested.Digest().String()
+	}
+	return parsed, nil
+}
+
+// Address returns the address to pull the artifact from, pinned to the given
+// digest if set.
+func (p *ParsedOCIRefString) Address(pin string) string {
+	return OCIRefString(p.Repository, p.Tag, cmp.Or(pin, p.Digest))
+}
+
+// OCIRefString formats a ref to an OCI artifact, e.g.
+// registry.example.com/mods/foo:1.2.0@sha256:...
+func OCIRefString(repository, tag, digest string) string {
+	ref := repository
+	if tag != "" {
+		ref += ":" + tag
+	}
+	if digest != "" {
+		ref += "@" + digest
+	}
+	return ref
+}
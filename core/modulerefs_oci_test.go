@@ -0,0 +1,63 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOCIRefString(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	for ref, expected := range map[string]ParsedOCIRefString{
+		"registry.example.com/mods/foo:1.2.0": {
+			Repository: "registry.example.com/mods/foo",
+			Tag:        "1.2.0",
+		},
+		"localhost:5000/mods/foo:1.2.0": {
+			Repository: "localhost:5000/mods/foo",
+			Tag:        "1.2.0",
+		},
+		"registry.example.com/mods/foo@" + digest: {
+			Repository: "registry.example.com/mods/foo",
+			Digest:     digest,
+		},
+		"registry.example.com/mods/foo:1.2.0@" + digest: {
+			Repository: "registry.example.com/mods/foo",
+			Tag:        "1.2.0",
+			Digest:     digest,
+		},
+		"oci://harbor.internal/mods/foo:latest": {
+			Repository: "harbor.internal/mods/foo",
+			Tag:        "latest",
+		},
+	} {
+		require.True(t, isOCIRefString(ref), ref)
+		require.Equal(t, ModuleSourceKindOCI, fastModuleSourceKindCheck(ref, ""), ref)
+
+		parsed, err := ParseOCIRefString(ref)
+		require.NoError(t, err, ref)
+		require.Equal(t, expected, parsed, ref)
+		require.Equal(t, OCIRefString(expected.Repository, expected.Tag, expected.Digest), parsed.Address(""), ref)
+	}
+
+	for _, ref := range []string{
+		"github.com/shykes/daggerverse/hello@v0.3.0",
+		"github.com/shykes/daggerverse/hello",
+		"git@github.com:shykes/daggerverse.git",
+		"https://github.com/shykes/daggerverse",
+		"./hello",
+		"hello",
+	} {
+		require.False(t, isOCIRefString(ref), ref)
+	}
+}
+
+func TestParsedOCIRefStringAddress(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	parsed, err := ParseOCIRefString("registry.example.com/mods/foo:1.2.0")
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com/mods/foo:1.2.0", parsed.Address(""))
+	require.Equal(t, "registry.example.com/mods/foo:1.2.0@"+digest, parsed.Address(digest))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/dagger/dagger/core/modules"
 	"github.com/dagger/dagger/engine/vcs"
 	"github.com/stretchr/testify/require"
 )
@@ -31,6 +32,45 @@ func TestMatchVersion(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestMatchVersionConstraint(t *testing.T) {
+	vers := []string{"v1.0.0", "v1.4.1", "v1.10.0", "v2.0.0", "v1.11.0-rc.1", "path/v1.2.0", "path/v1.3.0", "path/v2.0.1"}
+
+	constraint, err := modules.ParseVersionConstraint("^1.2")
+	require.NoError(t, err)
+
+	match, err := matchVersionConstraint(vers, constraint, "/")
+	require.NoError(t, err)
+	require.Equal(t, "v1.10.0", match)
+
+	match, err = matchVersionConstraint(vers, constraint, "path")
+	require.NoError(t, err)
+	require.Equal(t, "path/v1.3.0", match)
+
+	match, err = matchVersionConstraint(vers, constraint, "/other")
+	require.NoError(t, err)
+	require.Equal(t, "v1.10.0", match)
+
+	constraint, err = modules.ParseVersionConstraint("^3")
+	require.NoError(t, err)
+	_, err = matchVersionConstraint(vers, constraint, "/")
+	require.Error(t, err)
+}
+
+func TestParseGitRefStringConstraint(t *testing.T) {
+	ctx := context.Background()
+	for ref, version := range map[string]string{
+		"github.com/shykes/daggerverse/ci@^1.4":           "^1.4",
+		"github.com/shykes/daggerverse/ci@>=1.0.0 <2.0.0": ">=1.0.0 <2.0.0",
+		"github.com/shykes/daggerverse/ci@1.x || 2.x":     "1.x || 2.x",
+	} {
+		parsed, err := ParseGitRefString(ctx, ref)
+		require.NoError(t, err, ref)
+		require.Equal(t, version, parsed.ModVersion, ref)
+		require.Equal(t, "ci", parsed.RepoRootSubdir, ref)
+		require.True(t, modules.IsVersionConstraint(parsed.ModVersion), ref)
+	}
+}
+
 // Test ParseRefString using an interface to control Host side effect
 func TestParseRefString(t *testing.T) {
 	ctx := context.Background()
@@ -3,6 +3,8 @@ package modules
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/dagger/dagger/engine"
@@ -84,6 +86,10 @@ type ModuleConfig struct {
 	// If true, disable the new default function caching behavior for this module. Functions will
 	// instead default to the old behavior of per-session caching.
 	DisableDefaultFunctionCaching *bool `json:"disableDefaultFunctionCaching,omitempty"`
+
+	// The path, relative to this config file, to the directory git modules and builtin SDKs are
+	// vendored in. Vendored copies are preferred over fetching them.
+	Vendor string `json:"vendor,omitempty"`
 }
 
 type ModuleConfigUserFields struct {
@@ -214,6 +220,113 @@ type ModuleConfigDependency struct {
 	// IgnoreGenerators is a list of generator patterns to exclude from this toolchain.
 	// Patterns can use glob syntax to match generator names.
 	IgnoreGenerators []string `json:"ignoreGenerators,omitempty"`
+
+	// Permissions restricts what the dependency's functions may access. If
+	// unset, the dependency is not restricted.
+	Permissions *ModulePermissions `json:"permissions,omitempty"`
+}
+
+// ModulePermissions is the access granted to a dependency's functions.
+// Functions asking for more access are denied unless the user allows it when
+// prompted.
+type ModulePermissions struct {
+	// Host paths the dependency may read, including their subpaths. Relative
+	// paths are relative to the source root of the depending module.
+	HostPaths []string `json:"hostPaths,omitempty"`
+
+	// Whether the dependency may access the network, from its containers and
+	// services, or with http and git.
+	Network bool `json:"network,omitempty"`
+
+	// Names of the secrets that may be passed to the dependency, either the
+	// name given to setSecret or the secret's URI (e.g. "env://GITHUB_TOKEN").
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Whether the dependency's containers may use privileged nesting to
+	// access the Dagger API. Nested clients are not restricted.
+	Nesting bool `json:"nesting,omitempty"`
+}
+
+// AllowsHostPath returns true if the given absolute host path is within one of
+// the allowed host paths.
+func (perms *ModulePermissions) AllowsHostPath(absPath string) bool {
+	if perms == nil {
+		return true
+	}
+	return slices.ContainsFunc(perms.HostPaths, func(allowed string) bool {
+		return withinPath(absPath, allowed)
+	})
+}
+
+// AllowsSecret returns true if the secret with the given name or URI is allowed.
+func (perms *ModulePermissions) AllowsSecret(name string) bool {
+	return perms == nil || slices.Contains(perms.Secrets, name)
+}
+
+// AllowsNetwork returns true if network access is allowed.
+func (perms *ModulePermissions) AllowsNetwork() bool {
+	return perms == nil || perms.Network
+}
+
+// AllowsNesting returns true if privileged nesting is allowed.
+func (perms *ModulePermissions) AllowsNesting() bool {
+	return perms == nil || perms.Nesting
+}
+
+// WithHostPathsRelativeTo returns a copy of the permissions with relative host
+// paths resolved against the given absolute directory. If dir is empty,
+// relative host paths are dropped, since they can't refer to the host.
+func (perms *ModulePermissions) WithHostPathsRelativeTo(dir string) *ModulePermissions {
+	if perms == nil {
+		return nil
+	}
+	cp := *perms
+	cp.HostPaths = make([]string, 0, len(perms.HostPaths))
+	for _, p := range perms.HostPaths {
+		switch {
+		case filepath.IsAbs(p):
+			cp.HostPaths = append(cp.HostPaths, filepath.Clean(p))
+		case dir != "":
+			cp.HostPaths = append(cp.HostPaths, filepath.Join(dir, p))
+		}
+	}
+	return &cp
+}
+
+// Restrict returns the permissions allowed by both perms and other, where nil
+// permissions allow everything.
+func (perms *ModulePermissions) Restrict(other *ModulePermissions) *ModulePermissions {
+	if perms == nil {
+		return other
+	}
+	if other == nil {
+		return perms
+	}
+	restricted := &ModulePermissions{
+		Network: perms.Network && other.Network,
+		Nesting: perms.Nesting && other.Nesting,
+	}
+	for _, p := range perms.HostPaths {
+		if other.AllowsHostPath(p) {
+			restricted.HostPaths = append(restricted.HostPaths, p)
+		}
+	}
+	for _, p := range other.HostPaths {
+		if perms.AllowsHostPath(p) && !slices.Contains(restricted.HostPaths, p) {
+			restricted.HostPaths = append(restricted.HostPaths, p)
+		}
+	}
+	for _, name := range perms.Secrets {
+		if other.AllowsSecret(name) {
+			restricted.Secrets = append(restricted.Secrets, name)
+		}
+	}
+	return restricted
+}
+
+func withinPath(p, parent string) bool {
+	parent = strings.TrimSuffix(parent, "/")
+	return p == parent || strings.HasPrefix(p, parent+"/")
 }
 
 // ModuleConfigArgument represents an argument override for a toolchain function
@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleConfigDependencyPermissions(t *testing.T) {
+	var cfg ModuleConfig
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"name": "app",
+		"dependencies": [
+			{"name": "trusted", "source": "github.com/acme/trusted"},
+			{
+				"name": "linter",
+				"source": "github.com/acme/linter",
+				"permissions": {"hostPaths": ["./src"], "secrets": ["env://GITHUB_TOKEN"]}
+			}
+		]
+	}`), &cfg))
+
+	trusted, ok := cfg.DependencyByName("trusted")
+	require.True(t, ok)
+	require.Nil(t, trusted.Permissions)
+
+	linter, ok := cfg.DependencyByName("linter")
+	require.True(t, ok)
+	require.Equal(t, &ModulePermissions{
+		HostPaths: []string{"./src"},
+		Secrets:   []string{"env://GITHUB_TOKEN"},
+	}, linter.Permissions)
+
+	out, err := json.Marshal(linter)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"name": "linter",
+		"source": "github.com/acme/linter",
+		"permissions": {"hostPaths": ["./src"], "secrets": ["env://GITHUB_TOKEN"]}
+	}`, string(out))
+}
+
+func TestModulePermissions(t *testing.T) {
+	var unrestricted *ModulePermissions
+	require.True(t, unrestricted.AllowsHostPath("/etc"))
+	require.True(t, unrestricted.AllowsNetwork())
+	require.True(t, unrestricted.AllowsSecret("token"))
+	require.True(t, unrestricted.AllowsNesting())
+
+	perms := (&ModulePermissions{
+		HostPaths: []string{"./src", "/tmp/cache/", "../other"},
+		Secrets:   []string{"token"},
+	}).WithHostPathsRelativeTo("/work/app")
+	require.Equal(t, []string{"/work/app/src", "/tmp/cache", "/work/other"}, perms.HostPaths)
+	require.True(t, perms.AllowsHostPath("/work/app/src"))
+	require.True(t, perms.AllowsHostPath("/work/app/src/main.go"))
+	require.False(t, perms.AllowsHostPath("/work/app/srcs"))
+	require.False(t, perms.AllowsHostPath("/work/app"))
+	require.False(t, perms.AllowsNetwork())
+	require.False(t, perms.AllowsNesting())
+	require.True(t, perms.AllowsSecret("token"))
+	require.False(t, perms.AllowsSecret("env://TOKEN"))
+
+	// relative paths can't refer to the host without a local source root
+	require.Equal(t, []string{"/tmp/cache"}, (&ModulePermissions{
+		HostPaths: []string{"./src", "/tmp/cache"},
+	}).WithHostPathsRelativeTo("").HostPaths)
+}
+
+func TestModulePermissionsRestrict(t *testing.T) {
+	outer := &ModulePermissions{
+		HostPaths: []string{"/work/app"},
+		Network:   true,
+		Secrets:   []string{"a", "b"},
+	}
+	inner := &ModulePermissions{
+		HostPaths: []string{"/work/app/src", "/etc"},
+		Network:   true,
+		Nesting:   true,
+		Secrets:   []string{"b", "c"},
+	}
+
+	require.Same(t, inner, (*ModulePermissions)(nil).Restrict(inner))
+	require.Same(t, inner, inner.Restrict(nil))
+
+	restricted := inner.Restrict(outer)
+	require.Equal(t, &ModulePermissions{
+		HostPaths: []string{"/work/app/src"},
+		Network:   true,
+		Secrets:   []string{"b"},
+	}, restricted)
+	require.Equal(t, restricted, outer.Restrict(inner))
+}
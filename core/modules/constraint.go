@@ -0,0 +1,321 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionConstraint is a semver range that a module dependency version must
+// satisfy, e.g. "^1.4", "~1.2.3", ">=1.0.0 <2.0.0" or "1.x || 2.x".
+//
+// The syntax follows npm: comparators separated by spaces must all match, and
+// comparator sets separated by "||" are alternatives.
+type VersionConstraint struct {
+	raw  string
+	sets [][]versionComparator
+}
+
+type versionComparator struct {
+	op string // one of "=", "<", "<=", ">", ">="
+	// version is always a canonical semver version, e.g. v1.2.3 or v1.2.3-rc.1
+	version string
+}
+
+// IsVersionConstraint returns whether the version of a module ref is a
+// constraint rather than a specific ref (a tag, branch or commit).
+func IsVersionConstraint(version string) bool {
+	if version == "" {
+		return false
+	}
+	if strings.ContainsAny(version, "^~<>=* ") || strings.Contains(version, "||") {
+		return true
+	}
+	// wildcards, e.g. 1.x or v1.2.X
+	for part := range strings.SplitSeq(strings.TrimPrefix(version, "v"), ".") {
+		if part == "x" || part == "X" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseVersionConstraint parses a semver range.
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	c := &VersionConstraint{raw: strings.TrimSpace(s)}
+	for alt := range strings.SplitSeq(c.raw, "||") {
+		set, err := parseComparatorSet(alt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
+
+// Match returns whether the given version satisfies the constraint. Versions
+// that aren't valid semver never match.
+//
+// Prerelease versions only match if a comparator of the same set names a
+// prerelease of the same major, minor and patch version, so that "^1.0.0"
+// doesn't resolve to "v1.5.0-rc.1".
+func (c *VersionConstraint) Match(version string) bool {
+	version = canonicalVersion(version)
+	if version == "" {
+		return false
+	}
+	for _, set := range c.sets {
+		if matchComparatorSet(set, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the highest of the given versions that satisfies the
+// constraint, as it was given. It returns false if none match.
+func (c *VersionConstraint) Latest(versions []string) (string, bool) {
+	var latest, latestCanonical string
+	for _, v := range versions {
+		if !c.Match(v) {
+			continue
+		}
+		canonical := canonicalVersion(v)
+		if latest == "" || semver.Compare(canonical, latestCanonical) > 0 {
+			latest, latestCanonical = v, canonical
+		}
+	}
+	return latest, latest != ""
+}
+
+func matchComparatorSet(set []versionComparator, version string) bool {
+	for _, cmp := range set {
+		if !cmp.match(version) {
+			return false
+		}
+	}
+	if semver.Prerelease(version) == "" {
+		return true
+	}
+	release := strings.TrimSuffix(version, semver.Prerelease(version))
+	for _, cmp := range set {
+		if semver.Prerelease(cmp.version) != "" &&
+			strings.TrimSuffix(cmp.version, semver.Prerelease(cmp.version)) == release {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmp versionComparator) match(version string) bool {
+	res := semver.Compare(version, cmp.version)
+	switch cmp.op {
+	case "<":
+		return res < 0
+	case "<=":
+		return res <= 0
+	case ">":
+		return res > 0
+	case ">=":
+		return res >= 0
+	default:
+		return res == 0
+	}
+}
+
+func parseComparatorSet(s string) ([]versionComparator, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty comparator set")
+	}
+
+	// hyphen ranges, e.g. "1.2 - 1.4"
+	if len(fields) == 3 && fields[1] == "-" {
+		lower, err := parsePartialVersion(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		upper, err := parsePartialVersion(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		set := []versionComparator{{op: ">=", version: lower.floor()}}
+		if upper.parts < 3 {
+			if ceil := upper.ceil(); ceil != "" {
+				set = append(set, versionComparator{op: "<", version: ceil})
+			}
+		} else {
+			set = append(set, versionComparator{op: "<=", version: upper.floor()})
+		}
+		return set, nil
+	}
+
+	var set []versionComparator
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// allow a space between the operator and the version, e.g. ">= 1.0"
+		if strings.Trim(field, "^~<>=") == "" && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		cmps, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cmps...)
+	}
+	return set, nil
+}
+
+func parseComparator(s string) ([]versionComparator, error) {
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			op, s = prefix, rest
+			break
+		}
+	}
+	v, err := parsePartialVersion(s)
+	if err != nil {
+		return nil, err
+	}
+	if v.parts == 0 {
+		// "*", "x" or ">=*" all match any release
+		if op == "<" || op == ">" {
+			return nil, fmt.Errorf("%s* matches nothing", op)
+		}
+		return []versionComparator{{op: ">=", version: "v0.0.0"}}, nil
+	}
+
+	switch op {
+	case "^":
+		// allow changes that don't modify the left-most non-zero part
+		var upper string
+		switch {
+		case v.major > 0 || v.parts == 1:
+			upper = fmt.Sprintf("v%d.0.0", v.major+1)
+		case v.minor > 0 || v.parts == 2:
+			upper = fmt.Sprintf("v0.%d.0", v.minor+1)
+		default:
+			upper = fmt.Sprintf("v0.0.%d", v.patch+1)
+		}
+		return []versionComparator{
+			{op: ">=", version: v.floor()},
+			{op: "<", version: upper},
+		}, nil
+	case "~":
+		// allow patch-level changes, or minor-level if only a major is given
+		upper := fmt.Sprintf("v%d.%d.0", v.major, v.minor+1)
+		if v.parts == 1 {
+			upper = fmt.Sprintf("v%d.0.0", v.major+1)
+		}
+		return []versionComparator{
+			{op: ">=", version: v.floor()},
+			{op: "<", version: upper},
+		}, nil
+	case ">":
+		if v.parts < 3 {
+			return []versionComparator{{op: ">=", version: v.ceil()}}, nil
+		}
+		return []versionComparator{{op: ">", version: v.floor()}}, nil
+	case "<=":
+		if v.parts < 3 {
+			return []versionComparator{{op: "<", version: v.ceil()}}, nil
+		}
+		return []versionComparator{{op: "<=", version: v.floor()}}, nil
+	case ">=", "<":
+		return []versionComparator{{op: op, version: v.floor()}}, nil
+	default:
+		if v.parts < 3 {
+			// partial versions match the whole range, e.g. 1.2 == 1.2.x
+			return []versionComparator{
+				{op: ">=", version: v.floor()},
+				{op: "<", version: v.ceil()},
+			}, nil
+		}
+		return []versionComparator{{op: "=", version: v.floor()}}, nil
+	}
+}
+
+// partialVersion is a version where trailing parts may be missing or
+// wildcards, e.g. 1, 1.2, 1.x or 1.2.3-rc.1.
+type partialVersion struct {
+	major, minor, patch int
+	// parts is the number of specified parts, from 0 to 3
+	parts      int
+	prerelease string
+}
+
+func parsePartialVersion(s string) (partialVersion, error) {
+	var v partialVersion
+	orig := s
+	s = strings.TrimPrefix(s, "v")
+	if s == "" {
+		return v, fmt.Errorf("missing version")
+	}
+	s, _, _ = strings.Cut(s, "+") // ignore build metadata
+	s, v.prerelease, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", orig)
+	}
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", orig)
+		}
+		switch i {
+		case 0:
+			v.major = n
+		case 1:
+			v.minor = n
+		case 2:
+			v.patch = n
+		}
+		v.parts++
+	}
+	if v.prerelease != "" && v.parts < 3 {
+		return v, fmt.Errorf("invalid version %q: prerelease requires a full version", orig)
+	}
+	return v, nil
+}
+
+// floor returns the lowest version in the partial version's range.
+func (v partialVersion) floor() string {
+	floor := fmt.Sprintf("v%d.%d.%d", v.major, v.minor, v.patch)
+	if v.prerelease != "" {
+		floor += "-" + v.prerelease
+	}
+	return floor
+}
+
+// ceil returns the lowest version after the partial version's range.
+func (v partialVersion) ceil() string {
+	switch v.parts {
+	case 1:
+		return fmt.Sprintf("v%d.0.0", v.major+1)
+	case 2:
+		return fmt.Sprintf("v%d.%d.0", v.major, v.minor+1)
+	default:
+		return fmt.Sprintf("v%d.%d.%d", v.major, v.minor, v.patch+1)
+	}
+}
+
+// canonicalVersion returns the canonical semver form of a tag, e.g. v1.2.0
+// for 1.2, or "" if it's not a valid semver version.
+func canonicalVersion(version string) string {
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return semver.Canonical(version)
+}
@@ -0,0 +1,136 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVersionConstraint(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"":                false,
+		"main":            false,
+		"v1.2.3":          false,
+		"mod/v1.2.3":      false,
+		"1.2.3":           false,
+		"feature/x-y":     false,
+		"^1.4":            true,
+		"~1.2.3":          true,
+		">=1.0.0 <2.0.0":  true,
+		"1.x":             true,
+		"v2.X":            true,
+		"*":               true,
+		"1.2.3 || ^2":     true,
+		"=1.2.3":          true,
+		"1.0 - 2.0":       true,
+		"8d7a2e8d0f0a1b2": false,
+	} {
+		require.Equal(t, expected, IsVersionConstraint(version), version)
+	}
+}
+
+func TestVersionConstraintMatch(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{
+			constraint: "^1.4",
+			match:      []string{"v1.4.0", "1.4.2", "v1.9.0"},
+			noMatch:    []string{"v1.3.9", "v2.0.0", "v1.5.0-rc.1", "main"},
+		},
+		{
+			constraint: "^0.2.3",
+			match:      []string{"v0.2.3", "v0.2.9"},
+			noMatch:    []string{"v0.3.0", "v0.2.2"},
+		},
+		{
+			constraint: "^0.0.3",
+			match:      []string{"v0.0.3"},
+			noMatch:    []string{"v0.0.4"},
+		},
+		{
+			constraint: "~1.2.3",
+			match:      []string{"v1.2.3", "v1.2.10"},
+			noMatch:    []string{"v1.3.0", "v1.2.2"},
+		},
+		{
+			constraint: "~1",
+			match:      []string{"v1.0.0", "v1.9.9"},
+			noMatch:    []string{"v2.0.0"},
+		},
+		{
+			constraint: ">=1.0.0 <2.0.0",
+			match:      []string{"v1.0.0", "v1.99.0"},
+			noMatch:    []string{"v0.9.0", "v2.0.0"},
+		},
+		{
+			constraint: ">= 1.2 <= 1.3",
+			match:      []string{"v1.2.0", "v1.3.7"},
+			noMatch:    []string{"v1.1.0", "v1.4.0"},
+		},
+		{
+			constraint: ">1.2",
+			match:      []string{"v1.3.0"},
+			noMatch:    []string{"v1.2.9"},
+		},
+		{
+			constraint: "1.x || 3.x",
+			match:      []string{"v1.0.0", "v3.2.1"},
+			noMatch:    []string{"v2.0.0"},
+		},
+		{
+			constraint: "1.2 - 1.4",
+			match:      []string{"v1.2.0", "v1.4.9"},
+			noMatch:    []string{"v1.5.0"},
+		},
+		{
+			constraint: "*",
+			match:      []string{"v0.0.1", "v10.0.0"},
+			noMatch:    []string{"v1.0.0-beta"},
+		},
+		{
+			constraint: "^1.4.0-rc.1",
+			match:      []string{"v1.4.0-rc.1", "v1.4.0-rc.2", "v1.4.0", "v1.5.0"},
+			noMatch:    []string{"v1.5.0-rc.1", "v1.4.0-beta"},
+		},
+	} {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tc.constraint)
+			require.NoError(t, err)
+			for _, v := range tc.match {
+				require.True(t, c.Match(v), v)
+			}
+			for _, v := range tc.noMatch {
+				require.False(t, c.Match(v), v)
+			}
+		})
+	}
+}
+
+func TestVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{
+		"^",
+		"^foo",
+		"1.2.3.4",
+		">=1.0 ||",
+		"^1-rc.1",
+		"<*",
+	} {
+		_, err := ParseVersionConstraint(constraint)
+		require.Error(t, err, constraint)
+	}
+}
+
+func TestVersionConstraintLatest(t *testing.T) {
+	c, err := ParseVersionConstraint("^1.4")
+	require.NoError(t, err)
+
+	latest, ok := c.Latest([]string{"v1.3.0", "v1.10.0", "v1.4.1", "v2.0.0", "v1.11.0-rc.1", "main"})
+	require.True(t, ok)
+	require.Equal(t, "v1.10.0", latest)
+
+	_, ok = c.Latest([]string{"v2.0.0", "main"})
+	require.False(t, ok)
+}
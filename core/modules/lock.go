@@ -0,0 +1,249 @@
+package modules
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// LockFilename is the name of the module lock file, written next to the
+// module config file.
+const LockFilename = "dagger.lock"
+
+// LockVersion is the current version of the lock file format.
+const LockVersion = 1
+
+// ModuleLock records the full transitive resolution of a module's
+// dependencies, as loaded from a dagger.lock file.
+type ModuleLock struct {
+	LockVersion int                `json:"lockVersion"`
+	Modules     []*ModuleLockEntry `json:"modules"`
+}
+
+// ModuleLockEntry is a single resolved git or OCI module in a ModuleLock.
+type ModuleLockEntry struct {
+	// The source ref of the module, without any version.
+	Source string `json:"source"`
+
+	// The version the module was resolved to, e.g. a tag or branch name.
+	Version string `json:"version,omitempty"`
+
+	// The commit the module was resolved to, or the manifest digest for
+	// modules distributed as OCI artifacts.
+	Commit string `json:"commit"`
+
+	// The version constraints placed on the module by the modules requiring it.
+	Constraints []string `json:"constraints,omitempty"`
+
+	// The modules requiring this module, "." being the root module.
+	RequiredBy []string `json:"requiredBy"`
+}
+
+func ParseModuleLock(src []byte) (*ModuleLock, error) {
+	var lock ModuleLock
+	if err := json.Unmarshal(src, &lock); err != nil {
+		return nil, fmt.Errorf("failed to decode module lock: %w", err)
+	}
+	if lock.LockVersion > LockVersion {
+		return nil, fmt.Errorf("module lock version %d is not supported, upgrade dagger", lock.LockVersion)
+	}
+	return &lock, nil
+}
+
+// Add records that a module was resolved to the given version and commit on
+// behalf of requiredBy. The same source may be added several times with
+// different commits, which is reported by Conflicts.
+func (lock *ModuleLock) Add(source, version, commit, constraint, requiredBy string) {
+	idx := slices.IndexFunc(lock.Modules, func(entry *ModuleLockEntry) bool {
+		return entry.Source == source && entry.Commit == commit
+	})
+	var entry *ModuleLockEntry
+	if idx == -1 {
+		entry = &ModuleLockEntry{
+			Source:  source,
+			Version: version,
+			Commit:  commit,
+		}
+		lock.Modules = append(lock.Modules, entry)
+	} else {
+		entry = lock.Modules[idx]
+	}
+	if constraint != "" && !slices.Contains(entry.Constraints, constraint) {
+		entry.Constraints = append(entry.Constraints, constraint)
+	}
+	if !slices.Contains(entry.RequiredBy, requiredBy) {
+		entry.RequiredBy = append(entry.RequiredBy, requiredBy)
+	}
+}
+
+// Lookup returns the entry a module was resolved to on behalf of requiredBy,
+// if any.
+func (lock *ModuleLock) Lookup(source, requiredBy string) (*ModuleLockEntry, bool) {
+	idx := slices.IndexFunc(lock.Modules, func(entry *ModuleLockEntry) bool {
+		return entry.Source == source && slices.Contains(entry.RequiredBy, requiredBy)
+	})
+	if idx == -1 {
+		return nil, false
+	}
+	return lock.Modules[idx], true
+}
+
+// LookupRef is like Lookup, but finds the entry by a ref string as written in
+// dagger.json, e.g. "github.com/org/repo/mod@v1.0.0", without parsing it. Only
+// an entry that satisfies the version of the ref is returned.
+func (lock *ModuleLock) LookupRef(refString, requiredBy string) (*ModuleLockEntry, bool) {
+	for _, entry := range lock.Modules {
+		rest, ok := strings.CutPrefix(refString, entry.Source)
+		if !ok || (rest != "" && rest[0] != '@') {
+			continue
+		}
+		if !slices.Contains(entry.RequiredBy, requiredBy) {
+			continue
+		}
+		if version, _ := strings.CutPrefix(rest, "@"); entry.Satisfies(version) {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// Subset returns the entries of the modules required by requiredBy, and in
+// turn by those modules, as needed to load it. Constraints and the other
+// modules requiring the same entries are left out.
+func (lock *ModuleLock) Subset(requiredBy string) *ModuleLock {
+	sub := &ModuleLock{
+		LockVersion: lock.LockVersion,
+		Modules:     []*ModuleLockEntry{},
+	}
+	reached := map[string]struct{}{requiredBy: {}}
+	queue := []string{requiredBy}
+	for len(queue) > 0 {
+		by := queue[0]
+		queue = queue[1:]
+		for _, entry := range lock.Modules {
+			if !slices.Contains(entry.RequiredBy, by) {
+				continue
+			}
+			sub.Add(entry.Source, entry.Version, entry.Commit, "", by)
+			if _, ok := reached[entry.Source]; !ok {
+				reached[entry.Source] = struct{}{}
+				queue = append(queue, entry.Source)
+			}
+		}
+	}
+	sub.Sort()
+	return sub
+}
+
+// Satisfies returns whether the entry can be used for a module requested at
+// the given version, which may be empty, a tag, branch or commit, or a
+// version constraint. Entries that don't satisfy the requested version are
+// stale, e.g. after the version was bumped in dagger.json.
+func (entry *ModuleLockEntry) Satisfies(version string) bool {
+	switch {
+	case version == "":
+		return true
+	case IsVersionConstraint(version):
+		c, err := ParseVersionConstraint(version)
+		return err == nil && entry.Version != "" && c.Match(lockVersionTag(entry.Version))
+	default:
+		return version == entry.Version || version == lockVersionTag(entry.Version) || version == entry.Commit
+	}
+}
+
+// Sort sorts the modules and their fields, so that the lock file is stable.
+func (lock *ModuleLock) Sort() {
+	for _, entry := range lock.Modules {
+		slices.Sort(entry.Constraints)
+		slices.Sort(entry.RequiredBy)
+	}
+	slices.SortFunc(lock.Modules, func(a, b *ModuleLockEntry) int {
+		return cmp.Or(
+			strings.Compare(a.Source, b.Source),
+			strings.Compare(a.Version, b.Version),
+			strings.Compare(a.Commit, b.Commit),
+		)
+	})
+}
+
+// ModuleLockConflict is a module that is required at several commits.
+type ModuleLockConflict struct {
+	Source  string
+	Entries []*ModuleLockEntry
+
+	// Suggestion is a version satisfying the constraints of all entries, if
+	// one could be found among the resolved versions.
+	Suggestion string
+}
+
+func (c *ModuleLockConflict) String() string {
+	var versions []string
+	for _, entry := range c.Entries {
+		versions = append(versions, fmt.Sprintf("%s (required by %s)",
+			cmp.Or(entry.Version, entry.Commit), strings.Join(entry.RequiredBy, ", ")))
+	}
+	msg := fmt.Sprintf("%s is required at conflicting versions: %s", c.Source, strings.Join(versions, "; "))
+	if c.Suggestion != "" {
+		msg += fmt.Sprintf("; %s satisfies all constraints", c.Suggestion)
+	}
+	return msg
+}
+
+// Conflicts returns the modules that are resolved to different commits by
+// different dependents, i.e. diamond dependencies that don't agree.
+func (lock *ModuleLock) Conflicts() []*ModuleLockConflict {
+	var conflicts []*ModuleLockConflict
+	bySource := map[string]*ModuleLockConflict{}
+	for _, entry := range lock.Modules {
+		conflict, ok := bySource[entry.Source]
+		if !ok {
+			conflict = &ModuleLockConflict{Source: entry.Source}
+			bySource[entry.Source] = conflict
+			conflicts = append(conflicts, conflict)
+		}
+		conflict.Entries = append(conflict.Entries, entry)
+	}
+	conflicts = slices.DeleteFunc(conflicts, func(c *ModuleLockConflict) bool {
+		return len(c.Entries) < 2
+	})
+
+	for _, conflict := range conflicts {
+		var versions []string
+		var constraints []*VersionConstraint
+		for _, entry := range conflict.Entries {
+			if entry.Version != "" {
+				versions = append(versions, entry.Version)
+			}
+			for _, raw := range entry.Constraints {
+				if c, err := ParseVersionConstraint(raw); err == nil {
+					constraints = append(constraints, c)
+				}
+			}
+		}
+		if len(constraints) == 0 {
+			continue
+		}
+		// suggest the highest resolved version that satisfies everyone
+		versions = slices.DeleteFunc(versions, func(v string) bool {
+			return slices.ContainsFunc(constraints, func(c *VersionConstraint) bool {
+				return !c.Match(lockVersionTag(v))
+			})
+		})
+		if len(versions) > 0 {
+			conflict.Suggestion = slices.MaxFunc(versions, func(a, b string) int {
+				return semver.Compare(canonicalVersion(lockVersionTag(a)), canonicalVersion(lockVersionTag(b)))
+			})
+		}
+	}
+	return conflicts
+}
+
+// lockVersionTag strips the subpath prefix of monorepo tags, e.g.
+// mod/v1.0.0 -> v1.0.0.
+func lockVersionTag(version string) string {
+	return version[strings.LastIndex(version, "/")+1:]
+}
@@ -0,0 +1,126 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLockConflicts(t *testing.T) {
+	lock := &ModuleLock{LockVersion: LockVersion}
+	lock.Add("github.com/org/a", "v1.0.0", "aaa", "", ".")
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", "github.com/org/a")
+	lock.Add("github.com/org/b", "main", "bbb", "", ".")
+	lock.Add("github.com/org/shared", "v1.6.0", "222", "^1.5", "github.com/org/b")
+	lock.Add("github.com/org/shared", "v1.6.0", "222", "^1.5", ".")
+	lock.Add("github.com/org/other", "v2.0.0", "333", "", "github.com/org/a")
+	lock.Add("github.com/org/other", "v3.0.0", "444", "", "github.com/org/b")
+	lock.Sort()
+
+	require.Len(t, lock.Modules, 6)
+	require.Equal(t, "github.com/org/a", lock.Modules[0].Source)
+	shared := lock.Modules[5]
+	require.Equal(t, "v1.6.0", shared.Version)
+	require.Equal(t, []string{".", "github.com/org/b"}, shared.RequiredBy)
+	require.Equal(t, []string{"^1.5"}, shared.Constraints)
+
+	conflicts := lock.Conflicts()
+	require.Len(t, conflicts, 2)
+
+	require.Equal(t, "github.com/org/other", conflicts[0].Source)
+	require.Empty(t, conflicts[0].Suggestion)
+
+	require.Equal(t, "github.com/org/shared", conflicts[1].Source)
+	require.Len(t, conflicts[1].Entries, 2)
+	require.Equal(t, "v1.6.0", conflicts[1].Suggestion)
+	require.Contains(t, conflicts[1].String(), "v1.4.0 (required by github.com/org/a)")
+	require.Contains(t, conflicts[1].String(), "v1.6.0 satisfies all constraints")
+}
+
+func TestParseModuleLock(t *testing.T) {
+	lock, err := ParseModuleLock([]byte(`{"lockVersion":1,"modules":[{"source":"github.com/org/a","version":"v1.0.0","commit":"aaa","requiredBy":["."]}]}`))
+	require.NoError(t, err)
+	require.Len(t, lock.Modules, 1)
+	require.Empty(t, lock.Conflicts())
+
+	_, err = ParseModuleLock([]byte(`{"lockVersion":99}`))
+	require.ErrorContains(t, err, "not supported")
+}
+
+func TestModuleLockLookup(t *testing.T) {
+	lock := &ModuleLock{LockVersion: LockVersion}
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", "github.com/org/a")
+	lock.Add("github.com/org/shared", "v1.6.0", "222", "^1.5", "github.com/org/b")
+	lock.Add("github.com/org/mono/mod", "mod/v2.1.0", "333", "", ".")
+
+	entry, ok := lock.Lookup("github.com/org/shared", "github.com/org/a")
+	require.True(t, ok)
+	require.Equal(t, "111", entry.Commit)
+	entry, ok = lock.Lookup("github.com/org/shared", "github.com/org/b")
+	require.True(t, ok)
+	require.Equal(t, "222", entry.Commit)
+	_, ok = lock.Lookup("github.com/org/shared", ".")
+	require.False(t, ok)
+
+	entry, ok = lock.Lookup("github.com/org/mono/mod", ".")
+	require.True(t, ok)
+	require.True(t, entry.Satisfies(""))
+	require.True(t, entry.Satisfies("v2.1.0"))
+	require.True(t, entry.Satisfies("mod/v2.1.0"))
+	require.True(t, entry.Satisfies("333"))
+	require.True(t, entry.Satisfies("^2.0"))
+	require.False(t, entry.Satisfies("v2.2.0"))
+	require.False(t, entry.Satisfies("^3.0"))
+}
+
+func TestModuleLockLookupRef(t *testing.T) {
+	lock := &ModuleLock{LockVersion: LockVersion}
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", ".")
+	lock.Add("github.com/org/mono/mod", "mod/v2.1.0", "333", "", ".")
+
+	entry, ok := lock.LookupRef("github.com/org/shared@v1.4.0", ".")
+	require.True(t, ok)
+	require.Equal(t, "111", entry.Commit)
+	entry, ok = lock.LookupRef("github.com/org/shared", ".")
+	require.True(t, ok)
+	require.Equal(t, "111", entry.Commit)
+	entry, ok = lock.LookupRef("github.com/org/mono/mod@v2.1.0", ".")
+	require.True(t, ok)
+	require.Equal(t, "333", entry.Commit)
+
+	// stale entries, other modules and other requirers are ignored
+	_, ok = lock.LookupRef("github.com/org/shared@v1.5.0", ".")
+	require.False(t, ok)
+	_, ok = lock.LookupRef("github.com/org/shared-fork@v1.4.0", ".")
+	require.False(t, ok)
+	_, ok = lock.LookupRef("github.com/org/mono@v2.1.0", ".")
+	require.False(t, ok)
+	_, ok = lock.LookupRef("github.com/org/shared@v1.4.0", "github.com/org/a")
+	require.False(t, ok)
+}
+
+func TestModuleLockSubset(t *testing.T) {
+	lock := &ModuleLock{LockVersion: LockVersion}
+	lock.Add("github.com/org/a", "v1.0.0", "aaa", "", ".")
+	lock.Add("github.com/org/b", "main", "bbb", "", ".")
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", "github.com/org/a")
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", "github.com/org/b")
+	lock.Add("github.com/org/deep", "v0.1.0", "ddd", "", "github.com/org/shared")
+	lock.Add("github.com/org/other", "v2.0.0", "333", "", "github.com/org/b")
+	lock.Sort()
+
+	sub := lock.Subset("github.com/org/a")
+	require.Equal(t, LockVersion, sub.LockVersion)
+	require.Equal(t, []*ModuleLockEntry{
+		{Source: "github.com/org/deep", Version: "v0.1.0", Commit: "ddd", RequiredBy: []string{"github.com/org/shared"}},
+		{Source: "github.com/org/shared", Version: "v1.4.0", Commit: "111", RequiredBy: []string{"github.com/org/a"}},
+	}, sub.Modules)
+
+	// entries that a doesn't use don't affect its subset
+	lock.Add("github.com/org/other", "v2.1.0", "444", "", "github.com/org/b")
+	lock.Add("github.com/org/shared", "v1.4.0", "111", "^1.4", ".")
+	lock.Sort()
+	require.Equal(t, sub, lock.Subset("github.com/org/a"))
+
+	require.Empty(t, lock.Subset("github.com/org/deep").Modules)
+}
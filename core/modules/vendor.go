@@ -0,0 +1,147 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// VendorManifestFilename is the name of the manifest listing the contents of
+// a module vendor directory.
+const VendorManifestFilename = "modules.json"
+
+// DefaultVendorDir is the vendor directory path used when none is configured,
+// relative to the module source root.
+const DefaultVendorDir = "dagger_vendor"
+
+// VendorManifest lists the git modules and builtin SDKs copied into a vendor
+// directory, so that a module can be loaded without network access.
+type VendorManifest struct {
+	Modules []*VendoredModule `json:"modules,omitempty"`
+	SDKs    []*VendoredSDK    `json:"sdks,omitempty"`
+}
+
+// VendoredModule is a git repo vendored at a single commit.
+type VendoredModule struct {
+	// The ref the repo is cloned from, as written in module refs.
+	CloneRef string `json:"cloneRef"`
+
+	// The import path corresponding to the root of the git repo.
+	RepoRootPath string `json:"repoRootPath"`
+
+	// The URL to the repo in a web browser.
+	HTMLRepoURL string `json:"htmlRepoURL"`
+
+	// The version the repo was resolved to, e.g. a tag or branch name.
+	Version string `json:"version,omitempty"`
+
+	// The fully resolved git ref of the version.
+	Ref string `json:"ref,omitempty"`
+
+	// The commit the repo is vendored at.
+	Commit string `json:"commit"`
+
+	// The path of the repo's tree, relative to the vendor directory.
+	Path string `json:"path"`
+}
+
+// VendoredSDK is a builtin SDK exported as an OCI image tarball.
+type VendoredSDK struct {
+	// The name of the builtin SDK, e.g. python.
+	Name string `json:"name"`
+
+	// The digest of the SDK image manifest.
+	Digest string `json:"digest"`
+
+	// The path of the image tarball, relative to the vendor directory.
+	Path string `json:"path"`
+}
+
+func ParseVendorManifest(src []byte) (*VendorManifest, error) {
+	var manifest VendorManifest
+	if err := json.Unmarshal(src, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode vendor manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// VendoredModulePath returns the path to vendor a git repo at, relative to
+// the vendor directory.
+func VendoredModulePath(cloneRef, commit string) string {
+	_, ref, ok := strings.Cut(cloneRef, "://")
+	if !ok {
+		ref = cloneRef
+	}
+	// drop any user and turn scp-like refs into paths
+	if _, rest, ok := strings.Cut(ref, "@"); ok {
+		ref = rest
+	}
+	ref = strings.Replace(ref, ":", "/", 1)
+	return path.Join("git", path.Clean("/"+ref), commit)
+}
+
+// VendoredSDKPath returns the path to vendor a builtin SDK image at,
+// relative to the vendor directory.
+func VendoredSDKPath(name string) string {
+	return path.Join("sdk", name+".tar")
+}
+
+// AddModule records a vendored git repo, unless it's already recorded.
+func (m *VendorManifest) AddModule(mod *VendoredModule) {
+	for _, existing := range m.Modules {
+		if existing.CloneRef == mod.CloneRef && existing.Commit == mod.Commit {
+			return
+		}
+	}
+	m.Modules = append(m.Modules, mod)
+}
+
+// AddSDK records a vendored builtin SDK, unless it's already recorded.
+func (m *VendorManifest) AddSDK(sdk *VendoredSDK) {
+	for _, existing := range m.SDKs {
+		if existing.Name == sdk.Name {
+			return
+		}
+	}
+	m.SDKs = append(m.SDKs, sdk)
+}
+
+// LookupModule returns the vendored repo of a git module ref. If pin is set,
+// the repo must be vendored at that commit; otherwise, the version of the
+// ref must be the one the repo was vendored at.
+func (m *VendorManifest) LookupModule(refString, pin string) (*VendoredModule, bool) {
+	var found *VendoredModule
+	for _, mod := range m.Modules {
+		rest, ok := strings.CutPrefix(refString, mod.CloneRef)
+		if !ok || (rest != "" && rest[0] != '/' && rest[0] != '@') {
+			continue
+		}
+		_, version, _ := strings.Cut(rest, "@")
+		switch {
+		case pin != "":
+			if mod.Commit != pin {
+				continue
+			}
+		case version == "":
+			continue
+		case version != mod.Version && version != mod.Commit && !strings.HasSuffix(mod.Version, "/"+version):
+			continue
+		}
+		// prefer the most specific repo, in case repos are nested
+		if found == nil || len(mod.CloneRef) > len(found.CloneRef) {
+			found = mod
+		}
+	}
+	return found, found != nil
+}
+
+// LookupSDK returns the vendored image of a builtin SDK.
+func (m *VendorManifest) LookupSDK(name string) (*VendoredSDK, bool) {
+	for _, sdk := range m.SDKs {
+		if sdk.Name == name {
+			return sdk, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,95 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendoredModulePath(t *testing.T) {
+	for _, tc := range []struct {
+		cloneRef string
+		want     string
+	}{
+		{"https://github.com/org/repo", "git/github.com/org/repo/abc"},
+		{"ssh://git@github.com/org/repo", "git/github.com/org/repo/abc"},
+		{"git@github.com:org/repo", "git/github.com/org/repo/abc"},
+		{"github.com/org/repo", "git/github.com/org/repo/abc"},
+		{"https://gitlab.com/../../escape", "git/escape/abc"},
+	} {
+		t.Run(tc.cloneRef, func(t *testing.T) {
+			require.Equal(t, tc.want, VendoredModulePath(tc.cloneRef, "abc"))
+		})
+	}
+}
+
+func TestVendorManifestLookupModule(t *testing.T) {
+	manifest := &VendorManifest{}
+	manifest.AddModule(&VendoredModule{
+		CloneRef: "https://github.com/org/repo",
+		Version:  "v1.2.0",
+		Commit:   "111",
+		Path:     VendoredModulePath("https://github.com/org/repo", "111"),
+	})
+	manifest.AddModule(&VendoredModule{
+		CloneRef: "https://github.com/org/repo",
+		Version:  "sdk/go/v0.19.0",
+		Commit:   "222",
+		Path:     VendoredModulePath("https://github.com/org/repo", "222"),
+	})
+	manifest.AddModule(&VendoredModule{
+		CloneRef: "https://github.com/org/repo",
+		Version:  "v1.2.0",
+		Commit:   "111",
+	})
+	manifest.AddModule(&VendoredModule{
+		CloneRef: "https://github.com/org/repo-other",
+		Version:  "main",
+		Commit:   "333",
+	})
+	require.Len(t, manifest.Modules, 3)
+
+	for _, tc := range []struct {
+		ref, pin   string
+		wantCommit string
+	}{
+		{"https://github.com/org/repo/mod@v1.2.0", "111", "111"},
+		{"https://github.com/org/repo@^1.0", "111", "111"},
+		{"https://github.com/org/repo/mod@v1.2.0", "", "111"},
+		{"https://github.com/org/repo/sdk/go@v0.19.0", "", "222"},
+		{"https://github.com/org/repo@222", "", "222"},
+		{"https://github.com/org/repo-other@main", "", "333"},
+		// not vendored at that pin or version
+		{"https://github.com/org/repo/mod@v1.2.0", "999", ""},
+		{"https://github.com/org/repo/mod@v1.3.0", "", ""},
+		// no version to match without a pin
+		{"https://github.com/org/repo/mod", "", ""},
+		// not a prefix at a path boundary
+		{"https://github.com/org/repository@v1.2.0", "", ""},
+	} {
+		t.Run(tc.ref+"@"+tc.pin, func(t *testing.T) {
+			mod, ok := manifest.LookupModule(tc.ref, tc.pin)
+			if tc.wantCommit == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tc.wantCommit, mod.Commit)
+		})
+	}
+}
+
+func TestVendorManifestLookupSDK(t *testing.T) {
+	manifest := &VendorManifest{}
+	manifest.AddSDK(&VendoredSDK{Name: "go", Digest: "sha256:aaa", Path: VendoredSDKPath("go")})
+	manifest.AddSDK(&VendoredSDK{Name: "go", Digest: "sha256:bbb", Path: VendoredSDKPath("go")})
+	require.Len(t, manifest.SDKs, 1)
+
+	sdk, ok := manifest.LookupSDK("go")
+	require.True(t, ok)
+	require.Equal(t, "sdk/go.tar", sdk.Path)
+	require.Equal(t, "sha256:aaa", sdk.Digest)
+
+	_, ok = manifest.LookupSDK("python")
+	require.False(t, ok)
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -41,6 +43,8 @@ var (
 	_                     = ModuleSourceKindEnum.AliasView("GIT", "GIT_SOURCE", enumView)
 	ModuleSourceKindDir   = ModuleSourceKindEnum.Register("DIR_SOURCE")
 	_                     = ModuleSourceKindEnum.AliasView("DIR", "DIR_SOURCE", enumView)
+	ModuleSourceKindOCI   = ModuleSourceKindEnum.Register("OCI_SOURCE")
+	_                     = ModuleSourceKindEnum.AliasView("OCI", "OCI_SOURCE", enumView)
 )
 
 func (proto ModuleSourceKind) Type() *ast.Type {
@@ -100,6 +104,8 @@ func (proto ModuleSourceKind) HumanString() string {
 		return "git"
 	case ModuleSourceKindDir:
 		return "directory"
+	case ModuleSourceKindOCI:
+		return "oci"
 	default:
 		return string(proto)
 	}
@@ -169,6 +175,19 @@ type ModuleSource struct {
 	ConfigToolchains []*modules.ModuleConfigDependency
 	Toolchains       dagql.ObjectResultArray[*ModuleSource] `field:"true" name:"toolchains" doc:"The toolchains referenced by the module source."`
 
+	// ConfigVendor is the vendor directory path, relative to the source root, as read from the
+	// module's dagger.json or set by withVendor
+	ConfigVendor string
+	// Vendor is the loaded vendor directory git modules and builtin SDKs are preferably loaded
+	// from, either the module's own or inherited from the module that vendored this one
+	Vendor *ModuleVendor
+	// Lockfile is the loaded dagger.lock git and OCI modules are pinned to, either the module's
+	// own or inherited from the module requiring this one
+	Lockfile *modules.ModuleLock
+	// VendorRequested is set by withVendor to (re-)vendor the module's git modules and builtin
+	// SDKs in its generated context directory
+	VendorRequested bool
+
 	UserDefaults *EnvFile `field:"true" name:"userDefaults" doc:"User-defined defaults read from local .env files"`
 	// Clients are the clients generated for the module.
 	ConfigClients []*modules.ModuleConfigClient `field:"true" name:"configClients" doc:"The clients generated for the module."`
@@ -184,10 +203,11 @@ type ModuleSource struct {
 
 	Digest string `field:"true" name:"digest" doc:"A content-hash of the module source. Module sources with the same digest will output the same generated context and convert into the same module instance."`
 
-	Kind   ModuleSourceKind `field:"true" name:"kind" doc:"The kind of module source (currently local, git or dir)."`
+	Kind   ModuleSourceKind `field:"true" name:"kind" doc:"The kind of module source (currently local, git, dir or oci)."`
 	Local  *LocalModuleSource
 	Git    *GitModuleSource
 	DirSrc *DirModuleSource
+	OCI    *OCIModuleSource
 }
 
 func (src *ModuleSource) Type() *ast.Type {
@@ -239,6 +259,10 @@ func (src ModuleSource) Clone() *ModuleSource {
 		src.Git = src.Git.Clone()
 	}
 
+	if src.OCI != nil {
+		src.OCI = src.OCI.Clone()
+	}
+
 	oriConfigClients := src.ConfigClients
 	src.ConfigClients = make([]*modules.ModuleConfigClient, len(oriConfigClients))
 	copy(src.ConfigClients, oriConfigClients)
@@ -258,11 +282,28 @@ func (src *ModuleSource) AsString() string {
 	case ModuleSourceKindGit:
 		return GitRefString(src.Git.CloneRef, src.SourceRootSubpath, src.Git.Version)
 
+	case ModuleSourceKindOCI:
+		if src.OCI.Tag == "" {
+			return OCIRefString(src.OCI.Repository, "", src.OCI.Digest)
+		}
+		return OCIRefString(src.OCI.Repository, src.OCI.Tag, "")
+
 	default:
 		return ""
 	}
 }
 
+// ConfigRefString is the ref string to record for the source in the config
+// of a module depending on it. It's the same as AsString, except that git
+// sources resolved from a version constraint keep the constraint, so that
+// updating them resolves the constraint again.
+func (src *ModuleSource) ConfigRefString() string {
+	if src.Kind == ModuleSourceKindGit && src.Git.VersionConstraint != "" {
+		return GitRefString(src.Git.CloneRef, src.SourceRootSubpath, src.Git.VersionConstraint)
+	}
+	return src.AsString()
+}
+
 func GitRefString(cloneRef, sourceRootSubpath, version string) string {
 	refPath := cloneRef
 	subPath := filepath.Join("/", sourceRootSubpath)
@@ -281,11 +322,55 @@ func (src *ModuleSource) Pin() string {
 		return ""
 	case ModuleSourceKindGit:
 		return src.Git.Commit
+	case ModuleSourceKindOCI:
+		return src.OCI.Digest
 	default:
 		return ""
 	}
 }
 
+// Lock returns the transitive resolution of the source's git and OCI dependencies,
+// toolchains and blueprint, to be written to a lock file next to its config.
+func (src *ModuleSource) Lock() *modules.ModuleLock {
+	lock := &modules.ModuleLock{
+		LockVersion: modules.LockVersion,
+		Modules:     []*modules.ModuleLockEntry{},
+	}
+	visited := map[*ModuleSource]struct{}{}
+	var walk func(parent *ModuleSource, requiredBy string)
+	walk = func(parent *ModuleSource, requiredBy string) {
+		if _, ok := visited[parent]; ok {
+			return
+		}
+		visited[parent] = struct{}{}
+
+		related := slices.Concat(parent.Dependencies, parent.Toolchains)
+		if parent.Blueprint.Self() != nil {
+			related = append(related, parent.Blueprint)
+		}
+		for _, dep := range related {
+			depSrc := dep.Self()
+			if depSrc == nil {
+				continue
+			}
+			switch depSrc.Kind {
+			case ModuleSourceKindGit:
+				lock.Add(depSrc.Git.Symbolic, depSrc.Git.Version, depSrc.Git.Commit, depSrc.Git.VersionConstraint, requiredBy)
+				walk(depSrc, depSrc.Git.Symbolic)
+			case ModuleSourceKindOCI:
+				lock.Add(depSrc.OCI.Repository, depSrc.OCI.Tag, depSrc.OCI.Digest, "", requiredBy)
+				walk(depSrc, depSrc.OCI.Repository)
+			default:
+				// local dependencies are part of the module itself
+				walk(depSrc, requiredBy)
+			}
+		}
+	}
+	walk(src, ".")
+	lock.Sort()
+	return lock
+}
+
 // GetRelatedModules returns the related modules (dependencies or toolchains) based on the type
 func (src *ModuleSource) GetRelatedModules(typ ModuleRelationType) []dagql.ObjectResult[*ModuleSource] {
 	if typ == ModuleRelationTypeDependency {
@@ -536,6 +621,14 @@ func (src *ModuleSource) ContentCacheScope() string {
 	if src == nil {
 		return ""
 	}
+	if src.Kind == ModuleSourceKindOCI && src.OCI != nil {
+		return hashutil.HashStrings(
+			"oci-module-cache-scope",
+			src.OCI.Repository,
+			src.OCI.Digest,
+			src.SourceRootSubpath,
+		).String()
+	}
 	if src.Kind != ModuleSourceKindGit || src.Git == nil {
 		return ""
 	}
@@ -775,7 +868,7 @@ func (src *ModuleSource) loadContextFromSource(
 
 		inst = ctxDir
 
-	case ModuleSourceKindDir:
+	case ModuleSourceKindDir, ModuleSourceKindOCI:
 		if !filepath.IsAbs(path) {
 			path = filepath.Join("/", src.SourceRootSubpath, path)
 		}
@@ -905,7 +998,7 @@ func (src *ModuleSource) LoadContextFile(
 			return inst, fmt.Errorf("failed to select context directory subpath: %w", err)
 		}
 
-	case ModuleSourceKindDir:
+	case ModuleSourceKindDir, ModuleSourceKindOCI:
 		if !filepath.IsAbs(path) {
 			path = filepath.Join("/", src.SourceRootSubpath, path)
 		}
@@ -1010,6 +1103,9 @@ type GitModuleSource struct {
 	// The version of the source; may be a branch, tag, or commit hash
 	Version string
 
+	// The semver constraint the version was resolved from, if any, e.g. ^1.4
+	VersionConstraint string
+
 	// The resolved commit hash of the source
 	Commit string
 	// The fully resolved git ref string of the source
@@ -1074,6 +1170,21 @@ func (s SchemeType) IsSSH() bool {
 	return s == SchemeSSH
 }
 
+type OCIModuleSource struct {
+	// The repository the artifact was pulled from, without tag or digest
+	Repository string
+
+	// The tag the artifact was referenced by, if any
+	Tag string
+
+	// The manifest digest the artifact is pinned to
+	Digest string
+}
+
+func (src OCIModuleSource) Clone() *OCIModuleSource {
+	return &src
+}
+
 type DirModuleSource struct {
 	// the original dir that AsModuleSource was called on
 	OriginalContextDir dagql.ObjectResult[*Directory]
@@ -1102,6 +1213,21 @@ func ResolveDepToSource(
 		}
 	}
 
+	if parentSrc != nil && parentSrc.Vendor != nil {
+		// prefer vendored copies, before anything hits the network, at the
+		// commit recorded in the lock like below
+		vendorPin := depPin
+		if parentSrc.Lockfile != nil {
+			if entry, ok := parentSrc.Lockfile.LookupRef(depSrcRef, parentSrc.lockRequiredBy()); ok {
+				vendorPin = entry.Commit
+			}
+		}
+		inst, ok, err := parentSrc.Vendor.selectModuleSource(ctx, dag, depSrcRef, vendorPin, false, depName)
+		if err != nil || ok {
+			return inst, err
+		}
+	}
+
 	parsedDepRef, err := ParseRefString(
 		ctx,
 		ModuleSourceStatFS{bk, parentSrc},
@@ -1169,14 +1295,19 @@ func ResolveDepToSource(
 				filepath.Join(parentSrc.SourceRootSubpath, depSrcRef),
 				parentSrc.Git.Version,
 			)
-			selectors := []dagql.Selector{{
-				Field: "moduleSource",
-				Args: []dagql.NamedInput{
-					{Name: "refString", Value: dagql.String(refString)},
-					{Name: "refPin", Value: dagql.String(parentSrc.Git.Commit)},
-					{Name: "disableFindUp", Value: dagql.Boolean(true)},
-				},
-			}}
+			if parentSrc.Vendor != nil {
+				inst, ok, err := parentSrc.Vendor.selectModuleSource(ctx, dag, refString, parentSrc.Git.Commit, true, depName)
+				if err != nil || ok {
+					return inst, err
+				}
+			}
+			// the dep is part of the parent module, so it uses the parent's
+			// part of the lock
+			selector, err := moduleSourceSelector(parentSrc.Lockfile, parentSrc.lockRequiredBy(), refString, parentSrc.Git.Commit, true)
+			if err != nil {
+				return inst, err
+			}
+			selectors := []dagql.Selector{selector}
 			if depName != "" {
 				selectors = append(selectors, dagql.Selector{
 					Field: "withName",
@@ -1185,7 +1316,7 @@ func ResolveDepToSource(
 					},
 				})
 			}
-			err := dag.Select(ctx, dag.Root(), &inst, selectors...)
+			err = dag.Select(ctx, dag.Root(), &inst, selectors...)
 			if err != nil {
 				return inst, err
 			}
@@ -1215,19 +1346,30 @@ func ResolveDepToSource(
 			}
 			return inst, nil
 
+		case ModuleSourceKindOCI:
+			// parent=oci, dep=local
+			return inst, fmt.Errorf("local module dep %q of OCI module source %q is not supported", depSrcRef, parentSrc.AsString())
+
 		default:
 			return inst, fmt.Errorf("unsupported parent module source kind: %s", parentSrc.Kind)
 		}
 
-	case ModuleSourceKindGit:
-		// parent=*, dep=git
-		selectors := []dagql.Selector{{
-			Field: "moduleSource",
-			Args: []dagql.NamedInput{
-				{Name: "refString", Value: dagql.String(depSrcRef)},
-				{Name: "refPin", Value: dagql.String(depPin)},
-			},
-		}}
+	case ModuleSourceKindGit, ModuleSourceKindOCI:
+		// parent=*, dep=git or oci
+		var lock *modules.ModuleLock
+		source, version := parsedDepRef.lockSource()
+		if parentSrc != nil && parentSrc.Lockfile != nil {
+			// pin to the commit recorded in the lock, unless the lock is stale
+			lock = parentSrc.Lockfile
+			if entry, ok := lock.Lookup(source, parentSrc.lockRequiredBy()); ok && entry.Satisfies(version) {
+				depPin = entry.Commit
+			}
+		}
+		selector, err := moduleSourceSelector(lock, source, depSrcRef, depPin, false)
+		if err != nil {
+			return inst, err
+		}
+		selectors := []dagql.Selector{selector}
 		if depName != "" {
 			selectors = append(selectors, dagql.Selector{
 				Field: "withName",
@@ -1236,9 +1378,9 @@ func ResolveDepToSource(
 				},
 			})
 		}
-		err := dag.Select(ctx, dag.Root(), &inst, selectors...)
+		err = dag.Select(ctx, dag.Root(), &inst, selectors...)
 		if err != nil {
-			return inst, fmt.Errorf("failed to load git dep: %w", err)
+			return inst, fmt.Errorf("failed to load %s dep: %w", parsedDepRef.Kind.HumanString(), err)
 		}
 		return inst, nil
 
@@ -1247,6 +1389,90 @@ func ResolveDepToSource(
 	}
 }
 
+// moduleSourceSelector selects a git or OCI module source. If a lock is given,
+// the part of it used by the module, as requiredBy, is passed on to the module
+// source so that its own dependencies are pinned to it too. The rest of the
+// lock is left out, so that changes to it don't change the module source's ID.
+func moduleSourceSelector(lock *modules.ModuleLock, requiredBy, refString, refPin string, disableFindUp bool) (dagql.Selector, error) {
+	args := []dagql.NamedInput{
+		{Name: "refString", Value: dagql.String(refString)},
+		{Name: "refPin", Value: dagql.String(refPin)},
+	}
+	if disableFindUp {
+		args = append(args, dagql.NamedInput{Name: "disableFindUp", Value: dagql.Boolean(true)})
+	}
+	if lock != nil {
+		lock = lock.Subset(requiredBy)
+	}
+	if lock == nil || len(lock.Modules) == 0 {
+		return dagql.Selector{Field: "moduleSource", Args: args}, nil
+	}
+	lockBytes, err := json.Marshal(lock)
+	if err != nil {
+		return dagql.Selector{}, fmt.Errorf("failed to encode module lock: %w", err)
+	}
+	args = append(args, dagql.NamedInput{Name: "lock", Value: dagql.String(lockBytes)})
+	return dagql.Selector{Field: "_lockedModuleSource", Args: args}, nil
+}
+
+// lockRequiredBy returns how the source requires its dependencies in a lock,
+// as recorded by Lock: "." for the root module, local modules being part of
+// it, or the source ref of git and OCI modules.
+func (src *ModuleSource) lockRequiredBy() string {
+	switch src.Kind {
+	case ModuleSourceKindGit:
+		return src.Git.Symbolic
+	case ModuleSourceKindOCI:
+		return src.OCI.Repository
+	default:
+		return "."
+	}
+}
+
+// ModuleVendor is a loaded vendor directory, that git modules and builtin SDKs
+// are loaded from instead of being fetched.
+type ModuleVendor struct {
+	Directory dagql.ObjectResult[*Directory]
+	Manifest  *modules.VendorManifest
+}
+
+// selectModuleSource loads a git module source from the vendor directory. It
+// returns false if the ref isn't vendored at the given pin.
+func (vendor *ModuleVendor) selectModuleSource(
+	ctx context.Context,
+	dag *dagql.Server,
+	refString string,
+	refPin string,
+	disableFindUp bool,
+	depName string,
+) (inst dagql.ObjectResult[*ModuleSource], _ bool, _ error) {
+	vendored, ok := vendor.Manifest.LookupModule(refString, refPin)
+	if !ok {
+		return inst, false, nil
+	}
+	selectors := []dagql.Selector{{
+		Field: "_vendoredModuleSource",
+		Args: []dagql.NamedInput{
+			{Name: "refString", Value: dagql.String(refString)},
+			{Name: "refPin", Value: dagql.String(vendored.Commit)},
+			{Name: "vendorDirectory", Value: dagql.NewID[*Directory](vendor.Directory.ID())},
+			{Name: "disableFindUp", Value: dagql.Boolean(disableFindUp)},
+		},
+	}}
+	if depName != "" {
+		selectors = append(selectors, dagql.Selector{
+			Field: "withName",
+			Args: []dagql.NamedInput{
+				{Name: "name", Value: dagql.String(depName)},
+			},
+		})
+	}
+	if err := dag.Select(ctx, dag.Root(), &inst, selectors...); err != nil {
+		return inst, false, fmt.Errorf("failed to load vendored git dep: %w", err)
+	}
+	return inst, true, nil
+}
+
 type StatFS interface {
 	Stat(ctx context.Context, path string) (string, *Stat, error)
 }
@@ -1326,7 +1552,7 @@ func (fs ModuleSourceStatFS) Stat(ctx context.Context, path string) (string, *St
 	case ModuleSourceKindGit:
 		path = filepath.Join("/", fs.src.SourceRootSubpath, path)
 		return CallDirStat(ctx, fs.src.Git.UnfilteredContextDir, path)
-	case ModuleSourceKindDir:
+	case ModuleSourceKindDir, ModuleSourceKindOCI:
 		path = filepath.Join("/", fs.src.SourceRootSubpath, path)
 		return CallDirStat(ctx, fs.src.ContextDirectory, path)
 	default:
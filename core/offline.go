@@ -0,0 +1,215 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	_ "modernc.org/sqlite"
+
+	"github.com/dagger/dagger/engine/slog"
+)
+
+// OfflineError is returned when the engine or client is offline and a
+// resource isn't available from the cache or a mirror.
+//
+// It supports being serialized/deserialized through graphql.
+type OfflineError struct {
+	// Resource is the image ref, git remote or URL that was requested
+	Resource string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("offline: %s is not cached and cannot be fetched from the network", e.Resource)
+}
+
+func (e *OfflineError) Extensions() map[string]any {
+	return map[string]any{
+		"_type":    "OFFLINE",
+		"resource": e.Resource,
+	}
+}
+
+// Offline returns whether network access is disallowed for resolving remote
+// resources, either by the engine config or by the main client.
+func (q *Query) Offline(ctx context.Context) (bool, error) {
+	if q.OfflineMode() {
+		return true, nil
+	}
+	md, err := q.MainClientCallerMetadata(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get main client caller metadata: %w", err)
+	}
+	return md.Offline, nil
+}
+
+// CheckOfflineImage returns an OfflineError if offline and the image is
+// neither in the local content store nor served by a registry mirror.
+func (q *Query) CheckOfflineImage(ctx context.Context, ref reference.Canonical) error {
+	offline, err := q.Offline(ctx)
+	if err != nil || !offline {
+		return err
+	}
+	if _, err := q.OCIStore().Info(ctx, ref.Digest()); err == nil {
+		return nil
+	}
+	if len(q.RegistryMirrors(reference.Domain(ref))) > 0 {
+		return nil
+	}
+	return &OfflineError{Resource: ref.String()}
+}
+
+// OfflineImageDigest returns the digest an image tag was last resolved to for
+// the given platform, to be used instead of resolving it while offline. If
+// the tag was never resolved, it returns an OfflineError, even if the image
+// is served by a registry mirror: resolving it would fall back to the
+// upstream registry if the mirror doesn't have it.
+func (q *Query) OfflineImageDigest(ctx context.Context, ref reference.NamedTagged, platform Platform) (digest.Digest, error) {
+	if dgst, ok := q.Resolutions().Get(ctx, ResolutionImage, imageResolutionKey(ref, platform)); ok {
+		return digest.Digest(dgst), nil
+	}
+	return "", &OfflineError{Resource: ref.String()}
+}
+
+// RecordImageDigest records the digest an image tag resolved to, so it can be
+// served while offline.
+func (q *Query) RecordImageDigest(ctx context.Context, ref reference.NamedTagged, platform Platform, dgst digest.Digest) error {
+	return q.Resolutions().Put(ctx, ResolutionImage, imageResolutionKey(ref, platform), dgst.String())
+}
+
+func imageResolutionKey(ref reference.Named, platform Platform) string {
+	return ref.String() + "@" + platform.Format()
+}
+
+// ResolutionKind is a kind of remote resolution recorded in a
+// ResolutionCache.
+type ResolutionKind string
+
+const (
+	// Image tag (ref@platform) to manifest digest
+	ResolutionImage ResolutionKind = "image"
+	// Git remote URL to its ls-remote output
+	ResolutionGitRemote ResolutionKind = "gitRemote"
+	// Module import path on a custom domain to its "root repo" pair
+	ResolutionModuleRepoRoot ResolutionKind = "moduleRepoRoot"
+)
+
+// ResolutionCache records the results of resolving remote resources while
+// online, persisted to a SQLite DB so they can be served when offline.
+type ResolutionCache struct {
+	db *sql.DB
+}
+
+// maxResolutions is the number of resolutions kept by the cache, the least
+// recently recorded ones being garbage collected first.
+const maxResolutions = 10_000
+
+const resolutionsSchema = `
+CREATE TABLE IF NOT EXISTS resolutions (
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (kind, key)
+) STRICT, WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS resolutions_updated_at_idx ON resolutions(updated_at);
+`
+
+func NewResolutionCache(dbPath string) (*ResolutionCache, error) {
+	connURL := &url.URL{
+		Scheme: "file",
+		Path:   dbPath,
+		RawQuery: url.Values{
+			"_pragma": []string{
+				"journal_mode=WAL",
+				"busy_timeout=10000",
+				// losing recent resolutions after a crash only means re-resolving them
+				"synchronous=OFF",
+			},
+		}.Encode(),
+	}
+	db, err := sql.Open("sqlite", connURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", connURL, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping %s: %w", connURL, err)
+	}
+	if _, err := db.Exec(resolutionsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return &ResolutionCache{db: db}, nil
+}
+
+// Get returns the recorded resolution of the given key.
+func (c *ResolutionCache) Get(ctx context.Context, kind ResolutionKind, key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	var value string
+	err := c.db.QueryRowContext(ctx,
+		`SELECT value FROM resolutions WHERE kind = ? AND key = ?`,
+		string(kind), key,
+	).Scan(&value)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to read resolution", "kind", kind, "key", key, "error", err)
+		}
+		return "", false
+	}
+	return value, true
+}
+
+// Put records the resolution of the given key.
+func (c *ResolutionCache) Put(ctx context.Context, kind ResolutionKind, key, value string) error {
+	if c == nil {
+		return nil
+	}
+	_, err := c.db.ExecContext(ctx, `
+INSERT INTO resolutions (kind, key, value, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (kind, key) DO UPDATE SET
+	value = EXCLUDED.value,
+	updated_at = EXCLUDED.updated_at
+`, string(kind), key, value, time.Now().Unix())
+	return err
+}
+
+// GCLoop periodically garbage collects the least recently recorded
+// resolutions, to keep at most maxResolutions of them.
+func (c *ResolutionCache) GCLoop(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		if err := c.gc(ctx, maxResolutions); err != nil {
+			slog.Warn("failed to GC resolutions", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *ResolutionCache) gc(ctx context.Context, keep int) error {
+	_, err := c.db.ExecContext(ctx, `
+DELETE FROM resolutions
+WHERE (kind, key) IN (
+	SELECT kind, key FROM resolutions
+	ORDER BY updated_at DESC
+	LIMIT -1 OFFSET ?
+)`, keep)
+	return err
+}
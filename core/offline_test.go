@@ -0,0 +1,114 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolutionCache(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "resolutions.db")
+	cache, err := NewResolutionCache(path)
+	require.NoError(t, err)
+
+	_, ok := cache.Get(ctx, ResolutionImage, "docker.io/library/alpine:3.20@linux/amd64")
+	require.False(t, ok)
+
+	require.NoError(t, cache.Put(ctx, ResolutionImage, "docker.io/library/alpine:3.20@linux/amd64", "sha256:aaa"))
+	require.NoError(t, cache.Put(ctx, ResolutionGitRemote, "https://github.com/dagger/dagger", `{"refs":[]}`))
+
+	// resolutions persist across engine restarts
+	reloaded, err := NewResolutionCache(path)
+	require.NoError(t, err)
+	dgst, ok := reloaded.Get(ctx, ResolutionImage, "docker.io/library/alpine:3.20@linux/amd64")
+	require.True(t, ok)
+	require.Equal(t, "sha256:aaa", dgst)
+	_, ok = reloaded.Get(ctx, ResolutionImage, "https://github.com/dagger/dagger")
+	require.False(t, ok)
+
+	require.NoError(t, reloaded.Put(ctx, ResolutionImage, "docker.io/library/alpine:3.20@linux/amd64", "sha256:bbb"))
+	dgst, ok = cache.Get(ctx, ResolutionImage, "docker.io/library/alpine:3.20@linux/amd64")
+	require.True(t, ok)
+	require.Equal(t, "sha256:bbb", dgst)
+
+	// a corrupt db fails to open, for the engine to recreate it
+	corrupt := filepath.Join(t.TempDir(), "resolutions.db")
+	require.NoError(t, os.WriteFile(corrupt, []byte("not a database, but long enough to have a header"), 0o600))
+	_, err = NewResolutionCache(corrupt)
+	require.Error(t, err)
+
+	// no cache, no resolutions
+	var nilCache *ResolutionCache
+	require.NoError(t, nilCache.Put(ctx, ResolutionImage, "foo", "bar"))
+	_, ok = nilCache.Get(ctx, ResolutionImage, "foo")
+	require.False(t, ok)
+}
+
+func TestResolutionCacheGC(t *testing.T) {
+	ctx := t.Context()
+	cache, err := NewResolutionCache(filepath.Join(t.TempDir(), "resolutions.db"))
+	require.NoError(t, err)
+
+	for i, key := range []string{"old", "mid", "new"} {
+		require.NoError(t, cache.Put(ctx, ResolutionGitRemote, key, key))
+		// order the resolutions by recording time
+		_, err := cache.db.ExecContext(ctx, `UPDATE resolutions SET updated_at = ? WHERE key = ?`, i, key)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, cache.gc(ctx, 2))
+	_, ok := cache.Get(ctx, ResolutionGitRemote, "old")
+	require.False(t, ok)
+	for _, key := range []string{"mid", "new"} {
+		_, ok := cache.Get(ctx, ResolutionGitRemote, key)
+		require.True(t, ok)
+	}
+}
+
+type offlineTestServer struct {
+	mockServer
+	resolutions *ResolutionCache
+}
+
+func (s *offlineTestServer) Resolutions() *ResolutionCache { return s.resolutions }
+
+func (s *offlineTestServer) RegistryMirrors(string) []string {
+	return []string{"mirror.example.com"}
+}
+
+func TestOfflineImageDigest(t *testing.T) {
+	ctx := t.Context()
+	cache, err := NewResolutionCache(filepath.Join(t.TempDir(), "resolutions.db"))
+	require.NoError(t, err)
+	q := &Query{Server: &offlineTestServer{resolutions: cache}}
+	platform := Platform{OS: "linux", Architecture: "amd64"}
+
+	ref, err := reference.ParseNormalizedNamed("alpine:3.20")
+	require.NoError(t, err)
+	tagged := ref.(reference.NamedTagged)
+
+	// a configured mirror doesn't make unresolved tags resolvable
+	_, err = q.OfflineImageDigest(ctx, tagged, platform)
+	var offlineErr *OfflineError
+	require.ErrorAs(t, err, &offlineErr)
+	require.Equal(t, "docker.io/library/alpine:3.20", offlineErr.Resource)
+
+	require.NoError(t, q.RecordImageDigest(ctx, tagged, platform, "sha256:aaa"))
+	dgst, err := q.OfflineImageDigest(ctx, tagged, platform)
+	require.NoError(t, err)
+	require.Equal(t, digest.Digest("sha256:aaa"), dgst)
+}
+
+func TestOfflineError(t *testing.T) {
+	err := &OfflineError{Resource: "docker.io/library/alpine:3.20"}
+	require.Equal(t, "offline: docker.io/library/alpine:3.20 is not cached and cannot be fetched from the network", err.Error())
+	require.Equal(t, map[string]any{
+		"_type":    "OFFLINE",
+		"resource": "docker.io/library/alpine:3.20",
+	}, err.Extensions())
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+
+	"github.com/dagger/dagger/engine/config"
+	srctypes "github.com/dagger/dagger/internal/buildkit/source/types"
+)
+
+// PolicyViolationError is returned when a call is denied by the engine's
+// security policy.
+//
+// It supports being serialized/deserialized through graphql.
+type PolicyViolationError struct {
+	// Rule is the key of the policy that denied the call, e.g.
+	// "images.deniedRegistries"
+	Rule string
+	// Subject is what was denied, e.g. an image, module source or host path
+	Subject string
+	Reason  string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("denied by engine security policy %s: %s", e.Rule, e.Reason)
+}
+
+func (e *PolicyViolationError) Extensions() map[string]any {
+	return map[string]any{
+		"_type":   "POLICY_VIOLATION",
+		"rule":    e.Rule,
+		"subject": e.Subject,
+	}
+}
+
+// ModuleCapability is a privilege that the security policy may deny to
+// modules.
+type ModuleCapability string
+
+const (
+	ModuleCapabilityPrivilegedNesting ModuleCapability = "denyPrivilegedNesting"
+	ModuleCapabilityGPUs              ModuleCapability = "denyGPUs"
+	ModuleCapabilityHostSockets       ModuleCapability = "denyHostSockets"
+)
+
+// CheckImagePolicy returns an error if the engine's security policy denies
+// pulling the given image. Images without a digest are only checked against
+// the registry rules that cannot be satisfied by a digest, so they must be
+// checked again once their tag is resolved to a digest.
+func (q *Query) CheckImagePolicy(ref reference.Named) error {
+	policy := q.SecurityPolicy()
+	if policy == nil || policy.Images == nil {
+		return nil
+	}
+	return checkImagePolicy(policy.Images, ref)
+}
+
+// imageSourceRef returns the image pulled by an LLB image source, e.g. a base
+// image of a Dockerfile build, to check it against the security policy.
+func imageSourceRef(identifier string) (reference.Named, error) {
+	refStr, _ := strings.CutPrefix(identifier, srctypes.DockerImageScheme+"://")
+	ref, err := reference.ParseNormalizedNamed(refStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image %q: %w", refStr, err)
+	}
+	return ref, nil
+}
+
+func checkImagePolicy(policy *config.ImagePolicy, ref reference.Named) error {
+	registry := reference.Domain(ref)
+	var dgst string
+	if canonical, ok := ref.(reference.Canonical); ok {
+		dgst = canonical.Digest().String()
+	}
+
+	if dgst != "" && slices.Contains(policy.DeniedDigests, dgst) {
+		return &PolicyViolationError{
+			Rule:    "images.deniedDigests",
+			Subject: ref.String(),
+			Reason:  fmt.Sprintf("image digest %s is denied", dgst),
+		}
+	}
+	if matchAny(policy.DeniedRegistries, registry) {
+		return &PolicyViolationError{
+			Rule:    "images.deniedRegistries",
+			Subject: ref.String(),
+			Reason:  fmt.Sprintf("registry %s is denied", registry),
+		}
+	}
+	if len(policy.AllowedRegistries) == 0 || matchAny(policy.AllowedRegistries, registry) {
+		return nil
+	}
+	if dgst == "" && len(policy.AllowedDigests) > 0 {
+		// the image may still be allowed by its digest once it's resolved,
+		// when it's checked again
+		return nil
+	}
+	if slices.Contains(policy.AllowedDigests, dgst) {
+		return nil
+	}
+	return &PolicyViolationError{
+		Rule:    "images.allowedRegistries",
+		Subject: ref.String(),
+		Reason:  fmt.Sprintf("registry %s is not allowed", registry),
+	}
+}
+
+// CheckModulePolicy returns an error if the engine's security policy denies
+// the given capability to the module making the call. Calls made directly by
+// clients, rather than by module functions, are not restricted.
+func (q *Query) CheckModulePolicy(ctx context.Context, capability ModuleCapability) error {
+	policy := q.SecurityPolicy()
+	if policy == nil || len(policy.Modules) == 0 {
+		return nil
+	}
+	mod, err := q.CurrentModule(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCurrentModule) {
+			return nil
+		}
+		return err
+	}
+	var source string
+	if src := mod.GetSource(); src != nil {
+		source = src.AsString()
+	}
+	return checkModulePolicy(policy.Modules, source, capability)
+}
+
+func checkModulePolicy(rules []config.ModulePolicy, source string, capability ModuleCapability) error {
+	for i, rule := range rules {
+		if !matchModuleSource(rule.Source, source) {
+			continue
+		}
+		var denied bool
+		var what string
+		switch capability {
+		case ModuleCapabilityPrivilegedNesting:
+			denied, what = rule.DenyPrivilegedNesting, "privileged nesting"
+		case ModuleCapabilityGPUs:
+			denied, what = rule.DenyGPUs, "GPU access"
+		case ModuleCapabilityHostSockets:
+			denied, what = rule.DenyHostSockets, "host unix sockets"
+		}
+		if !denied {
+			return nil
+		}
+		return &PolicyViolationError{
+			Rule:    fmt.Sprintf("modules[%d].%s", i, capability),
+			Subject: source,
+			Reason:  fmt.Sprintf("%s is denied to module %s", what, source),
+		}
+	}
+	return nil
+}
+
+// CheckHostPathPolicy returns an error if the engine's security policy denies
+// syncing the given absolute host path.
+func (q *Query) CheckHostPathPolicy(absPath string) error {
+	policy := q.SecurityPolicy()
+	if policy == nil || policy.HostPaths == nil {
+		return nil
+	}
+	return checkHostPathPolicy(policy.HostPaths, absPath)
+}
+
+func checkHostPathPolicy(policy *config.HostPathPolicy, absPath string) error {
+	for _, denied := range policy.Denied {
+		if withinHostPath(absPath, denied) {
+			return &PolicyViolationError{
+				Rule:    "hostPaths.denied",
+				Subject: absPath,
+				Reason:  fmt.Sprintf("host path %s is denied", absPath),
+			}
+		}
+	}
+	if len(policy.Allowed) == 0 {
+		return nil
+	}
+	for _, allowed := range policy.Allowed {
+		if withinHostPath(absPath, allowed) {
+			return nil
+		}
+	}
+	return &PolicyViolationError{
+		Rule:    "hostPaths.allowed",
+		Subject: absPath,
+		Reason:  fmt.Sprintf("host path %s is not allowed", absPath),
+	}
+}
+
+// DeniedHostSubpaths returns the host paths denied by the engine's security
+// policy that are within the given absolute host path, and so must be left out
+// when syncing it.
+func (q *Query) DeniedHostSubpaths(absPath string) []string {
+	policy := q.SecurityPolicy()
+	if policy == nil || policy.HostPaths == nil {
+		return nil
+	}
+	return deniedHostSubpaths(policy.HostPaths, absPath)
+}
+
+func deniedHostSubpaths(policy *config.HostPathPolicy, absPath string) []string {
+	var subpaths []string
+	for _, denied := range policy.Denied {
+		denied = strings.TrimSuffix(denied, "/")
+		if denied != absPath && withinHostPath(denied, absPath) {
+			subpaths = append(subpaths, denied)
+		}
+	}
+	return subpaths
+}
+
+func withinHostPath(p, parent string) bool {
+	parent = strings.TrimSuffix(parent, "/")
+	return p == parent || strings.HasPrefix(p, parent+"/") || parent == ""
+}
+
+// matchModuleSource matches a module source against a pattern that is either
+// exact (ignoring the source's version) or a prefix ending in "*"
+func matchModuleSource(pattern, source string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(source, prefix)
+	}
+	return source == pattern || strings.HasPrefix(source, pattern+"@")
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/distribution/reference"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/engine/config"
+)
+
+func TestCheckImagePolicy(t *testing.T) {
+	const dgst = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+	policy := &config.ImagePolicy{
+		AllowedRegistries: []string{"docker.io", "*.example.com"},
+		DeniedRegistries:  []string{"untrusted.example.com"},
+		AllowedDigests:    []string{dgst},
+		DeniedDigests:     []string{"sha256:0000000000000000000000000000000000000000000000000000000000000002"},
+	}
+
+	for _, tc := range []struct {
+		ref  string
+		rule string
+	}{
+		{ref: "alpine"},
+		{ref: "registry.example.com/foo:v1"},
+		{ref: "untrusted.example.com/foo", rule: "images.deniedRegistries"},
+		{ref: "ghcr.io/foo/bar@" + dgst},
+		// the digest isn't known until the tag is resolved...
+		{ref: "ghcr.io/foo/bar:latest"},
+		// ...after which it's checked again with its digest
+		{ref: "ghcr.io/foo/bar:latest@" + dgst},
+		{ref: "ghcr.io/foo/bar:latest@sha256:0000000000000000000000000000000000000000000000000000000000000003", rule: "images.allowedRegistries"},
+		{ref: "ghcr.io/foo/bar@sha256:0000000000000000000000000000000000000000000000000000000000000003", rule: "images.allowedRegistries"},
+		{ref: "alpine@sha256:0000000000000000000000000000000000000000000000000000000000000002", rule: "images.deniedDigests"},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			ref, err := reference.ParseNormalizedNamed(tc.ref)
+			require.NoError(t, err)
+			err = checkImagePolicy(policy, ref)
+			if tc.rule == "" {
+				require.NoError(t, err)
+				return
+			}
+			var violation *PolicyViolationError
+			require.ErrorAs(t, err, &violation)
+			require.Equal(t, tc.rule, violation.Rule)
+			require.Equal(t, "POLICY_VIOLATION", violation.Extensions()["_type"])
+		})
+	}
+
+	t.Run("tags without allowed digests", func(t *testing.T) {
+		ref, err := reference.ParseNormalizedNamed("ghcr.io/foo/bar:latest")
+		require.NoError(t, err)
+		err = checkImagePolicy(&config.ImagePolicy{AllowedRegistries: []string{"docker.io"}}, ref)
+		require.ErrorContains(t, err, "registry ghcr.io is not allowed")
+	})
+}
+
+func TestImageSourceRef(t *testing.T) {
+	const dgst = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+	policy := &config.ImagePolicy{
+		AllowedRegistries: []string{"docker.io"},
+		DeniedDigests:     []string{dgst},
+	}
+
+	// Dockerfile base images are pinned to their digest in their LLB source
+	ref, err := imageSourceRef("docker-image://docker.io/library/alpine:3.20@" + dgst)
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/library/alpine:3.20@"+dgst, ref.String())
+	var violation *PolicyViolationError
+	require.ErrorAs(t, checkImagePolicy(policy, ref), &violation)
+	require.Equal(t, "images.deniedDigests", violation.Rule)
+
+	ref, err = imageSourceRef("docker-image://ghcr.io/foo/bar:latest@sha256:0000000000000000000000000000000000000000000000000000000000000002")
+	require.NoError(t, err)
+	require.ErrorContains(t, checkImagePolicy(policy, ref), "registry ghcr.io is not allowed")
+}
+
+func TestCheckModulePolicy(t *testing.T) {
+	rules := []config.ModulePolicy{
+		{Source: "github.com/acme/trusted", DenyHostSockets: true},
+		{Source: "github.com/acme/*", DenyGPUs: true, DenyPrivilegedNesting: true},
+		{Source: "*", DenyGPUs: true, DenyHostSockets: true, DenyPrivilegedNesting: true},
+	}
+
+	// the first matching rule applies
+	require.NoError(t, checkModulePolicy(rules, "github.com/acme/trusted@v1.0.0", ModuleCapabilityGPUs))
+	err := checkModulePolicy(rules, "github.com/acme/trusted@v1.0.0", ModuleCapabilityHostSockets)
+	var violation *PolicyViolationError
+	require.ErrorAs(t, err, &violation)
+	require.Equal(t, "modules[0].denyHostSockets", violation.Rule)
+	require.Equal(t, "github.com/acme/trusted@v1.0.0", violation.Subject)
+
+	require.NoError(t, checkModulePolicy(rules, "github.com/acme/other", ModuleCapabilityHostSockets))
+	require.ErrorContains(t, checkModulePolicy(rules, "github.com/acme/other", ModuleCapabilityGPUs), "GPU access is denied")
+	require.ErrorContains(t, checkModulePolicy(rules, "github.com/evil/mod", ModuleCapabilityPrivilegedNesting), "modules[2].denyPrivilegedNesting")
+
+	// a prefix without "*" doesn't match other modules
+	require.NoError(t, checkModulePolicy(rules[:1], "github.com/acme/trusted-other", ModuleCapabilityHostSockets))
+}
+
+func TestCheckHostPathPolicy(t *testing.T) {
+	policy := &config.HostPathPolicy{
+		Allowed: []string{"/home/ci/"},
+		Denied:  []string{"/home/ci/.ssh"},
+	}
+	require.NoError(t, checkHostPathPolicy(policy, "/home/ci"))
+	require.NoError(t, checkHostPathPolicy(policy, "/home/ci/src/app"))
+	require.ErrorContains(t, checkHostPathPolicy(policy, "/home/ci/.ssh/id_rsa"), "hostPaths.denied")
+	require.ErrorContains(t, checkHostPathPolicy(policy, "/home/cidr"), "hostPaths.allowed")
+	require.ErrorContains(t, checkHostPathPolicy(policy, "/etc"), "host path /etc is not allowed")
+
+	require.NoError(t, checkHostPathPolicy(&config.HostPathPolicy{Denied: []string{"/etc"}}, "/home"))
+
+	// syncing a parent of a denied path leaves the denied path out
+	require.Equal(t, []string{"/home/ci/.ssh"}, deniedHostSubpaths(policy, "/home/ci"))
+	require.Equal(t, []string{"/home/ci/.ssh"}, deniedHostSubpaths(policy, "/"))
+	require.Empty(t, deniedHostSubpaths(policy, "/home/ci/.ssh"))
+	require.Empty(t, deniedHostSubpaths(policy, "/home/ci/src"))
+	require.Empty(t, deniedHostSubpaths(policy, "/home/cidr"))
+}
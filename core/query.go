@@ -22,6 +22,7 @@ import (
 	"github.com/dagger/dagger/engine/buildkit"
 	engineclient "github.com/dagger/dagger/engine/client"
 	"github.com/dagger/dagger/engine/clientdb"
+	"github.com/dagger/dagger/engine/config"
 	"github.com/dagger/dagger/engine/filesync"
 	"github.com/dagger/dagger/engine/server/resource"
 )
@@ -143,8 +144,21 @@ type Server interface {
 	// The name of the engine
 	EngineName() string
 
-	// The list of connected client IDs
-	Clients() []string
+	// The engine-wide security policy, or nil if none is configured
+	SecurityPolicy() *config.SecurityPolicy
+
+	// Whether the engine is configured to never reach out to the network
+	OfflineMode() bool
+
+	// The record of remote resolutions (image tags, git refs) used to serve
+	// them when offline
+	Resolutions() *ResolutionCache
+
+	// The mirrors configured for the given registry host, if any
+	RegistryMirrors(host string) []string
+
+	// The list of connected main clients
+	Clients() []*EngineClient
 
 	// Return a client connected to a cloud engine. If bool return is false, the local engine should be used. Session attachables for the returned client will be proxied back to the calling client.
 	CloudEngineClient(
@@ -109,6 +109,8 @@ func namespaceFromModule(m *core.Module) string {
 		symbolic = src.Self().SourceRootSubpath
 	case core.ModuleSourceKindGit:
 		symbolic = src.Self().Git.Symbolic
+	case core.ModuleSourceKindOCI:
+		symbolic = src.Self().OCI.Repository
 	case core.ModuleSourceKindDir:
 		symbolic = m.Source.Value.ID().Digest().String()
 	}
@@ -310,7 +310,7 @@ func (s *containerSchema) Install(srv *dagql.Server) {
 					`environment variables defined in the container (e.g. "/$VAR/foo").`),
 			),
 
-		dagql.Func("withUnixSocket", s.withUnixSocket).
+		dagql.FuncWithCacheKey("withUnixSocket", s.withUnixSocket, CheckModulePolicy[*core.Container, containerWithUnixSocketArgs](core.ModuleCapabilityHostSockets)).
 			Doc(`Retrieves this container plus a socket forwarded to the given Unix socket path.`).
 			Args(
 				dagql.Arg("path").Doc(`Location of the forwarded Unix socket (e.g., "/tmp/socket").`),
@@ -758,7 +758,7 @@ func (s *containerSchema) Install(srv *dagql.Server) {
 				absolutely necessary and only with trusted commands.`),
 			),
 
-		dagql.Func("experimentalWithGPU", s.withGPU).
+		dagql.FuncWithCacheKey("experimentalWithGPU", s.withGPU, CheckModulePolicy[*core.Container, containerGpuArgs](core.ModuleCapabilityGPUs)).
 			Doc(`EXPERIMENTAL API! Subject to change/removal at any time.`,
 				`Configures the provided list of devices to be accessible to this container.`,
 				`This currently works for Nvidia devices only.`).
@@ -766,7 +766,7 @@ func (s *containerSchema) Install(srv *dagql.Server) {
 				dagql.Arg("devices").Doc(`List of devices to be accessible to this container.`),
 			),
 
-		dagql.Func("experimentalWithAllGPUs", s.withAllGPUs).
+		dagql.FuncWithCacheKey("experimentalWithAllGPUs", s.withAllGPUs, CheckModulePolicy[*core.Container, struct{}](core.ModuleCapabilityGPUs)).
 			Doc(`EXPERIMENTAL API! Subject to change/removal at any time.`,
 				`Configures all available GPUs on the host to be accessible to this container.`,
 				`This currently works for Nvidia devices only.`),
@@ -815,6 +815,15 @@ func (s *containerSchema) fromCacheKey(
 		return nil, fmt.Errorf("failed to parse image address %s: %w", args.Address, err)
 	}
 
+	// checked here rather than in from so it's enforced on cache hits
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := query.CheckImagePolicy(refName); err != nil {
+		return nil, err
+	}
+
 	var imageRef string
 	if refName, isCanonical := refName.(reference.Canonical); isCanonical {
 		imageRef = "digest:" + string(refName.Digest())
@@ -861,6 +870,10 @@ func (s *containerSchema) from(ctx context.Context, parent dagql.ObjectResult[*c
 			return dagql.NewObjectResultForCurrentID(ctx, srv, ctr)
 		}
 
+		if err := query.CheckOfflineImage(ctx, refName); err != nil {
+			return inst, err
+		}
+
 		ctr, effectID, err := DagOpContainer(ctx, srv, parent.Self(), args, s.from)
 		if err != nil {
 			return inst, err
@@ -895,19 +908,45 @@ func (s *containerSchema) from(ctx context.Context, parent dagql.ObjectResult[*c
 	// Doesn't have a digest, resolve that now and re-call this field using the canonical
 	// digested ref instead. This ensures the ID returned here is always stable w/ the
 	// digested image ref.
-	_, digest, _, err := bk.ResolveImageConfig(ctx, refName.String(), sourceresolver.Opt{
-		Platform: ptr(platform.Spec()),
-		ImageOpt: &sourceresolver.ResolveImageOpt{
-			ResolveMode: llb.ResolveModeDefault.String(),
-		},
-	})
+	taggedRef, ok := refName.(reference.NamedTagged)
+	if !ok {
+		return inst, fmt.Errorf("image address %s has neither tag nor digest", refName.String())
+	}
+	offline, err := query.Offline(ctx)
 	if err != nil {
-		return inst, fmt.Errorf("failed to resolve image %q (platform: %q): %w", refName.String(), platform.Format(), err)
+		return inst, err
+	}
+	var digest digest.Digest
+	if offline {
+		// serve the tag from its last resolution
+		digest, err = query.OfflineImageDigest(ctx, taggedRef, platform)
+		if err != nil {
+			return inst, err
+		}
+	} else {
+		_, digest, _, err = bk.ResolveImageConfig(ctx, refName.String(), sourceresolver.Opt{
+			Platform: ptr(platform.Spec()),
+			ImageOpt: &sourceresolver.ResolveImageOpt{
+				ResolveMode: llb.ResolveModeDefault.String(),
+			},
+		})
+		if err != nil {
+			return inst, fmt.Errorf("failed to resolve image %q (platform: %q): %w", refName.String(), platform.Format(), err)
+		}
+		if err := query.RecordImageDigest(ctx, taggedRef, platform, digest); err != nil {
+			slog.Warn("failed to record image resolution", "ref", refName.String(), "error", err)
+		}
 	}
-	refName, err = reference.WithDigest(refName, digest)
+	canonicalRef, err := reference.WithDigest(refName, digest)
 	if err != nil {
 		return inst, fmt.Errorf("failed to set digest on image %s: %w", refName.String(), err)
 	}
+	refName = canonicalRef
+	// the tag was only checked against the registry rules; now that its digest
+	// is known, check it against the digest rules too
+	if err := query.CheckImagePolicy(canonicalRef); err != nil {
+		return inst, err
+	}
 
 	ctx, span := core.Tracer(ctx).Start(ctx, fmt.Sprintf("from %s", refName),
 		telemetry.Internal(),
@@ -926,7 +965,15 @@ func (s *containerSchema) from(ctx context.Context, parent dagql.ObjectResult[*c
 		return inst, err
 	}
 
-	return inst, nil
+	// cache hits on the tag skip its resolution above, so check the resolved
+	// image again each time it's returned
+	return inst.ObjectResultWithPostCall(func(ctx context.Context) error {
+		query, err := core.CurrentQuery(ctx)
+		if err != nil {
+			return err
+		}
+		return query.CheckImagePolicy(canonicalRef)
+	}), nil
 }
 
 type containerBuildArgs struct {
@@ -1116,16 +1163,48 @@ func (s *containerSchema) withExecCacheKey(
 	args containerExecArgs,
 	req dagql.GetCacheConfigRequest,
 ) (*dagql.GetCacheConfigResponse, error) {
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// module runtimes are exec'd with execution metadata on behalf of the
+	// calling client, so aren't restricted like the calling module
+	var noNetwork bool
+	if args.ExecMD.Self == nil {
+		// checked here rather than in withExec so they're enforced on cache hits
+		if args.ExperimentalPrivilegedNesting {
+			if err := query.CheckModulePolicy(ctx, core.ModuleCapabilityPrivilegedNesting); err != nil {
+				return nil, err
+			}
+			if err := query.CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionNesting}); err != nil {
+				return nil, err
+			}
+		} else {
+			allowed, err := query.ModuleGranted(ctx, core.ModulePermission{Kind: core.ModulePermissionNetwork})
+			if err != nil {
+				return nil, err
+			}
+			noNetwork = !allowed
+		}
+	}
+
 	argDigest, err := args.Digest()
 	if err != nil {
 		return nil, err
 	}
 
-	resp := &dagql.GetCacheConfigResponse{CacheKey: req.CacheKey}
-	resp.CacheKey.CallKey = hashutil.HashStrings(
+	inputs := []string{
 		parent.ID().Digest().String(),
 		string(argDigest),
-	).String()
+	}
+	if noNetwork {
+		// don't share results with execs that had network access
+		inputs = append(inputs, "noNetwork")
+	}
+
+	resp := &dagql.GetCacheConfigResponse{CacheKey: req.CacheKey}
+	resp.CacheKey.CallKey = hashutil.HashStrings(inputs...).String()
 	return resp, nil
 }
 
@@ -301,9 +301,11 @@ func (s *directorySchema) Install(srv *dagql.Server) {
 			Doc(`Return a Git-compatible patch of the changes`),
 		dagql.NodeFuncWithCacheKey("export", DagOpWrapper(srv, s.changesetExport), dagql.CachePerClient).
 			DoNotCache("Writes to the local host.").
-			Doc(`Applies the diff represented by this changeset to a path on the host.`).
+			Doc(`Applies the diff represented by this changeset to a path on the host.`,
+				`By default, host paths are overwritten. If onConflict is set, the host's current content is first compared against the changeset's before snapshot, and paths changed on the host since are handled according to onConflict instead of being clobbered.`).
 			Args(
 				dagql.Arg("path").Doc(`Location of the copied directory (e.g., "logs/").`),
+				dagql.Arg("onConflict").Doc(`What to do with host paths that changed since the before snapshot.`),
 			),
 		dagql.NodeFunc("isEmpty", DagOpWrapper(srv, s.changesetEmpty)).
 			Doc(`Returns true if the changeset is empty (i.e. there are no changes).`),
@@ -337,6 +339,7 @@ func (s *directorySchema) Install(srv *dagql.Server) {
 
 	ChangesetMergeConflictEnum.Install(srv)
 	ChangesetsMergeConflictEnum.Install(srv)
+	ChangesetExportConflictEnum.Install(srv)
 }
 
 type directoryPipelineArgs struct {
@@ -1032,13 +1035,18 @@ func (s *directorySchema) changesetAsPatch(ctx context.Context, parent dagql.Obj
 }
 
 type changesetExportArgs struct {
-	Path string
+	Path       string
+	OnConflict dagql.Optional[ChangesetExportConflict]
 
 	RawDagOpInternalArgs
 }
 
 func (s *directorySchema) changesetExport(ctx context.Context, parent dagql.ObjectResult[*core.Changeset], args changesetExportArgs) (dagql.String, error) {
-	err := parent.Self().Export(ctx, args.Path)
+	onConflict := core.OverwriteOnExportConflict
+	if args.OnConflict.Valid {
+		onConflict = exportConflictStrategyToCore(args.OnConflict.Value)
+	}
+	err := parent.Self().Export(ctx, args.Path, onConflict)
 	if err != nil {
 		return "", err
 	}
@@ -1175,6 +1183,56 @@ func (proto ChangesetMergeConflict) ToLiteral() call.Literal {
 	return ChangesetMergeConflictEnum.Literal(proto)
 }
 
+// ChangesetExportConflict is the enum for handling host paths that changed
+// since a changeset's before snapshot when exporting it.
+type ChangesetExportConflict string
+
+var ChangesetExportConflictEnum = dagql.NewEnum[ChangesetExportConflict]()
+
+var (
+	// like FAIL_EARLY above, FAIL is only exposed on engines >= 0.15.0 where
+	// Go codegen scopes enum values, since it's also a ChangesetMergeConflict value
+	FailOnExportConflict = ChangesetExportConflictEnum.RegisterView("FAIL",
+		AfterVersion("v0.15.0"),
+		`Fail without writing anything to the host`)
+	SkipOnExportConflict = ChangesetExportConflictEnum.Register("SKIP",
+		`Leave the conflicting host paths untouched and apply all other changes`)
+	BackupOnExportConflict = ChangesetExportConflictEnum.Register("BACKUP",
+		`Copy the conflicting host paths next to themselves with a .orig suffix, then apply all changes`)
+)
+
+func (proto ChangesetExportConflict) Type() *ast.Type {
+	return &ast.Type{
+		NamedType: "ChangesetExportConflict",
+		NonNull:   true,
+	}
+}
+
+func (proto ChangesetExportConflict) TypeDescription() string {
+	return "Strategy to use when exporting a changeset over host paths that changed since its before snapshot."
+}
+
+func (proto ChangesetExportConflict) Decoder() dagql.InputDecoder {
+	return ChangesetExportConflictEnum
+}
+
+func (proto ChangesetExportConflict) ToLiteral() call.Literal {
+	return ChangesetExportConflictEnum.Literal(proto)
+}
+
+func exportConflictStrategyToCore(onConflict ChangesetExportConflict) core.ChangesetExportConflict {
+	switch onConflict {
+	case SkipOnExportConflict:
+		return core.SkipOnExportConflict
+	case BackupOnExportConflict:
+		return core.BackupOnExportConflict
+	case FailOnExportConflict:
+		fallthrough
+	default:
+		return core.FailOnExportConflict
+	}
+}
+
 // ChangesetsMergeConflict is the enum for octopus merge conflict strategies (WithChangesets).
 // Only FAIL_EARLY and FAIL are supported (no -X ours/theirs with octopus merge).
 type ChangesetsMergeConflict string
@@ -6,6 +6,7 @@ import (
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/dagql/call"
 	"github.com/dagger/dagger/internal/buildkit/identity"
 )
 
@@ -22,7 +23,16 @@ func (s *engineSchema) Install(srv *dagql.Server) {
 	dagql.Fields[*core.Engine]{
 		dagql.Func("clients", s.clients).
 			DoNotCache("Clients can connect and disconnect at any time").
-			Doc("The list of connected client IDs"),
+			Doc("The list of connected client IDs").
+			Deprecated("Use `connectedClients` instead"),
+		// a separate field, since changing the type of clients would break
+		// its existing callers
+		dagql.Func("connectedClients", s.connectedClients).
+			DoNotCache("Clients can connect and disconnect at any time").
+			Doc(
+				"The list of connected clients, with the identity each one authenticated as.",
+				"It supersedes `clients`, which only lists their IDs.",
+			),
 	}.Install(srv)
 
 	dagql.Fields[*core.Engine]{
@@ -30,6 +40,18 @@ func (s *engineSchema) Install(srv *dagql.Server) {
 			Doc("The local (on-disk) cache for the Dagger engine"),
 	}.Install(srv)
 
+	dagql.Fields[*core.Engine]{
+		dagql.Func("explainCacheMiss", s.explainCacheMiss).
+			DoNotCache("The calls recorded by the engine change as calls are run").
+			Doc(
+				"Explain why a call did not reuse the cached result of an earlier call.",
+				"The call is compared to the closest earlier call of the same pipeline step recorded by the engine. Both call DAGs are walked in lockstep, reporting the inputs that differ from the root of the DAG to its tip, so the first cause is typically the root cause.",
+			).
+			Args(
+				dagql.Arg("call").Doc("The encoded ID of the call that missed the cache."),
+			),
+	}.Install(srv)
+
 	dagql.Fields[*core.EngineCache]{
 		dagql.NodeFuncWithCacheKey("entrySet", s.cacheEntrySet, dagql.CachePerCall).
 			Doc("The current set of entries in the cache"),
@@ -51,6 +73,10 @@ func (s *engineSchema) Install(srv *dagql.Server) {
 	}.Install(srv)
 
 	dagql.Fields[*core.EngineCacheEntry]{}.Install(srv)
+
+	dagql.Fields[*core.EngineCacheMissCause]{}.Install(srv)
+
+	dagql.Fields[*core.EngineClient]{}.Install(srv)
 }
 
 func (s *engineSchema) engine(ctx context.Context, parent *core.Query, args struct{}) (*core.Engine, error) {
@@ -83,7 +109,30 @@ func (s *engineSchema) localCache(ctx context.Context, parent *core.Engine, args
 	}, nil
 }
 
+func (s *engineSchema) explainCacheMiss(ctx context.Context, parent *core.Engine, args struct {
+	Call string
+}) (dagql.Array[*core.EngineCacheMissCause], error) {
+	var current call.ID
+	if err := current.Decode(args.Call); err != nil {
+		return nil, fmt.Errorf("decode call: %w", err)
+	}
+	return core.ExplainCacheMiss(ctx, &current)
+}
+
 func (s *engineSchema) clients(ctx context.Context, parent *core.Engine, args struct{}) ([]string, error) {
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clients := query.Clients()
+	ids := make([]string, 0, len(clients))
+	for _, client := range clients {
+		ids = append(ids, client.ClientID)
+	}
+	return ids, nil
+}
+
+func (s *engineSchema) connectedClients(ctx context.Context, parent *core.Engine, args struct{}) (dagql.Array[*core.EngineClient], error) {
 	query, err := core.CurrentQuery(ctx)
 	if err != nil {
 		return nil, err
@@ -30,6 +30,16 @@ func (s environmentSchema) Install(srv *dagql.Server) {
 				`When called from a function invoked via an LLM tool call, this will be the LLM's current environment, including any modifications made through calling tools. Env values returned by functions become the new environment for subsequent calls, and Changeset values returned by functions are applied to the environment's workspace.`,
 				`When called from a module function outside of an LLM, this returns an Env with the current module installed, and with the current module's source directory as its workspace.`,
 			).Experimental("Programmatic env access is speculative and might be replaced."),
+		dagql.FuncWithCacheKey("loadEnv", s.loadEnv,
+			dagql.CachePerClientSchema[*core.Query, loadEnvArgs](srv)).
+			Doc(
+				`Restores an environment encoded with Env.encode`,
+				`Objects bound in the environment are loaded from their IDs, so objects that refer to a host (e.g. host directories) are reloaded from the current client's host.`,
+			).
+			Experimental("Environments are not yet stabilized").
+			Args(
+				dagql.Arg("file").Doc("The file returned by Env.encode"),
+			),
 	}.Install(srv)
 	dagql.Fields[*core.Env]{
 		dagql.Func("inputs", s.inputs).
@@ -38,6 +48,12 @@ func (s environmentSchema) Install(srv *dagql.Server) {
 			Doc("Retrieves an input binding by name"),
 		dagql.Func("outputs", s.outputs).
 			Doc("Returns all declared output bindings for the environment"),
+		dagql.Func("encode", s.encode).
+			DoNotCache("Bindings are mutable").
+			Doc(
+				"Serializes the environment to a JSON file, to be restored with loadEnv",
+				"The file lists the bindings with their names, types and descriptions, and refers to objects by their IDs.",
+			),
 		dagql.Func("withoutOutputs", s.withoutOutputs).
 			Doc("Returns a new environment without any outputs"),
 		dagql.Func("output", s.output).
@@ -184,6 +200,43 @@ func (s environmentSchema) currentEnvironment(ctx context.Context, parent *core.
 	return env, nil
 }
 
+type loadEnvArgs struct {
+	File core.FileID
+}
+
+func (s environmentSchema) loadEnv(ctx context.Context, parent *core.Query, args loadEnvArgs) (*core.Env, error) {
+	file, err := args.File.Load(ctx, s.srv)
+	if err != nil {
+		return nil, err
+	}
+	data, err := file.Self().Contents(ctx, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("read env: %w", err)
+	}
+	deps, err := parent.CurrentServedDeps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return core.LoadEnv(ctx, s.srv, data, deps)
+}
+
+func (s environmentSchema) encode(ctx context.Context, env *core.Env, args struct{}) (res dagql.ObjectResult[*core.File], _ error) {
+	data, err := env.Encode()
+	if err != nil {
+		return res, err
+	}
+	if err := s.srv.Select(ctx, s.srv.Root(), &res, dagql.Selector{
+		Field: "file",
+		Args: []dagql.NamedInput{
+			{Name: "name", Value: dagql.NewString("env.json")},
+			{Name: "contents", Value: dagql.NewString(string(data))},
+		},
+	}); err != nil {
+		return res, fmt.Errorf("create env file: %w", err)
+	}
+	return res, nil
+}
+
 func (s environmentSchema) inputs(ctx context.Context, env *core.Env, args struct{}) (dagql.Array[*core.Binding], error) {
 	return env.Inputs(), nil
 }
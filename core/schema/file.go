@@ -105,6 +105,11 @@ func (s *fileSchema) Install(srv *dagql.Server) {
 			),
 		dagql.Func("asJSON", s.asJSON).
 			Doc(`Parse the file contents as JSON.`),
+		dagql.Func("asYAML", s.asYAML).
+			Doc(`Parse the file contents as YAML.`,
+				`Files containing several documents are rejected.`),
+		dagql.Func("asTOML", s.asTOML).
+			Doc(`Parse the file contents as TOML.`),
 	}.Install(srv)
 }
 
@@ -319,3 +324,11 @@ func (s *fileSchema) asJSON(ctx context.Context, parent *core.File, args struct{
 	}
 	return &core.JSONValue{Data: []byte(json)}, nil
 }
+
+func (s *fileSchema) asYAML(ctx context.Context, parent *core.File, args struct{}) (*core.JSONValue, error) {
+	return parent.AsYAML(ctx)
+}
+
+func (s *fileSchema) asTOML(ctx context.Context, parent *core.File, args struct{}) (*core.JSONValue, error) {
+	return parent.AsTOML(ctx)
+}
@@ -48,7 +48,7 @@ type gitSchema struct{}
 
 func (s *gitSchema) Install(srv *dagql.Server) {
 	dagql.Fields[*core.Query]{
-		dagql.NodeFuncWithCacheKey("git", s.git, dagql.CachePerClient).
+		dagql.NodeFuncWithCacheKey("git", s.git, CheckModuleNetwork(dagql.CachePerClient[*core.Query, gitArgs])).
 			View(AllVersion).
 			Doc(`Queries a Git repository.`).
 			Args(
@@ -145,10 +145,10 @@ func (s *hostSchema) Install(srv *dagql.Server) {
 
 	dagql.Fields[*core.Host]{
 		dagql.NodeFuncWithCacheKey("directory",
-			DagOpDirectoryWrapper(
+			recordingDirectoryContents(DagOpDirectoryWrapper(
 				srv, s.directory,
 				WithHashContentDir[*core.Host, hostDirectoryArgs](),
-			), dagql.CacheAsRequested).
+			)), s.directoryCacheKey).
 			Doc(`Accesses a directory on the host.`).
 			Args(
 				dagql.Arg("path").Doc(`Location of the directory to access (e.g., ".").`),
@@ -237,6 +237,31 @@ type hostDirectoryArgs struct {
 	DagOpInternalArgs
 }
 
+func (s *hostSchema) directoryCacheKey(
+	ctx context.Context,
+	host dagql.ObjectResult[*core.Host],
+	args hostDirectoryArgs,
+	req dagql.GetCacheConfigRequest,
+) (*dagql.GetCacheConfigResponse, error) {
+	// checked here rather than in directory so it's enforced on cache hits
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bk, err := query.Buildkit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+	absPath, err := bk.AbsPath(ctx, path.Clean(args.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host absolute path for %s: %w", args.Path, err)
+	}
+	if err := query.CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionHostPaths, Value: absPath}); err != nil {
+		return nil, err
+	}
+	return dagql.CacheAsRequested(ctx, host, args, req)
+}
+
 func (s *hostSchema) directory(ctx context.Context, host dagql.ObjectResult[*core.Host], args hostDirectoryArgs) (inst dagql.ObjectResult[*core.Directory], err error) {
 	srv, err := core.CurrentDagqlServer(ctx)
 	if err != nil {
@@ -258,6 +283,9 @@ func (s *hostSchema) directory(ctx context.Context, host dagql.ObjectResult[*cor
 	if err != nil {
 		return inst, fmt.Errorf("failed to get host absolute path for %s: %w", copyPath, err)
 	}
+	if err := query.CheckHostPathPolicy(initialAbsCopyPath); err != nil {
+		return inst, err
+	}
 
 	// Relpath is the actual path the user wants to copy, relative to the absRootCopyPath.
 	// If `args.GitIgnore` is set, absRootCopyPath changes to the .git directory location
@@ -336,6 +364,14 @@ func (s *hostSchema) directory(ctx context.Context, host dagql.ObjectResult[*cor
 		}
 		excludePatterns = append(excludePatterns, exclude)
 	}
+	// never sync paths denied by the security policy, even from a parent
+	for _, denied := range query.DeniedHostSubpaths(initialAbsCopyPath) {
+		rel, err := filepath.Rel(absRootCopyPath, denied)
+		if err != nil {
+			return inst, fmt.Errorf("failed to get relative path from %q: %w", denied, err)
+		}
+		excludePatterns = append(excludePatterns, hostPathPattern.Replace(rel))
+	}
 
 	dir, err := host.Self().Directory(ctx, absRootCopyPath, core.CopyFilter{
 		Include:   includePatterns,
@@ -350,6 +386,27 @@ func (s *hostSchema) directory(ctx context.Context, host dagql.ObjectResult[*cor
 	return dagql.NewObjectResultForCurrentID(ctx, srv, dir)
 }
 
+// recordingDirectoryContents records the paths in a loaded directory, if the
+// engine records calls, so that a later cache miss caused by a change on the
+// host can list the changed paths.
+func recordingDirectoryContents[T dagql.Typed, A DagOpInternalArgsIface](
+	fn dagql.NodeFuncHandler[T, A, dagql.ObjectResult[*core.Directory]],
+) dagql.NodeFuncHandler[T, A, dagql.ObjectResult[*core.Directory]] {
+	return func(ctx context.Context, self dagql.ObjectResult[T], args A) (inst dagql.ObjectResult[*core.Directory], err error) {
+		inst, err = fn(ctx, self, args)
+		if err != nil || args.InDagOp() {
+			return inst, err
+		}
+		if err := core.RecordDirectoryContents(ctx, inst); err != nil {
+			slog.WarnContext(ctx, "failed to record directory contents", "err", err)
+		}
+		return inst, nil
+	}
+}
+
+// hostPathPattern escapes a path to match only itself as an exclude pattern.
+var hostPathPattern = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
 type hostSocketArgs struct {
 	Path string
 }
@@ -359,6 +416,9 @@ func (s *hostSchema) socket(ctx context.Context, host dagql.ObjectResult[*core.H
 	if err != nil {
 		return inst, err
 	}
+	if err := query.CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionHostPaths, Value: path.Clean(args.Path)}); err != nil {
+		return inst, err
+	}
 
 	accessor, err := core.GetClientResourceAccessor(ctx, query, args.Path)
 	if err != nil {
@@ -778,6 +838,9 @@ func (s *hostSchema) service(ctx context.Context, parent dagql.ObjectResult[*cor
 	if err != nil {
 		return inst, err
 	}
+	if err := query.CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionNetwork}); err != nil {
+		return inst, err
+	}
 	socketStore, err := query.Sockets(ctx)
 	if err != nil {
 		return inst, fmt.Errorf("failed to get socket store: %w", err)
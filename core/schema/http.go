@@ -19,7 +19,7 @@ type httpSchema struct{}
 
 func (s *httpSchema) Install(srv *dagql.Server) {
 	dagql.Fields[*core.Query]{
-		dagql.NodeFuncWithCacheKey("http", s.http, dagql.CachePerClient).
+		dagql.NodeFuncWithCacheKey("http", s.http, CheckModuleNetwork(dagql.CachePerClient[*core.Query, httpArgs])).
 			Doc(`Returns a file containing an http remote url content.`).
 			Args(
 				dagql.Arg("url").Doc(`HTTP url to get the content from (e.g., "https://docs.dagger.io").`),
@@ -51,6 +51,12 @@ func (s jsonvalueSchema) Install(srv *dagql.Server) {
 			dagql.Arg("path").Doc("Path of the field to set, encoded as an array of field names"),
 			dagql.Arg("value").Doc("The new value of the field"),
 		),
+		dagql.Func("asYAML", s.asYAML).Doc(
+			"Return the value encoded as YAML",
+			"If the value was parsed from YAML, comments and key order of the original document are preserved."),
+		dagql.Func("asTOML", s.asTOML).Doc(
+			"Return the value encoded as TOML",
+			"Only objects can be encoded as TOML."),
 	}.Install(srv)
 }
 
@@ -229,54 +235,30 @@ func (s jsonvalueSchema) withField(ctx context.Context, obj *core.JSONValue, arg
 	if err != nil {
 		return nil, err
 	}
-	var root any
-	if err := json.Unmarshal(obj.Data, &root); err != nil {
-		return nil, err
-	}
-	// Ensure root is an object
-	rootMap, ok := root.(map[string]any)
-	if !ok {
-		rootMap = make(map[string]any)
-	}
-
 	// Get the value to set
 	value, err := args.Value.Load(ctx, srv)
 	if err != nil {
 		return nil, err
 	}
-	var setValue any
-	if err := json.Unmarshal(value.Self().Data, &setValue); err != nil {
-		return nil, err
-	}
-	// Navigate to the parent of the target field
-	current := rootMap
+	path := make([]string, len(args.Path))
 	for i, pathSegment := range args.Path {
-		key := pathSegment.String()
-		if i == len(args.Path)-1 {
-			// Set the final value
-			current[key] = setValue
-		} else {
-			// Navigate deeper, creating objects as needed
-			if val, exists := current[key]; exists {
-				if m, ok := val.(map[string]any); ok {
-					current = m
-				} else {
-					// Replace non-object with new object
-					newMap := make(map[string]any)
-					current[key] = newMap
-					current = newMap
-				}
-			} else {
-				// Create new object
-				newMap := make(map[string]any)
-				current[key] = newMap
-				current = newMap
-			}
-		}
+		path[i] = pathSegment.String()
+	}
+	return obj.WithField(path, value.Self().Data)
+}
+
+func (s jsonvalueSchema) asYAML(ctx context.Context, obj *core.JSONValue, args struct{}) (dagql.String, error) {
+	data, err := obj.AsYAML()
+	if err != nil {
+		return "", err
 	}
-	data, err := json.Marshal(rootMap)
+	return dagql.String(data), nil
+}
+
+func (s jsonvalueSchema) asTOML(ctx context.Context, obj *core.JSONValue, args struct{}) (dagql.String, error) {
+	data, err := obj.AsTOML()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	return &core.JSONValue{Data: data}, nil
+	return dagql.String(data), nil
 }
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
@@ -15,6 +16,7 @@ type llmSchema struct {
 var _ SchemaResolvers = &llmSchema{}
 
 func (s llmSchema) Install(srv *dagql.Server) {
+	core.LLMHistoryStrategies.Install(srv)
 	dagql.Fields[*core.Query]{
 		dagql.FuncWithCacheKey("llm", s.llm, dagql.CachePerSession).
 			Experimental("LLM support is not yet stabilized").
@@ -54,6 +56,14 @@ func (s llmSchema) Install(srv *dagql.Server) {
 			Args(
 				dagql.Arg("model").Doc("The model to use"),
 			),
+		dagql.FuncWithCacheKey("withRecording", s.withRecording, dagql.CachePerClient).
+			Doc("Record the conversation to a replay fixture on the host. Only the main client can record.").
+			Args(
+				dagql.Arg("path").Doc(
+					"Location of the fixture on the host (e.g., \"testdata/review.json\").",
+					"It is rewritten after every response, with secrets scrubbed, and can be replayed with the model \"replay-file/<path>\".",
+				),
+			),
 		dagql.Func("withPrompt", s.withPrompt).
 			Doc("append a prompt to the llm context").
 			Args(
@@ -81,12 +91,44 @@ func (s llmSchema) Install(srv *dagql.Server) {
 				dagql.Arg("typeName").Doc("The type name whose function will be blocked"),
 				dagql.Arg("function").Doc("The function to block", "Will be converted to lowerCamelCase if necessary."),
 			),
+		dagql.Func("withApprovalRequired", s.withApprovalRequired).
+			Doc(
+				"Return a new LLM that asks the user before calling the specified function",
+				"The user is shown the function and its arguments. If they deny the call, or can't be asked because the session isn't interactive, the call fails. Approvals and denials are recorded in the history.",
+			).
+			Args(
+				dagql.Arg("typeName").Doc("The type name whose function requires approval"),
+				dagql.Arg("function").Doc("The function that requires approval", "Will be converted to lowerCamelCase if necessary."),
+			),
 		dagql.Func("withMCPServer", s.withMCPServer).
 			Doc("Add an external MCP server to the LLM").
 			Args(
 				dagql.Arg("name").Doc("The name of the MCP server"),
 				dagql.Arg("service").Doc("The MCP service to run and communicate with over stdio"),
 			),
+		dagql.Func("withSubAgent", s.withSubAgent).
+			Doc("Add another LLM as a tool that this LLM can delegate tasks to").
+			Args(
+				dagql.Arg("name").Doc(
+					"The name of the sub-agent, used to name its tool (e.g., \"reviewer\" becomes DelegateReviewer)",
+				),
+				dagql.Arg("llm").Doc(
+					"The sub-agent, with its own environment, prompts and API call budget",
+					"Each delegated task starts from this state, and the outputs it saves are returned as objects.",
+				),
+			),
+		dagql.Func("withToolConcurrency", s.withToolConcurrency).
+			Doc("Limit how many independent tool calls from a single response run at once").
+			Args(
+				dagql.Arg("limit").Doc("The maximum number of concurrent tool calls, or 0 for no limit"),
+			),
+		dagql.Func("withHistoryStrategy", s.withHistoryStrategy).
+			Doc("Configure how the history is compacted as it approaches the model's context window",
+				"By default, the results of the oldest tool calls are truncated once the history fills 80% of the context window.").
+			Args(
+				dagql.Arg("strategy").Doc("How to compact the history"),
+				dagql.Arg("threshold").Doc("The share of the context window that the history may fill before it's compacted, between 0 and 1"),
+			),
 		dagql.NodeFunc("sync", func(ctx context.Context, self dagql.ObjectResult[*core.LLM], _ struct{}) (res dagql.Result[dagql.ID[*core.LLM]], _ error) {
 			var inst dagql.Result[*core.LLM]
 			if err := srv.Select(ctx, self, &inst, dagql.Selector{
@@ -166,9 +208,43 @@ func (s *llmSchema) lastReply(ctx context.Context, llm *core.LLM, args struct{})
 func (s *llmSchema) withModel(ctx context.Context, llm *core.LLM, args struct {
 	Model string
 }) (*core.LLM, error) {
+	if err := requireHostAccess(ctx, args.Model); err != nil {
+		return nil, err
+	}
 	return llm.WithModel(args.Model), nil
 }
 
+func (s *llmSchema) withRecording(ctx context.Context, llm *core.LLM, args struct {
+	Path string
+}) (*core.LLM, error) {
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// modules must not write to arbitrary paths on the host
+	if err := query.RequireMainClient(ctx); err != nil {
+		return nil, fmt.Errorf("record LLM session: %w", err)
+	}
+	return llm.WithRecording(args.Path), nil
+}
+
+// requireHostAccess checks that a model replaying a fixture from the host was
+// requested by the main client, since modules must not read arbitrary paths on
+// the host.
+func requireHostAccess(ctx context.Context, model string) error {
+	if !core.IsLLMReplayFile(model) {
+		return nil
+	}
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return err
+	}
+	if err := query.RequireMainClient(ctx); err != nil {
+		return fmt.Errorf("replay LLM fixture: %w", err)
+	}
+	return nil
+}
+
 func (s *llmSchema) withPrompt(ctx context.Context, llm *core.LLM, args struct {
 	Prompt string
 }) (*core.LLM, error) {
@@ -197,6 +273,16 @@ func (s *llmSchema) withBlockedFunction(ctx context.Context, llm *core.LLM, args
 	)
 }
 
+func (s *llmSchema) withApprovalRequired(ctx context.Context, llm *core.LLM, args struct {
+	TypeName string
+	Function string
+}) (*core.LLM, error) {
+	return llm.WithApprovalRequired(ctx,
+		args.TypeName,
+		strcase.ToLowerCamel(args.Function),
+	)
+}
+
 func (s *llmSchema) withMCPServer(ctx context.Context, llm *core.LLM, args struct {
 	Name    string
 	Service core.ServiceID
@@ -208,6 +294,30 @@ func (s *llmSchema) withMCPServer(ctx context.Context, llm *core.LLM, args struc
 	return llm.WithMCPServer(args.Name, svc), nil
 }
 
+func (s *llmSchema) withSubAgent(ctx context.Context, llm *core.LLM, args struct {
+	Name string
+	LLM  core.LLMID `name:"llm"`
+}) (*core.LLM, error) {
+	agent, err := args.LLM.Load(ctx, s.srv)
+	if err != nil {
+		return nil, err
+	}
+	return llm.WithSubAgent(args.Name, agent.Self())
+}
+
+func (s *llmSchema) withToolConcurrency(ctx context.Context, llm *core.LLM, args struct {
+	Limit int
+}) (*core.LLM, error) {
+	return llm.WithToolConcurrency(args.Limit)
+}
+
+func (s *llmSchema) withHistoryStrategy(ctx context.Context, llm *core.LLM, args struct {
+	Strategy  core.LLMHistoryStrategy
+	Threshold float64 `default:"0.8"`
+}) (*core.LLM, error) {
+	return llm.WithHistoryStrategy(args.Strategy, args.Threshold)
+}
+
 func (s *llmSchema) withPromptFile(ctx context.Context, llm *core.LLM, args struct {
 	File core.FileID
 }) (*core.LLM, error) {
@@ -255,6 +365,9 @@ func (s *llmSchema) llm(ctx context.Context, parent *core.Query, args struct {
 	if args.Model.Valid {
 		model = args.Model.Value.String()
 	}
+	if err := requireHostAccess(ctx, model); err != nil {
+		return nil, err
+	}
 	var maxAPICalls int
 	if args.MaxAPICalls.Valid {
 		maxAPICalls = args.MaxAPICalls.Value.Int()
@@ -76,6 +76,24 @@ func (s *moduleSourceSchema) Install(dag *dagql.Server) {
 				dagql.Arg("requireKind").Doc(`If set, error out if the ref string is not of the provided requireKind.`),
 			),
 
+		dagql.NodeFuncWithCacheKey("_vendoredModuleSource", s.vendoredModuleSource, dagql.CachePerClient).
+			Doc(`Load a git module source from a vendor directory instead of fetching it.`).
+			Args(
+				dagql.Arg("refString").Doc(`The string ref representation of the module source`),
+				dagql.Arg("refPin").Doc(`The commit the module source is vendored at`),
+				dagql.Arg("vendorDirectory").Doc(`The vendor directory, containing a modules.json manifest`),
+				dagql.Arg("disableFindUp").Doc(`If true, do not attempt to find dagger.json in a parent directory of the provided path.`),
+			),
+
+		dagql.NodeFuncWithCacheKey("_lockedModuleSource", s.lockedModuleSource, dagql.CachePerClient).
+			Doc(`Load a git or OCI module source, pinning its dependencies to a lock.`).
+			Args(
+				dagql.Arg("refString").Doc(`The string ref representation of the module source`),
+				dagql.Arg("refPin").Doc(`The pinned version of the module source`),
+				dagql.Arg("lock").Doc(`The JSON encoded lock of the module requiring this one`),
+				dagql.Arg("disableFindUp").Doc(`If true, do not attempt to find dagger.json in a parent directory of the provided path.`),
+			),
+
 		dagql.NodeFuncWithCacheKey("_contextDirectory", s.contextDirectory, dagql.CachePerCall).
 			Doc(`Obtain a contextual directory argument for the given path, include/excludes and module.`),
 		dagql.NodeFuncWithCacheKey("_contextFile", s.contextFile, dagql.CachePerCall).
@@ -144,6 +162,12 @@ func (s *moduleSourceSchema) Install(dag *dagql.Server) {
 				dagql.Arg("source").Doc(`The SDK source to set.`),
 			),
 
+		dagql.Func("withVendor", s.moduleSourceWithVendor).
+			Doc(`Vendor the module's git dependencies, toolchains, blueprint and SDK in its generated context directory, so it can be loaded without network access.`).
+			Args(
+				dagql.Arg("path").Doc(`The path of the vendor directory, relative to the source root. Defaults to the one already configured, if any.`),
+			),
+
 		dagql.Func("withEngineVersion", s.moduleSourceWithEngineVersion).
 			Doc(`Upgrade the engine version of the module to the given value.`).
 			Args(
@@ -219,6 +243,14 @@ func (s *moduleSourceSchema) Install(dag *dagql.Server) {
 		dagql.Func("pin", s.moduleSourcePin).
 			Doc(`The pinned version of this module source.`),
 
+		dagql.NodeFunc("publish", s.moduleSourcePublish).
+			DoNotCache("side effect on an external system (OCI registry)").
+			Doc(`Publish the module source to an OCI registry with its resolved dagger.json, returning the published ref pinned by digest.`,
+				`Every dependency, toolchain and blueprint must be a remote (git or OCI) module source.`).
+			Args(
+				dagql.Arg("address").Doc(`The registry address to publish to (e.g., "registry.example.com/mods/foo:1.2.0").`),
+			),
+
 		dagql.Func("localContextDirectoryPath", s.moduleSourceLocalContextDirectoryPath).
 			Doc(`The full absolute path to the context directory on the caller's host filesystem that this module source is loaded from. Only valid for local module sources.`),
 
@@ -246,7 +278,7 @@ func (s *moduleSourceSchema) Install(dag *dagql.Server) {
 			Doc(`The URL to access the web view of the repository (e.g., GitHub, GitLab, Bitbucket).`),
 
 		dagql.Func("version", s.moduleSourceVersion).
-			Doc(`The specified version of the git repo this source points to.`),
+			Doc(`The specified version of the git repo or OCI tag this source points to.`),
 
 		dagql.Func("commit", s.moduleSourceCommit).
 			Doc(`The resolved commit of the git repo this source points to.`),
@@ -324,7 +356,12 @@ func (s *moduleSourceSchema) moduleSource(
 			return inst, err
 		}
 	case core.ModuleSourceKindGit:
-		inst, err = s.gitModuleSource(ctx, query, parsedRef.Git, args.RefPin, !args.DisableFindUp)
+		inst, err = s.gitModuleSource(ctx, query, parsedRef.Git, args.RefPin, !args.DisableFindUp, nil, nil, nil)
+		if err != nil {
+			return inst, err
+		}
+	case core.ModuleSourceKindOCI:
+		inst, err = s.ociModuleSource(ctx, query, parsedRef.OCI, args.RefPin, nil)
 		if err != nil {
 			return inst, err
 		}
@@ -413,7 +450,9 @@ func (s *moduleSourceSchema) localModuleSource(
 					depModPath := filepath.Join(defaultFindUpSourceRootDir, namedDep.Source)
 					return s.localModuleSource(ctx, query, bk, depModPath, false, allowNotExists)
 				case core.ModuleSourceKindGit:
-					return s.gitModuleSource(ctx, query, parsedRef.Git, namedDep.Pin, false)
+					return s.gitModuleSource(ctx, query, parsedRef.Git, namedDep.Pin, false, nil, nil, nil)
+				case core.ModuleSourceKindOCI:
+					return s.ociModuleSource(ctx, query, parsedRef.OCI, namedDep.Pin, nil)
 				}
 			}
 		}
@@ -521,6 +560,12 @@ func (s *moduleSourceSchema) localModuleSource(
 		if err := s.initFromModConfig(contents, localSrc); err != nil {
 			return inst, err
 		}
+		if err := s.loadModuleSourceVendor(ctx, bk, dag, localSrc); err != nil {
+			return inst, err
+		}
+		if err := s.loadModuleSourceLock(ctx, bk, dag, localSrc); err != nil {
+			return inst, err
+		}
 
 		// load this module source's context directory, ignore patterns, sdk and deps in parallel
 		var eg errgroup.Group
@@ -576,28 +621,29 @@ func (s *moduleSourceSchema) gitModuleSource(
 	refPin string,
 	// whether to search up the directory tree for a dagger.json file
 	doFindUp bool,
+	// if set, the repo is loaded from the vendor dir instead of being fetched
+	vendor *core.ModuleVendor,
+	vendored *modules.VendoredModule,
+	// if set, the module's git and OCI dependencies are pinned to the lock
+	lock *modules.ModuleLock,
 ) (inst dagql.Result[*core.ModuleSource], err error) {
 	dag, err := query.Self().Server.Server(ctx)
 	if err != nil {
 		return inst, fmt.Errorf("failed to get dag server: %w", err)
 	}
 
-	gitRef, err := parsed.GitRef(ctx, dag, refPin)
-	if err != nil {
-		return inst, fmt.Errorf("failed to resolve git src: %w", err)
-	}
-
 	gitSrc := &core.ModuleSource{
 		ConfigExists: true, // we can't load uninitialized git modules, we'll error out later if it's not there
 		Kind:         core.ModuleSourceKindGit,
 		Git: &core.GitModuleSource{
 			HTMLRepoURL:  parsed.RepoRoot.Repo,
 			RepoRootPath: parsed.RepoRoot.Root,
-			Version:      cmp.Or(gitRef.Self().Ref.ShortName(), gitRef.Self().Ref.SHA),
-			Commit:       gitRef.Self().Ref.SHA,
-			Ref:          gitRef.Self().Ref.Name,
 			CloneRef:     parsed.SourceCloneRef,
 		},
+		Lockfile: lock,
+	}
+	if modules.IsVersionConstraint(parsed.ModVersion) {
+		gitSrc.Git.VersionConstraint = parsed.ModVersion
 	}
 
 	bk, err := query.Self().Buildkit(ctx)
@@ -605,13 +651,40 @@ func (s *moduleSourceSchema) gitModuleSource(
 		return inst, fmt.Errorf("failed to get buildkit client: %w", err)
 	}
 
-	// TODO:(sipsma) support sparse loading of git repos similar to how local dirs are loaded.
-	// Related: https://github.com/dagger/dagger/issues/6292
-	err = dag.Select(ctx, gitRef, &gitSrc.ContextDirectory,
-		dagql.Selector{Field: "tree"},
-	)
-	if err != nil {
-		return inst, fmt.Errorf("failed to load git dir: %w", err)
+	if vendored != nil {
+		gitSrc.Git.Version = cmp.Or(vendored.Version, vendored.Commit)
+		gitSrc.Git.Commit = vendored.Commit
+		gitSrc.Git.Ref = vendored.Ref
+		// deps of vendored modules are vendored too
+		gitSrc.Vendor = vendor
+		err = dag.Select(ctx, vendor.Directory, &gitSrc.ContextDirectory,
+			dagql.Selector{
+				Field: "directory",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(vendored.Path)},
+				},
+			},
+		)
+		if err != nil {
+			return inst, fmt.Errorf("failed to load vendored git dir: %w", err)
+		}
+	} else {
+		gitRef, err := parsed.GitRef(ctx, dag, refPin)
+		if err != nil {
+			return inst, fmt.Errorf("failed to resolve git src: %w", err)
+		}
+		gitSrc.Git.Version = cmp.Or(gitRef.Self().Ref.ShortName(), gitRef.Self().Ref.SHA)
+		gitSrc.Git.Commit = gitRef.Self().Ref.SHA
+		gitSrc.Git.Ref = gitRef.Self().Ref.Name
+
+		// TODO:(sipsma) support sparse loading of git repos similar to how local dirs are loaded.
+		// Related: https://github.com/dagger/dagger/issues/6292
+		err = dag.Select(ctx, gitRef, &gitSrc.ContextDirectory,
+			dagql.Selector{Field: "tree"},
+		)
+		if err != nil {
+			return inst, fmt.Errorf("failed to load git dir: %w", err)
+		}
 	}
 	gitSrc.Git.UnfilteredContextDir = gitSrc.ContextDirectory
 
@@ -873,6 +946,12 @@ func (s *moduleSourceSchema) directoryAsModuleSource(
 	if err != nil {
 		return inst, fmt.Errorf("failed to get buildkit client: %w", err)
 	}
+	if err := s.loadModuleSourceVendor(ctx, bk, dag, dirSrc); err != nil {
+		return inst, err
+	}
+	if err := s.loadModuleSourceLock(ctx, bk, dag, dirSrc); err != nil {
+		return inst, err
+	}
 
 	var eg errgroup.Group
 
@@ -1209,6 +1288,16 @@ func (s *moduleSourceSchema) initFromModConfig(configBytes []byte, src *core.Mod
 	}
 	src.RebasedIncludePaths = append(src.RebasedIncludePaths, rebasedIncludes...)
 
+	if modCfg.Vendor != "" {
+		if !filepath.IsLocal(modCfg.Vendor) {
+			return fmt.Errorf("vendor path %q contains parent directory components", modCfg.Vendor)
+		}
+		src.ConfigVendor = modCfg.Vendor
+		// the vendor dir is loaded separately, never as part of the module's source
+		src.RebasedIncludePaths = append(src.RebasedIncludePaths,
+			"!"+filepath.Join(src.SourceRootSubpath, src.ConfigVendor))
+	}
+
 	return nil
 }
 
@@ -1546,11 +1635,14 @@ func (s *moduleSourceSchema) moduleSourceVersion(
 	src *core.ModuleSource,
 	args struct{},
 ) (string, error) {
-	if src.Kind != core.ModuleSourceKindGit {
+	switch src.Kind {
+	case core.ModuleSourceKindGit:
+		return src.Git.Version, nil
+	case core.ModuleSourceKindOCI:
+		return src.OCI.Tag, nil
+	default:
 		return "", nil
 	}
-
-	return src.Git.Version, nil
 }
 
 func (s *moduleSourceSchema) moduleSourceCommit(
@@ -1665,8 +1757,8 @@ func (s *moduleSourceSchema) validateAndCollectRelatedModules(
 				}
 				allRelatedModules = append(allRelatedModules, newRelatedModule)
 
-			case core.ModuleSourceKindGit:
-				// parent=local, item=git
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=local, item=git|oci
 				allRelatedModules = append(allRelatedModules, newRelatedModule)
 
 			default:
@@ -1680,8 +1772,23 @@ func (s *moduleSourceSchema) validateAndCollectRelatedModules(
 				// cannot add a module source that's local to the caller as an item of a git module source
 				return nil, fmt.Errorf("cannot add local module source as %s of git module source", accessor.typ)
 
-			case core.ModuleSourceKindGit:
-				// parent=git, item=git
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=git, item=git|oci
+				allRelatedModules = append(allRelatedModules, newRelatedModule)
+
+			default:
+				return nil, fmt.Errorf("unhandled module source kind: %s", newRelatedModule.Self().Kind)
+			}
+
+		case core.ModuleSourceKindOCI:
+			switch newRelatedModule.Self().Kind {
+			case core.ModuleSourceKindLocal:
+				// parent=oci, item=local
+				// cannot add a module source that's local to the caller as an item of an OCI module source
+				return nil, fmt.Errorf("cannot add local module source as %s of OCI module source", accessor.typ)
+
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=oci, item=git|oci
 				allRelatedModules = append(allRelatedModules, newRelatedModule)
 
 			default:
@@ -1727,6 +1834,15 @@ func (s *moduleSourceSchema) deduplicateAndSortItems(
 					symbolicItemStr += "@" + item.Self().Git.Commit
 				}
 			}
+		case core.ModuleSourceKindOCI:
+			symbolicItemStr = item.Self().OCI.Repository
+			if accessor.typ == core.ModuleRelationTypeToolchain {
+				if item.Self().OCI.Tag != "" {
+					symbolicItemStr += ":" + item.Self().OCI.Tag
+				} else {
+					symbolicItemStr += "@" + item.Self().OCI.Digest
+				}
+			}
 		}
 
 		_, isDuplicateSymbolic := symbolicItems[symbolicItemStr]
@@ -1757,6 +1873,11 @@ func (s *moduleSourceSchema) deduplicateAndSortItems(
 	return finalItems, nil
 }
 
+type updateReq struct {
+	symbolic string // either 1) a name of a dep or 2) the source minus any @version
+	version  string // the version to update to, if any specified
+}
+
 // moduleSourceUpdateItems processes update requests for items (dependencies or toolchains)
 func (s *moduleSourceSchema) moduleSourceUpdateItems(
 	ctx context.Context,
@@ -1769,10 +1890,6 @@ func (s *moduleSourceSchema) moduleSourceUpdateItems(
 		return nil, fmt.Errorf("failed to get dag server: %w", err)
 	}
 
-	type updateReq struct {
-		symbolic string // either 1) a name of a dep or 2) the source minus any @version
-		version  string // the version to update to, if any specified
-	}
 	updateReqs := make(map[updateReq]struct{}, len(updateArgs))
 	for _, updateArg := range updateArgs {
 		req := updateReq{}
@@ -1797,7 +1914,7 @@ func (s *moduleSourceSchema) moduleSourceUpdateItems(
 				dagql.Selector{
 					Field: "moduleSource",
 					Args: []dagql.NamedInput{
-						{Name: "refString", Value: dagql.String(existingItem.Self().AsString())},
+						{Name: "refString", Value: dagql.String(existingItem.Self().ConfigRefString())},
 					},
 				},
 			)
@@ -1820,7 +1937,7 @@ func (s *moduleSourceSchema) moduleSourceUpdateItems(
 				switch parentSrc.Self().Kind {
 				case core.ModuleSourceKindLocal:
 					contextRoot = parentSrc.Self().Local.ContextDirectoryPath
-				case core.ModuleSourceKindGit:
+				case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
 					contextRoot = "/"
 				default:
 					return nil, fmt.Errorf("unknown module source kind: %s", parentSrc.Self().Kind)
@@ -1840,8 +1957,21 @@ func (s *moduleSourceSchema) moduleSourceUpdateItems(
 			continue
 		}
 
+		if existingItem.Self().Kind == core.ModuleSourceKindOCI {
+			updatedID, matched, err := s.updateOCIItem(ctx, dag, existingItem, updateReqs, accessor)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				updatedID = dagql.NewID[*core.ModuleSource](existingItem.ID())
+			}
+			newUpdatedArgs = append(newUpdatedArgs, updatedID)
+			continue
+		}
+
 		existingName := existingItem.Self().ModuleName
-		existingVersion := existingItem.Self().Git.Version
+		// keep resolving from the constraint, if any
+		existingVersion := cmp.Or(existingItem.Self().Git.VersionConstraint, existingItem.Self().Git.Version)
 		existingSymbolic := existingItem.Self().Git.CloneRef
 		if itemSrcRoot := existingItem.Self().SourceRootSubpath; itemSrcRoot != "" {
 			existingSymbolic += "/" + strings.TrimPrefix(itemSrcRoot, "/")
@@ -1942,6 +2072,9 @@ func (s *moduleSourceSchema) moduleSourceRemoveItems(
 			}
 			existingVersion = existingItem.Self().Git.Version
 
+		case core.ModuleSourceKindOCI:
+			existingSymbolic = existingItem.Self().OCI.Repository
+
 		default:
 			return nil, fmt.Errorf("unhandled %s kind: %s", accessor.typ, existingItem.Self().Kind)
 		}
@@ -1950,6 +2083,10 @@ func (s *moduleSourceSchema) moduleSourceRemoveItems(
 		for _, removeArg := range removeArgs {
 			argSymbolic, argVersion, _ := strings.Cut(removeArg, "@")
 			argSymbolic = filepath.Clean(argSymbolic)
+			if existingItem.Self().Kind == core.ModuleSourceKindOCI {
+				// an OCI ref's tag or digest selects the same item, so match on the repository alone
+				argSymbolic, argVersion = ociRepository(removeArg), ""
+			}
 
 			if argSymbolic != existingName && argSymbolic != existingSymbolic {
 				continue
@@ -2363,6 +2500,7 @@ func (s *moduleSourceSchema) loadModuleSourceConfig(
 	if src.DisableDefaultFunctionCaching {
 		modCfg.DisableDefaultFunctionCaching = ptr(true)
 	}
+	modCfg.Vendor = src.ConfigVendor
 
 	if src.SDK != nil {
 		modCfg.SDK = &modules.SDK{
@@ -2415,6 +2553,14 @@ func (s *moduleSourceSchema) loadModuleSourceConfig(
 
 		modCfg.Dependencies[i] = depCfg
 
+		// Preserve the permissions granted to the dependency, which aren't part of its module source
+		for _, origDep := range src.ConfigDependencies {
+			if origDep.Name == depCfg.Name {
+				depCfg.Permissions = origDep.Permissions
+				break
+			}
+		}
+
 		switch src.Kind {
 		case core.ModuleSourceKindLocal:
 			switch depSrc.Self().Kind {
@@ -2428,10 +2574,10 @@ func (s *moduleSourceSchema) loadModuleSourceConfig(
 				}
 				depCfg.Source = depSrcRoot
 
-			case core.ModuleSourceKindGit:
-				// parent=local, dep=git
-				depCfg.Source = depSrc.Self().AsString()
-				depCfg.Pin = depSrc.Self().Git.Commit
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=local, dep=git|oci
+				depCfg.Source = depSrc.Self().ConfigRefString()
+				depCfg.Pin = depSrc.Self().Pin()
 
 			default:
 				return nil, fmt.Errorf("unhandled module source kind: %s", src.Kind.HumanString())
@@ -2455,14 +2601,33 @@ func (s *moduleSourceSchema) loadModuleSourceConfig(
 					}
 					depCfg.Source = depSrcRoot
 				} else {
-					depCfg.Source = depSrc.Self().AsString()
+					depCfg.Source = depSrc.Self().ConfigRefString()
 					depCfg.Pin = depSrc.Self().Git.Commit
 				}
 
+			case core.ModuleSourceKindOCI:
+				// parent=git, dep=oci
+				depCfg.Source = depSrc.Self().ConfigRefString()
+				depCfg.Pin = depSrc.Self().Pin()
+
 			default:
 				return nil, fmt.Errorf("unhandled module source kind: %s", src.Kind.HumanString())
 			}
 
+		case core.ModuleSourceKindOCI:
+			switch depSrc.Self().Kind {
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=oci, dep=git|oci
+				depCfg.Source = depSrc.Self().ConfigRefString()
+				depCfg.Pin = depSrc.Self().Pin()
+
+			default:
+				return nil, fmt.Errorf("parent module source kind %s cannot have dependency of kind %s",
+					src.Kind.HumanString(),
+					depSrc.Self().Kind.HumanString(),
+				)
+			}
+
 		case core.ModuleSourceKindDir:
 			switch depSrc.Self().Kind {
 			case core.ModuleSourceKindDir:
@@ -2479,10 +2644,10 @@ func (s *moduleSourceSchema) loadModuleSourceConfig(
 				}
 				depCfg.Source = depSrcRoot
 
-			case core.ModuleSourceKindGit:
-				// parent=dir, dep=git
-				depCfg.Source = depSrc.Self().AsString()
-				depCfg.Pin = depSrc.Self().Git.Commit
+			case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+				// parent=dir, dep=git|oci
+				depCfg.Source = depSrc.Self().ConfigRefString()
+				depCfg.Pin = depSrc.Self().Pin()
 
 			default:
 				// Local not supported since there's nothing we could plausibly put in the dagger.json for
@@ -2823,6 +2988,52 @@ func (s *moduleSourceSchema) moduleSourceGeneratedContextDirectory(
 		return res, fmt.Errorf("failed to add updated dagger.json to context dir: %w", err)
 	}
 
+	// write dagger.lock next to it, if there's any git or OCI module to lock or
+	// an existing lock to update; conflicts are recorded for the CLI to report
+	lock := srcInst.Self().Lock()
+	if len(lock.Modules) > 0 || srcInst.Self().Lockfile != nil {
+		lockBytes, err := json.MarshalIndent(lock, "", "  ")
+		if err != nil {
+			return res, fmt.Errorf("failed to encode module lock: %w", err)
+		}
+		lockBytes = append(lockBytes, '\n')
+		lockPath := filepath.Join(srcInst.Self().SourceRootSubpath, modules.LockFilename)
+		err = dag.Select(ctx, genDirInst, &genDirInst,
+			dagql.Selector{
+				Field: "withNewFile",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(lockPath)},
+					{Name: "contents", Value: dagql.String(lockBytes)},
+					{Name: "permissions", Value: dagql.Int(0o644)},
+				},
+			},
+		)
+		if err != nil {
+			return res, fmt.Errorf("failed to add dagger.lock to context dir: %w", err)
+		}
+	}
+
+	// vendor git modules and builtin sdks, if requested
+	if srcInst.Self().VendorRequested {
+		vendorDir, err := s.vendorDirectory(ctx, srcInst.Self())
+		if err != nil {
+			return res, fmt.Errorf("failed to vendor module: %w", err)
+		}
+		vendorPath := filepath.Join(srcInst.Self().SourceRootSubpath, srcInst.Self().ConfigVendor)
+		err = dag.Select(ctx, genDirInst, &genDirInst,
+			dagql.Selector{
+				Field: "withDirectory",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(vendorPath)},
+					{Name: "source", Value: dagql.NewID[*core.Directory](vendorDir.ID())},
+				},
+			},
+		)
+		if err != nil {
+			return res, fmt.Errorf("failed to add vendor dir to context dir: %w", err)
+		}
+	}
+
 	// return just the diff of what we generated relative to the original context directory
 	err = dag.Select(ctx, srcInst.Self().ContextDirectory, &genDirInst,
 		dagql.Selector{
@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine/buildkit"
+)
+
+// loadModuleSourceLock loads the dagger.lock next to the module's dagger.json,
+// so that its git and OCI dependencies are pinned to the commits recorded
+// there. Nothing is loaded if the module has no lock yet.
+func (s *moduleSourceSchema) loadModuleSourceLock(
+	ctx context.Context,
+	bk *buildkit.Client,
+	dag *dagql.Server,
+	src *core.ModuleSource,
+) error {
+	lockPath := filepath.Join(src.SourceRootSubpath, modules.LockFilename)
+
+	var lockContents []byte
+	switch src.Kind {
+	case core.ModuleSourceKindLocal:
+		lockPath = filepath.Join(src.Local.ContextDirectoryPath, lockPath)
+		if _, err := bk.StatCallerHostPath(ctx, lockPath, false); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return nil
+			}
+			return fmt.Errorf("failed to stat module lock: %w", err)
+		}
+		contents, err := bk.ReadCallerHostFile(ctx, lockPath)
+		if err != nil {
+			return fmt.Errorf("failed to read module lock: %w", err)
+		}
+		lockContents = contents
+
+	case core.ModuleSourceKindDir:
+		var contents string
+		err := dag.Select(ctx, src.DirSrc.OriginalContextDir, &contents,
+			dagql.Selector{
+				Field: "file",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(lockPath)},
+				},
+			},
+			dagql.Selector{Field: "contents"},
+		)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return fmt.Errorf("failed to read module lock: %w", err)
+		}
+		lockContents = []byte(contents)
+
+	default:
+		// git and OCI modules are pinned to the lock of the module requiring them
+		return nil
+	}
+
+	lock, err := modules.ParseModuleLock(lockContents)
+	if err != nil {
+		return fmt.Errorf("%s: %w", lockPath, err)
+	}
+	src.Lockfile = lock
+	return nil
+}
+
+type lockedModuleSourceArgs struct {
+	RefString     string
+	RefPin        string `default:""`
+	Lock          string
+	DisableFindUp bool `default:"false"`
+}
+
+func (s *moduleSourceSchema) lockedModuleSource(
+	ctx context.Context,
+	query dagql.ObjectResult[*core.Query],
+	args lockedModuleSourceArgs,
+) (inst dagql.Result[*core.ModuleSource], err error) {
+	lock, err := modules.ParseModuleLock([]byte(args.Lock))
+	if err != nil {
+		return inst, err
+	}
+	bk, err := query.Self().Buildkit(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+	parsedRef, err := core.ParseRefString(ctx, core.NewCallerStatFS(bk), args.RefString, args.RefPin)
+	if err != nil {
+		return inst, err
+	}
+
+	switch parsedRef.Kind {
+	case core.ModuleSourceKindGit:
+		return s.gitModuleSource(ctx, query, parsedRef.Git, args.RefPin, !args.DisableFindUp, nil, nil, lock)
+	case core.ModuleSourceKindOCI:
+		return s.ociModuleSource(ctx, query, parsedRef.OCI, args.RefPin, lock)
+	default:
+		return inst, fmt.Errorf("locked module source %q must be a git or OCI module source", args.RefString)
+	}
+}
@@ -0,0 +1,307 @@
+package schema
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/distribution/reference"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/core/sdk"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/server/resource"
+	"golang.org/x/sync/errgroup"
+)
+
+func (s *moduleSourceSchema) ociModuleSource(
+	ctx context.Context,
+	query dagql.ObjectResult[*core.Query],
+	parsed *core.ParsedOCIRefString,
+	refPin string,
+	// if set, the module's git and OCI dependencies are pinned to the lock
+	lock *modules.ModuleLock,
+) (inst dagql.Result[*core.ModuleSource], err error) {
+	dag, err := query.Self().Server.Server(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get dag server: %w", err)
+	}
+	bk, err := query.Self().Buildkit(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+
+	var artifact dagql.ObjectResult[*core.Container]
+	err = dag.Select(ctx, dag.Root(), &artifact,
+		dagql.Selector{Field: "container"},
+		dagql.Selector{
+			Field: "from",
+			Args: []dagql.NamedInput{
+				{Name: "address", Value: dagql.String(parsed.Address(refPin))},
+			},
+		},
+	)
+	if err != nil {
+		return inst, fmt.Errorf("failed to pull OCI module source: %w", err)
+	}
+
+	// the image ref is canonical after a pull, which pins the tag to a digest
+	ref, err := reference.ParseNormalizedNamed(artifact.Self().ImageRef)
+	if err != nil {
+		return inst, fmt.Errorf("failed to parse pulled image ref %q: %w", artifact.Self().ImageRef, err)
+	}
+	digested, ok := ref.(reference.Digested)
+	if !ok {
+		return inst, fmt.Errorf("pulled image ref %q is not pinned to a digest", artifact.Self().ImageRef)
+	}
+	imgCfg, err := artifact.Self().ImageConfig(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get OCI module source config: %w", err)
+	}
+	sourceRootSubpath, ok := imgCfg.Labels[core.OCIModuleSourceRootLabel]
+	if !ok {
+		return inst, fmt.Errorf("OCI artifact %q is not a dagger module: missing %s label", parsed.Address(refPin), core.OCIModuleSourceRootLabel)
+	}
+	if !filepath.IsLocal(sourceRootSubpath) {
+		return inst, fmt.Errorf("OCI artifact %q has invalid source root %q", parsed.Address(refPin), sourceRootSubpath)
+	}
+
+	ociSrc := &core.ModuleSource{
+		ConfigExists:      true, // we can't load uninitialized OCI modules, we'll error out later if it's not there
+		Kind:              core.ModuleSourceKindOCI,
+		SourceRootSubpath: sourceRootSubpath,
+		OriginalSubpath:   sourceRootSubpath,
+		OCI: &core.OCIModuleSource{
+			Repository: parsed.Repository,
+			Tag:        parsed.Tag,
+			Digest:     digested.Digest().String(),
+		},
+		Lockfile: lock,
+	}
+	err = dag.Select(ctx, artifact, &ociSrc.ContextDirectory,
+		dagql.Selector{Field: "rootfs"},
+	)
+	if err != nil {
+		return inst, fmt.Errorf("failed to load OCI module source dir: %w", err)
+	}
+
+	var configContents string
+	err = dag.Select(ctx, ociSrc.ContextDirectory, &configContents,
+		dagql.Selector{
+			Field: "file",
+			Args: []dagql.NamedInput{
+				{Name: "path", Value: dagql.String(filepath.Join(ociSrc.SourceRootSubpath, modules.Filename))},
+			},
+		},
+		dagql.Selector{Field: "contents"},
+	)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return inst, fmt.Errorf("OCI module source %q does not contain a dagger config file", ociSrc.AsString())
+		}
+		return inst, fmt.Errorf("failed to load OCI module dagger config: %w", err)
+	}
+	if err := s.initFromModConfig([]byte(configContents), ociSrc); err != nil {
+		return inst, err
+	}
+
+	// load this module source's sdk and deps in parallel
+	var eg errgroup.Group
+	if ociSrc.SDK != nil {
+		eg.Go(func() error {
+			var err error
+			ociSrc.SDKImpl, err = sdk.NewLoader().SDKForModule(ctx, query.Self(), ociSrc.SDK, ociSrc)
+			if err != nil {
+				return fmt.Errorf("failed to load sdk for OCI module source: %w", err)
+			}
+			return nil
+		})
+	}
+
+	eg.Go(func() error {
+		return s.loadBlueprintModule(ctx, bk, ociSrc)
+	})
+
+	ociSrc.Dependencies = make([]dagql.ObjectResult[*core.ModuleSource], len(ociSrc.ConfigDependencies))
+	for i, depCfg := range ociSrc.ConfigDependencies {
+		eg.Go(func() error {
+			var err error
+			ociSrc.Dependencies[i], err = core.ResolveDepToSource(ctx, bk, dag, ociSrc, depCfg.Source, depCfg.Pin, depCfg.Name)
+			if err != nil {
+				return fmt.Errorf("failed to resolve dep to source: %w", err)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return inst, err
+	}
+
+	if err := ociSrc.LoadUserDefaults(ctx); err != nil {
+		return inst, fmt.Errorf("load user defaults: %w", err)
+	}
+	ociSrc.Digest = ociSrc.CalcDigest(ctx).String()
+
+	inst, err = dagql.NewResultForCurrentID(ctx, ociSrc)
+	if err != nil {
+		return inst, fmt.Errorf("failed to create instance: %w", err)
+	}
+
+	clientMetadata, err := engine.ClientMetadataFromContext(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get client metadata: %w", err)
+	}
+	secretTransferPostCall, _, err := core.ResourceTransferPostCall(ctx, query.Self(), clientMetadata.ClientID, &resource.ID{
+		ID: *ociSrc.ContextDirectory.ID(),
+	})
+	if err != nil {
+		return inst, fmt.Errorf("failed to create secret transfer post call: %w", err)
+	}
+
+	return inst.ResultWithPostCall(secretTransferPostCall), nil
+}
+
+func (s *moduleSourceSchema) moduleSourcePublish(
+	ctx context.Context,
+	srcInst dagql.ObjectResult[*core.ModuleSource],
+	args struct {
+		Address string
+	},
+) (dagql.String, error) {
+	src := srcInst.Self()
+	if !src.ConfigExists {
+		return "", fmt.Errorf("module must be fully initialized")
+	}
+	// local modules can't be resolved from the artifact, only remote ones
+	related := slices.Concat(src.Dependencies, src.Toolchains)
+	if src.Blueprint.Self() != nil {
+		related = append(related, src.Blueprint)
+	}
+	for _, dep := range related {
+		switch dep.Self().Kind {
+		case core.ModuleSourceKindGit, core.ModuleSourceKindOCI:
+		default:
+			return "", fmt.Errorf("cannot publish module with %s dependency %q as an OCI artifact",
+				dep.Self().Kind.HumanString(), dep.Self().ModuleName)
+		}
+	}
+
+	dag, err := core.CurrentDagqlServer(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dag server: %w", err)
+	}
+
+	// publish the resolved dagger.json, with every dependency pinned
+	modCfg, err := s.loadModuleSourceConfig(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to load module source config: %w", err)
+	}
+	modCfgBytes, err := json.MarshalIndent(modCfg, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode module config: %w", err)
+	}
+	modCfgBytes = append(modCfgBytes, '\n')
+
+	var ref dagql.String
+	err = dag.Select(ctx, dag.Root(), &ref,
+		dagql.Selector{Field: "container"},
+		dagql.Selector{
+			Field: "withRootfs",
+			Args: []dagql.NamedInput{
+				{Name: "directory", Value: dagql.NewID[*core.Directory](src.ContextDirectory.ID())},
+			},
+		},
+		dagql.Selector{
+			Field: "withNewFile",
+			Args: []dagql.NamedInput{
+				{Name: "path", Value: dagql.String(filepath.Join("/", src.SourceRootSubpath, modules.Filename))},
+				{Name: "contents", Value: dagql.String(modCfgBytes)},
+				{Name: "permissions", Value: dagql.Int(0o644)},
+			},
+		},
+		dagql.Selector{
+			Field: "withLabel",
+			Args: []dagql.NamedInput{
+				{Name: "name", Value: dagql.String(core.OCIModuleSourceRootLabel)},
+				{Name: "value", Value: dagql.String(src.SourceRootSubpath)},
+			},
+		},
+		dagql.Selector{
+			Field: "withLabel",
+			Args: []dagql.NamedInput{
+				{Name: "name", Value: dagql.String(core.OCIModuleNameLabel)},
+				{Name: "value", Value: dagql.String(src.ModuleOriginalName)},
+			},
+		},
+		dagql.Selector{
+			Field: "publish",
+			Args: []dagql.NamedInput{
+				{Name: "address", Value: dagql.String(args.Address)},
+			},
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish module: %w", err)
+	}
+	return ref, nil
+}
+
+// updateOCIItem re-resolves an OCI item if any of the update requests match it,
+// either by module name or by repository (optionally with a new tag).
+func (s *moduleSourceSchema) updateOCIItem(
+	ctx context.Context,
+	dag *dagql.Server,
+	existingItem dagql.ObjectResult[*core.ModuleSource],
+	updateReqs map[updateReq]struct{},
+	accessor moduleRelationTypeAccessor,
+) (core.ModuleSourceID, bool, error) {
+	existing := existingItem.Self().OCI
+	for req := range updateReqs {
+		tag := existing.Tag
+		switch {
+		case req.symbolic == existingItem.Self().ModuleName, req.symbolic == existing.Repository:
+			tag = cmp.Or(req.version, tag)
+		case ociRepository(req.symbolic) == existing.Repository:
+			parsed, err := core.ParseOCIRefString(req.symbolic)
+			if err != nil {
+				return core.ModuleSourceID{}, false, err
+			}
+			tag = cmp.Or(parsed.Tag, tag)
+		default:
+			continue
+		}
+		delete(updateReqs, req)
+
+		var updatedItem dagql.ObjectResult[*core.ModuleSource]
+		err := dag.Select(ctx, dag.Root(), &updatedItem,
+			dagql.Selector{
+				Field: "moduleSource",
+				Args: []dagql.NamedInput{
+					{Name: "refString", Value: dagql.String(core.OCIRefString(existing.Repository, tag, ""))},
+				},
+			},
+		)
+		if err != nil {
+			return core.ModuleSourceID{}, false, fmt.Errorf("failed to load updated %s: %w", accessor.typ, err)
+		}
+		return dagql.NewID[*core.ModuleSource](updatedItem.ID()), true, nil
+	}
+	return core.ModuleSourceID{}, false, nil
+}
+
+// ociRepository returns the repository of an OCI ref string, or the ref string
+// itself if it can't be parsed as one.
+func ociRepository(refString string) string {
+	parsed, err := core.ParseOCIRefString(refString)
+	if err != nil {
+		return refString
+	}
+	return parsed.Repository
+}
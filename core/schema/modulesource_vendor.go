@@ -0,0 +1,364 @@
+package schema
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/opencontainers/go-digest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/core/modules"
+	"github.com/dagger/dagger/core/sdk"
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine/buildkit"
+	"github.com/dagger/dagger/engine/slog"
+)
+
+// loadModuleSourceVendor loads the vendor directory configured in the module's
+// dagger.json, so that its git modules and builtin SDKs are loaded from there.
+// Nothing is loaded if the module hasn't been vendored yet.
+func (s *moduleSourceSchema) loadModuleSourceVendor(
+	ctx context.Context,
+	bk *buildkit.Client,
+	dag *dagql.Server,
+	src *core.ModuleSource,
+) error {
+	if src.ConfigVendor == "" {
+		return nil
+	}
+	vendorPath := filepath.Join(src.SourceRootSubpath, src.ConfigVendor)
+	manifestPath := filepath.Join(vendorPath, modules.VendorManifestFilename)
+
+	vendor := &core.ModuleVendor{}
+	var manifestContents []byte
+	switch src.Kind {
+	case core.ModuleSourceKindLocal:
+		vendorPath = filepath.Join(src.Local.ContextDirectoryPath, vendorPath)
+		manifestPath = filepath.Join(src.Local.ContextDirectoryPath, manifestPath)
+		if _, err := bk.StatCallerHostPath(ctx, manifestPath, false); err != nil {
+			if status.Code(err) == codes.NotFound {
+				slog.Debug("module is not vendored yet", "path", vendorPath)
+				return nil
+			}
+			return fmt.Errorf("failed to stat vendor manifest: %w", err)
+		}
+		contents, err := bk.ReadCallerHostFile(ctx, manifestPath)
+		if err != nil {
+			return fmt.Errorf("failed to read vendor manifest: %w", err)
+		}
+		manifestContents = contents
+		err = dag.Select(ctx, dag.Root(), &vendor.Directory,
+			dagql.Selector{Field: "host"},
+			dagql.Selector{
+				Field: "directory",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(vendorPath)},
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to load vendor directory: %w", err)
+		}
+
+	case core.ModuleSourceKindDir:
+		var contents string
+		err := dag.Select(ctx, src.DirSrc.OriginalContextDir, &contents,
+			dagql.Selector{
+				Field: "file",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(manifestPath)},
+				},
+			},
+			dagql.Selector{Field: "contents"},
+		)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				slog.Debug("module is not vendored yet", "path", vendorPath)
+				return nil
+			}
+			return fmt.Errorf("failed to read vendor manifest: %w", err)
+		}
+		manifestContents = []byte(contents)
+		err = dag.Select(ctx, src.DirSrc.OriginalContextDir, &vendor.Directory,
+			dagql.Selector{
+				Field: "directory",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(vendorPath)},
+				},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to load vendor directory: %w", err)
+		}
+
+	default:
+		// git modules are only loaded from the vendor dir of the module vendoring them
+		return nil
+	}
+
+	manifest, err := modules.ParseVendorManifest(manifestContents)
+	if err != nil {
+		return err
+	}
+	vendor.Manifest = manifest
+	src.Vendor = vendor
+	return nil
+}
+
+type vendoredModuleSourceArgs struct {
+	RefString       string
+	RefPin          string
+	VendorDirectory core.DirectoryID
+	DisableFindUp   bool `default:"false"`
+}
+
+func (s *moduleSourceSchema) vendoredModuleSource(
+	ctx context.Context,
+	query dagql.ObjectResult[*core.Query],
+	args vendoredModuleSourceArgs,
+) (inst dagql.Result[*core.ModuleSource], err error) {
+	dag, err := query.Self().Server.Server(ctx)
+	if err != nil {
+		return inst, fmt.Errorf("failed to get dag server: %w", err)
+	}
+	vendorDir, err := args.VendorDirectory.Load(ctx, dag)
+	if err != nil {
+		return inst, err
+	}
+
+	var manifestContents string
+	err = dag.Select(ctx, vendorDir, &manifestContents,
+		dagql.Selector{
+			Field: "file",
+			Args: []dagql.NamedInput{
+				{Name: "path", Value: dagql.String(modules.VendorManifestFilename)},
+			},
+		},
+		dagql.Selector{Field: "contents"},
+	)
+	if err != nil {
+		return inst, fmt.Errorf("failed to read vendor manifest: %w", err)
+	}
+	manifest, err := modules.ParseVendorManifest([]byte(manifestContents))
+	if err != nil {
+		return inst, err
+	}
+	vendored, ok := manifest.LookupModule(args.RefString, args.RefPin)
+	if !ok {
+		return inst, fmt.Errorf("module source %q is not vendored at %q", args.RefString, args.RefPin)
+	}
+
+	parsed, err := core.ParseVendoredGitRefString(args.RefString, vendored)
+	if err != nil {
+		return inst, err
+	}
+	vendor := &core.ModuleVendor{
+		Directory: vendorDir,
+		Manifest:  manifest,
+	}
+	return s.gitModuleSource(ctx, query, &parsed, args.RefPin, !args.DisableFindUp, vendor, vendored, nil)
+}
+
+func (s *moduleSourceSchema) moduleSourceWithVendor(
+	ctx context.Context,
+	src *core.ModuleSource,
+	args struct {
+		Path string `default:""`
+	},
+) (*core.ModuleSource, error) {
+	vendorPath := args.Path
+	if vendorPath == "" {
+		vendorPath = cmp.Or(src.ConfigVendor, modules.DefaultVendorDir)
+	}
+	vendorPath = filepath.Clean(vendorPath)
+	if !filepath.IsLocal(vendorPath) {
+		return nil, fmt.Errorf("vendor path %q contains parent directory components", vendorPath)
+	}
+
+	src = src.Clone()
+	src.ConfigVendor = vendorPath
+	src.VendorRequested = true
+	return src, nil
+}
+
+// vendorDirectory returns a directory containing the git modules and builtin
+// SDK images the module source transitively loads, along with a manifest of
+// them.
+func (s *moduleSourceSchema) vendorDirectory(
+	ctx context.Context,
+	src *core.ModuleSource,
+) (res dagql.ObjectResult[*core.Directory], _ error) {
+	query, err := core.CurrentQuery(ctx)
+	if err != nil {
+		return res, err
+	}
+	dag, err := query.Server.Server(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to get dag server: %w", err)
+	}
+	bk, err := query.Buildkit(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to get buildkit client: %w", err)
+	}
+
+	if err := dag.Select(ctx, dag.Root(), &res, dagql.Selector{Field: "directory"}); err != nil {
+		return res, fmt.Errorf("failed to create vendor directory: %w", err)
+	}
+	withDirectory := func(path string, dir dagql.ObjectResult[*core.Directory]) error {
+		return dag.Select(ctx, res, &res,
+			dagql.Selector{
+				Field: "withDirectory",
+				Args: []dagql.NamedInput{
+					{Name: "path", Value: dagql.String(path)},
+					{Name: "source", Value: dagql.NewID[*core.Directory](dir.ID())},
+				},
+			},
+		)
+	}
+
+	manifest := &modules.VendorManifest{}
+	visited := map[*core.ModuleSource]struct{}{}
+	var walk func(src *core.ModuleSource) error
+	walk = func(src *core.ModuleSource) error {
+		if _, ok := visited[src]; ok {
+			return nil
+		}
+		visited[src] = struct{}{}
+
+		if src.Kind == core.ModuleSourceKindGit {
+			vendored := &modules.VendoredModule{
+				CloneRef:     src.Git.CloneRef,
+				RepoRootPath: src.Git.RepoRootPath,
+				HTMLRepoURL:  src.Git.HTMLRepoURL,
+				Version:      src.Git.Version,
+				Ref:          src.Git.Ref,
+				Commit:       src.Git.Commit,
+				Path:         modules.VendoredModulePath(src.Git.CloneRef, src.Git.Commit),
+			}
+			if _, ok := manifest.LookupModule(vendored.CloneRef, vendored.Commit); !ok {
+				if err := withDirectory(vendored.Path, src.Git.UnfilteredContextDir); err != nil {
+					return fmt.Errorf("failed to vendor %s: %w", src.AsString(), err)
+				}
+				manifest.AddModule(vendored)
+			}
+		}
+
+		if src.SDK != nil {
+			builtinName, manifestDigest, sdkRef, err := sdk.VendorSDKRef(src.SDK.Source)
+			if err != nil {
+				return err
+			}
+			switch {
+			case builtinName != "":
+				if _, ok := manifest.LookupSDK(builtinName); ok {
+					break
+				}
+				var tarball dagql.ObjectResult[*core.File]
+				err := dag.Select(ctx, dag.Root(), &tarball,
+					dagql.Selector{
+						Field: "_builtinContainer",
+						Args: []dagql.NamedInput{
+							{Name: "digest", Value: dagql.String(manifestDigest.String())},
+						},
+					},
+					dagql.Selector{Field: "asTarball"},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to export %s sdk image: %w", builtinName, err)
+				}
+				// record the manifest that was exported, which the tarball is
+				// verified against when loaded
+				tarballDigest, err := vendoredSDKManifestDigest(ctx, tarball.Self())
+				if err != nil {
+					return fmt.Errorf("failed to export %s sdk image: %w", builtinName, err)
+				}
+				vendored := &modules.VendoredSDK{
+					Name:   builtinName,
+					Digest: tarballDigest.String(),
+					Path:   modules.VendoredSDKPath(builtinName),
+				}
+				err = dag.Select(ctx, res, &res,
+					dagql.Selector{
+						Field: "withFile",
+						Args: []dagql.NamedInput{
+							{Name: "path", Value: dagql.String(vendored.Path)},
+							{Name: "source", Value: dagql.NewID[*core.File](tarball.ID())},
+						},
+					},
+				)
+				if err != nil {
+					return fmt.Errorf("failed to vendor %s sdk image: %w", builtinName, err)
+				}
+				manifest.AddSDK(vendored)
+			case sdkRef != "":
+				sdkSrc, err := core.ResolveDepToSource(ctx, bk, dag, src, sdkRef, "", "")
+				if err != nil {
+					return fmt.Errorf("failed to resolve sdk %q: %w", sdkRef, err)
+				}
+				if err := walk(sdkSrc.Self()); err != nil {
+					return err
+				}
+			}
+		}
+
+		deps := slices.Concat(src.Dependencies, src.Toolchains)
+		if src.Blueprint.Self() != nil {
+			deps = append(deps, src.Blueprint)
+		}
+		for _, dep := range deps {
+			if err := walk(dep.Self()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(src); err != nil {
+		return res, err
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return res, fmt.Errorf("failed to encode vendor manifest: %w", err)
+	}
+	manifestBytes = append(manifestBytes, '\n')
+	err = dag.Select(ctx, res, &res,
+		dagql.Selector{
+			Field: "withNewFile",
+			Args: []dagql.NamedInput{
+				{Name: "path", Value: dagql.String(modules.VendorManifestFilename)},
+				{Name: "contents", Value: dagql.String(manifestBytes)},
+				{Name: "permissions", Value: dagql.Int(0o644)},
+			},
+		},
+	)
+	if err != nil {
+		return res, fmt.Errorf("failed to add vendor manifest: %w", err)
+	}
+	return res, nil
+}
+
+// vendoredSDKManifestDigest returns the digest of the single image manifest
+// in an exported SDK image tarball.
+func vendoredSDKManifestDigest(ctx context.Context, tarball *core.File) (digest.Digest, error) {
+	r, err := tarball.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	manifests, err := sdk.TarballManifestDigests(r)
+	if err != nil {
+		return "", err
+	}
+	if len(manifests) != 1 {
+		return "", fmt.Errorf("expected a single image manifest, found %d", len(manifests))
+	}
+	return manifests[0], nil
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"context"
+
+	"github.com/dagger/dagger/core"
+	"github.com/dagger/dagger/dagql"
+)
+
+// CheckModulePolicy is a cache key func that fails the call if the engine's
+// security policy denies the given capability to the calling module.
+//
+// The policy is checked while computing the cache key, rather than in the
+// resolver, so that it's also enforced when the call hits the cache.
+func CheckModulePolicy[P dagql.Typed, A any](capability core.ModuleCapability) func(context.Context, dagql.ObjectResult[P], A, dagql.GetCacheConfigRequest) (*dagql.GetCacheConfigResponse, error) {
+	return func(ctx context.Context, _ dagql.ObjectResult[P], _ A, req dagql.GetCacheConfigRequest) (*dagql.GetCacheConfigResponse, error) {
+		query, err := core.CurrentQuery(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := query.CheckModulePolicy(ctx, capability); err != nil {
+			return nil, err
+		}
+		return &dagql.GetCacheConfigResponse{CacheKey: req.CacheKey}, nil
+	}
+}
+
+// CheckModuleNetwork wraps a cache key func to fail the call if the calling
+// module function wasn't granted network access by the module depending on it.
+//
+// Like CheckModulePolicy, it's checked while computing the cache key so that
+// it's also enforced when the call hits the cache.
+func CheckModuleNetwork[P dagql.Typed, A any](cacheFn dagql.GetCacheConfigFunc[P, A]) dagql.GetCacheConfigFunc[P, A] {
+	return func(ctx context.Context, inst dagql.ObjectResult[P], args A, req dagql.GetCacheConfigRequest) (*dagql.GetCacheConfigResponse, error) {
+		query, err := core.CurrentQuery(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := query.CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionNetwork}); err != nil {
+			return nil, err
+		}
+		if cacheFn == nil {
+			return &dagql.GetCacheConfigResponse{CacheKey: req.CacheKey}, nil
+		}
+		return cacheFn(ctx, inst, args, req)
+	}
+}
@@ -77,6 +77,9 @@ func (s *secretSchema) secret(
 	if err != nil {
 		return i, fmt.Errorf("failed to get client metadata from context: %w", err)
 	}
+	if err := parent.Self().CheckModulePermission(ctx, core.ModulePermission{Kind: core.ModulePermissionSecrets, Value: args.URI}); err != nil {
+		return i, err
+	}
 
 	secretStore, err := parent.Self().Secrets(ctx)
 	if err != nil {
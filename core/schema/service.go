@@ -16,12 +16,12 @@ var _ SchemaResolvers = &serviceSchema{}
 
 func (s *serviceSchema) Install(srv *dagql.Server) {
 	dagql.Fields[*core.Container]{
-		dagql.NodeFunc("asService", s.containerAsServiceLegacy).
+		dagql.NodeFuncWithCacheKey("asService", s.containerAsServiceLegacy, CheckModuleNetwork[*core.Container, struct{}](nil)).
 			View(BeforeVersion("v0.15.0")).
 			Doc(`Turn the container into a Service.`,
 				`Be sure to set any exposed ports before this conversion.`),
 
-		dagql.Func("asService", s.containerAsService).
+		dagql.FuncWithCacheKey("asService", s.containerAsService, CheckModuleNetwork[*core.Container, core.ContainerAsServiceArgs](nil)).
 			View(AfterVersion("v0.15.0")).
 			Doc(`Turn the container into a Service.`,
 				`Be sure to set any exposed ports before this conversion.`).
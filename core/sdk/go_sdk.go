@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"dagger.io/dagger/telemetry"
@@ -13,7 +12,6 @@ import (
 	"github.com/dagger/dagger/dagql/call"
 	"github.com/dagger/dagger/engine"
 	"github.com/dagger/dagger/engine/buildkit"
-	"github.com/dagger/dagger/engine/distconsts"
 	"github.com/dagger/dagger/internal/buildkit/identity"
 	"github.com/mitchellh/mapstructure"
 )
@@ -38,6 +36,9 @@ it with the resulting /runtime binary.
 type goSDK struct {
 	root      *core.Query
 	rawConfig map[string]any
+	// parentSrc is the module using the sdk, whose vendored sdk image is
+	// preferred if any
+	parentSrc *core.ModuleSource
 }
 
 type goSDKConfig struct {
@@ -645,18 +646,8 @@ func (sdk *goSDK) base(ctx context.Context) (dagql.ObjectResult[*core.Container]
 		return inst, fmt.Errorf("failed to get dag for go module sdk client generation: %w", err)
 	}
 
-	var baseCtr dagql.ObjectResult[*core.Container]
-	if err := dag.Select(ctx, dag.Root(), &baseCtr,
-		dagql.Selector{
-			Field: "_builtinContainer",
-			Args: []dagql.NamedInput{
-				{
-					Name:  "digest",
-					Value: dagql.String(os.Getenv(distconsts.GoSDKManifestDigestEnvName)),
-				},
-			},
-		},
-	); err != nil {
+	baseCtr, err := builtinSDKContainer(ctx, dag, sdkGo, sdk.parentSrc)
+	if err != nil {
 		return inst, fmt.Errorf("failed to get base container from go module sdk tarball: %w", err)
 	}
 
@@ -7,6 +7,7 @@ import (
 	"os"
 	"slices"
 	"strings"
+	"sync"
 
 	"dagger.io/dagger/telemetry"
 	"github.com/dagger/dagger/core"
@@ -45,7 +46,7 @@ func (l *Loader) SDKForModule(
 	ctx, span := core.Tracer(ctx).Start(ctx, fmt.Sprintf("load SDK: %s", sdk.Source))
 	defer telemetry.EndWithCause(span, &rerr)
 
-	builtinSDK, builtinErr := l.namedSDK(ctx, query, sdk)
+	builtinSDK, builtinErr := l.namedSDK(ctx, query, sdk, parentSrc)
 	if builtinErr == nil {
 		return builtinSDK, nil
 	} else if !errors.Is(builtinErr, errUnknownBuiltinSDK) {
@@ -119,6 +120,7 @@ func (l *Loader) namedSDK(
 	ctx context.Context,
 	root *core.Query,
 	sdk *core.SDKConfig,
+	parentSrc *core.ModuleSource,
 ) (core.SDK, error) {
 	sdkNamedParsed, sdkSuffix, err := parseSDKName(sdk.Source)
 	if err != nil {
@@ -127,17 +129,16 @@ func (l *Loader) namedSDK(
 
 	switch sdkNamedParsed {
 	case sdkGo:
-		return &goSDK{root: root, rawConfig: sdk.Config}, nil
-	case sdkPython:
-		return l.loadBuiltinSDK(ctx, root, sdk, digest.Digest(os.Getenv(distconsts.PythonSDKManifestDigestEnvName)))
-	case sdkTypescript:
-		return l.loadBuiltinSDK(ctx, root, sdk, digest.Digest(os.Getenv(distconsts.TypescriptSDKManifestDigestEnvName)))
-	case sdkJava:
-		return l.SDKForModule(ctx, root, &core.SDKConfig{Source: "github.com/dagger/dagger/sdk/java" + sdkSuffix, Config: sdk.Config, Experimental: sdk.Experimental}, nil)
-	case sdkPHP:
-		return l.SDKForModule(ctx, root, &core.SDKConfig{Source: "github.com/dagger/dagger/sdk/php" + sdkSuffix, Config: sdk.Config, Experimental: sdk.Experimental}, nil)
-	case sdkElixir:
-		return l.SDKForModule(ctx, root, &core.SDKConfig{Source: "github.com/dagger/dagger/sdk/elixir" + sdkSuffix, Config: sdk.Config, Experimental: sdk.Experimental}, nil)
+		return &goSDK{root: root, rawConfig: sdk.Config, parentSrc: parentSrc}, nil
+	case sdkPython, sdkTypescript:
+		return l.loadBuiltinSDK(ctx, root, sdk, sdkNamedParsed, parentSrc)
+	case sdkJava, sdkPHP, sdkElixir:
+		// only pass the parent module along if it's vendored, so the sdk module is loaded from its vendor dir
+		var vendorSrc *core.ModuleSource
+		if parentSrc != nil && parentSrc.Vendor != nil {
+			vendorSrc = parentSrc
+		}
+		return l.SDKForModule(ctx, root, &core.SDKConfig{Source: builtinSDKModuleRef(sdkNamedParsed, sdkSuffix), Config: sdk.Config, Experimental: sdk.Experimental}, vendorSrc)
 	}
 
 	return nil, errUnknownBuiltinSDK
@@ -150,7 +151,8 @@ func (l *Loader) loadBuiltinSDK(
 	ctx context.Context,
 	root *core.Query,
 	sdk *core.SDKConfig,
-	manifestDigest digest.Digest,
+	name sdk,
+	parentSrc *core.ModuleSource,
 ) (*module, error) {
 	dag, err := root.Server.Server(ctx)
 	if err != nil {
@@ -160,17 +162,12 @@ func (l *Loader) loadBuiltinSDK(
 	// TODO: currently hardcoding assumption that builtin sdks put *module* source code at
 	// "runtime" subdir right under the *full* sdk source dir. Can be generalized once we support
 	// default-args/scripts in dagger.json
+	sdkCtr, err := builtinSDKContainer(ctx, dag, name, parentSrc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import full sdk source for sdk %s: %w", sdk.Source, err)
+	}
 	var fullSDKDir dagql.ObjectResult[*core.Directory]
-	if err := dag.Select(ctx, dag.Root(), &fullSDKDir,
-		dagql.Selector{
-			Field: "_builtinContainer",
-			Args: []dagql.NamedInput{
-				{
-					Name:  "digest",
-					Value: dagql.String(manifestDigest.String()),
-				},
-			},
-		},
+	if err := dag.Select(ctx, sdkCtr, &fullSDKDir,
 		dagql.Selector{
 			Field: "rootfs",
 		},
@@ -203,6 +200,147 @@ func (l *Loader) loadBuiltinSDK(
 	return newModuleSDK(ctx, root, sdkMod, fullSDKDir, sdk.Config)
 }
 
+// builtinSDKDigest returns the manifest digest of the image of a builtin SDK
+// pre-packaged with the engine container.
+func builtinSDKDigest(name sdk) digest.Digest {
+	switch name {
+	case sdkGo:
+		return digest.Digest(os.Getenv(distconsts.GoSDKManifestDigestEnvName))
+	case sdkPython:
+		return digest.Digest(os.Getenv(distconsts.PythonSDKManifestDigestEnvName))
+	case sdkTypescript:
+		return digest.Digest(os.Getenv(distconsts.TypescriptSDKManifestDigestEnvName))
+	}
+	return ""
+}
+
+// builtinSDKModuleRef returns the ref of the git module implementing a builtin
+// SDK that isn't pre-packaged with the engine container.
+func builtinSDKModuleRef(name sdk, sdkSuffix string) string {
+	return "github.com/dagger/dagger/sdk/" + string(name) + sdkSuffix
+}
+
+// builtinSDKContainer returns the image of a builtin SDK, preferring the one
+// vendored by the parent module over the one pre-packaged with the engine
+// container.
+func builtinSDKContainer(
+	ctx context.Context,
+	dag *dagql.Server,
+	name sdk,
+	parentSrc *core.ModuleSource,
+) (inst dagql.ObjectResult[*core.Container], _ error) {
+	if parentSrc != nil && parentSrc.Vendor != nil {
+		if vendored, ok := parentSrc.Vendor.Manifest.LookupSDK(string(name)); ok {
+			var tarball dagql.ObjectResult[*core.File]
+			if err := dag.Select(ctx, parentSrc.Vendor.Directory, &tarball,
+				dagql.Selector{
+					Field: "file",
+					Args: []dagql.NamedInput{
+						{Name: "path", Value: dagql.String(vendored.Path)},
+					},
+				},
+			); err != nil {
+				return inst, fmt.Errorf("failed to load vendored %s sdk image: %w", name, err)
+			}
+			if err := verifyVendoredSDK(ctx, name, tarball.Self(), digest.Digest(vendored.Digest)); err != nil {
+				return inst, fmt.Errorf("vendored %s sdk image %s: %w", name, vendored.Path, err)
+			}
+			if err := dag.Select(ctx, dag.Root(), &inst,
+				dagql.Selector{Field: "container"},
+				dagql.Selector{
+					Field: "import",
+					Args: []dagql.NamedInput{
+						{Name: "source", Value: dagql.NewID[*core.File](tarball.ID())},
+					},
+				},
+			); err != nil {
+				return inst, fmt.Errorf("failed to import vendored %s sdk image: %w", name, err)
+			}
+			return inst, nil
+		}
+	}
+
+	if err := dag.Select(ctx, dag.Root(), &inst,
+		dagql.Selector{
+			Field: "_builtinContainer",
+			Args: []dagql.NamedInput{
+				{
+					Name:  "digest",
+					Value: dagql.String(builtinSDKDigest(name).String()),
+				},
+			},
+		},
+	); err != nil {
+		return inst, fmt.Errorf("failed to load %s sdk image from engine container filesystem: %w", name, err)
+	}
+	return inst, nil
+}
+
+// verifiedVendoredSDKs holds the digests of the vendored SDK image tarballs
+// that were verified, so that each is only read once.
+var verifiedVendoredSDKs sync.Map
+
+// verifyVendoredSDK checks that a vendored SDK image tarball holds the image
+// of the builtin SDK pre-packaged with the engine, as recorded in the vendor
+// manifest, and nothing else.
+func verifyVendoredSDK(ctx context.Context, name sdk, tarball *core.File, expected digest.Digest) error {
+	if err := expected.Validate(); err != nil {
+		return fmt.Errorf("invalid digest %q in vendor manifest: %w", expected, err)
+	}
+	// a module may only vendor the image the engine would have used anyway,
+	// not replace the SDK runtime with its own
+	if builtin := builtinSDKDigest(name); expected != builtin {
+		return fmt.Errorf("recorded digest %s does not match the engine's %s sdk image %s, vendor it again with this engine", expected, name, builtin)
+	}
+
+	tarballDigest, err := tarball.Digest(ctx, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := verifiedVendoredSDKs.Load(tarballDigest + "=" + expected.String()); ok {
+		return nil
+	}
+
+	r, err := tarball.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	manifests, err := TarballManifestDigests(r)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return fmt.Errorf("no image found, expected %s", expected)
+	}
+	for _, manifest := range manifests {
+		if manifest != expected {
+			return fmt.Errorf("image %s does not match the digest %s recorded in the vendor manifest", manifest, expected)
+		}
+	}
+	verifiedVendoredSDKs.Store(tarballDigest+"="+expected.String(), struct{}{})
+	return nil
+}
+
+// VendorSDKRef returns what a module using the given SDK must vendor to load
+// it offline: either the name and manifest digest of a builtin SDK image, or
+// the ref of the module implementing the SDK.
+func VendorSDKRef(source string) (builtinName string, manifestDigest digest.Digest, modRef string, _ error) {
+	name, sdkSuffix, err := parseSDKName(source)
+	switch {
+	case errors.Is(err, errUnknownBuiltinSDK):
+		return "", "", source, nil
+	case err != nil:
+		return "", "", "", err
+	}
+	switch name {
+	case sdkGo, sdkPython, sdkTypescript:
+		return string(name), builtinSDKDigest(name), "", nil
+	default:
+		return "", "", builtinSDKModuleRef(name, sdkSuffix), nil
+	}
+}
+
 // parse and validate the name and version from sdkName
 //
 // for sdkName with format <sdk-name>@<version>, it returns
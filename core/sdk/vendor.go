@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// maxTarballIndexSize is the largest blob read into memory while looking for
+// the nested indexes of an image tarball.
+const maxTarballIndexSize = 4 << 20
+
+// attestationReferenceType marks the manifests of attestations in an index,
+// which aren't images.
+const attestationReferenceType = "vnd.docker.reference.type"
+
+// TarballManifestDigests returns the digests of the image manifests an OCI
+// image tarball refers to, following nested indexes.
+//
+// Blobs are stored under their own digest when a tarball is imported, so if
+// these are the expected digests, so is everything the imported image is made
+// of.
+func TarballManifestDigests(r io.Reader) ([]digest.Digest, error) {
+	var index []byte
+	blobs := map[digest.Digest][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read image tarball: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxTarballIndexSize {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		var dgst digest.Digest
+		switch dir := path.Dir(name); {
+		case name == ocispecs.ImageIndexFile:
+		case path.Dir(dir) == ocispecs.ImageBlobsDir:
+			dgst = digest.NewDigestFromEncoded(digest.Algorithm(path.Base(dir)), path.Base(name))
+			if dgst.Validate() != nil {
+				continue
+			}
+		default:
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s from image tarball: %w", name, err)
+		}
+		if dgst == "" {
+			index = data
+		} else {
+			blobs[dgst] = data
+		}
+	}
+	if index == nil {
+		return nil, fmt.Errorf("image tarball has no %s", ocispecs.ImageIndexFile)
+	}
+
+	var manifests []digest.Digest
+	var walk func(data []byte, depth int) error
+	walk = func(data []byte, depth int) error {
+		if depth > 10 {
+			return fmt.Errorf("image tarball indexes are nested too deep")
+		}
+		var idx ocispecs.Index
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("decode image index: %w", err)
+		}
+		for _, desc := range idx.Manifests {
+			switch {
+			case desc.Annotations[attestationReferenceType] != "":
+			case images.IsManifestType(desc.MediaType):
+				manifests = append(manifests, desc.Digest)
+			case images.IsIndexType(desc.MediaType):
+				nested, ok := blobs[desc.Digest]
+				if !ok || digest.FromBytes(nested) != desc.Digest {
+					return fmt.Errorf("image tarball is missing index %s", desc.Digest)
+				}
+				if err := walk(nested, depth+1); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected media type %q of %s in image tarball", desc.MediaType, desc.Digest)
+			}
+		}
+		return nil
+	}
+	if err := walk(index, 0); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
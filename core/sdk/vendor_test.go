@@ -0,0 +1,91 @@
+package sdk
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+type testTarball struct {
+	buf bytes.Buffer
+	tw  *tar.Writer
+}
+
+func newTestTarball() *testTarball {
+	tb := &testTarball{}
+	tb.tw = tar.NewWriter(&tb.buf)
+	return tb
+}
+
+func (tb *testTarball) add(t *testing.T, name string, data []byte) {
+	require.NoError(t, tb.tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+	_, err := tb.tw.Write(data)
+	require.NoError(t, err)
+}
+
+func (tb *testTarball) blob(t *testing.T, mediaType string, v any) ocispecs.Descriptor {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	desc := ocispecs.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(data), Size: int64(len(data))}
+	tb.add(t, "blobs/sha256/"+desc.Digest.Encoded(), data)
+	return desc
+}
+
+func (tb *testTarball) index(t *testing.T, manifests ...ocispecs.Descriptor) *bytes.Buffer {
+	data, err := json.Marshal(ocispecs.Index{MediaType: ocispecs.MediaTypeImageIndex, Manifests: manifests})
+	require.NoError(t, err)
+	tb.add(t, ocispecs.ImageIndexFile, data)
+	require.NoError(t, tb.tw.Close())
+	return &tb.buf
+}
+
+func TestTarballManifestDigests(t *testing.T) {
+	manifest := ocispecs.Manifest{MediaType: ocispecs.MediaTypeImageManifest}
+
+	t.Run("manifest", func(t *testing.T) {
+		tb := newTestTarball()
+		desc := tb.blob(t, ocispecs.MediaTypeImageManifest, manifest)
+		digests, err := TarballManifestDigests(tb.index(t, desc))
+		require.NoError(t, err)
+		require.Equal(t, []digest.Digest{desc.Digest}, digests)
+	})
+
+	t.Run("nested index", func(t *testing.T) {
+		tb := newTestTarball()
+		desc := tb.blob(t, ocispecs.MediaTypeImageManifest, manifest)
+		attestation := tb.blob(t, ocispecs.MediaTypeImageManifest, ocispecs.Manifest{
+			MediaType:   ocispecs.MediaTypeImageManifest,
+			Annotations: map[string]string{"attestation": "true"},
+		})
+		attestation.Annotations = map[string]string{attestationReferenceType: "attestation-manifest"}
+		nested := tb.blob(t, ocispecs.MediaTypeImageIndex, ocispecs.Index{
+			MediaType: ocispecs.MediaTypeImageIndex,
+			Manifests: []ocispecs.Descriptor{desc, attestation},
+		})
+		digests, err := TarballManifestDigests(tb.index(t, nested))
+		require.NoError(t, err)
+		require.Equal(t, []digest.Digest{desc.Digest}, digests)
+	})
+
+	t.Run("missing nested index", func(t *testing.T) {
+		tb := newTestTarball()
+		_, err := TarballManifestDigests(tb.index(t, ocispecs.Descriptor{
+			MediaType: ocispecs.MediaTypeImageIndex,
+			Digest:    digest.FromString("missing"),
+		}))
+		require.ErrorContains(t, err, "missing index")
+	})
+
+	t.Run("no index", func(t *testing.T) {
+		tb := newTestTarball()
+		tb.blob(t, ocispecs.MediaTypeImageManifest, manifest)
+		require.NoError(t, tb.tw.Close())
+		_, err := TarballManifestDigests(&tb.buf)
+		require.ErrorContains(t, err, "no index.json")
+	})
+}
@@ -221,6 +221,27 @@ func (store *SecretStore) GetSecretPlaintextDirect(ctx context.Context, secret *
 	return resp.Data, nil
 }
 
+// Plaintexts returns the plaintext of every secret in the store, skipping any
+// that can no longer be retrieved, e.g. because their session has ended.
+func (store *SecretStore) Plaintexts(ctx context.Context) [][]byte {
+	store.mu.RLock()
+	all := make([]*Secret, 0, len(store.secrets))
+	for _, secret := range store.secrets {
+		all = append(all, secret.Self())
+	}
+	store.mu.RUnlock()
+
+	plaintexts := make([][]byte, 0, len(all))
+	for _, secret := range all {
+		plaintext, err := store.GetSecretPlaintextDirect(ctx, secret)
+		if err != nil || len(plaintext) == 0 {
+			continue
+		}
+		plaintexts = append(plaintexts, plaintext)
+	}
+	return plaintexts
+}
+
 func (store *SecretStore) AsBuildkitSecretStore() secrets.SecretStore {
 	return &buildkitSecretStore{inner: store}
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/dagger/dagger/engine/buildkit"
 	engineclient "github.com/dagger/dagger/engine/client"
 	"github.com/dagger/dagger/engine/clientdb"
+	"github.com/dagger/dagger/engine/config"
 	"github.com/dagger/dagger/engine/filesync"
 	"github.com/dagger/dagger/engine/server/resource"
 	bkcache "github.com/dagger/dagger/internal/buildkit/cache"
@@ -113,8 +114,15 @@ func (ms *mockServer) FileSyncer() *filesync.FileSyncer            { return nil
 func (ms *mockServer) ClientTelemetry(ctc context.Context, sessID, clientID string) (*clientdb.DB, error) {
 	return nil, nil
 }
-func (ms *mockServer) EngineName() string { return "mockEngine" }
-func (ms *mockServer) Clients() []string  { return []string{} }
+func (ms *mockServer) EngineName() string       { return "mockEngine" }
+func (ms *mockServer) Clients() []*EngineClient { return nil }
+func (ms *mockServer) SecurityPolicy() *config.SecurityPolicy {
+	return nil
+}
+
+func (ms *mockServer) OfflineMode() bool               { return false }
+func (ms *mockServer) Resolutions() *ResolutionCache   { return nil }
+func (ms *mockServer) RegistryMirrors(string) []string { return nil }
 
 func (ms *mockServer) CloudEngineClient(context.Context, string, string, []string) (*engineclient.Client, bool, error) {
 	return nil, false, nil
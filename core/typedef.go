@@ -13,6 +13,7 @@ import (
 	"github.com/iancoleman/strcase"
 	"github.com/vektah/gqlparser/v2/ast"
 
+	"github.com/dagger/dagger/core/modules"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/dagql/call"
 )
@@ -1427,6 +1428,10 @@ type FunctionCall struct {
 
 	ParentID *call.ID
 	EnvID    *call.ID
+
+	// The access granted to the function by the module depending on it, if
+	// restricted.
+	Permissions *modules.ModulePermissions
 }
 
 func (*FunctionCall) Type() *ast.Type {
@@ -0,0 +1,209 @@
+package call
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Difference describes an input that differs between two IDs.
+type Difference struct {
+	// Path is the display path of the call in the newer ID where the
+	// difference was found.
+	Path string
+
+	// Reason is a human-readable description of the difference.
+	Reason string
+
+	// Previous and Current are display values of the differing input, if
+	// applicable. Sensitive arguments are never displayed.
+	Previous string
+	Current  string
+
+	// PreviousContent and CurrentContent are the content digests of the call
+	// if its content changed, e.g. files in a host directory, so that the
+	// changed paths can be looked up.
+	PreviousContent digest.Digest
+	CurrentContent  digest.Digest
+}
+
+func (d *Difference) String() string {
+	if d.Previous == "" && d.Current == "" {
+		return fmt.Sprintf("%s: %s", d.Path, d.Reason)
+	}
+	return fmt.Sprintf("%s: %s (%s -> %s)", d.Path, d.Reason, d.Previous, d.Current)
+}
+
+// Diff walks two IDs in lockstep and returns the inputs that explain why their
+// digests differ, i.e. why a call with the current ID would not hit the cache
+// populated by a call with the previous ID.
+//
+// Only the deepest differences are reported: if an argument's ID differs
+// because of a changed input further up its own pipeline, the changed input
+// is reported rather than every call depending on it. Differences are
+// ordered from the root of the DAG to its tip, so the first one is typically
+// the root cause.
+func Diff(previous, current *ID) []*Difference {
+	d := &differ{seen: map[[2]string]struct{}{}}
+	d.diffID(previous, current)
+	return d.diffs
+}
+
+type differ struct {
+	diffs []*Difference
+	seen  map[[2]string]struct{}
+}
+
+// cacheDigest returns the digest used when the ID is referenced by other
+// calls, which prefers the content digest if set.
+func cacheDigest(id *ID) string {
+	if id == nil {
+		return ""
+	}
+	if id.pb.ContentDigest != "" {
+		return id.pb.ContentDigest
+	}
+	return id.pb.Digest
+}
+
+func (d *differ) add(id *ID, reason, previous, current string) {
+	path := "Query"
+	if id != nil {
+		path = id.Path()
+	}
+	d.diffs = append(d.diffs, &Difference{
+		Path:     path,
+		Reason:   reason,
+		Previous: previous,
+		Current:  current,
+	})
+}
+
+func (d *differ) diffID(previous, current *ID) {
+	if cacheDigest(previous) == cacheDigest(current) {
+		return
+	}
+	key := [2]string{cacheDigest(previous), cacheDigest(current)}
+	if _, ok := d.seen[key]; ok {
+		return
+	}
+	d.seen[key] = struct{}{}
+
+	switch {
+	case previous == nil:
+		d.add(current, "call added", "", current.Display())
+		return
+	case current == nil:
+		d.add(previous, "call removed", previous.Display(), "")
+		return
+	}
+
+	if previous.Field() != current.Field() || previous.Nth() != current.Nth() {
+		// a different call altogether; walking its inputs would only be noise
+		d.add(current, "different call", previous.DisplaySelf(), current.DisplaySelf())
+		return
+	}
+
+	before := len(d.diffs)
+
+	d.diffID(previous.receiver, current.receiver)
+
+	if previous.View() != current.View() {
+		d.add(current, "API version changed", previous.View().String(), current.View().String())
+	}
+	if prevMod, curMod := previous.Module(), current.Module(); prevMod != nil && curMod != nil {
+		d.diffID(prevMod.ID(), curMod.ID())
+	} else if prevMod != curMod {
+		d.add(current, "implementing module changed", "", "")
+	}
+	d.diffArgs(current, previous.args, current.args)
+
+	if !slices.Equal(previous.pb.EffectIds, current.pb.EffectIds) {
+		d.add(current, "side effects changed", "", "")
+	}
+
+	if len(d.diffs) == before {
+		// Nothing in the recipe differs, so the digest itself must have been
+		// set to something else, e.g. the content hash of a host directory or
+		// a per-client or per-session cache scope.
+		switch {
+		case previous.pb.ContentDigest != current.pb.ContentDigest:
+			d.add(current, "content changed", previous.pb.ContentDigest, current.pb.ContentDigest)
+			diff := d.diffs[len(d.diffs)-1]
+			diff.PreviousContent = previous.ContentDigest()
+			diff.CurrentContent = current.ContentDigest()
+		case previous.pb.IsCustomDigest || current.pb.IsCustomDigest:
+			d.add(current, "digest changed (content or cache scope changed)", previous.pb.Digest, current.pb.Digest)
+		default:
+			d.add(current, "digest changed", previous.pb.Digest, current.pb.Digest)
+		}
+	}
+}
+
+func (d *differ) diffArgs(id *ID, previous, current []*Argument) {
+	prevByName := make(map[string]*Argument, len(previous))
+	for _, arg := range previous {
+		prevByName[arg.Name()] = arg
+	}
+	curNames := make(map[string]struct{}, len(current))
+	for _, arg := range current {
+		curNames[arg.Name()] = struct{}{}
+		prev, ok := prevByName[arg.Name()]
+		if !ok {
+			d.add(id, fmt.Sprintf("argument %q added", arg.Name()), "", displayArg(arg))
+			continue
+		}
+		d.diffLiteral(id, arg.Name(), arg.IsSensitive() || prev.IsSensitive(), prev.Value(), arg.Value())
+	}
+	for _, arg := range previous {
+		if _, ok := curNames[arg.Name()]; !ok {
+			d.add(id, fmt.Sprintf("argument %q removed", arg.Name()), displayArg(arg), "")
+		}
+	}
+}
+
+func (d *differ) diffLiteral(id *ID, name string, sensitive bool, previous, current Literal) {
+	switch prev := previous.(type) {
+	case *LiteralID:
+		if cur, ok := current.(*LiteralID); ok {
+			d.diffID(prev.Value(), cur.Value())
+			return
+		}
+	case *LiteralList:
+		if cur, ok := current.(*LiteralList); ok && prev.Len() == cur.Len() {
+			for i := range prev.values {
+				d.diffLiteral(id, fmt.Sprintf("%s[%d]", name, i), sensitive, prev.values[i], cur.values[i])
+			}
+			return
+		}
+	case *LiteralObject:
+		if cur, ok := current.(*LiteralObject); ok {
+			prevFields := make([]*Argument, 0, prev.Len())
+			for _, arg := range prev.Args() {
+				prevFields = append(prevFields, NewArgument(name+"."+arg.Name(), arg.Value(), sensitive || arg.IsSensitive()))
+			}
+			curFields := make([]*Argument, 0, cur.Len())
+			for _, arg := range cur.Args() {
+				curFields = append(curFields, NewArgument(name+"."+arg.Name(), arg.Value(), sensitive || arg.IsSensitive()))
+			}
+			d.diffArgs(id, prevFields, curFields)
+			return
+		}
+	}
+	if previous.Display() == current.Display() {
+		return
+	}
+	if sensitive {
+		d.add(id, fmt.Sprintf("argument %q changed", name), "***", "***")
+		return
+	}
+	d.add(id, fmt.Sprintf("argument %q changed", name), previous.Display(), current.Display())
+}
+
+func displayArg(arg *Argument) string {
+	if arg.IsSensitive() {
+		return "***"
+	}
+	return arg.Value().Display()
+}
@@ -0,0 +1,87 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func testHostDirectory(path string, opts ...IDOpt) *ID {
+	return New().Append(ast.NonNullNamedType("Host", nil), "host").
+		Append(ast.NonNullNamedType("Directory", nil), "directory",
+			append([]IDOpt{WithArgs(NewArgument("path", NewLiteralString(path), false))}, opts...)...)
+}
+
+func testBuild(src *ID, env string) *ID {
+	return New().Append(ast.NonNullNamedType("Container", nil), "container").
+		Append(ast.NonNullNamedType("Container", nil), "withDirectory", WithArgs(
+			NewArgument("path", NewLiteralString("/src"), false),
+			NewArgument("source", NewLiteralID(src), false),
+		)).
+		Append(ast.NonNullNamedType("Container", nil), "withEnvVariable", WithArgs(
+			NewArgument("name", NewLiteralString("MODE"), false),
+			NewArgument("value", NewLiteralString(env), false),
+		)).
+		Append(ast.NonNullNamedType("Container", nil), "withExec", WithArgs(
+			NewArgument("args", NewLiteralList(NewLiteralString("make")), false),
+		))
+}
+
+func TestDiffIdentical(t *testing.T) {
+	src := testHostDirectory("/app")
+	require.Empty(t, Diff(testBuild(src, "dev"), testBuild(src, "dev")))
+}
+
+func TestDiffArgument(t *testing.T) {
+	src := testHostDirectory("/app")
+	diffs := Diff(testBuild(src, "dev"), testBuild(src, "prod"))
+	require.Len(t, diffs, 1)
+	require.Equal(t, `argument "value" changed`, diffs[0].Reason)
+	require.Equal(t, `"dev"`, diffs[0].Previous)
+	require.Equal(t, `"prod"`, diffs[0].Current)
+	require.Equal(t,
+		`container.withDirectory(path: "/src", source: {host.directory(path: "/app"): Directory!}).withEnvVariable(name: "MODE", value: "prod")`,
+		diffs[0].Path)
+}
+
+func TestDiffNestedContent(t *testing.T) {
+	prevSrc := testHostDirectory("/app", WithContentDigest(digest.FromString("a")))
+	curSrc := testHostDirectory("/app", WithContentDigest(digest.FromString("b")))
+
+	diffs := Diff(testBuild(prevSrc, "dev"), testBuild(curSrc, "dev"))
+	require.Len(t, diffs, 1)
+	require.Equal(t, `host.directory(path: "/app")`, diffs[0].Path)
+	require.Equal(t, "content changed", diffs[0].Reason)
+	require.Equal(t, digest.FromString("a").String(), diffs[0].Previous)
+	require.Equal(t, digest.FromString("b").String(), diffs[0].Current)
+	require.Equal(t, digest.FromString("a"), diffs[0].PreviousContent)
+	require.Equal(t, digest.FromString("b"), diffs[0].CurrentContent)
+}
+
+func TestDiffCustomDigest(t *testing.T) {
+	prev := testHostDirectory("/app").WithDigest(digest.FromString("client-a"))
+	cur := testHostDirectory("/app").WithDigest(digest.FromString("client-b"))
+	diffs := Diff(prev, cur)
+	require.Len(t, diffs, 1)
+	require.Equal(t, "digest changed (content or cache scope changed)", diffs[0].Reason)
+}
+
+func TestDiffSensitive(t *testing.T) {
+	withToken := func(token string) *ID {
+		return New().Append(ast.NonNullNamedType("Secret", nil), "setSecret", WithArgs(
+			NewArgument("name", NewLiteralString("token"), false),
+			NewArgument("plaintext", NewLiteralString(token), true),
+		)).WithDigest(digest.FromString(token))
+	}
+	diffs := Diff(withToken("hunter2"), withToken("hunter3"))
+	require.Len(t, diffs, 1)
+	require.Equal(t, `argument "plaintext" changed`, diffs[0].Reason)
+	require.NotContains(t, diffs[0].String(), "hunter")
+}
+
+func TestShape(t *testing.T) {
+	require.Equal(t, "container.withDirectory.withEnvVariable.withExec", testBuild(testHostDirectory("/app"), "dev").Shape())
+	require.Equal(t, testBuild(testHostDirectory("/app"), "dev").Shape(), testBuild(testHostDirectory("/src"), "prod").Shape())
+}
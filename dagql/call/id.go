@@ -267,6 +267,21 @@ func (id *ID) Path() string {
 	return buf.String()
 }
 
+// Shape returns the chain of fields selected to get to the ID, without their
+// arguments, e.g. "container.from.withExec". Calls with the same shape are
+// typically the same step of a pipeline across runs.
+func (id *ID) Shape() string {
+	buf := new(strings.Builder)
+	if id.receiver != nil {
+		fmt.Fprintf(buf, "%s.", id.receiver.Shape())
+	}
+	fmt.Fprint(buf, id.pb.Field)
+	if id.pb.Nth != 0 {
+		fmt.Fprintf(buf, "#%d", id.pb.Nth)
+	}
+	return buf.String()
+}
+
 func (id *ID) DisplaySelf() string {
 	buf := new(strings.Builder)
 	fmt.Fprintf(buf, "%s", id.pb.Field)
@@ -0,0 +1,393 @@
+package dagui
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dagger/dagger/dagql/call/callpbv1"
+)
+
+// analysisTopN is the number of rows shown in each section of a rendered
+// Analysis.
+const analysisTopN = 10
+
+// Analysis summarizes where the time of a run went: what was on its critical
+// path, what each span spent waiting on, how well each module hit the cache,
+// and how long the engine sat idle.
+type Analysis struct {
+	// Root is the span the analysis covers, typically the primary span.
+	Root *Span
+
+	// Duration is the wall-clock duration of the root span.
+	Duration time.Duration
+
+	// CriticalPath is the chain of work that determined the duration of the
+	// run, from its start. A span appears once, after the spans it waited on:
+	// its children, and the spans of the calls its call was chained on or
+	// given as arguments, following the call DAG. Shortening anything else
+	// won't make the run any faster.
+	CriticalPath []CriticalStep
+
+	// Spans contains the timing of every span beneath the root, sorted by the
+	// time spent waiting on dependencies.
+	Spans []SpanTiming
+
+	// Modules contains cache hit stats for each module that calls were made
+	// to, sorted by name. Calls to the core API are counted under "core".
+	Modules []ModuleCacheStats
+
+	// Busy is the time during which at least one span beneath the root was
+	// running, and Idle is the remainder of Duration.
+	Busy, Idle time.Duration
+
+	// Work is the sum of the self time of every span beneath the root.
+	Work time.Duration
+}
+
+// CriticalStep is a span's contribution to the critical path: the time it
+// spent on the path outside of its children.
+type CriticalStep struct {
+	Span *Span
+	Self time.Duration
+}
+
+// SpanTiming breaks down the duration of a span into time spent waiting on
+// its dependencies and time spent on its own. A span's dependencies are its
+// child spans, and the spans of the calls its call depends on in the call DAG:
+// its receiver and the objects given as arguments.
+type SpanTiming struct {
+	Span     *Span
+	Duration time.Duration
+	Waiting  time.Duration
+	Self     time.Duration
+}
+
+type ModuleCacheStats struct {
+	Module string
+	Cached int
+	Total  int
+}
+
+// HitRatio returns the ratio of calls that were cache hits.
+func (stats ModuleCacheStats) HitRatio() float64 {
+	if stats.Total == 0 {
+		return 0
+	}
+	return float64(stats.Cached) / float64(stats.Total)
+}
+
+// Parallelism returns the average number of spans doing work at once while
+// the engine was busy.
+func (a *Analysis) Parallelism() float64 {
+	if a.Busy <= 0 {
+		return 0
+	}
+	return float64(a.Work) / float64(a.Busy)
+}
+
+// Analyze analyzes the spans beneath the given span, falling back to the root
+// span if it's not found. It returns nil if there's nothing to analyze.
+func (db *DB) Analyze(spanID SpanID) *Analysis {
+	root, ok := db.Spans.Map[spanID]
+	if !ok {
+		root = db.RootSpan
+	}
+	if root == nil || root.StartTime.IsZero() {
+		return nil
+	}
+	a := &analyzer{
+		db:        db,
+		end:       root.EndTimeOrFallback(db.End),
+		intervals: make(map[SpanID]Interval),
+	}
+	if a.end.Before(root.StartTime) {
+		a.end = root.StartTime
+	}
+	a.clip(root, Interval{Start: root.StartTime, End: a.end})
+
+	analysis := &Analysis{
+		Root:     root,
+		Duration: a.end.Sub(root.StartTime),
+	}
+
+	var all []Interval
+	modules := map[string]*ModuleCacheStats{}
+	root.Descendants(func(span *Span) bool {
+		ival, ok := a.intervals[span.ID]
+		if !ok {
+			return true
+		}
+		all = append(all, ival)
+
+		dur := ival.End.Sub(ival.Start)
+		waiting := unionDuration(a.dependencyIntervals(span))
+		analysis.Spans = append(analysis.Spans, SpanTiming{
+			Span:     span,
+			Duration: dur,
+			Waiting:  waiting,
+			Self:     dur - waiting,
+		})
+		analysis.Work += dur - waiting
+
+		if call := span.Call(); call != nil {
+			name := "core"
+			if mod := call.GetModule(); mod != nil {
+				name = mod.GetName()
+			}
+			stats, ok := modules[name]
+			if !ok {
+				stats = &ModuleCacheStats{Module: name}
+				modules[name] = stats
+			}
+			stats.Total++
+			if span.IsCached() {
+				stats.Cached++
+			}
+		}
+		return true
+	})
+	slices.SortStableFunc(analysis.Spans, func(a, b SpanTiming) int {
+		return cmp.Compare(b.Waiting, a.Waiting)
+	})
+	for _, stats := range modules {
+		analysis.Modules = append(analysis.Modules, *stats)
+	}
+	slices.SortFunc(analysis.Modules, func(a, b ModuleCacheStats) int {
+		return cmp.Compare(a.Module, b.Module)
+	})
+
+	analysis.Busy = unionDuration(all)
+	analysis.Idle = analysis.Duration - analysis.Busy
+
+	var path []CriticalStep
+	a.criticalPath(root, a.end, &path)
+	slices.Reverse(path)
+	analysis.CriticalPath = path
+
+	return analysis
+}
+
+type analyzer struct {
+	db  *DB
+	end time.Time
+
+	// the interval of each span, clipped to its parent's
+	intervals map[SpanID]Interval
+}
+
+func (a *analyzer) clip(span *Span, ival Interval) {
+	a.intervals[span.ID] = ival
+	for _, child := range span.ChildSpans.Order {
+		if child.StartTime.IsZero() {
+			// not received yet
+			continue
+		}
+		childIval := Interval{
+			Start: child.StartTime,
+			End:   child.EndTimeOrFallback(a.end),
+		}
+		if childIval.Start.Before(ival.Start) {
+			childIval.Start = ival.Start
+		}
+		if childIval.End.After(ival.End) {
+			childIval.End = ival.End
+		}
+		if childIval.End.Before(childIval.Start) {
+			childIval.End = childIval.Start
+		}
+		a.clip(child, childIval)
+	}
+}
+
+// callDependencies returns the spans beneath the root that ran the calls that
+// the span's call depends on in the call DAG: its receiver, and the objects
+// passed to it as arguments.
+func (a *analyzer) callDependencies(span *Span) map[SpanID]bool {
+	call := span.Call()
+	if call == nil {
+		return nil
+	}
+	digests := []string{}
+	if call.ReceiverDigest != "" {
+		digests = append(digests, call.ReceiverDigest)
+	}
+	for _, arg := range call.Args {
+		if lit, ok := arg.GetValue().GetValue().(*callpbv1.Literal_CallDigest); ok && lit != nil {
+			digests = append(digests, lit.CallDigest)
+		}
+	}
+	deps := map[SpanID]bool{}
+	for _, dgst := range digests {
+		for _, dep := range a.db.Intervals[dgst] {
+			if _, ok := a.intervals[dep.ID]; ok && dep.ID != span.ID {
+				deps[dep.ID] = true
+			}
+		}
+	}
+	return deps
+}
+
+// dependencyIntervals returns the intervals of the span's children and call
+// dependencies, clipped to the span's own interval.
+func (a *analyzer) dependencyIntervals(span *Span) []Interval {
+	own := a.intervals[span.ID]
+	var ivals []Interval
+	add := func(id SpanID) {
+		ival, ok := a.intervals[id]
+		if !ok {
+			return
+		}
+		ival.Start = later(ival.Start, own.Start)
+		ival.End = earlier(ival.End, own.End)
+		if ival.End.After(ival.Start) {
+			ivals = append(ivals, ival)
+		}
+	}
+	for _, child := range span.ChildSpans.Order {
+		add(child.ID)
+	}
+	for id := range a.callDependencies(span) {
+		add(id)
+	}
+	return ivals
+}
+
+// criticalPath walks backwards from the end of the span through its children,
+// and attributes any gaps between them to the span itself. It follows the
+// call DAG where it can: before a child, it steps to whichever of the child's
+// call dependencies among its siblings finished last, and only falls back to
+// whichever sibling finished last before the cursor otherwise. Steps are
+// appended in reverse order.
+func (a *analyzer) criticalPath(span *Span, end time.Time, path *[]CriticalStep) {
+	start := a.intervals[span.ID].Start
+	step := len(*path)
+	*path = append(*path, CriticalStep{Span: span})
+
+	cursor := end
+	var deps map[SpanID]bool
+	for cursor.After(start) {
+		var last *Span
+		var lastEnd time.Time
+		var lastIsDep bool
+		for _, child := range span.ChildSpans.Order {
+			ival, ok := a.intervals[child.ID]
+			if !ok || !ival.Start.Before(cursor) {
+				continue
+			}
+			childEnd := earlier(ival.End, cursor)
+			isDep := deps[child.ID]
+			switch {
+			case last == nil,
+				isDep && !lastIsDep,
+				isDep == lastIsDep && childEnd.After(lastEnd):
+				last, lastEnd, lastIsDep = child, childEnd, isDep
+			}
+		}
+		if last == nil {
+			(*path)[step].Self += cursor.Sub(start)
+			break
+		}
+		(*path)[step].Self += cursor.Sub(lastEnd)
+		a.criticalPath(last, lastEnd, path)
+		cursor = a.intervals[last.ID].Start
+		deps = a.callDependencies(last)
+	}
+}
+
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// unionDuration returns the total time covered by the given intervals.
+func unionDuration(ivals []Interval) time.Duration {
+	ivals = slices.Clone(ivals)
+	slices.SortFunc(ivals, func(a, b Interval) int {
+		return a.Start.Compare(b.Start)
+	})
+	var total time.Duration
+	var cur Interval
+	for i, ival := range ivals {
+		switch {
+		case i == 0:
+			cur = ival
+		case ival.Start.After(cur.End):
+			total += cur.End.Sub(cur.Start)
+			cur = ival
+		case ival.End.After(cur.End):
+			cur.End = ival.End
+		}
+	}
+	if len(ivals) > 0 {
+		total += cur.End.Sub(cur.Start)
+	}
+	return total
+}
+
+// Render writes a human-readable summary of the analysis.
+func (a *Analysis) Render(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	pct := func(d time.Duration) string {
+		if a.Duration <= 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", float64(d)/float64(a.Duration)*100)
+	}
+
+	fmt.Fprintf(tw, "Analysis of %s (%s)\n", a.Root.Name, FormatDuration(a.Duration))
+
+	steps := slices.Clone(a.CriticalPath)
+	slices.SortStableFunc(steps, func(a, b CriticalStep) int {
+		return cmp.Compare(b.Self, a.Self)
+	})
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Critical path:")
+	fmt.Fprintln(tw, "  TIME\tSHARE\tSPAN")
+	for _, step := range steps[:min(len(steps), analysisTopN)] {
+		if step.Self <= 0 {
+			break
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", FormatDuration(step.Self), pct(step.Self), callLabel(step.Span.db, step.Span))
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Waiting on dependencies:")
+	fmt.Fprintln(tw, "  WAITING\tSELF\tTOTAL\tSPAN")
+	for _, timing := range a.Spans[:min(len(a.Spans), analysisTopN)] {
+		if timing.Waiting <= 0 {
+			break
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n",
+			FormatDuration(timing.Waiting),
+			FormatDuration(timing.Self),
+			FormatDuration(timing.Duration),
+			callLabel(timing.Span.db, timing.Span))
+	}
+
+	if len(a.Modules) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "Cache hits by module:")
+		fmt.Fprintln(tw, "  MODULE\tCACHED\tCALLS\tRATIO")
+		for _, stats := range a.Modules {
+			fmt.Fprintf(tw, "  %s\t%d\t%d\t%.0f%%\n", stats.Module, stats.Cached, stats.Total, stats.HitRatio()*100)
+		}
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Engine idle: %s (%s)\n", FormatDuration(a.Idle), pct(a.Idle))
+	fmt.Fprintf(tw, "Parallelism: %.1fx\n", a.Parallelism())
+	return tw.Flush()
+}
@@ -0,0 +1,172 @@
+package dagui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"dagger.io/dagger/telemetry"
+	"github.com/dagger/dagger/dagql/call/callpbv1"
+)
+
+// analysisFixture records a run that looks like this:
+//
+//	root  |0-----------------------10|
+//	a     |0-----3|
+//	d       |1-2|
+//	b             |3--------------9|
+//	c               |4--------8|
+//
+// b's call is chained on the call made by bReceiver, if set.
+func analysisFixture(t *testing.T, bReceiver string) (*DB, SpanID) {
+	t.Helper()
+	ctx := t.Context()
+	db := NewDB()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(db))
+	tracer := tp.Tracer("test")
+	epoch := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	at := func(s int) time.Time { return epoch.Add(time.Duration(s) * time.Second) }
+
+	span := func(ctx context.Context, name string, start int, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+		return tracer.Start(ctx, name, trace.WithTimestamp(at(start)), trace.WithAttributes(attrs...))
+	}
+	call := func(field, module string, cached bool, receiver string) []attribute.KeyValue {
+		c := &callpbv1.Call{Field: field, Type: &callpbv1.Type{NamedType: "Container"}, ReceiverDigest: receiver}
+		if module != "" {
+			c.Module = &callpbv1.Module{Name: module}
+		}
+		payload, err := c.Encode()
+		require.NoError(t, err)
+		return []attribute.KeyValue{
+			attribute.String(telemetry.DagDigestAttr, field),
+			attribute.String(telemetry.DagCallAttr, payload),
+			attribute.Bool(telemetry.CachedAttr, cached),
+		}
+	}
+
+	rootCtx, root := span(ctx, "dagger call test", 0)
+	_, a := span(rootCtx, "a", 0, call("build", "app", false, "")...)
+	_, d := span(rootCtx, "d", 1, call("lint", "app", true, "")...)
+	bCtx, b := span(rootCtx, "b", 3, call("test", "", false, bReceiver)...)
+	_, c := span(bCtx, "c", 4)
+	d.End(trace.WithTimestamp(at(2)))
+	a.End(trace.WithTimestamp(at(3)))
+	c.End(trace.WithTimestamp(at(8)))
+	b.End(trace.WithTimestamp(at(9)))
+	root.End(trace.WithTimestamp(at(10)))
+	require.NoError(t, tp.Shutdown(ctx))
+
+	rootID := SpanID{root.SpanContext().SpanID()}
+	db.SetPrimarySpan(rootID)
+	return db, rootID
+}
+
+func TestAnalyze(t *testing.T) {
+	db, rootID := analysisFixture(t, "")
+
+	analysis := db.Analyze(rootID)
+	require.NotNil(t, analysis)
+	require.Equal(t, 10*time.Second, analysis.Duration)
+
+	type step struct {
+		name string
+		self time.Duration
+	}
+	var path []step
+	for _, s := range analysis.CriticalPath {
+		path = append(path, step{s.Span.Name, s.Self})
+	}
+	require.ElementsMatch(t, []step{
+		{"dagger call test", 1 * time.Second},
+		{"b", 2 * time.Second},
+		{"c", 4 * time.Second},
+		{"a", 3 * time.Second},
+	}, path)
+
+	require.Equal(t, "b", analysis.Spans[0].Span.Name)
+	require.Equal(t, 4*time.Second, analysis.Spans[0].Waiting)
+	require.Equal(t, 2*time.Second, analysis.Spans[0].Self)
+
+	require.Equal(t, 9*time.Second, analysis.Busy)
+	require.Equal(t, 1*time.Second, analysis.Idle)
+	require.Equal(t, 10*time.Second, analysis.Work)
+
+	require.Equal(t, []ModuleCacheStats{
+		{Module: "app", Cached: 1, Total: 2},
+		{Module: "core", Cached: 0, Total: 1},
+	}, analysis.Modules)
+
+	var buf bytes.Buffer
+	require.NoError(t, analysis.Render(&buf))
+	require.Contains(t, buf.String(), "Critical path:")
+	require.Contains(t, buf.String(), "build")
+	require.Contains(t, buf.String(), "app     1       2      50%")
+	require.Contains(t, buf.String(), "Engine idle: 1.0s (10%)")
+	require.Contains(t, buf.String(), "Parallelism: 1.1x")
+}
+
+func TestAnalyzeFollowsCallDAG(t *testing.T) {
+	// b is chained on d, so the critical path goes through d rather than a,
+	// even though a finished later
+	db, rootID := analysisFixture(t, "lint")
+
+	analysis := db.Analyze(rootID)
+	require.NotNil(t, analysis)
+
+	type step struct {
+		name string
+		self time.Duration
+	}
+	var path []step
+	for _, s := range analysis.CriticalPath {
+		path = append(path, step{s.Span.Name, s.Self})
+	}
+	require.Equal(t, []step{
+		{"a", 1 * time.Second},
+		{"d", 1 * time.Second},
+		{"c", 4 * time.Second},
+		{"b", 2 * time.Second},
+		{"dagger call test", 2 * time.Second},
+	}, path)
+
+	// d ran before b started, so b didn't spend any of its own time waiting
+	// on it
+	for _, timing := range analysis.Spans {
+		if timing.Span.Name == "b" {
+			require.Equal(t, 4*time.Second, timing.Waiting)
+		}
+	}
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	db, _ := analysisFixture(t, "")
+
+	var buf bytes.Buffer
+	require.NoError(t, db.writeChromeTrace(&buf))
+
+	var trace chromeTrace
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+	require.Len(t, trace.TraceEvents, 5)
+
+	byName := map[string]chromeTraceEvent{}
+	for _, ev := range trace.TraceEvents {
+		require.Equal(t, "X", ev.Phase)
+		byName[ev.Name] = ev
+	}
+	require.Equal(t, int64(0), byName["dagger call test"].TS)
+	require.Equal(t, (10 * time.Second).Microseconds(), byName["dagger call test"].Dur)
+	require.Equal(t, (3 * time.Second).Microseconds(), byName["b"].TS)
+	require.Equal(t, "cached", byName["d"].Cat)
+
+	// a and d overlap, so they can't share a thread
+	require.Equal(t, byName["dagger call test"].TID, byName["a"].TID)
+	require.NotEqual(t, byName["a"].TID, byName["d"].TID)
+	require.Equal(t, byName["b"].TID, byName["c"].TID)
+}
@@ -0,0 +1,155 @@
+package dagui
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+)
+
+// WriteChromeTrace writes the spans beneath the primary span (or all spans, if
+// there's no primary span) to the given path in the Chrome trace event format,
+// which can be loaded into Perfetto or chrome://tracing.
+func (db *DB) WriteChromeTrace(outputFilePath string) error {
+	if outputFilePath == "" {
+		return nil
+	}
+	out, err := os.Create(outputFilePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := db.writeChromeTrace(out); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+type chromeTraceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Phase string         `json:"ph"`
+	TS    int64          `json:"ts"`
+	Dur   int64          `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+func (db *DB) writeChromeTrace(w io.Writer) error {
+	var spans []*Span
+	collect := func(span *Span) bool {
+		if !span.StartTime.IsZero() {
+			spans = append(spans, span)
+		}
+		return true
+	}
+	if root, ok := db.Spans.Map[db.PrimarySpan]; ok {
+		collect(root)
+		root.Descendants(collect)
+	} else {
+		for _, span := range db.Spans.Order {
+			collect(span)
+		}
+	}
+
+	epoch := db.Epoch
+	if len(spans) > 0 {
+		epoch = spans[0].StartTime
+		for _, span := range spans {
+			if span.StartTime.Before(epoch) {
+				epoch = span.StartTime
+			}
+		}
+	}
+
+	// Complete events on the same thread must nest properly, so sort parents
+	// before their children and place each span on the first thread where it
+	// nests beneath one of its ancestors (preferring its parent's), or that's
+	// otherwise free.
+	slices.SortStableFunc(spans, func(a, b *Span) int {
+		if c := a.StartTime.Compare(b.StartTime); c != 0 {
+			return c
+		}
+		return b.EndTimeOrFallback(db.End).Compare(a.EndTimeOrFallback(db.End))
+	})
+	var threads [][]*Span // stack of open spans on each thread
+	tids := map[SpanID]int{}
+	fits := func(tid int, span *Span) bool {
+		stack := threads[tid]
+		for len(stack) > 0 && !stack[len(stack)-1].EndTimeOrFallback(db.End).After(span.StartTime) {
+			stack = stack[:len(stack)-1]
+		}
+		threads[tid] = stack
+		if len(stack) == 0 {
+			return true
+		}
+		top := stack[len(stack)-1]
+		return span.HasParent(top) &&
+			!top.EndTimeOrFallback(db.End).Before(span.EndTimeOrFallback(db.End))
+	}
+
+	trace := chromeTrace{
+		TraceEvents:     []chromeTraceEvent{},
+		DisplayTimeUnit: "ms",
+	}
+	for _, span := range spans {
+		start, end := span.StartTime, span.EndTimeOrFallback(db.End)
+		if end.Before(start) {
+			end = start
+		}
+		tid := -1
+		if parentTID, ok := tids[span.ParentID]; ok && fits(parentTID, span) {
+			tid = parentTID
+		} else {
+			for t := range threads {
+				if fits(t, span) {
+					tid = t
+					break
+				}
+			}
+		}
+		if tid == -1 {
+			tid = len(threads)
+			threads = append(threads, nil)
+		}
+		threads[tid] = append(threads[tid], span)
+		tids[span.ID] = tid
+
+		args := map[string]any{
+			"status": spanStatus(span),
+		}
+		if span.IsFailed() && span.Status.Description != "" {
+			args["error"] = span.Status.Description
+		}
+		if call := span.Call(); call != nil {
+			args["call"] = callLabel(db, span)
+			if mod := call.GetModule(); mod != nil {
+				args["module"] = mod.GetName()
+			}
+		}
+		cat := "span"
+		if span.IsCached() {
+			cat = "cached"
+		}
+		trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+			Name:  span.Name,
+			Cat:   cat,
+			Phase: "X",
+			TS:    start.Sub(epoch).Microseconds(),
+			Dur:   end.Sub(start).Microseconds(),
+			PID:   1,
+			TID:   tid + 1,
+			Args:  args,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trace)
+}
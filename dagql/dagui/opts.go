@@ -44,6 +44,14 @@ type FrontendOpts struct {
 	// DotShowInternal indicates whether to include internal steps in the DOT output
 	DotShowInternal bool
 
+	// Analyze prints a critical path and parallelism analysis of the run after
+	// execution
+	Analyze bool
+
+	// ChromeTraceOutputFilePath is the path to write a Chrome trace to after
+	// execution, if any
+	ChromeTraceOutputFilePath string
+
 	// ZoomedSpan configures a span to be zoomed in on, revealing
 	// its child spans.
 	ZoomedSpan SpanID
@@ -0,0 +1,374 @@
+package dagui
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/dagger/dagger/dagql/call/callpbv1"
+)
+
+// reportMaxLogBytes is the maximum amount of logs kept for each span in an
+// HTML report; only the tail is kept beyond that.
+const reportMaxLogBytes = 256 * 1024
+
+//go:embed report.html.tmpl
+var reportTemplateSrc string
+
+var reportTemplate = template.Must(template.New("report").Parse(reportTemplateSrc))
+
+// HTMLReport collects telemetry into a self-contained HTML report of a run,
+// with its span tree, logs, durations, cache hits and errors, and a flame
+// chart.
+type HTMLReport struct {
+	mu   sync.Mutex
+	db   *DB
+	logs map[SpanID]*bytes.Buffer
+}
+
+func NewHTMLReport() *HTMLReport {
+	return &HTMLReport{
+		db:   NewDB(),
+		logs: make(map[SpanID]*bytes.Buffer),
+	}
+}
+
+// SetPrimary sets the span the report is focused on, typically the root span
+// of a command.
+func (report *HTMLReport) SetPrimary(spanID SpanID) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.db.SetPrimarySpan(spanID)
+}
+
+func (report *HTMLReport) SpanExporter() sdktrace.SpanExporter {
+	return reportSpans{report}
+}
+
+func (report *HTMLReport) LogExporter() sdklog.Exporter {
+	return reportLogs{report}
+}
+
+type reportSpans struct {
+	*HTMLReport
+}
+
+func (report reportSpans) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	return report.db.ExportSpans(ctx, spans)
+}
+
+func (report reportSpans) Shutdown(context.Context) error { return nil }
+
+type reportLogs struct {
+	*HTMLReport
+}
+
+func (report reportLogs) Export(ctx context.Context, logs []sdklog.Record) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	for _, log := range logs {
+		body := log.Body().AsString()
+		if body == "" {
+			continue
+		}
+		spanID := SpanID{log.SpanID()}
+		buf, ok := report.logs[spanID]
+		if !ok {
+			buf = new(bytes.Buffer)
+			report.logs[spanID] = buf
+		}
+		buf.WriteString(body)
+		if over := buf.Len() - reportMaxLogBytes; over > 0 {
+			buf.Next(over)
+		}
+	}
+	return nil
+}
+
+func (report reportLogs) ForceFlush(context.Context) error { return nil }
+func (report reportLogs) Shutdown(context.Context) error   { return nil }
+
+// WriteFile writes the report to the given path.
+func (report *HTMLReport) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Write renders the report as HTML.
+func (report *HTMLReport) Write(w io.Writer) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	return reportTemplate.Execute(w, report.data())
+}
+
+type reportData struct {
+	Title    string
+	Status   string
+	Started  string
+	Duration string
+	Error    string
+	Logs     string
+
+	Spans  int
+	Cached int
+	Failed int
+
+	Tree []*reportNode
+
+	Flame      []reportBar
+	FlameLanes int
+}
+
+type reportNode struct {
+	Label    string
+	Status   string
+	Duration string
+	Error    string
+	Logs     string
+	Open     bool
+	Children []*reportNode
+}
+
+type reportBar struct {
+	Label  string
+	Status string
+	Title  string
+	Lane   int
+	Left   string
+	Width  string
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07]*\x07`)
+
+func (report *HTMLReport) data() reportData {
+	db := report.db
+	opts := FrontendOpts{
+		Verbosity:  ShowCompletedVerbosity,
+		ZoomedSpan: db.PrimarySpan,
+	}
+	root, ok := db.Spans.Map[db.PrimarySpan]
+	if !ok {
+		root = db.RootSpan
+		opts.ZoomedSpan = SpanID{}
+	}
+
+	data := reportData{Title: "Dagger run"}
+	start, end := db.Epoch, db.End
+	if root != nil {
+		data.Title = root.Name
+		data.Status = spanStatus(root)
+		start = root.StartTime
+		end = root.EndTimeOrFallback(db.End)
+		if root.IsFailed() {
+			data.Error = root.Status.Description
+		}
+		if logs, ok := report.logs[root.ID]; ok && opts.ZoomedSpan == root.ID {
+			data.Logs = ansiEscape.ReplaceAllString(logs.String(), "")
+		}
+	}
+	if end.Before(start) {
+		end = start
+	}
+	data.Started = start.Local().Format(time.DateTime)
+	data.Duration = FormatDuration(end.Sub(start))
+
+	var spans []*Span
+	var convert func(*TraceTree) *reportNode
+	convert = func(tree *TraceTree) *reportNode {
+		span := tree.Span
+		node := &reportNode{
+			Label:    callLabel(db, span),
+			Status:   spanStatus(span),
+			Duration: FormatDuration(span.EndTimeOrFallback(end).Sub(span.StartTime)),
+		}
+		if span.IsFailed() {
+			node.Error = span.Status.Description
+		}
+		if logs, ok := report.logs[span.ID]; ok {
+			node.Logs = ansiEscape.ReplaceAllString(logs.String(), "")
+		}
+		spans = append(spans, span)
+		data.Spans++
+		switch node.Status {
+		case "cached":
+			data.Cached++
+		case "failed":
+			data.Failed++
+		}
+		for _, child := range tree.Children {
+			node.Children = append(node.Children, convert(child))
+		}
+		node.Open = node.Status == "failed"
+		return node
+	}
+	for _, tree := range db.RowsView(opts).Body {
+		data.Tree = append(data.Tree, convert(tree))
+	}
+
+	data.Flame, data.FlameLanes = reportFlame(db, data.Tree, spans, start, end)
+	return data
+}
+
+// reportFlame lays out the spans of the tree as a flame chart, with spans
+// that ran in parallel at the same depth placed in separate lanes.
+func reportFlame(db *DB, tree []*reportNode, spans []*Span, start, end time.Time) ([]reportBar, int) {
+	total := end.Sub(start)
+	if total <= 0 || len(spans) == 0 {
+		return nil, 0
+	}
+
+	// recover the depth of each span, in the same order the tree was walked
+	depths := make([]int, 0, len(spans))
+	var walk func([]*reportNode, int)
+	walk = func(nodes []*reportNode, depth int) {
+		for _, node := range nodes {
+			depths = append(depths, depth)
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(tree, 0)
+
+	type placed struct {
+		span  *Span
+		depth int
+	}
+	byDepth := map[int][]placed{}
+	maxDepth := 0
+	for i, span := range spans {
+		byDepth[depths[i]] = append(byDepth[depths[i]], placed{span, depths[i]})
+		maxDepth = max(maxDepth, depths[i])
+	}
+
+	var bars []reportBar
+	lane := 0
+	for depth := 0; depth <= maxDepth; depth++ {
+		row := byDepth[depth]
+		slices.SortStableFunc(row, func(a, b placed) int {
+			return a.span.StartTime.Compare(b.span.StartTime)
+		})
+		// greedily place each span in the first lane that's free
+		var laneEnds []time.Time
+		for _, p := range row {
+			spanEnd := p.span.EndTimeOrFallback(end)
+			l := slices.IndexFunc(laneEnds, func(t time.Time) bool {
+				return !t.After(p.span.StartTime)
+			})
+			if l == -1 {
+				l = len(laneEnds)
+				laneEnds = append(laneEnds, spanEnd)
+			} else {
+				laneEnds[l] = spanEnd
+			}
+			left := float64(p.span.StartTime.Sub(start)) / float64(total) * 100
+			width := float64(spanEnd.Sub(p.span.StartTime)) / float64(total) * 100
+			label := callLabel(db, p.span)
+			bars = append(bars, reportBar{
+				Label:  label,
+				Status: spanStatus(p.span),
+				Title:  label + " (" + FormatDuration(spanEnd.Sub(p.span.StartTime)) + ")",
+				Lane:   lane + l,
+				Left:   strconv.FormatFloat(max(left, 0), 'f', 3, 64) + "%",
+				Width:  strconv.FormatFloat(max(width, 0.1), 'f', 3, 64) + "%",
+			})
+		}
+		lane += max(len(laneEnds), 1)
+	}
+	return bars, lane
+}
+
+func spanStatus(span *Span) string {
+	switch {
+	case span.IsFailedOrCausedFailure():
+		return "failed"
+	case span.IsCanceled():
+		return "canceled"
+	case span.IsCached():
+		return "cached"
+	case span.IsRunning():
+		return "running"
+	default:
+		return "ok"
+	}
+}
+
+// callLabel renders a span as its call, if it has one, or its name.
+func callLabel(db *DB, span *Span) string {
+	call := span.Call()
+	if call == nil {
+		return span.Name
+	}
+	return db.FormatCall(call)
+}
+
+// FormatCall renders a call without its receiver, e.g.
+// withExec(args: ["go", "test"]). Long strings are truncated, and IDs are
+// abbreviated to the field that produced them.
+func (db *DB) FormatCall(call *callpbv1.Call) string {
+	var args []string
+	for _, arg := range call.GetArgs() {
+		args = append(args, arg.GetName()+": "+callLiteral(db, arg.GetValue()))
+	}
+	label := call.GetField()
+	if len(args) > 0 {
+		label += "(" + strings.Join(args, ", ") + ")"
+	}
+	return label
+}
+
+func callLiteral(db *DB, lit *callpbv1.Literal) string {
+	switch v := lit.GetValue().(type) {
+	case *callpbv1.Literal_CallDigest:
+		if call := db.Call(v.CallDigest); call != nil {
+			return call.Field + "(…)"
+		}
+		return "…"
+	case *callpbv1.Literal_Null:
+		return "null"
+	case *callpbv1.Literal_Bool:
+		return strconv.FormatBool(v.Bool)
+	case *callpbv1.Literal_Enum:
+		return v.Enum
+	case *callpbv1.Literal_Int:
+		return strconv.FormatInt(v.Int, 10)
+	case *callpbv1.Literal_Float:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case *callpbv1.Literal_String_:
+		s := v.String_
+		if len(s) > 80 {
+			s = s[:80] + "…"
+		}
+		return strconv.Quote(s)
+	case *callpbv1.Literal_List:
+		var vals []string
+		for _, val := range v.List.GetValues() {
+			vals = append(vals, callLiteral(db, val))
+		}
+		return "[" + strings.Join(vals, ", ") + "]"
+	case *callpbv1.Literal_Object:
+		var fields []string
+		for _, field := range v.Object.GetValues() {
+			fields = append(fields, field.GetName()+": "+callLiteral(db, field.GetValue()))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,63 @@
+package dagui
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestHTMLReport(t *testing.T) {
+	ctx := t.Context()
+	report := NewHTMLReport()
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(report.SpanExporter()))
+	lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(report.LogExporter())))
+	tracer := tp.Tracer("test")
+	logger := lp.Logger("test")
+
+	rootCtx, root := tracer.Start(ctx, "dagger call test")
+	report.SetPrimary(SpanID{root.SpanContext().SpanID()})
+	var out log.Record
+	out.SetBody(log.StringValue("running tests\n"))
+	logger.Emit(rootCtx, out)
+
+	_, build := tracer.Start(rootCtx, "build")
+	build.End()
+
+	testCtx, test := tracer.Start(rootCtx, "run tests")
+	var rec log.Record
+	rec.SetBody(log.StringValue("\x1b[31mFAIL\x1b[0m: TestFoo <script>\n"))
+	logger.Emit(testCtx, rec)
+	test.RecordError(errors.New("exit code 1"))
+	test.SetStatus(codes.Error, "exit code 1")
+	test.End()
+
+	root.SetStatus(codes.Error, "tests failed")
+	root.End()
+	require.NoError(t, tp.Shutdown(ctx))
+	require.NoError(t, lp.Shutdown(ctx))
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf))
+	html := buf.String()
+
+	require.Contains(t, html, "<title>dagger call test</title>")
+	require.Contains(t, html, "build")
+	require.Contains(t, html, "run tests")
+	require.Contains(t, html, "exit code 1")
+	require.Contains(t, html, "tests failed")
+	require.Contains(t, html, "2 spans")
+	require.Contains(t, html, "running tests")
+	require.Contains(t, html, "FAIL: TestFoo &lt;script&gt;")
+	require.NotContains(t, html, "\x1b[")
+	require.Contains(t, html, "<details open>")
+	require.Contains(t, html, `class="bar failed"`)
+	require.NotContains(t, html, "<link")
+	require.NotContains(t, html, "<script")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/dagger/dagger/dagql/call/callpbv1"
 	"github.com/dagger/dagger/dagql/dagui"
 	"github.com/dagger/dagger/engine/session/prompt"
+	"github.com/dagger/dagger/engine/slog"
 	"github.com/dagger/dagger/util/cleanups"
 )
 
@@ -188,6 +189,22 @@ type renderer struct {
 	final         bool
 }
 
+// writeAnalysis prints an analysis of the primary span and writes a Chrome
+// trace, if requested.
+func writeAnalysis(w io.Writer, db *dagui.DB, analyze bool, chromeTracePath string) {
+	if analyze {
+		if analysis := db.Analyze(db.PrimarySpan); analysis != nil {
+			fmt.Fprintln(w)
+			if err := analysis.Render(w); err != nil {
+				slog.Warn("failed to render analysis", "error", err)
+			}
+		}
+	}
+	if err := db.WriteChromeTrace(chromeTracePath); err != nil {
+		slog.Warn("failed to write Chrome trace", "error", err)
+	}
+}
+
 func newRenderer(db *dagui.DB, maxLiteralLen int, fe dagui.FrontendOpts, final bool) *renderer {
 	return &renderer{
 		FrontendOpts:  fe,
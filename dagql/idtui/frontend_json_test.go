@@ -0,0 +1,189 @@
+package idtui
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gotest.tools/v3/golden"
+
+	"dagger.io/dagger/telemetry"
+	"github.com/dagger/dagger/dagql/call/callpbv1"
+	"github.com/dagger/dagger/dagql/dagui"
+	"github.com/dagger/dagger/util/cleanups"
+)
+
+// seqIDs generates predictable trace and span IDs, so the output is stable.
+type seqIDs struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+func (ids *seqIDs) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	var tid trace.TraceID
+	tid[15] = 1
+	return tid, ids.NewSpanID(ctx, tid)
+}
+
+func (ids *seqIDs) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	ids.n++
+	var sid trace.SpanID
+	binary.BigEndian.PutUint64(sid[:], ids.n)
+	return sid
+}
+
+// liveSyncer synchronously exports spans when they start and end, like the
+// CLI's live span processor but without batching.
+type liveSyncer struct {
+	sdktrace.SpanProcessor
+}
+
+func (p liveSyncer) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	p.SpanProcessor.OnEnd(telemetry.SnapshotSpan(span))
+}
+
+type jsonFixture struct {
+	t      *testing.T
+	epoch  time.Time
+	tracer trace.Tracer
+	logger log.Logger
+}
+
+func (f jsonFixture) at(ms int) time.Time {
+	return f.epoch.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func (f jsonFixture) start(ctx context.Context, name string, ms int, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return f.tracer.Start(ctx, name, trace.WithTimestamp(f.at(ms)), trace.WithAttributes(attrs...))
+}
+
+func (f jsonFixture) log(ctx context.Context, ms int, stream int, body string) {
+	var rec log.Record
+	rec.SetTimestamp(f.at(ms))
+	rec.SetBody(log.StringValue(body))
+	rec.AddAttributes(log.Int(telemetry.StdioStreamAttr, stream))
+	f.logger.Emit(ctx, rec)
+}
+
+func (f jsonFixture) call(digest, receiver, field string, cached bool, args ...*callpbv1.Argument) []attribute.KeyValue {
+	call := &callpbv1.Call{
+		ReceiverDigest: receiver,
+		Field:          field,
+		Args:           args,
+		Type:           &callpbv1.Type{NamedType: "Container"},
+	}
+	payload, err := call.Encode()
+	require.NoError(f.t, err)
+	return []attribute.KeyValue{
+		attribute.String(telemetry.DagDigestAttr, digest),
+		attribute.String(telemetry.DagCallAttr, payload),
+		attribute.Bool(telemetry.CachedAttr, cached),
+	}
+}
+
+func stringArg(name, val string) *callpbv1.Argument {
+	return &callpbv1.Argument{
+		Name:  name,
+		Value: &callpbv1.Literal{Value: &callpbv1.Literal_String_{String_: val}},
+	}
+}
+
+func TestJSONFrontend(t *testing.T) {
+	success := func(ctx context.Context, f jsonFixture) error {
+		_, from := f.start(ctx, "from", 10, append(
+			f.call("sha256:from", "", "container", true),
+			attribute.Bool(telemetry.UIInternalAttr, true))...)
+		from.End(trace.WithTimestamp(f.at(20)))
+
+		execCtx, exec := f.start(ctx, "withExec", 20,
+			f.call("sha256:exec", "sha256:from", "withExec", false, stringArg("args", "go test"))...)
+		f.log(execCtx, 30, 1, "ok  \tgithub.com/dagger/dagger\t0.1s\n")
+		exec.End(trace.WithTimestamp(f.at(1520)))
+
+		f.log(ctx, 1530, 1, "PASS\n")
+		return nil
+	}
+	for _, tc := range []struct {
+		name string
+		opts dagui.FrontendOpts
+		run  func(context.Context, jsonFixture) error
+	}{
+		{
+			name: "success",
+			run:  success,
+		},
+		{
+			name: "analyze",
+			opts: dagui.FrontendOpts{Analyze: true},
+			run:  success,
+		},
+		{
+			name: "failure",
+			run: func(ctx context.Context, f jsonFixture) error {
+				execCtx, exec := f.start(ctx, "withExec", 20,
+					f.call("sha256:exec", "", "withExec", false, stringArg("args", "go test"))...)
+				f.log(execCtx, 30, 2, "--- FAIL: TestFoo\n")
+				exec.SetStatus(codes.Error, "process \"go test\" did not complete successfully: exit code: 1")
+				exec.End(trace.WithTimestamp(f.at(530)))
+				return errors.New("tests failed")
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var events, stdout bytes.Buffer
+			fe := NewJSON(&events).(*frontendJSON)
+			fe.stdout = &stdout
+			epoch := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+			fe.now = func() time.Time { return epoch.Add(time.Minute) }
+
+			tp := sdktrace.NewTracerProvider(
+				sdktrace.WithSpanProcessor(liveSyncer{sdktrace.NewSimpleSpanProcessor(fe.SpanExporter())}),
+				sdktrace.WithIDGenerator(&seqIDs{}),
+			)
+			lp := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(fe.LogExporter())))
+			f := jsonFixture{
+				t:      t,
+				epoch:  epoch,
+				tracer: tp.Tracer("test"),
+				logger: lp.Logger("test"),
+			}
+
+			err := fe.Run(t.Context(), tc.opts, func(ctx context.Context) (cleanups.CleanupF, error) {
+				ctx, root := f.start(ctx, "dagger call test", 0)
+				fe.SetPrimary(dagui.SpanID{SpanID: root.SpanContext().SpanID()})
+				err := tc.run(ctx, f)
+				if err != nil {
+					root.SetStatus(codes.Error, err.Error())
+				}
+				root.End(trace.WithTimestamp(f.at(2000)))
+				require.NoError(t, tp.ForceFlush(ctx))
+				require.NoError(t, lp.ForceFlush(ctx))
+				return nil, err
+			})
+
+			golden.Assert(t, events.String(), t.Name()+".jsonl")
+			if tc.name == "failure" {
+				var exitErr ExitError
+				require.ErrorAs(t, err, &exitErr)
+				require.Equal(t, 1, exitErr.Code)
+				require.Empty(t, stdout.String())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "PASS\n", stdout.String())
+			}
+		})
+	}
+}
@@ -210,6 +210,10 @@ func (fe *frontendPlain) Run(ctx context.Context, opts dagui.FrontendOpts, run f
 
 	fe.db.WriteDot(opts.DotOutputFilePath, opts.DotFocusField, opts.DotShowInternal)
 
+	fe.mu.Lock()
+	writeAnalysis(fe.output, fe.db, opts.Analyze, opts.ChromeTraceOutputFilePath)
+	fe.mu.Unlock()
+
 	return runErr
 }
 
@@ -351,7 +351,14 @@ func (fe *frontendPretty) Run(ctx context.Context, opts dagui.FrontendOpts, run
 	}
 
 	// print the final output display to stderr
-	if renderErr := fe.FinalRender(os.Stderr); renderErr != nil {
+	renderErr := fe.FinalRender(os.Stderr)
+
+	fe.mu.Lock()
+	// reports always include the analysis, it's what they're read for
+	writeAnalysis(os.Stderr, fe.db, opts.Analyze || fe.reportOnly, opts.ChromeTraceOutputFilePath)
+	fe.mu.Unlock()
+
+	if renderErr != nil {
 		return renderErr
 	}
 
@@ -786,6 +786,11 @@ func (r ObjectResult[T]) call(
 			}
 		}
 
+		if !cacheKey.DoNotCache {
+			// record the call so that a later run missing the cache can be compared to it
+			s.Cache.RecordCall(newID)
+		}
+
 		return &CacheValWithCallbacks{
 			Value:              val,
 			PostCall:           valWithCallbacks.PostCall,
@@ -2,10 +2,18 @@ package dagql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
 
+	"github.com/opencontainers/go-digest"
+
+	"github.com/dagger/dagger/dagql/call"
 	"github.com/dagger/dagger/engine/cache"
+	cachedb "github.com/dagger/dagger/engine/cache/db"
 )
 
 type CacheKeyType = string
@@ -21,7 +29,10 @@ type SessionCache struct {
 	cache cache.Cache[CacheKeyType, CacheValueType]
 
 	results []cache.Result[CacheKeyType, CacheValueType]
-	mu      sync.Mutex
+	// ttlCallKeys are the keys of the calls with a TTL made in the session, whose
+	// metadata is persisted in the cache db and can be exported.
+	ttlCallKeys map[CacheKeyType]struct{}
+	mu          sync.Mutex
 
 	// isClosed is set to true when ReleaseAndClose is called.
 	// Any in-progress results will be released and errors returned.
@@ -31,6 +42,10 @@ type SessionCache struct {
 
 	// noCacheNext keeps track of keys for which the next cache attempt should bypass the cache.
 	noCacheNext sync.Map
+
+	// recordScope is the scope the calls run in the session are recorded
+	// under, or empty if they aren't recorded.
+	recordScope string
 }
 
 func NewSessionCache(
@@ -208,11 +223,107 @@ func (c *SessionCache) GetOrInitializeWithCallbacks(
 
 	if !key.DoNotCache || forcedDoNotCache {
 		c.results = append(c.results, res)
+		if key.TTL != 0 {
+			if c.ttlCallKeys == nil {
+				c.ttlCallKeys = map[CacheKeyType]struct{}{}
+			}
+			c.ttlCallKeys[key.CallKey] = struct{}{}
+		}
 	}
 
 	return res, nil
 }
 
+// ExportCalls returns the persisted metadata of the unexpired calls with a TTL
+// made in the session, so that it can be shared with other engines.
+func (c *SessionCache) ExportCalls(ctx context.Context) ([]*cachedb.Call, error) {
+	c.mu.Lock()
+	callKeys := slices.Collect(maps.Keys(c.ttlCallKeys))
+	c.mu.Unlock()
+	slices.Sort(callKeys)
+	return c.cache.ExportCalls(ctx, callKeys)
+}
+
+// RecordCallsAs records the calls run in the session under the given scope,
+// e.g. the client running them. Calls are only compared to earlier calls
+// recorded under the same scope.
+func (c *SessionCache) RecordCallsAs(scope string) {
+	c.recordScope = scope
+}
+
+// RecordsCalls returns whether the calls run in the session are recorded.
+func (c *SessionCache) RecordsCalls() bool {
+	return c.recordScope != ""
+}
+
+// RecordCall records the ID of a call that was run, if the session records
+// calls, so that a later call of the same shape that misses the cache can be
+// explained. The ID is encoded and written to the cache db in the background,
+// if there's room for it.
+func (c *SessionCache) RecordCall(id *call.ID) {
+	if c.recordScope == "" {
+		return
+	}
+	c.cache.RecordCall(c.recordScope, CacheKeyType(id.Digest()), func() (string, string, error) {
+		enc, err := id.Encode()
+		return id.Shape(), enc, err
+	})
+}
+
+// RecordedCalls returns the IDs of the most recently run calls with the same
+// shape as the given ID recorded under the session's scope, other than the ID
+// itself, newest first.
+func (c *SessionCache) RecordedCalls(ctx context.Context, id *call.ID, limit int) ([]*call.ID, error) {
+	if c.recordScope == "" {
+		return nil, nil
+	}
+	encs, err := c.cache.RecordedCalls(ctx, c.recordScope, id.Shape(), CacheKeyType(id.Digest()), limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*call.ID, 0, len(encs))
+	for _, enc := range encs {
+		var recorded call.ID
+		if err := recorded.Decode(enc); err != nil {
+			return nil, fmt.Errorf("decode recorded call: %w", err)
+		}
+		ids = append(ids, &recorded)
+	}
+	return ids, nil
+}
+
+// RecordContents records the digest of each path in some content, e.g. a
+// host directory, under its content digest, if the session records calls, so
+// that a later call that misses the cache because the content changed can
+// list the changed paths.
+func (c *SessionCache) RecordContents(ctx context.Context, contentDigest digest.Digest, paths map[string]digest.Digest) error {
+	if c.recordScope == "" {
+		return nil
+	}
+	enc, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+	return c.cache.RecordContents(ctx, c.recordScope, contentDigest.String(), string(enc))
+}
+
+// RecordedContents returns the digest of each path recorded for the given
+// content digest under the session's scope, or nil if none were recorded.
+func (c *SessionCache) RecordedContents(ctx context.Context, contentDigest digest.Digest) (map[string]digest.Digest, error) {
+	if c.recordScope == "" {
+		return nil, nil
+	}
+	enc, err := c.cache.RecordedContents(ctx, c.recordScope, contentDigest.String())
+	if err != nil || enc == "" {
+		return nil, err
+	}
+	var paths map[string]digest.Digest
+	if err := json.Unmarshal([]byte(enc), &paths); err != nil {
+		return nil, fmt.Errorf("decode recorded contents: %w", err)
+	}
+	return paths, nil
+}
+
 func (c *SessionCache) ReleaseAndClose(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -335,6 +335,7 @@ module.exports = {
           ],
         },
         "reference/ide-setup",
+        "reference/progress-json",
       ],
     },
   ],
@@ -81,6 +81,9 @@ type Opts struct {
 	Interactive        bool
 	InteractiveCommand []string
 
+	// Answers to module permission prompts in the session, keyed by prompt
+	PermissionResponses *sync.Map
+
 	ParentClient *Client
 }
 
@@ -616,6 +619,61 @@ func (c *Client) PromptAllowLLM(ctx context.Context, moduleRepoURL string) error
 	return fmt.Errorf("module %s was denied LLM access; pass --allow-llm=%s or --allow-llm=all to allow", moduleRepoURL, moduleRepoURL)
 }
 
+// PromptAllowModulePermission asks the user whether a module may have access
+// beyond the permissions declared for it. The answer is remembered for the rest
+// of the session, and allowing is also remembered across sessions.
+func (c *Client) PromptAllowModulePermission(ctx context.Context, moduleSource, permission string) (bool, error) {
+	key := "allow_module_permission:" + moduleSource + ":" + permission
+	if c.PermissionResponses != nil {
+		if allowed, ok := c.PermissionResponses.Load(key); ok {
+			return allowed.(bool), nil
+		}
+	}
+
+	caller, err := c.GetMainClientCaller()
+	if err != nil {
+		return false, fmt.Errorf("failed to get main client caller to prompt for module permission: %w", err)
+	}
+
+	response, err := prompt.NewPromptClient(caller.Conn()).PromptBool(ctx, &prompt.BoolRequest{
+		Title:         "Allow module access?",
+		Prompt:        fmt.Sprintf("Module **%s** requested %s, which is not in the permissions declared for it. Allow it?", moduleSource, permission),
+		PersistentKey: key,
+		Default:       false,
+	})
+	if err != nil {
+		if !prompt.IsUnavailable(err) {
+			return false, fmt.Errorf("failed to prompt user for %s from %s: %w", permission, moduleSource, err)
+		}
+		// the user can't be asked, e.g. in CI, so deny it like they would
+		response = &prompt.BoolResponse{Response: false}
+	}
+	if c.PermissionResponses != nil {
+		c.PermissionResponses.Store(key, response.Response)
+	}
+	return response.Response, nil
+}
+
+// PromptApproveToolCall asks the user whether an LLM may make a tool call
+// that requires their approval. Approvals aren't remembered, since the same
+// function may be called with different arguments.
+func (c *Client) PromptApproveToolCall(ctx context.Context, question string) (bool, error) {
+	caller, err := c.GetMainClientCaller()
+	if err != nil {
+		return false, fmt.Errorf("failed to get main client caller to prompt for tool call approval: %w", err)
+	}
+
+	response, err := prompt.NewPromptClient(caller.Conn()).PromptBool(ctx, &prompt.BoolRequest{
+		Title:   "Allow tool call?",
+		Prompt:  question,
+		Default: false,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to prompt user for tool call approval: %w", err)
+	}
+	return response.Response, nil
+}
+
 func (c *Client) PromptHumanHelp(ctx context.Context, title, question string) (string, error) {
 	caller, err := c.GetMainClientCaller()
 	if err != nil {
@@ -46,6 +46,36 @@ type Cache[K KeyType, V any] interface {
 
 	// Run a blocking loop that periodically garbage collects expired entries from the cache db.
 	GCLoop(context.Context)
+
+	// Returns the persisted metadata of the given calls that are unexpired in the cache db,
+	// so that it can be shared with other engines.
+	ExportCalls(context.Context, []K) ([]*cachedb.Call, error)
+
+	// Merges calls exported from another engine into the cache db. Unexpired local entries
+	// take precedence over imported ones.
+	ImportCalls(context.Context, []*cachedb.Call) error
+
+	// Queues a call that was run to be recorded under the given scope and its shape, with its
+	// encoded ID, so that a later call of the same shape in the same scope that misses the
+	// cache can be compared to it. It never blocks: encode is called later, and the call is
+	// dropped if too many are queued.
+	RecordCall(scope string, callKey K, encode func() (shape string, id string, err error))
+
+	// Run a blocking loop that writes queued calls to the cache db in batches.
+	RecordLoop(context.Context)
+
+	// Returns the encoded IDs of the most recently run calls with the given scope and shape,
+	// other than the given call, newest first.
+	RecordedCalls(ctx context.Context, scope, shape string, callKey K, limit int) ([]string, error)
+
+	// Records the encoded digests of the paths in some content, e.g. a host directory, under
+	// the given scope and its content digest, so that a change in content can be explained
+	// by the paths that changed.
+	RecordContents(ctx context.Context, scope, contentDigest, paths string) error
+
+	// Returns the encoded paths recorded for the given content digest in the given scope, or
+	// an empty string if none were recorded.
+	RecordedContents(ctx context.Context, scope, contentDigest string) (string, error)
 }
 
 type Result[K KeyType, V any] interface {
@@ -180,6 +210,7 @@ func NewCache[K KeyType, V any](ctx context.Context, dbPath string) (Cache[K, V]
 		db.Close()
 		return nil, fmt.Errorf("prepare queries: %w", err)
 	}
+	c.recordQueue = make(chan recordedCall, recordCallQueueSize)
 
 	return c, nil
 }
@@ -199,6 +230,9 @@ type cache[K KeyType, V any] struct {
 
 	// db for persistence; currently only used for metadata supporting ttl-based expiration
 	db *cachedb.Queries
+
+	// calls waiting to be recorded in the db by RecordLoop
+	recordQueue chan recordedCall
 }
 
 type callConcurrencyKeys struct {
@@ -276,7 +310,212 @@ func (c *cache[K, V]) GCLoop(ctx context.Context) {
 		}); err != nil {
 			slog.Warn("failed to GC expired function calls", "err", err)
 		}
+		if err := c.db.GCRecordedCalls(ctx, cachedb.GCRecordedCallsParams{
+			Keep: maxRecordedCalls,
+		}); err != nil {
+			slog.Warn("failed to GC recorded function calls", "err", err)
+		}
+		if err := c.db.GCRecordedContents(ctx, cachedb.GCRecordedContentsParams{
+			Keep: maxRecordedContents,
+		}); err != nil {
+			slog.Warn("failed to GC recorded contents", "err", err)
+		}
+	}
+}
+
+// maxRecordedCalls is the number of recorded calls kept in the cache db. It
+// needs to cover at least the calls of a couple of runs of a big pipeline.
+const maxRecordedCalls = 10_000
+
+// maxRecordedContents is the number of recorded contents kept in the cache
+// db. Contents are much bigger than calls, but only recorded for the few
+// calls that load content from the host.
+const maxRecordedContents = 1_000
+
+const (
+	// recordCallQueueSize is the number of calls that may be waiting to be
+	// recorded; calls run while the queue is full aren't recorded.
+	recordCallQueueSize = 4096
+
+	// recordCallBatchSize is the most calls written to the db at once.
+	recordCallBatchSize = 256
+)
+
+type recordedCall struct {
+	scope      string
+	callKey    string
+	encode     func() (shape string, id string, err error)
+	recordedAt int64
+}
+
+func (c *cache[K, V]) RecordCall(scope string, callKey K, encode func() (string, string, error)) {
+	if c.recordQueue == nil {
+		return
 	}
+	select {
+	case c.recordQueue <- recordedCall{
+		scope:      scope,
+		callKey:    string(callKey),
+		encode:     encode,
+		recordedAt: time.Now().UnixNano(),
+	}:
+	default:
+		// recording is only used to explain cache misses, so it's not worth
+		// holding up the call for
+	}
+}
+
+func (c *cache[K, V]) RecordLoop(ctx context.Context) {
+	if c.recordQueue == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec := <-c.recordQueue:
+			c.writeRecordedCalls(ctx, rec)
+		}
+	}
+}
+
+// flushRecordedCalls writes all the queued calls to the db.
+func (c *cache[K, V]) flushRecordedCalls(ctx context.Context) {
+	for {
+		select {
+		case rec := <-c.recordQueue:
+			c.writeRecordedCalls(ctx, rec)
+		default:
+			return
+		}
+	}
+}
+
+// writeRecordedCalls writes the given call to the db, along with a batch of
+// the calls queued after it.
+func (c *cache[K, V]) writeRecordedCalls(ctx context.Context, first recordedCall) {
+	pending := []recordedCall{first}
+drain:
+	for len(pending) < recordCallBatchSize {
+		select {
+		case rec := <-c.recordQueue:
+			pending = append(pending, rec)
+		default:
+			break drain
+		}
+	}
+
+	params := make([]cachedb.RecordCallParams, 0, len(pending))
+	// a call run more than once in the batch is only written once
+	indexes := make(map[[3]string]int, len(pending))
+	for _, rec := range pending {
+		shape, id, err := rec.encode()
+		if err != nil {
+			slog.Warn("failed to encode recorded call", "err", err)
+			continue
+		}
+		p := cachedb.RecordCallParams{
+			Scope:      rec.scope,
+			Shape:      shape,
+			CallKey:    rec.callKey,
+			ID:         id,
+			RecordedAt: rec.recordedAt,
+		}
+		key := [3]string{rec.scope, shape, rec.callKey}
+		if i, ok := indexes[key]; ok {
+			params[i] = p
+			continue
+		}
+		indexes[key] = len(params)
+		params = append(params, p)
+	}
+	if len(params) == 0 {
+		return
+	}
+	if err := c.db.RecordCalls(ctx, params); err != nil {
+		slog.Warn("failed to record function calls", "err", err)
+	}
+}
+
+func (c *cache[K, V]) RecordedCalls(ctx context.Context, scope, shape string, callKey K, limit int) ([]string, error) {
+	if c.db == nil {
+		return nil, nil
+	}
+	return c.db.SelectRecordedCalls(ctx, cachedb.SelectRecordedCallsParams{
+		Scope:   scope,
+		Shape:   shape,
+		CallKey: string(callKey),
+		Limit:   limit,
+	})
+}
+
+func (c *cache[K, V]) RecordContents(ctx context.Context, scope, contentDigest, paths string) error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.RecordContents(ctx, cachedb.RecordContentsParams{
+		Scope:         scope,
+		ContentDigest: contentDigest,
+		Paths:         paths,
+		RecordedAt:    time.Now().UnixNano(),
+	})
+}
+
+func (c *cache[K, V]) RecordedContents(ctx context.Context, scope, contentDigest string) (string, error) {
+	if c.db == nil {
+		return "", nil
+	}
+	paths, err := c.db.SelectRecordedContents(ctx, cachedb.SelectRecordedContentsParams{
+		Scope:         scope,
+		ContentDigest: contentDigest,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return paths, err
+}
+
+func (c *cache[K, V]) ExportCalls(ctx context.Context, callKeys []K) ([]*cachedb.Call, error) {
+	if c.db == nil {
+		return nil, nil
+	}
+	now := time.Now().Unix()
+	var calls []*cachedb.Call
+	for _, callKey := range callKeys {
+		call, err := c.db.SelectCall(ctx, string(callKey))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("select call %s: %w", callKey, err)
+		}
+		if call.Expiration < now {
+			continue
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+func (c *cache[K, V]) ImportCalls(ctx context.Context, calls []*cachedb.Call) error {
+	if c.db == nil {
+		return nil
+	}
+	now := time.Now().Unix()
+	for _, call := range calls {
+		if call.Expiration < now {
+			continue
+		}
+		if err := c.db.ImportCall(ctx, cachedb.ImportCallParams{
+			CallKey:    call.CallKey,
+			StorageKey: call.StorageKey,
+			Expiration: call.Expiration,
+			Now:        now,
+		}); err != nil {
+			return fmt.Errorf("import call %s: %w", call.CallKey, err)
+		}
+	}
+	return nil
 }
 
 func (c *cache[K, V]) Size() int {
@@ -2,8 +2,10 @@ package cache
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -11,6 +13,8 @@ import (
 	"golang.org/x/sync/errgroup"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
+
+	cachedb "github.com/dagger/dagger/engine/cache/db"
 )
 
 func TestCacheConcurrent(t *testing.T) {
@@ -427,3 +431,156 @@ func TestSkipDedupe(t *testing.T) {
 		t.Fatal("timed out waiting for resCh2")
 	}
 }
+
+func TestCacheExportImportCalls(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	src, err := NewCache[string, int](ctx, filepath.Join(t.TempDir(), "src.db"))
+	assert.NilError(t, err)
+	dst, err := NewCache[string, int](ctx, filepath.Join(t.TempDir(), "dst.db"))
+	assert.NilError(t, err)
+
+	now := time.Now().Unix()
+	srcDB := src.(*cache[string, int]).db
+	dstDB := dst.(*cache[string, int]).db
+	for _, call := range []cachedb.SetExpirationParams{
+		{CallKey: "fresh", StorageKey: "fresh-remote", Expiration: now + 600},
+		{CallKey: "expired", StorageKey: "expired-remote", Expiration: now - 600},
+		{CallKey: "local", StorageKey: "local-remote", Expiration: now + 600},
+		{CallKey: "stale", StorageKey: "stale-remote", Expiration: now + 600},
+	} {
+		assert.NilError(t, srcDB.SetExpiration(ctx, call))
+	}
+	for _, call := range []cachedb.SetExpirationParams{
+		{CallKey: "local", StorageKey: "local-local", Expiration: now + 60},
+		{CallKey: "stale", StorageKey: "stale-local", Expiration: now - 60},
+	} {
+		assert.NilError(t, dstDB.SetExpiration(ctx, call))
+	}
+
+	// only the requested calls are exported, if unexpired
+	calls, err := src.ExportCalls(ctx, []string{"fresh", "expired", "local", "stale", "missing"})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(calls, 3))
+	calls, err = src.ExportCalls(ctx, []string{"fresh"})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(calls, 1))
+	calls, err = src.ExportCalls(ctx, []string{"fresh", "expired", "local", "stale"})
+	assert.NilError(t, err)
+
+	assert.NilError(t, dst.ImportCalls(ctx, calls))
+
+	for callKey, storageKey := range map[string]string{
+		// not present locally, imported
+		"fresh": "fresh-remote",
+		// unexpired local entries win
+		"local": "local-local",
+		// expired local entries are replaced
+		"stale": "stale-remote",
+	} {
+		call, err := dstDB.SelectCall(ctx, callKey)
+		assert.NilError(t, err)
+		assert.Equal(t, storageKey, call.StorageKey)
+	}
+	_, err = dstDB.SelectCall(ctx, "expired")
+	assert.Assert(t, is.ErrorIs(err, sql.ErrNoRows))
+}
+
+func TestCacheRecordedCalls(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	c, err := NewCache[string, int](ctx, filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+
+	record := func(scope, shape, callKey string) {
+		c.RecordCall(scope, callKey, func() (string, string, error) {
+			return shape, callKey + "-id", nil
+		})
+	}
+	record("client", "container.withExec", "first")
+	record("client", "container.withExec", "second")
+	record("client", "container.withExec", "current")
+	record("client", "container.withEnvVariable", "other")
+	record("other-client", "container.withExec", "other-client")
+	// running a call again makes it the most recent one
+	record("client", "container.withExec", "first")
+	c.(*cache[string, int]).flushRecordedCalls(ctx)
+
+	// the call itself, calls of other shapes and calls of other clients are
+	// excluded, newest first
+	ids, err := c.RecordedCalls(ctx, "client", "container.withExec", "current", 10)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"first-id", "second-id"}, ids)
+
+	ids, err = c.RecordedCalls(ctx, "client", "container.withExec", "current", 1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"first-id"}, ids)
+
+	// only the most recently run calls are kept
+	db := c.(*cache[string, int]).db
+	assert.NilError(t, db.GCRecordedCalls(ctx, cachedb.GCRecordedCallsParams{Keep: 2}))
+	ids, err = c.RecordedCalls(ctx, "client", "container.withExec", "", 10)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"first-id"}, ids)
+}
+
+func TestCacheRecordedContents(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	c, err := NewCache[string, int](ctx, filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+
+	assert.NilError(t, c.RecordContents(ctx, "client", "sha256:a", `{"main.go":"sha256:1"}`))
+	paths, err := c.RecordedContents(ctx, "client", "sha256:a")
+	assert.NilError(t, err)
+	assert.Equal(t, `{"main.go":"sha256:1"}`, paths)
+
+	// contents recorded for other clients aren't visible
+	paths, err = c.RecordedContents(ctx, "other-client", "sha256:a")
+	assert.NilError(t, err)
+	assert.Equal(t, "", paths)
+}
+
+func TestCacheRecordCallOverflow(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+
+	c, err := NewCache[string, int](ctx, filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+
+	// calls are dropped rather than blocking when the queue is full
+	for i := range recordCallQueueSize + 10 {
+		c.RecordCall("client", fmt.Sprint(i), func() (string, string, error) {
+			return "container.withExec", fmt.Sprint(i), nil
+		})
+	}
+	c.(*cache[string, int]).flushRecordedCalls(ctx)
+
+	ids, err := c.RecordedCalls(ctx, "client", "container.withExec", "", 2*recordCallQueueSize)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(ids, recordCallQueueSize))
+}
+
+// BenchmarkCacheRecordCall measures the cost added to each cacheable call
+// by recording it.
+func BenchmarkCacheRecordCall(b *testing.B) {
+	ctx := b.Context()
+	c, err := NewCache[string, int](ctx, filepath.Join(b.TempDir(), "cache.db"))
+	assert.NilError(b, err)
+	go c.RecordLoop(ctx)
+
+	encode := func() (string, string, error) {
+		return "container.withExec", "id", nil
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.RecordCall("client", fmt.Sprint(i), encode)
+			i++
+		}
+	})
+}
@@ -1,7 +1,7 @@
 package db
 
 type Call struct {
-	CallKey    string
-	StorageKey string
-	Expiration int64
+	CallKey    string `json:"callKey"`
+	StorageKey string `json:"storageKey"`
+	Expiration int64  `json:"expiration"`
 }
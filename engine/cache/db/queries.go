@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"strings"
 )
 
 const selectCall = `SELECT call_key, storage_key, expiration FROM calls WHERE call_key = ?`
@@ -71,3 +73,166 @@ func (q *Queries) GCExpiredCalls(ctx context.Context, arg GCExpiredCallsParams)
 	}
 	return nil
 }
+
+// Insert a call imported from another engine. An existing entry is only replaced
+// if it has already expired, so that results computed locally are preferred.
+const importCall = `
+INSERT INTO calls (call_key, storage_key, expiration)
+VALUES (?, ?, ?)
+ON CONFLICT (call_key) DO UPDATE SET
+	expiration = EXCLUDED.expiration,
+	storage_key = EXCLUDED.storage_key
+WHERE calls.expiration < ?
+`
+
+type ImportCallParams struct {
+	CallKey    string
+	StorageKey string
+	Expiration int64
+	Now        int64
+}
+
+func (q *Queries) ImportCall(ctx context.Context, arg ImportCallParams) error {
+	_, err := q.exec(ctx, nil, importCall,
+		arg.CallKey, arg.StorageKey, arg.Expiration, arg.Now,
+	)
+	return err
+}
+
+// Record calls that were run, or bump the time they were last run at.
+const recordCalls = `
+INSERT INTO recorded_calls (scope, shape, call_key, id, recorded_at)
+VALUES %s
+ON CONFLICT (scope, shape, call_key) DO UPDATE SET
+	id = EXCLUDED.id,
+	recorded_at = EXCLUDED.recorded_at
+`
+
+type RecordCallParams struct {
+	Scope      string
+	Shape      string
+	CallKey    string
+	ID         string
+	RecordedAt int64
+}
+
+// RecordCalls records a batch of calls in a single statement. Each call must
+// only appear once.
+func (q *Queries) RecordCalls(ctx context.Context, args []RecordCallParams) error {
+	if len(args) == 0 {
+		return nil
+	}
+	values := make([]any, 0, 5*len(args))
+	for _, arg := range args {
+		values = append(values, arg.Scope, arg.Shape, arg.CallKey, arg.ID, arg.RecordedAt)
+	}
+	rows := strings.Repeat("(?, ?, ?, ?, ?), ", len(args))
+	_, err := q.exec(ctx, nil, fmt.Sprintf(recordCalls, strings.TrimSuffix(rows, ", ")), values...)
+	return err
+}
+
+const selectRecordedCalls = `
+SELECT id FROM recorded_calls
+WHERE scope = ? AND shape = ? AND call_key != ?
+ORDER BY recorded_at DESC
+LIMIT ?
+`
+
+type SelectRecordedCallsParams struct {
+	Scope   string
+	Shape   string
+	CallKey string
+	Limit   int
+}
+
+// SelectRecordedCalls returns the IDs of the most recently run calls with the
+// given scope and shape, other than the given call, newest first.
+func (q *Queries) SelectRecordedCalls(ctx context.Context, arg SelectRecordedCallsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, selectRecordedCalls, arg.Scope, arg.Shape, arg.CallKey, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Only keep the most recently run calls.
+const gcRecordedCalls = `
+DELETE FROM recorded_calls
+WHERE recorded_at <= (
+	SELECT recorded_at FROM recorded_calls
+	ORDER BY recorded_at DESC
+	LIMIT 1 OFFSET ?
+)`
+
+type GCRecordedCallsParams struct {
+	Keep int
+}
+
+func (q *Queries) GCRecordedCalls(ctx context.Context, arg GCRecordedCallsParams) error {
+	_, err := q.exec(ctx, nil, gcRecordedCalls, arg.Keep)
+	return err
+}
+
+// Record the paths in some content, or bump the time they were last recorded at.
+const recordContents = `
+INSERT INTO recorded_contents (scope, content_digest, paths, recorded_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (scope, content_digest) DO UPDATE SET
+	paths = EXCLUDED.paths,
+	recorded_at = EXCLUDED.recorded_at
+`
+
+type RecordContentsParams struct {
+	Scope         string
+	ContentDigest string
+	Paths         string
+	RecordedAt    int64
+}
+
+func (q *Queries) RecordContents(ctx context.Context, arg RecordContentsParams) error {
+	_, err := q.exec(ctx, nil, recordContents, arg.Scope, arg.ContentDigest, arg.Paths, arg.RecordedAt)
+	return err
+}
+
+const selectRecordedContents = `
+SELECT paths FROM recorded_contents
+WHERE scope = ? AND content_digest = ?
+`
+
+type SelectRecordedContentsParams struct {
+	Scope         string
+	ContentDigest string
+}
+
+func (q *Queries) SelectRecordedContents(ctx context.Context, arg SelectRecordedContentsParams) (string, error) {
+	var paths string
+	err := q.queryRow(ctx, nil, selectRecordedContents, arg.Scope, arg.ContentDigest).Scan(&paths)
+	return paths, err
+}
+
+// Only keep the most recently recorded contents.
+const gcRecordedContents = `
+DELETE FROM recorded_contents
+WHERE recorded_at <= (
+	SELECT recorded_at FROM recorded_contents
+	ORDER BY recorded_at DESC
+	LIMIT 1 OFFSET ?
+)`
+
+type GCRecordedContentsParams struct {
+	Keep int
+}
+
+func (q *Queries) GCRecordedContents(ctx context.Context, arg GCRecordedContentsParams) error {
+	_, err := q.exec(ctx, nil, gcRecordedContents, arg.Keep)
+	return err
+}
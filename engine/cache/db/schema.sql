@@ -5,3 +5,32 @@ CREATE TABLE IF NOT EXISTS calls (
 ) STRICT, WITHOUT ROWID;
 
 CREATE INDEX IF NOT EXISTS calls_exp_idx ON calls(expiration);
+
+-- The encoded IDs of calls that were run, keyed by the client they were run
+-- for and their shape (the chain of fields selected to get to them), so that a
+-- call that missed the cache can be compared to earlier calls of the same
+-- pipeline step by the same client.
+CREATE TABLE IF NOT EXISTS recorded_calls (
+    scope TEXT NOT NULL,
+    shape TEXT NOT NULL,
+    call_key TEXT NOT NULL,
+    id TEXT NOT NULL,
+    recorded_at INTEGER NOT NULL,
+    PRIMARY KEY (scope, shape, call_key)
+) STRICT, WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS recorded_calls_shape_idx ON recorded_calls(scope, shape, recorded_at);
+CREATE INDEX IF NOT EXISTS recorded_calls_time_idx ON recorded_calls(recorded_at);
+
+-- The digest of each path in recorded content, e.g. a host directory, keyed by
+-- the client it was loaded for and its content digest, so that a call that
+-- missed the cache because the content changed can list the changed paths.
+CREATE TABLE IF NOT EXISTS recorded_contents (
+    scope TEXT NOT NULL,
+    content_digest TEXT NOT NULL,
+    paths TEXT NOT NULL,
+    recorded_at INTEGER NOT NULL,
+    PRIMARY KEY (scope, content_digest)
+) STRICT, WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS recorded_contents_time_idx ON recorded_contents(recorded_at);
@@ -94,12 +94,17 @@ type Params struct {
 
 	SecretToken string
 
-	RunnerHost string // host of dagger engine runner serving buildkit apis
+	RunnerHost string            // host of dagger engine runner serving buildkit apis
+	RunnerTLS  drivers.TLSConfig // TLS files for connecting to a tcp+tls runner host
 
 	DisableHostRW bool
 
 	CloudURLCallback func(context.Context, string, string, bool)
 
+	// HostPathSyncCallback, if set, is called for each host path read by the
+	// engine during the session, e.g. to watch them for changes.
+	HostPathSyncCallback func(HostPathSync)
+
 	EngineTrace   sdktrace.SpanExporter
 	EngineLogs    sdklog.Exporter
 	EngineMetrics []sdkmetric.Exporter
@@ -114,6 +119,9 @@ type Params struct {
 
 	AllowedLLMModules []string
 
+	// Serve remote resources from the cache or a mirror, never the network
+	Offline bool
+
 	PromptHandler prompt.PromptHandler
 
 	Stdin  io.Reader
@@ -420,6 +428,8 @@ func (c *Client) startEngine(ctx context.Context, params Params) (rerr error) {
 		}
 	}
 
+	repository, _ := c.labels.Get("dagger.io/git.remote")
+
 	provisionCtx, provisionSpan := Tracer(ctx).Start(ctx, "starting engine")
 	provisionCtx, provisionCancel := context.WithTimeout(provisionCtx, 10*time.Minute)
 	c.connector, err = driver.Provision(provisionCtx, remote, &drivers.DriverOpts{
@@ -430,6 +440,8 @@ func (c *Client) startEngine(ctx context.Context, params Params) (rerr error) {
 		ExecCmd:          params.ExecCmd,
 		ClientID:         c.ID,
 		CloudAuth:        params.CloudAuth,
+		TLS:              params.RunnerTLS,
+		Repository:       repository,
 	})
 	provisionCancel()
 	telemetry.EndWithCause(provisionSpan, &err)
@@ -537,6 +549,9 @@ func (c *Client) startSession(ctx context.Context) (rerr error) {
 		if err != nil {
 			return fmt.Errorf("new filesyncer: %w", err)
 		}
+		if c.HostPathSyncCallback != nil {
+			filesyncer = filesyncer.WithSyncCallback(c.HostPathSyncCallback)
+		}
 		attachables = append(attachables, filesyncer.AsSource(), filesyncer.AsTarget())
 	}
 	if c.Params.PromptHandler != nil {
@@ -1390,11 +1405,12 @@ func (c *Client) clientMetadata() engine.ClientMetadata {
 		UpstreamCacheExportConfig: c.upstreamCacheExportOptions,
 		Labels:                    c.labels.AsMap(),
 		CloudOrg:                  cloudOrg,
-		DoNotTrack:                analytics.DoNotTrack(),
+		DoNotTrack:                analytics.DoNotTrack() || c.Offline,
 		Interactive:               c.Interactive,
 		InteractiveCommand:        c.InteractiveCommand,
 		SSHAuthSocketPath:         sshAuthSock,
 		AllowedLLMModules:         c.AllowedLLMModules,
+		Offline:                   c.Offline,
 		EagerRuntime:              c.EagerRuntime,
 		CloudAuth:                 c.CloudAuth,
 		EnableCloudScaleOut:       c.EnableCloudScaleOut,
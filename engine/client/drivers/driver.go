@@ -43,11 +43,17 @@ type DriverOpts struct {
 	ExecCmd          []string
 	ClientID         string
 	CloudAuth        *auth.Cloud
+	TLS              TLSConfig
+	// The git remote of the repository the client runs in, if any
+	Repository string
 }
 
 const (
 	EnvDaggerCloudToken = "DAGGER_CLOUD_TOKEN"
 	EnvGPUSupport       = "_EXPERIMENTAL_DAGGER_GPU_SUPPORT"
+	EnvRunnerTLSCACert  = "_EXPERIMENTAL_DAGGER_RUNNER_TLSCACERT"
+	EnvRunnerTLSCert    = "_EXPERIMENTAL_DAGGER_RUNNER_TLSCERT"
+	EnvRunnerTLSKey     = "_EXPERIMENTAL_DAGGER_RUNNER_TLSKEY"
 )
 
 var drivers = map[string][]Driver{}
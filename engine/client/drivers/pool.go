@@ -0,0 +1,123 @@
+package drivers
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/dagger/dagger/engine/client/imageload"
+	"github.com/dagger/dagger/engine/slog"
+)
+
+func init() {
+	register("pool", &poolDriver{})
+}
+
+const defaultPoolHealthTimeout = 5 * time.Second
+
+// poolDriver connects to one of a fleet of engines, e.g.
+//
+//	pool://?endpoint=unix:///run/dagger/a.sock&endpoint=tcp+tls://b.example.com:1234
+//
+// Clients working on the same module (or in the same repository) are routed
+// to the same engine, so they can reuse its cache. Engines are ranked for each
+// client by rendezvous hashing, so only clients routed to an engine that's
+// added or removed from the pool are routed elsewhere. Engines that fail their
+// health check are skipped in favor of the next in the ranking.
+//
+// The routing key can be set explicitly with the "key" query param, and the
+// health check timeout with "healthTimeout".
+type poolDriver struct{}
+
+func (d *poolDriver) Available(ctx context.Context) (bool, error) {
+	return true, nil // assume always available
+}
+
+func (d *poolDriver) Provision(ctx context.Context, target *url.URL, opts *DriverOpts) (Connector, error) {
+	query := target.Query()
+	endpoints := query["endpoint"]
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints in %s", target)
+	}
+	healthTimeout := defaultPoolHealthTimeout
+	if v := query.Get("healthTimeout"); v != "" {
+		var err error
+		healthTimeout, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid healthTimeout: %w", err)
+		}
+	}
+
+	var key string
+	if opts != nil {
+		key = cmp.Or(opts.Module, opts.Repository)
+	}
+	key = cmp.Or(query.Get("key"), key)
+
+	var errs error
+	for _, endpoint := range rankPoolEndpoints(key, endpoints) {
+		connector, err := provisionPoolEndpoint(ctx, endpoint, opts, healthTimeout)
+		if err != nil {
+			slog.Warn("skipping pool engine", "endpoint", endpoint, "error", err)
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", endpoint, err))
+			continue
+		}
+		slog.Debug("selected pool engine", "endpoint", endpoint, "key", key)
+		return connector, nil
+	}
+	return nil, fmt.Errorf("no engine in the pool is available: %w", errs)
+}
+
+func (d *poolDriver) ImageLoader(ctx context.Context) imageload.Backend {
+	return nil
+}
+
+// provisionPoolEndpoint provisions a connector for an engine in the pool,
+// checking that the engine accepts connections.
+func provisionPoolEndpoint(ctx context.Context, endpoint string, opts *DriverOpts, healthTimeout time.Duration) (Connector, error) {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme == "pool" {
+		return nil, errors.New("pools cannot be nested")
+	}
+	driver, err := GetDriver(ctx, target.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := driver.Provision(ctx, target, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	healthCtx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	conn, err := connector.Connect(healthCtx)
+	if err != nil {
+		return nil, fmt.Errorf("health check: %w", err)
+	}
+	conn.Close()
+
+	return connector, nil
+}
+
+// rankPoolEndpoints orders endpoints by their rendezvous hash with the key,
+// highest first.
+func rankPoolEndpoints(key string, endpoints []string) []string {
+	weight := func(endpoint string) uint64 {
+		sum := sha256.Sum256([]byte(key + "\x00" + endpoint))
+		return binary.BigEndian.Uint64(sum[:8])
+	}
+	ranked := slices.Clone(endpoints)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		return cmp.Compare(weight(b), weight(a))
+	})
+	return slices.Compact(ranked)
+}
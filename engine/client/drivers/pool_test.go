@@ -0,0 +1,171 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// poolEngine is a stand-in for an engine listening on a unix socket, which
+// records the connections it accepts
+type poolEngine struct {
+	endpoint string
+	listener net.Listener
+	accepted chan struct{}
+}
+
+func newPoolEngine(t *testing.T, dir, name string) *poolEngine {
+	t.Helper()
+	path := filepath.Join(dir, name+".sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	e := &poolEngine{
+		endpoint: "unix://" + path,
+		listener: l,
+		accepted: make(chan struct{}, 100),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			e.accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+	return e
+}
+
+func poolURL(t *testing.T, engines []*poolEngine, params string) *url.URL {
+	t.Helper()
+	query := url.Values{}
+	for _, e := range engines {
+		query.Add("endpoint", e.endpoint)
+	}
+	u, err := url.Parse("pool://?" + query.Encode() + params)
+	require.NoError(t, err)
+	return u
+}
+
+// connectedEngine connects through the connector and returns the engine that
+// accepted the connection
+func connectedEngine(t *testing.T, connector Connector, engines []*poolEngine) *poolEngine {
+	t.Helper()
+	for _, e := range engines {
+		for len(e.accepted) > 0 {
+			<-e.accepted
+		}
+	}
+	conn, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	conn.Close()
+	var accepted *poolEngine
+	require.Eventually(t, func() bool {
+		for _, e := range engines {
+			select {
+			case <-e.accepted:
+				accepted = e
+				return true
+			default:
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	return accepted
+}
+
+func TestPoolDriver(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var engines []*poolEngine
+	for i := range 3 {
+		engines = append(engines, newPoolEngine(t, dir, fmt.Sprintf("engine-%d", i)))
+	}
+	target := poolURL(t, engines, "")
+
+	t.Run("affinity", func(t *testing.T) {
+		chosen := map[*poolEngine]bool{}
+		for i := range 20 {
+			module := fmt.Sprintf("github.com/acme/mods/mod-%d", i)
+			first, err := (&poolDriver{}).Provision(ctx, target, &DriverOpts{Module: module})
+			require.NoError(t, err)
+			engine := connectedEngine(t, first, engines)
+
+			again, err := (&poolDriver{}).Provision(ctx, target, &DriverOpts{Module: module})
+			require.NoError(t, err)
+			require.Same(t, engine, connectedEngine(t, again, engines))
+			chosen[engine] = true
+		}
+		// modules are spread across the pool
+		require.Greater(t, len(chosen), 1)
+	})
+
+	t.Run("key param", func(t *testing.T) {
+		keyed := poolURL(t, engines, "&key=my-repo")
+		byKey, err := (&poolDriver{}).Provision(ctx, keyed, &DriverOpts{Module: "github.com/acme/mods/foo"})
+		require.NoError(t, err)
+		byRepo, err := (&poolDriver{}).Provision(ctx, target, &DriverOpts{Repository: "my-repo"})
+		require.NoError(t, err)
+		require.Same(t, connectedEngine(t, byRepo, engines), connectedEngine(t, byKey, engines))
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		dir := t.TempDir()
+		var engines []*poolEngine
+		for i := range 3 {
+			engines = append(engines, newPoolEngine(t, dir, fmt.Sprintf("engine-%d", i)))
+		}
+		target := poolURL(t, engines, "&healthTimeout=1s")
+		opts := &DriverOpts{Module: "github.com/acme/mods/foo"}
+
+		connector, err := (&poolDriver{}).Provision(ctx, target, opts)
+		require.NoError(t, err)
+		down := connectedEngine(t, connector, engines)
+		down.listener.Close()
+
+		connector, err = (&poolDriver{}).Provision(ctx, target, opts)
+		require.NoError(t, err)
+		fallback := connectedEngine(t, connector, engines)
+		require.NotSame(t, down, fallback)
+
+		// the fallback is the next engine in the ranking for the key
+		ranked := rankPoolEndpoints(opts.Module, target.Query()["endpoint"])
+		require.Equal(t, down.endpoint, ranked[0])
+		require.Equal(t, fallback.endpoint, ranked[1])
+
+		for _, e := range engines {
+			e.listener.Close()
+		}
+		_, err = (&poolDriver{}).Provision(ctx, target, opts)
+		require.ErrorContains(t, err, "no engine in the pool is available")
+	})
+
+	t.Run("no endpoints", func(t *testing.T) {
+		_, err := (&poolDriver{}).Provision(ctx, &url.URL{Scheme: "pool"}, &DriverOpts{})
+		require.ErrorContains(t, err, "no endpoints")
+	})
+}
+
+func TestRankPoolEndpoints(t *testing.T) {
+	endpoints := []string{"unix:///a.sock", "unix:///b.sock", "unix:///c.sock", "unix:///d.sock"}
+	for i := range 50 {
+		key := fmt.Sprintf("key-%d", i)
+		ranked := rankPoolEndpoints(key, endpoints)
+		require.ElementsMatch(t, endpoints, ranked)
+
+		// removing an engine only moves the keys that were routed to it
+		without := rankPoolEndpoints(key, endpoints[1:])
+		if ranked[0] != endpoints[0] {
+			require.Equal(t, ranked[0], without[0])
+		}
+	}
+}
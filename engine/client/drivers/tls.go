@@ -0,0 +1,116 @@
+package drivers
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/dagger/dagger/engine/client/imageload"
+)
+
+func init() {
+	register("tcp+tls", &tlsDriver{})
+}
+
+// TLSConfig holds the paths of the PEM files used to connect to an engine
+// over TLS.
+type TLSConfig struct {
+	// CACert verifies the engine's certificate, instead of the system roots.
+	CACert string
+	// Cert and Key are presented to the engine as the client certificate,
+	// for engines that require mutual TLS.
+	Cert string
+	Key  string
+}
+
+// tlsConfigFromEnv returns the TLS config set in the environment, used for
+// any files not set explicitly.
+func tlsConfigFromEnv() TLSConfig {
+	return TLSConfig{
+		CACert: os.Getenv(EnvRunnerTLSCACert),
+		Cert:   os.Getenv(EnvRunnerTLSCert),
+		Key:    os.Getenv(EnvRunnerTLSKey),
+	}
+}
+
+// clientConfig loads the files into a TLS config for connecting to the given
+// host.
+func (cfg TLSConfig) clientConfig(serverName string) (*tls.Config, error) {
+	tlsConf := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.CACert != "" {
+		ca, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca certificate: %w", err)
+		}
+		certPool := x509.NewCertPool()
+		if ok := certPool.AppendCertsFromPEM(ca); !ok {
+			return nil, errors.New("failed to append ca cert")
+		}
+		tlsConf.RootCAs = certPool
+	}
+	if (cfg.Cert == "") != (cfg.Key == "") {
+		return nil, errors.New("you must specify key and cert file if one is specified")
+	}
+	if cfg.Cert != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client key pair: %w", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConf, nil
+}
+
+// tlsDriver connects to an engine listening on tcp with TLS enabled (i.e.
+// started with --tlscert and --tlskey), verifying the engine's certificate
+// and presenting a client certificate if configured.
+type tlsDriver struct{}
+
+func (d *tlsDriver) Available(ctx context.Context) (bool, error) {
+	return true, nil // assume always available
+}
+
+func (d *tlsDriver) Provision(ctx context.Context, target *url.URL, opts *DriverOpts) (Connector, error) {
+	if target.Host == "" {
+		return nil, fmt.Errorf("invalid address %s", target)
+	}
+	cfg := tlsConfigFromEnv()
+	if opts != nil {
+		cfg.CACert = cmp.Or(opts.TLS.CACert, cfg.CACert)
+		cfg.Cert = cmp.Or(opts.TLS.Cert, cfg.Cert)
+		cfg.Key = cmp.Or(opts.TLS.Key, cfg.Key)
+	}
+	tlsConf, err := cfg.clientConfig(target.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	return tlsConnector{addr: target.Host, config: tlsConf}, nil
+}
+
+func (d *tlsDriver) ImageLoader(ctx context.Context) imageload.Backend {
+	return nil
+}
+
+type tlsConnector struct {
+	addr   string
+	config *tls.Config
+}
+
+func (d tlsConnector) Connect(ctx context.Context) (net.Conn, error) {
+	dialer := &tls.Dialer{Config: d.config}
+	return dialer.DialContext(ctx, "tcp", d.addr)
+}
+
+func (d tlsConnector) EngineID() string {
+	// not supported yet
+	return ""
+}
@@ -0,0 +1,156 @@
+package drivers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, tmpl *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := tmpl, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// writeFiles writes the cert and key as PEM files, returning their paths
+func (c *testCert) writeFiles(t *testing.T, name string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, name+".pem")
+	keyPath := filepath.Join(dir, name+"-key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certPath, keyPath
+}
+
+func TestTLSDriver(t *testing.T) {
+	ca := newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	serverCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "engine"},
+		DNSNames:    []string{"localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "ci", Organization: []string{"team-a"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.cert)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caPool,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	subjects := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			if err := tlsConn.Handshake(); err == nil {
+				subjects <- tlsConn.ConnectionState().PeerCertificates[0].Subject.String()
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	target, err := url.Parse("tcp+tls://localhost:" + port)
+	require.NoError(t, err)
+
+	caPath, _ := ca.writeFiles(t, "ca")
+	certPath, keyPath := clientCert.writeFiles(t, "client")
+
+	t.Run("mutual TLS", func(t *testing.T) {
+		ctx := context.Background()
+		connector, err := (&tlsDriver{}).Provision(ctx, target, &DriverOpts{
+			TLS: TLSConfig{CACert: caPath, Cert: certPath, Key: keyPath},
+		})
+		require.NoError(t, err)
+		conn, err := connector.Connect(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, "CN=ci,O=team-a", <-subjects)
+	})
+
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv(EnvRunnerTLSCACert, caPath)
+		t.Setenv(EnvRunnerTLSCert, certPath)
+		t.Setenv(EnvRunnerTLSKey, keyPath)
+		ctx := context.Background()
+		connector, err := (&tlsDriver{}).Provision(ctx, target, &DriverOpts{})
+		require.NoError(t, err)
+		conn, err := connector.Connect(ctx)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, "CN=ci,O=team-a", <-subjects)
+	})
+
+	t.Run("unknown server", func(t *testing.T) {
+		ctx := context.Background()
+		connector, err := (&tlsDriver{}).Provision(ctx, target, &DriverOpts{
+			TLS: TLSConfig{Cert: certPath, Key: keyPath},
+		})
+		require.NoError(t, err)
+		_, err = connector.Connect(ctx)
+		require.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := (&tlsDriver{}).Provision(context.Background(), target, &DriverOpts{
+			TLS: TLSConfig{CACert: caPath, Cert: certPath},
+		})
+		require.ErrorContains(t, err, "you must specify key and cert file")
+	})
+}
@@ -27,6 +27,17 @@ import (
 
 type Filesyncer struct {
 	uid, gid uint32
+
+	// onSync, if set, is called for each host path read by the engine.
+	onSync func(HostPathSync)
+}
+
+// HostPathSync describes a host path that was read by the engine, along with
+// the patterns that were used to filter it.
+type HostPathSync struct {
+	Path            string
+	IncludePatterns []string
+	ExcludePatterns []string
 }
 
 func NewFilesyncer() (Filesyncer, error) {
@@ -38,6 +49,13 @@ func NewFilesyncer() (Filesyncer, error) {
 	return f, nil
 }
 
+// WithSyncCallback returns a copy of the Filesyncer that calls fn for each
+// host path read by the engine.
+func (f Filesyncer) WithSyncCallback(fn func(HostPathSync)) Filesyncer {
+	f.onSync = fn
+	return f
+}
+
 func (f Filesyncer) AsSource() FilesyncSource {
 	return FilesyncSource(f)
 }
@@ -89,6 +107,10 @@ func (s FilesyncSource) DiffCopy(stream filesync.FileSync_DiffCopyServer) error
 		return stream.SendMsg(stat)
 
 	case opts.ReadSingleFileOnly:
+		if s.onSync != nil {
+			s.onSync(HostPathSync{Path: absPath})
+		}
+
 		// just stream the file bytes to the caller
 		fileContents, err := os.ReadFile(absPath)
 		if err != nil {
@@ -105,6 +127,13 @@ func (s FilesyncSource) DiffCopy(stream filesync.FileSync_DiffCopyServer) error
 
 	default:
 		// otherwise, do the whole directory sync back to the caller
+		if s.onSync != nil {
+			s.onSync(HostPathSync{
+				Path:            absPath,
+				IncludePatterns: opts.IncludePatterns,
+				ExcludePatterns: opts.ExcludePatterns,
+			})
+		}
 		fs, err := fsutil.NewFS(absPath)
 		if err != nil {
 			return err
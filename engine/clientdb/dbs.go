@@ -21,6 +21,10 @@ import (
 type DBs struct {
 	Root string
 
+	// Retention is the time after which a database that's no longer written
+	// to is considered garbage. Defaults to CollectGarbageAfter.
+	Retention time.Duration
+
 	open map[string]*DB
 	mu   sync.RWMutex // mutex just for reading writing map
 
@@ -34,6 +38,7 @@ const CollectGarbageAfter = time.Hour
 func NewDBs(root string) *DBs {
 	return &DBs{
 		Root:      root,
+		Retention: CollectGarbageAfter,
 		open:      make(map[string]*DB),
 		perDBLock: locker.New(),
 	}
@@ -156,7 +161,7 @@ func (dbs *DBs) close(db *DB, lg *slog.Logger) (rerr error) {
 	return rerr
 }
 
-// GC removes databases that are older than CollectGarbageAfter based on mtime.
+// GC removes databases that are older than the retention based on mtime.
 func (dbs *DBs) GC(keep map[string]bool) error {
 	ents, err := os.ReadDir(dbs.Root)
 	if err != nil {
@@ -181,7 +186,7 @@ func (dbs *DBs) GC(keep map[string]bool) error {
 			// client still active; keep it around
 			continue
 		}
-		if time.Since(info.ModTime()) < CollectGarbageAfter {
+		if time.Since(info.ModTime()) < dbs.Retention {
 			// DB is still fresh; keep
 			continue
 		}
@@ -3,6 +3,7 @@ package clientdb
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -85,3 +86,25 @@ func TestOpenWithNonDirRoot(t *testing.T) {
 	_, err := dbs.Open(t.Context(), "client1")
 	require.Error(t, err)
 }
+
+func TestGCRetention(t *testing.T) {
+	root := t.TempDir()
+	dbs := NewDBs(root)
+	dbs.Retention = 24 * time.Hour
+
+	for _, clientID := range []string{"old", "recent", "active"} {
+		db, err := dbs.Open(t.Context(), clientID)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+	}
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	for _, clientID := range []string{"old", "active"} {
+		require.NoError(t, os.Chtimes(dbs.path(clientID), twoDaysAgo, twoDaysAgo))
+	}
+	require.NoError(t, os.Chtimes(dbs.path("recent"), time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)))
+
+	require.NoError(t, dbs.GC(map[string]bool{"active": true}))
+	require.NoFileExists(t, dbs.path("old"))
+	require.FileExists(t, dbs.path("recent"))
+	require.FileExists(t, dbs.path("active"))
+}
@@ -1,11 +1,15 @@
 package clientdb
 
 import (
+	"database/sql"
+	"fmt"
 	"log/slog"
 
 	"dagger.io/dagger/telemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkresource "go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/trace"
 	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
@@ -125,3 +129,64 @@ func LogsToPB(dbLog []Log) []*otlplogsv1.ResourceLogs {
 	}
 	return rss
 }
+
+// InsertLogParamsFromRecord converts a log record into the params for
+// inserting it.
+func InsertLogParamsFromRecord(rec *sdklog.Record) (*InsertLogParams, error) {
+	traceID := rec.TraceID().String()
+	spanID := rec.SpanID().String()
+	timestamp := rec.Timestamp().UnixNano()
+	severity := int64(rec.Severity())
+
+	var body []byte
+	if !rec.Body().Empty() {
+		var err error
+		body, err = proto.Marshal(telemetry.LogValueToPB(rec.Body()))
+		if err != nil {
+			return nil, fmt.Errorf("marshal log record body: %w", err)
+		}
+	}
+
+	attrs := []*otlpcommonv1.KeyValue{}
+	rec.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs = append(attrs, &otlpcommonv1.KeyValue{
+			Key:   kv.Key,
+			Value: telemetry.LogValueToPB(kv.Value),
+		})
+		return true
+	})
+	attributes, err := MarshalProtoJSONs(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal log record attributes: %w", err)
+	}
+
+	scope, err := protojson.Marshal(telemetry.InstrumentationScopeToPB(rec.InstrumentationScope()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal log record instrumentation scope: %w", err)
+	}
+
+	res := rec.Resource()
+	resource, err := protojson.Marshal(telemetry.ResourcePtrToPB(res))
+	if err != nil {
+		return nil, fmt.Errorf("marshal log record resource: %w", err)
+	}
+
+	return &InsertLogParams{
+		TraceID: sql.NullString{
+			String: traceID,
+			Valid:  rec.TraceID().IsValid(),
+		},
+		SpanID: sql.NullString{
+			String: spanID,
+			Valid:  rec.SpanID().IsValid(),
+		},
+		Timestamp:            timestamp,
+		SeverityNumber:       severity,
+		SeverityText:         rec.SeverityText(),
+		Body:                 body,
+		Attributes:           attributes,
+		InstrumentationScope: scope,
+		Resource:             resource,
+		ResourceSchemaUrl:    res.SchemaURL(),
+	}, nil
+}
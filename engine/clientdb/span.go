@@ -1,6 +1,7 @@
 package clientdb
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -209,3 +210,60 @@ func (ros *readOnlySpan) InstrumentationScope() instrumentation.Scope {
 func (ros *readOnlySpan) Ended() bool {
 	return ros.DB.EndTime.Valid
 }
+
+// InsertSpanParamsFromSpan converts a span into the params for inserting it.
+func InsertSpanParamsFromSpan(span sdktrace.ReadOnlySpan) (*InsertSpanParams, error) {
+	endTime := sql.NullInt64{
+		Int64: span.EndTime().UnixNano(),
+		Valid: !span.EndTime().IsZero(),
+	}
+	if span.EndTime().Before(span.StartTime()) {
+		endTime.Int64 = 0
+		endTime.Valid = false
+	}
+	attributes, err := MarshalProtoJSONs(telemetry.KeyValues(span.Attributes()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal attributes: %w", err)
+	}
+	events, err := MarshalProtoJSONs(telemetry.SpanEventsToPB(span.Events()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal events: %w", err)
+	}
+	links, err := MarshalProtoJSONs(telemetry.SpanLinksToPB(span.Links()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal links: %w", err)
+	}
+	instrumentationScope, err := protojson.Marshal(telemetry.InstrumentationScopeToPB(span.InstrumentationScope()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal instrumentation scope: %w", err)
+	}
+	resource, err := protojson.Marshal(telemetry.ResourcePtrToPB(span.Resource()))
+	if err != nil {
+		return nil, fmt.Errorf("marshal resource: %w", err)
+	}
+
+	return &InsertSpanParams{
+		TraceID:    span.SpanContext().TraceID().String(),
+		SpanID:     span.SpanContext().SpanID().String(),
+		TraceState: span.SpanContext().TraceState().String(),
+		ParentSpanID: sql.NullString{
+			String: span.Parent().SpanID().String(),
+			Valid:  span.Parent().IsValid(),
+		},
+		Flags:                  int64(span.SpanContext().TraceFlags()),
+		Name:                   span.Name(),
+		Kind:                   span.SpanKind().String(),
+		StartTime:              span.StartTime().UnixNano(),
+		EndTime:                endTime,
+		Attributes:             attributes,
+		DroppedAttributesCount: int64(span.DroppedAttributes()),
+		Events:                 events,
+		DroppedEventsCount:     int64(span.DroppedEvents()),
+		Links:                  links,
+		DroppedLinksCount:      int64(span.DroppedLinks()),
+		StatusCode:             int64(span.Status().Code),
+		StatusMessage:          span.Status().Description,
+		InstrumentationScope:   instrumentationScope,
+		Resource:               resource,
+	}, nil
+}
@@ -33,6 +33,17 @@ type Config struct {
 	// Registries configures custom registry mirrors, root CAs, and
 	// insecure/HTTP access.
 	Registries map[string]RegistryConfig `json:"registries,omitempty"`
+
+	// Offline prevents the engine from reaching out to the network to resolve
+	// image tags, git refs, HTTP resources and modules. Everything must be
+	// served from the cache or a registry mirror, and resolving an uncached
+	// resource fails with an error naming it.
+	Offline bool `json:"offline,omitempty"`
+
+	// RecordCalls records the calls run by each client in the cache db, so
+	// that `dagger cache explain` can tell why a later call missed the
+	// cache. Calls are only compared to calls recorded for the same client.
+	RecordCalls bool `json:"recordCalls,omitempty"`
 }
 
 type LogLevel string
@@ -227,4 +238,66 @@ type Security struct {
 	// Disabling this option ensures that dagger build containers do not run as
 	// privileged, and is a basic form of security hardening.
 	InsecureRootCapabilities *bool `json:"insecureRootCapabilities,omitempty"`
+
+	// Policy restricts the images, privileges and host access available to
+	// clients and modules. Calls that violate it fail with an error.
+	Policy *SecurityPolicy `json:"policy,omitempty"`
+}
+
+type SecurityPolicy struct {
+	// Images restricts the images that may be pulled with Container.from, and
+	// the base images of Dockerfile builds.
+	Images *ImagePolicy `json:"images,omitempty"`
+
+	// Modules restricts the privileges of module functions, by the source of
+	// their module. The first rule matching a module's source applies to it.
+	Modules []ModulePolicy `json:"modules,omitempty"`
+
+	// HostPaths restricts the host paths that clients may sync to the engine.
+	HostPaths *HostPathPolicy `json:"hostPaths,omitempty"`
+}
+
+type ImagePolicy struct {
+	// AllowedRegistries, if set, are the only registries images may be pulled
+	// from (e.g. "docker.io" or "*.example.com").
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// DeniedRegistries are registries images may never be pulled from.
+	DeniedRegistries []string `json:"deniedRegistries,omitempty"`
+
+	// AllowedDigests are image digests that may be pulled even if their
+	// registry is not in AllowedRegistries.
+	AllowedDigests []string `json:"allowedDigests,omitempty"`
+
+	// DeniedDigests are image digests that may never be pulled.
+	DeniedDigests []string `json:"deniedDigests,omitempty"`
+}
+
+type ModulePolicy struct {
+	// Source matches the source of a module, either exactly (ignoring its
+	// version) or as a prefix ending in "*" (e.g. "github.com/acme/*"). A
+	// single "*" matches every module.
+	Source string `json:"source"`
+
+	// DenyPrivilegedNesting denies experimentalPrivilegedNesting in
+	// Container.withExec.
+	DenyPrivilegedNesting bool `json:"denyPrivilegedNesting,omitempty"`
+
+	// DenyGPUs denies access to GPUs with Container.experimentalWithGPU and
+	// Container.experimentalWithAllGPUs.
+	DenyGPUs bool `json:"denyGPUs,omitempty"`
+
+	// DenyHostSockets denies mounting host unix sockets with
+	// Container.withUnixSocket.
+	DenyHostSockets bool `json:"denyHostSockets,omitempty"`
+}
+
+type HostPathPolicy struct {
+	// Allowed, if set, are the only absolute host paths (including their
+	// subdirectories) that clients may sync.
+	Allowed []string `json:"allowed,omitempty"`
+
+	// Denied are absolute host paths (including their subdirectories) that
+	// clients may never sync.
+	Denied []string `json:"denied,omitempty"`
 }
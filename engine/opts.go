@@ -92,6 +92,10 @@ type ClientMetadata struct {
 	// Disable lazy loading on module runtime.
 	EagerRuntime bool `json:"eager_runtime"`
 
+	// If true, remote resources must be served from the cache or a mirror
+	// rather than the network.
+	Offline bool `json:"offline,omitempty"`
+
 	// If set, the auth for cloud requests; used for PARC and scale-out
 	CloudAuth *auth.Cloud `json:"cloud_auth,omitempty"`
 
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine/cache"
+	cachedb "github.com/dagger/dagger/engine/cache/db"
+	"github.com/dagger/dagger/engine/slog"
+	"github.com/dagger/dagger/internal/buildkit/cache/remotecache"
+	bksession "github.com/dagger/dagger/internal/buildkit/session"
+)
+
+// callCacheMediaType is the media type of the blob attached to exported caches that
+// holds the dagql call cache metadata (call key -> storage key + expiration).
+//
+// The results of those calls are stored in buildkit's cache under keys derived from
+// the storage key, so exporting this metadata alongside the layer cache lets another
+// engine reuse entire function calls, including ones governed by a TTL.
+const callCacheMediaType = "application/vnd.dagger.dagql.calls.v1+json"
+
+type exportedCallCache struct {
+	Calls []*cachedb.Call `json:"calls"`
+}
+
+// callCacheAttachment returns the dagql call cache metadata of the session's calls to
+// attach to its cache exports. Calls made by other sessions on the engine are left out.
+func callCacheAttachment(ctx context.Context, sessionCache *dagql.SessionCache) (*remotecache.Attachment, error) {
+	calls, err := sessionCache.ExportCalls(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export calls: %w", err)
+	}
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(exportedCallCache{Calls: calls})
+	if err != nil {
+		return nil, err
+	}
+	return &remotecache.Attachment{
+		MediaType: callCacheMediaType,
+		Data:      data,
+	}, nil
+}
+
+// importCallCache imports the dagql call cache metadata attached to each of the session's
+// upstream cache imports, if any. Failures are logged rather than returned, since a missing
+// or unreadable cache should never fail a session.
+func (srv *Server) importCallCache(ctx context.Context, client *daggerClient) {
+	sessionGroup := bksession.NewGroup(client.bkClient.ID())
+	for _, cacheImportCfg := range client.daggerSession.cacheImporterCfgs {
+		importerFunc, ok := srv.cacheImporters[cacheImportCfg.Type]
+		if !ok {
+			continue
+		}
+		importer, desc, err := importerFunc(ctx, sessionGroup, cacheImportCfg.Attrs)
+		if err != nil {
+			slog.Debug("failed to resolve cache importer for call cache", "type", cacheImportCfg.Type, "err", err)
+			continue
+		}
+		attImporter, ok := importer.(remotecache.AttachmentImporter)
+		if !ok {
+			// this cache backend doesn't support attachments
+			continue
+		}
+		attachments, err := attImporter.Attachments(ctx, desc, callCacheMediaType)
+		if err != nil {
+			slog.Warn("failed to read call cache", "type", cacheImportCfg.Type, "err", err)
+			continue
+		}
+		for _, data := range attachments {
+			imported, err := importCallCacheAttachment(ctx, srv.baseDagqlCache, data)
+			if err != nil {
+				slog.Warn("failed to import call cache", "type", cacheImportCfg.Type, "err", err)
+				continue
+			}
+			slog.Debug("imported call cache", "type", cacheImportCfg.Type, "calls", imported)
+		}
+	}
+}
+
+// importCallCacheAttachment merges the calls of an attachment written by callCacheAttachment
+// into the engine's call cache, returning how many calls it held.
+func importCallCacheAttachment(ctx context.Context, baseCache cache.Cache[dagql.CacheKeyType, dagql.CacheValueType], data []byte) (int, error) {
+	var exported exportedCallCache
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return 0, fmt.Errorf("decode: %w", err)
+	}
+	if err := baseCache.ImportCalls(ctx, exported.Calls); err != nil {
+		return 0, err
+	}
+	return len(exported.Calls), nil
+}
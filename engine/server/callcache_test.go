@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/plugins/content/local"
+	"github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagger/dagger/dagql"
+	"github.com/dagger/dagger/engine"
+	"github.com/dagger/dagger/engine/cache"
+	"github.com/dagger/dagger/internal/buildkit/cache/remotecache"
+)
+
+func TestCallCacheAttachmentRoundTrip(t *testing.T) {
+	ctx := t.Context()
+
+	src, err := cache.NewCache[dagql.CacheKeyType, dagql.CacheValueType](ctx, filepath.Join(t.TempDir(), "src.db"))
+	require.NoError(t, err)
+	dst, err := cache.NewCache[dagql.CacheKeyType, dagql.CacheValueType](ctx, filepath.Join(t.TempDir(), "dst.db"))
+	require.NoError(t, err)
+
+	call := func(sessionID string, sessionCache *dagql.SessionCache, callKey string) {
+		ctx := engine.ContextWithClientMetadata(ctx, &engine.ClientMetadata{SessionID: sessionID})
+		_, err := sessionCache.GetOrInitializeWithCallbacks(ctx, dagql.CacheKey{CallKey: callKey, TTL: 600},
+			func(context.Context) (*dagql.CacheValWithCallbacks, error) {
+				return &dagql.CacheValWithCallbacks{SafeToPersistCache: true}, nil
+			})
+		require.NoError(t, err)
+	}
+	mine := dagql.NewSessionCache(src)
+	theirs := dagql.NewSessionCache(src)
+	call("mine", mine, "mine")
+	call("theirs", theirs, "theirs")
+
+	att, err := callCacheAttachment(ctx, mine)
+	require.NoError(t, err)
+	require.NotNil(t, att)
+	require.Equal(t, callCacheMediaType, att.MediaType)
+
+	// store the attachment in a cache index, as exporters do, and read it back
+	store, err := local.NewStore(t.TempDir())
+	require.NoError(t, err)
+	writeBlob := func(mediaType string, data []byte) ocispecs.Descriptor {
+		desc := ocispecs.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest.FromBytes(data),
+			Size:      int64(len(data)),
+		}
+		require.NoError(t, content.WriteBlob(ctx, store, desc.Digest.String(), bytes.NewReader(data), desc))
+		return desc
+	}
+	index, err := json.Marshal(ocispecs.Index{
+		MediaType: ocispecs.MediaTypeImageIndex,
+		Manifests: []ocispecs.Descriptor{writeBlob(att.MediaType, att.Data)},
+	})
+	require.NoError(t, err)
+	indexDesc := writeBlob(ocispecs.MediaTypeImageIndex, index)
+
+	importer, ok := remotecache.NewImporter(store).(remotecache.AttachmentImporter)
+	require.True(t, ok)
+	attachments, err := importer.Attachments(ctx, indexDesc, callCacheMediaType)
+	require.NoError(t, err)
+	require.Len(t, attachments, 1)
+
+	imported, err := importCallCacheAttachment(ctx, dst, attachments[0])
+	require.NoError(t, err)
+	require.Equal(t, 1, imported)
+
+	// only the calls of the exporting session are shared
+	calls, err := dst.ExportCalls(ctx, []string{"mine", "theirs"})
+	require.NoError(t, err)
+	require.Len(t, calls, 1)
+	require.Equal(t, "mine", calls[0].CallKey)
+
+	srcCalls, err := src.ExportCalls(ctx, []string{"mine", "theirs"})
+	require.NoError(t, err)
+	require.Len(t, srcCalls, 2)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+)
+
+type tlsConnKey struct{}
+
+// ConnContext is meant to be set as the ConnContext of the engine's
+// http.Server. It records the TLS connection clients connect over, so that
+// requests served over h2c (which hijacks the connection) can still find the
+// certificate the client presented.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		return context.WithValue(ctx, tlsConnKey{}, tlsConn)
+	}
+	return ctx
+}
+
+// clientIdentity returns the identity of the client that sent the request,
+// which is the subject of the certificate it presented when connecting over
+// mutual TLS, or empty if it did not present one.
+func clientIdentity(r *http.Request) string {
+	if r.TLS != nil {
+		return certIdentity(r.TLS.PeerCertificates)
+	}
+	if tlsConn, ok := r.Context().Value(tlsConnKey{}).(*tls.Conn); ok {
+		return certIdentity(tlsConn.ConnectionState().PeerCertificates)
+	}
+	return ""
+}
+
+func certIdentity(certs []*x509.Certificate) string {
+	if len(certs) == 0 {
+		return ""
+	}
+	return certs[0].Subject.String()
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/containerd/containerd/v2/plugins/diff/walking"
 	"github.com/containerd/go-runc"
 	"github.com/containerd/platforms"
+	"github.com/dagger/dagger/core"
 	"github.com/dagger/dagger/dagql"
 	"github.com/dagger/dagger/engine/cache"
 	"github.com/dagger/dagger/engine/config"
@@ -158,6 +159,11 @@ type Server struct {
 	apparmorProfile  string
 	selinux          bool
 	entitlements     entitlements.Set
+	securityPolicy   *config.SecurityPolicy
+	offline          bool
+	recordCalls      bool
+	resolutions      *core.ResolutionCache
+	registryMirrors  map[string][]string
 	parallelismSem   *semaphore.Weighted
 	enabledPlatforms []ocispecs.Platform
 	defaultPlatform  ocispecs.Platform
@@ -344,6 +350,7 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		if cfg.Security.InsecureRootCapabilities == nil || *cfg.Security.InsecureRootCapabilities {
 			srv.entitlements[entitlements.EntitlementSecurityInsecure] = struct{}{}
 		}
+		srv.securityPolicy = cfg.Security.Policy
 	} else if bkcfg.Entitlements != nil {
 		// fallback to the dagger config
 		for _, entStr := range bkcfg.Entitlements {
@@ -370,11 +377,32 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		srv.enabledPlatforms = []ocispecs.Platform{srv.defaultPlatform}
 	}
 
+	srv.offline = cfg.Offline
+	srv.recordCalls = cfg.RecordCalls
+	resolutionsDBPath := filepath.Join(srv.rootDir, "resolutions.db")
+	srv.resolutions, err = core.NewResolutionCache(resolutionsDBPath)
+	if err != nil {
+		// handle a corrupt db the same way as the dagql cache db, see below
+		slog.Error("failed to open resolution cache, attempting to recover by removing existing db", "error", err)
+		if err := os.Remove(resolutionsDBPath); err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to remove existing resolution cache db", "error", err)
+		}
+		srv.resolutions, err = core.NewResolutionCache(resolutionsDBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open resolution cache after removing existing db: %w", err)
+		}
+	}
+	go srv.resolutions.GCLoop(ctx)
+
+	srv.registryMirrors = map[string][]string{}
 	registries := bkcfg.Registries
 	if len(registries) == 0 {
 		registries = map[string]resolverconfig.RegistryConfig{}
 	}
 	for k, v := range cfg.Registries {
+		if len(v.Mirrors) > 0 {
+			srv.registryMirrors[k] = v.Mirrors
+		}
 		registries[k] = resolverconfig.RegistryConfig{
 			Mirrors:   v.Mirrors,
 			PlainHTTP: v.PlainHTTP,
@@ -621,6 +649,9 @@ func NewServer(ctx context.Context, opts *NewServerOpts) (*Server, error) {
 		}
 	}
 	go srv.baseDagqlCache.GCLoop(ctx)
+	if srv.recordCalls {
+		go srv.baseDagqlCache.RecordLoop(ctx)
+	}
 
 	// garbage collect client DBs
 	go srv.gcClientDBs()
@@ -713,20 +744,44 @@ func (srv *Server) initBoltDBs() (err error) {
 	return nil
 }
 
+// The engine-wide security policy, or nil if none is configured
+func (srv *Server) SecurityPolicy() *config.SecurityPolicy {
+	return srv.securityPolicy
+}
+
+// Whether the engine is configured to never reach out to the network
+func (srv *Server) OfflineMode() bool {
+	return srv.offline
+}
+
+// The record of remote resolutions (image tags, git refs) used to serve them
+// when offline
+func (srv *Server) Resolutions() *core.ResolutionCache {
+	return srv.resolutions
+}
+
+// The mirrors configured for the given registry host, if any
+func (srv *Server) RegistryMirrors(host string) []string {
+	return srv.registryMirrors[host]
+}
+
 func (srv *Server) EngineName() string {
 	return srv.engineName
 }
 
-func (srv *Server) Clients() []string {
+func (srv *Server) Clients() []*core.EngineClient {
 	srv.daggerSessionsMu.RLock()
 	defer srv.daggerSessionsMu.RUnlock()
 
-	clients := map[string]struct{}{}
+	clients := map[string]*core.EngineClient{}
 	for _, sess := range srv.daggerSessions {
-		clients[sess.mainClientCallerID] = struct{}{}
+		clients[sess.mainClientCallerID] = &core.EngineClient{
+			ClientID: sess.mainClientCallerID,
+			Identity: sess.mainClientIdentity,
+		}
 	}
 
-	return slices.Collect(maps.Keys(clients))
+	return slices.Collect(maps.Values(clients))
 }
 
 // GracefulStop attempts to close all boltdbs and do a final syncfs since all the DBs
@@ -58,11 +58,15 @@ import (
 	"github.com/dagger/dagger/engine/slog"
 	enginetel "github.com/dagger/dagger/engine/telemetry"
 	"github.com/dagger/dagger/util/cleanups"
+	"github.com/dagger/dagger/util/hashutil"
 )
 
 type daggerSession struct {
 	sessionID          string
 	mainClientCallerID string
+	// the subject of the certificate the main client presented when
+	// connecting over mutual TLS, if any
+	mainClientIdentity string
 
 	state   daggerSessionState
 	stateMu sync.RWMutex
@@ -90,9 +94,15 @@ type daggerSession struct {
 	cacheExporterCfgs []bkgw.CacheOptionsEntry
 	cacheImporterCfgs []bkgw.CacheOptionsEntry
 
+	// the dagql call cache attached to cacheImporterCfgs is imported once, on the first query
+	callCacheImportOnce sync.Once
+
 	refs   map[buildkit.Reference]struct{}
 	refsMu sync.Mutex
 
+	// answers to module permission prompts, so users are only asked once
+	permissionResponses sync.Map
+
 	containers   map[bkgw.Container]struct{}
 	containersMu sync.Mutex
 
@@ -264,6 +274,11 @@ func (srv *Server) initializeDaggerSession(
 	sess.refs = map[buildkit.Reference]struct{}{}
 	sess.containers = map[bkgw.Container]struct{}{}
 	sess.dagqlCache = dagql.NewSessionCache(srv.baseDagqlCache)
+	if srv.recordCalls && clientMetadata.ClientStableID != "" {
+		// calls are only ever compared to calls of the same client, so that
+		// clients of a shared engine can't see each other's inputs
+		sess.dagqlCache.RecordCallsAs(hashutil.HashStrings(clientMetadata.ClientStableID).String())
+	}
 	sess.telemetryPubSub = srv.telemetryPubSub
 	sess.interactive = clientMetadata.Interactive
 	sess.interactiveCommand = clientMetadata.InteractiveCommand
@@ -440,6 +455,10 @@ type ClientInitOpts struct {
 	// that this client should have access to due to being set in the parent
 	// object.
 	ParentIDs map[digest.Digest]*resource.ID
+
+	// The identity the client authenticated as, if it connected directly to
+	// the engine over mutual TLS.
+	Identity string
 }
 
 // requires that client.stateMu is held
@@ -589,6 +608,8 @@ func (srv *Server) initializeDaggerClient(
 		Refs:   client.daggerSession.refs,
 		RefsMu: &client.daggerSession.refsMu,
 
+		PermissionResponses: &client.daggerSession.permissionResponses,
+
 		Interactive:        client.daggerSession.interactive,
 		InteractiveCommand: client.daggerSession.interactiveCommand,
 
@@ -809,6 +830,7 @@ func (srv *Server) getOrInitClient(
 		if err := srv.initializeDaggerSession(opts.ClientMetadata, sess, failureCleanups); err != nil {
 			return nil, nil, fmt.Errorf("initialize session: %w", err)
 		}
+		sess.mainClientIdentity = opts.Identity
 	case sessionStateInitialized:
 		// nothing to do
 	case sessionStateRemoved:
@@ -934,6 +956,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	httpHandlerFunc(srv.serveHTTPToClient, &ClientInitOpts{
 		ClientMetadata: clientMetadata,
+		Identity:       clientIdentity(r),
 	}).ServeHTTP(w, r)
 }
 
@@ -1173,6 +1196,12 @@ func (srv *Server) serveQuery(w http.ResponseWriter, r *http.Request, client *da
 	// make query available via context to all APIs
 	ctx = core.ContextWithQuery(ctx, client.dagqlRoot)
 
+	if client.clientID == client.daggerSession.mainClientCallerID && len(client.daggerSession.cacheImporterCfgs) > 0 {
+		client.daggerSession.callCacheImportOnce.Do(func() {
+			srv.importCallCache(ctx, client)
+		})
+	}
+
 	r = r.WithContext(ctx)
 
 	// get the schema we're gonna serve to this client based on which modules they have loaded, if any
@@ -1255,6 +1284,12 @@ func (srv *Server) serveShutdown(w http.ResponseWriter, r *http.Request, client
 			ctx, cInternal := t.Start(ctx, "cache export internal", telemetry.Internal())
 			defer cInternal.End()
 			bklog.G(ctx).Infof("running cache export for client %s", client.clientID)
+			callCache, err := callCacheAttachment(ctx, sess.dagqlCache)
+			if err != nil {
+				bklog.G(ctx).WithError(err).Warnf("error exporting call cache for client %s", client.clientID)
+			} else if callCache != nil {
+				ctx = remotecache.WithAttachments(ctx, *callCache)
+			}
 			cacheExporterFuncs := make([]buildkit.ResolveCacheExporterFunc, len(sess.cacheExporterCfgs))
 			for i, cacheExportCfg := range sess.cacheExporterCfgs {
 				cacheExporterFuncs[i] = func(ctx context.Context, sessionGroup bksession.Group) (remotecache.Exporter, error) {
@@ -1265,7 +1300,7 @@ func (srv *Server) serveShutdown(w http.ResponseWriter, r *http.Request, client
 					return exporterFunc(ctx, sessionGroup, cacheExportCfg.Attrs)
 				}
 			}
-			err := client.bkClient.UpstreamCacheExport(ctx, cacheExporterFuncs)
+			err = client.bkClient.UpstreamCacheExport(ctx, cacheExporterFuncs)
 			if err != nil {
 				bklog.G(ctx).WithError(err).Errorf("error running cache export for client %s", client.clientID)
 			}
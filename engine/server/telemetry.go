@@ -2,14 +2,12 @@ package server
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	"dagger.io/dagger/telemetry"
-	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
@@ -18,7 +16,6 @@ import (
 	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
-	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -323,77 +320,12 @@ func (ps clientSpans) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnly
 
 	var inserts []*clientdb.InsertSpanParams
 	for _, span := range spans {
-		traceID := span.SpanContext().TraceID().String()
-		spanID := span.SpanContext().SpanID().String()
-		traceState := span.SpanContext().TraceState().String()
-		parentSpanID := span.Parent().SpanID().String()
-		flags := int64(span.SpanContext().TraceFlags())
-		name := span.Name()
-		kind := span.SpanKind().String()
-		startTime := span.StartTime().UnixNano()
-		endTime := sql.NullInt64{
-			Int64: span.EndTime().UnixNano(),
-			Valid: !span.EndTime().IsZero(),
-		}
-		if span.EndTime().Before(span.StartTime()) {
-			endTime.Int64 = 0
-			endTime.Valid = false
-		}
-		attributes, err := clientdb.MarshalProtoJSONs(telemetry.KeyValues(span.Attributes()))
-		if err != nil {
-			slog.Warn("failed to marshal attributes", "error", err)
-			continue
-		}
-		droppedAttributesCount := int64(span.DroppedAttributes())
-		events, err := clientdb.MarshalProtoJSONs(telemetry.SpanEventsToPB(span.Events()))
+		insert, err := clientdb.InsertSpanParamsFromSpan(span)
 		if err != nil {
-			slog.Warn("failed to marshal events", "error", err)
+			slog.Warn("failed to prepare span", "error", err)
 			continue
 		}
-		droppedEventsCount := int64(span.DroppedEvents())
-		links, err := clientdb.MarshalProtoJSONs(telemetry.SpanLinksToPB(span.Links()))
-		if err != nil {
-			slog.Warn("failed to marshal links", "error", err)
-			continue
-		}
-		droppedLinksCount := int64(span.DroppedLinks())
-		statusCode := int64(span.Status().Code)
-		statusMessage := span.Status().Description
-		instrumentationScope, err := protojson.Marshal(telemetry.InstrumentationScopeToPB(span.InstrumentationScope()))
-		if err != nil {
-			slog.Warn("failed to marshal instrumentation scope", "error", err)
-			continue
-		}
-		resource, err := protojson.Marshal(telemetry.ResourcePtrToPB(span.Resource()))
-		if err != nil {
-			slog.Warn("failed to marshal resource", "error", err)
-			continue
-		}
-
-		inserts = append(inserts, &clientdb.InsertSpanParams{
-			TraceID:    traceID,
-			SpanID:     spanID,
-			TraceState: traceState,
-			ParentSpanID: sql.NullString{
-				String: parentSpanID,
-				Valid:  span.Parent().IsValid(),
-			},
-			Flags:                  flags,
-			Name:                   name,
-			Kind:                   kind,
-			StartTime:              startTime,
-			EndTime:                endTime,
-			Attributes:             attributes,
-			DroppedAttributesCount: droppedAttributesCount,
-			Events:                 events,
-			DroppedEventsCount:     droppedEventsCount,
-			Links:                  links,
-			DroppedLinksCount:      droppedLinksCount,
-			StatusCode:             statusCode,
-			StatusMessage:          statusMessage,
-			InstrumentationScope:   instrumentationScope,
-			Resource:               resource,
-		})
+		inserts = append(inserts, insert)
 	}
 
 	db, err := ps.client.TelemetryDB(ctx)
@@ -431,7 +363,7 @@ type clientLogs struct {
 var _ sdklog.Processor = clientLogs{}
 
 func (ps clientLogs) OnEmit(ctx context.Context, rec *sdklog.Record) error {
-	insert, err := insertLogRecordParam(rec)
+	insert, err := clientdb.InsertLogParamsFromRecord(rec)
 	if err != nil {
 		return fmt.Errorf("prepare log record %v: %w", rec, err)
 	}
@@ -452,7 +384,7 @@ func (ps clientLogs) Export(ctx context.Context, logs []sdklog.Record) error {
 
 	var inserts []*clientdb.InsertLogParams
 	for _, rec := range logs {
-		insert, err := insertLogRecordParam(&rec)
+		insert, err := clientdb.InsertLogParamsFromRecord(&rec)
 		if err != nil {
 			return fmt.Errorf("prepare log record %v: %w", rec, err)
 		}
@@ -478,65 +410,6 @@ func (ps clientLogs) Export(ctx context.Context, logs []sdklog.Record) error {
 func (ps clientLogs) ForceFlush(ctx context.Context) error { return nil }
 func (ps clientLogs) Shutdown(context.Context) error       { return nil }
 
-func insertLogRecordParam(rec *sdklog.Record) (*clientdb.InsertLogParams, error) {
-	traceID := rec.TraceID().String()
-	spanID := rec.SpanID().String()
-	timestamp := rec.Timestamp().UnixNano()
-	severity := int64(rec.Severity())
-
-	var body []byte
-	if !rec.Body().Empty() {
-		var err error
-		body, err = proto.Marshal(telemetry.LogValueToPB(rec.Body()))
-		if err != nil {
-			return nil, fmt.Errorf("marshal log record body: %w", err)
-		}
-	}
-
-	attrs := []*otlpcommonv1.KeyValue{}
-	rec.WalkAttributes(func(kv log.KeyValue) bool {
-		attrs = append(attrs, &otlpcommonv1.KeyValue{
-			Key:   kv.Key,
-			Value: telemetry.LogValueToPB(kv.Value),
-		})
-		return true
-	})
-	attributes, err := clientdb.MarshalProtoJSONs(attrs)
-	if err != nil {
-		return nil, fmt.Errorf("marshal log record attributes: %w", err)
-	}
-
-	scope, err := protojson.Marshal(telemetry.InstrumentationScopeToPB(rec.InstrumentationScope()))
-	if err != nil {
-		return nil, fmt.Errorf("marshal log record instrumentation scope: %w", err)
-	}
-
-	res := rec.Resource()
-	resource, err := protojson.Marshal(telemetry.ResourcePtrToPB(res))
-	if err != nil {
-		return nil, fmt.Errorf("marshal log record resource: %w", err)
-	}
-
-	return &clientdb.InsertLogParams{
-		TraceID: sql.NullString{
-			String: traceID,
-			Valid:  rec.TraceID().IsValid(),
-		},
-		SpanID: sql.NullString{
-			String: spanID,
-			Valid:  rec.SpanID().IsValid(),
-		},
-		Timestamp:            timestamp,
-		SeverityNumber:       severity,
-		SeverityText:         rec.SeverityText(),
-		Body:                 body,
-		Attributes:           attributes,
-		InstrumentationScope: scope,
-		Resource:             resource,
-		ResourceSchemaUrl:    res.SchemaURL(),
-	}, nil
-}
-
 func (ps *PubSub) Metrics(client *daggerClient) sdkmetric.Exporter {
 	return clientMetrics{
 		PubSub: ps,
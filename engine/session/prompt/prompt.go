@@ -3,6 +3,7 @@ package prompt
 import (
 	context "context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
@@ -40,6 +41,29 @@ type PromptHandler interface {
 	HandleForm(ctx context.Context, form *huh.Form) error
 }
 
+// ErrUnavailable is returned by a PromptHandler that can't prompt the user,
+// e.g. because its output is machine-readable.
+var ErrUnavailable = errors.New("cannot prompt the user")
+
+// IsUnavailable returns whether a prompt failed because the client can't
+// prompt the user, either because it has no prompt attachable, e.g. when it's
+// not attached to a terminal, or because its handler returned ErrUnavailable.
+func IsUnavailable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unimplemented, codes.FailedPrecondition:
+		return true
+	default:
+		return false
+	}
+}
+
+func handlePromptError(err error) error {
+	if errors.Is(err, ErrUnavailable) {
+		return status.Errorf(codes.FailedPrecondition, "Failed to handle prompt: %v", err)
+	}
+	return status.Errorf(codes.Internal, "Failed to handle prompt: %v", err)
+}
+
 func NewPromptAttachable(promptHandler PromptHandler) PromptAttachable {
 	return PromptAttachable{
 		persistence:   &PromptResponses{},
@@ -74,7 +98,7 @@ func (p PromptAttachable) PromptBool(ctx context.Context, req *BoolRequest) (*Bo
 	confirm := req.GetDefault()
 	if p.promptHandler != nil {
 		if err := p.promptHandler.HandlePrompt(ctx, req.GetTitle(), req.GetPrompt(), &confirm); err != nil {
-			return nil, status.Errorf(codes.Internal, "Failed to handle prompt: %v", err)
+			return nil, handlePromptError(err)
 		}
 	}
 
@@ -100,7 +124,7 @@ func (p PromptAttachable) PromptString(ctx context.Context, req *StringRequest)
 	response := req.GetDefault()
 	if p.promptHandler != nil {
 		if err := p.promptHandler.HandlePrompt(ctx, req.GetTitle(), req.GetPrompt(), &response); err != nil {
-			return nil, status.Errorf(codes.Internal, "Failed to handle prompt: %v", err)
+			return nil, handlePromptError(err)
 		}
 	}
 
@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/charmbracelet/huh"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testPromptHandler struct {
+	err error
+}
+
+func (h testPromptHandler) HandlePrompt(context.Context, string, string, any) error {
+	return h.err
+}
+
+func (h testPromptHandler) HandleForm(context.Context, *huh.Form) error {
+	return h.err
+}
+
+// testPromptClient serves the attachable, if any, like a client session does.
+func testPromptClient(t *testing.T, attachable *PromptAttachable) PromptClient {
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	if attachable != nil {
+		attachable.Register(srv)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return NewPromptClient(conn)
+}
+
+func TestPromptUnavailable(t *testing.T) {
+	ctx := context.Background()
+	req := &BoolRequest{Title: "Allow?", Prompt: "Allow it?"}
+
+	t.Run("no attachable", func(t *testing.T) {
+		// clients without a terminal don't register the attachable
+		_, err := testPromptClient(t, nil).PromptBool(ctx, req)
+		require.Error(t, err)
+		require.True(t, IsUnavailable(err))
+	})
+
+	t.Run("handler cannot prompt", func(t *testing.T) {
+		attachable := NewPromptAttachable(testPromptHandler{err: ErrUnavailable})
+		_, err := testPromptClient(t, &attachable).PromptBool(ctx, req)
+		require.Error(t, err)
+		require.True(t, IsUnavailable(err))
+	})
+
+	t.Run("handler fails", func(t *testing.T) {
+		attachable := NewPromptAttachable(testPromptHandler{err: errors.New("boom")})
+		_, err := testPromptClient(t, &attachable).PromptBool(ctx, req)
+		require.Error(t, err)
+		require.False(t, IsUnavailable(err))
+	})
+}
@@ -0,0 +1,111 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/dagger/dagger/internal/buildkit/util/bklog"
+	"github.com/dagger/dagger/internal/buildkit/util/imageutil"
+	digest "github.com/opencontainers/go-digest"
+	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// Attachment is an extra blob exported alongside the cache layers and config,
+// for data that is not part of the solver cache itself but is only useful
+// together with it.
+type Attachment struct {
+	MediaType string
+	Data      []byte
+}
+
+type attachmentsKey struct{}
+
+// WithAttachments returns a context that makes content cache exporters
+// finalized with it also write the given attachments.
+func WithAttachments(ctx context.Context, attachments ...Attachment) context.Context {
+	return context.WithValue(ctx, attachmentsKey{}, attachments)
+}
+
+func attachmentsFromContext(ctx context.Context) []Attachment {
+	attachments, _ := ctx.Value(attachmentsKey{}).([]Attachment)
+	return attachments
+}
+
+func writeAttachments(ctx context.Context, ingester content.Ingester, cache *ExportableCache) error {
+	for _, att := range attachmentsFromContext(ctx) {
+		desc := ocispecs.Descriptor{
+			Digest:    digest.FromBytes(att.Data),
+			Size:      int64(len(att.Data)),
+			MediaType: att.MediaType,
+		}
+		if err := content.WriteBlob(ctx, ingester, desc.Digest.String(), bytes.NewReader(att.Data), desc); err != nil {
+			return errors.Wrapf(err, "error writing %s attachment", att.MediaType)
+		}
+		cache.AddCacheBlob(desc)
+	}
+	return nil
+}
+
+// WarnUnsupportedAttachments logs the attachments of the context for cache
+// exporters that can't write them, so that they aren't dropped silently.
+func WarnUnsupportedAttachments(ctx context.Context, exporter string) {
+	for _, att := range attachmentsFromContext(ctx) {
+		bklog.G(ctx).Warnf("%s cache exporter does not support attachments, skipping %s", exporter, att.MediaType)
+	}
+}
+
+// AttachmentImporter is implemented by importers that can read attachments
+// back from an exported cache.
+type AttachmentImporter interface {
+	// Attachments returns the contents of all attachments with the given
+	// media type in the cache manifest described by desc.
+	Attachments(ctx context.Context, desc ocispecs.Descriptor, mediaType string) ([][]byte, error)
+}
+
+var _ AttachmentImporter = &contentCacheImporter{}
+
+func (ci *contentCacheImporter) Attachments(ctx context.Context, desc ocispecs.Descriptor, mediaType string) ([][]byte, error) {
+	dt, err := readBlob(ctx, ci.provider, desc)
+	if err != nil {
+		return nil, err
+	}
+	manifestType, err := imageutil.DetectManifestBlobMediaType(dt)
+	if err != nil {
+		return nil, err
+	}
+
+	var descs []ocispecs.Descriptor
+	switch manifestType {
+	case images.MediaTypeDockerSchema2ManifestList, ocispecs.MediaTypeImageIndex:
+		var mfst ocispecs.Index
+		if err := json.Unmarshal(dt, &mfst); err != nil {
+			return nil, err
+		}
+		descs = mfst.Manifests
+	case images.MediaTypeDockerSchema2Manifest, ocispecs.MediaTypeImageManifest:
+		var mfst ocispecs.Manifest
+		if err := json.Unmarshal(dt, &mfst); err != nil {
+			return nil, err
+		}
+		descs = mfst.Layers
+	default:
+		return nil, errors.Errorf("unsupported cache manifest type %s", manifestType)
+	}
+
+	var attachments [][]byte
+	for _, d := range descs {
+		if d.MediaType != mediaType {
+			continue
+		}
+		dt, err := content.ReadBlob(ctx, ci.provider, d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s attachment", mediaType)
+		}
+		attachments = append(attachments, dt)
+	}
+	return attachments, nil
+}
@@ -211,6 +211,10 @@ func (ce *contentCacheExporter) Finalize(ctx context.Context) (map[string]string
 
 	cache.FinalizeCache(ctx)
 
+	if err := writeAttachments(ctx, ce.ingester, cache); err != nil {
+		return nil, err
+	}
+
 	dt, err := json.Marshal(config)
 	if err != nil {
 		return nil, err
@@ -176,6 +176,8 @@ func (ce *exporter) initActiveKeyMapOnce(ctx context.Context) (map[string]struct
 }
 
 func (ce *exporter) Finalize(ctx context.Context) (map[string]string, error) {
+	remotecache.WarnUnsupportedAttachments(ctx, "gha")
+
 	// res := make(map[string]string)
 	config, descs, err := ce.chains.Marshal(ctx)
 	if err != nil {
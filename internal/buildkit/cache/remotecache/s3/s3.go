@@ -199,6 +199,8 @@ type nopCloserSectionReader struct {
 func (*nopCloserSectionReader) Close() error { return nil }
 
 func (e *exporter) Finalize(ctx context.Context) (map[string]string, error) {
+	remotecache.WarnUnsupportedAttachments(ctx, "s3")
+
 	cacheConfig, descs, err := e.chains.Marshal(ctx)
 	if err != nil {
 		return nil, err
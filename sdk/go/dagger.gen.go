@@ -169,6 +169,12 @@ type EngineCacheEntrySetID string
 // The `EngineCacheID` scalar type represents an identifier for an object of type EngineCache.
 type EngineCacheID string
 
+// The `EngineCacheMissCauseID` scalar type represents an identifier for an object of type EngineCacheMissCause.
+type EngineCacheMissCauseID string
+
+// The `EngineClientID` scalar type represents an identifier for an object of type EngineClient.
+type EngineClientID string
+
 // The `EngineID` scalar type represents an identifier for an object of type Engine.
 type EngineID string
 
@@ -627,6 +633,24 @@ func (r *Binding) AsDirectory() *Directory {
 	}
 }
 
+// Retrieve the binding value, as type EngineCacheMissCause
+func (r *Binding) AsEngineCacheMissCause() *EngineCacheMissCause {
+	q := r.query.Select("asEngineCacheMissCause")
+
+	return &EngineCacheMissCause{
+		query: q,
+	}
+}
+
+// Retrieve the binding value, as type EngineClient
+func (r *Binding) AsEngineClient() *EngineClient {
+	q := r.query.Select("asEngineClient")
+
+	return &EngineClient{
+		query: q,
+	}
+}
+
 // Retrieve the binding value, as type Env
 func (r *Binding) AsEnv() *Env {
 	q := r.query.Select("asEnv")
@@ -999,12 +1023,26 @@ func (r *Changeset) Before() *Directory {
 	}
 }
 
+// ChangesetExportOpts contains options for Changeset.Export
+type ChangesetExportOpts struct {
+	// What to do with host paths that changed since the before snapshot.
+	OnConflict ChangesetExportConflict
+}
+
 // Applies the diff represented by this changeset to a path on the host.
-func (r *Changeset) Export(ctx context.Context, path string) (string, error) {
+//
+// By default, host paths are overwritten. If onConflict is set, the host's current content is first compared against the changeset's before snapshot, and paths changed on the host since are handled according to onConflict instead of being clobbered.
+func (r *Changeset) Export(ctx context.Context, path string, opts ...ChangesetExportOpts) (string, error) {
 	if r.export != nil {
 		return *r.export, nil
 	}
 	q := r.query.Select("export")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `onConflict` optional argument
+		if !querybuilder.IsZeroValue(opts[i].OnConflict) {
+			q = q.Arg("onConflict", opts[i].OnConflict)
+		}
+	}
 	q = q.Arg("path", path)
 
 	var response string
@@ -4401,6 +4439,8 @@ func (r *Engine) WithGraphQLQuery(q *querybuilder.Selection) *Engine {
 }
 
 // The list of connected client IDs
+//
+// Deprecated: Use ConnectedClients instead
 func (r *Engine) Clients(ctx context.Context) ([]string, error) {
 	q := r.query.Select("clients")
 
@@ -4410,6 +4450,77 @@ func (r *Engine) Clients(ctx context.Context) ([]string, error) {
 	return response, q.Execute(ctx)
 }
 
+// The list of connected clients, with the identity each one authenticated as.
+//
+// It supersedes Clients, which only lists their IDs.
+func (r *Engine) ConnectedClients(ctx context.Context) ([]EngineClient, error) {
+	q := r.query.Select("connectedClients")
+
+	q = q.Select("id")
+
+	type connectedClients struct {
+		Id EngineClientID
+	}
+
+	convert := func(fields []connectedClients) []EngineClient {
+		out := []EngineClient{}
+
+		for i := range fields {
+			val := EngineClient{id: &fields[i].Id}
+			val.query = q.Root().Select("loadEngineClientFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []connectedClients
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
+// Explain why a call did not reuse the cached result of an earlier call.
+//
+// The call is compared to the closest earlier call of the same pipeline step recorded by the engine. Both call DAGs are walked in lockstep, reporting the inputs that differ from the root of the DAG to its tip, so the first cause is typically the root cause.
+func (r *Engine) ExplainCacheMiss(ctx context.Context, call string) ([]EngineCacheMissCause, error) {
+	q := r.query.Select("explainCacheMiss")
+	q = q.Arg("call", call)
+
+	q = q.Select("id")
+
+	type explainCacheMiss struct {
+		Id EngineCacheMissCauseID
+	}
+
+	convert := func(fields []explainCacheMiss) []EngineCacheMissCause {
+		out := []EngineCacheMissCause{}
+
+		for i := range fields {
+			val := EngineCacheMissCause{id: &fields[i].Id}
+			val.query = q.Root().Select("loadEngineCacheMissCauseFromID").Arg("id", fields[i].Id)
+			out = append(out, val)
+		}
+
+		return out
+	}
+	var response []explainCacheMiss
+
+	q = q.Bind(&response)
+
+	err := q.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return convert(response), nil
+}
+
 // A unique identifier for this Engine.
 func (r *Engine) ID(ctx context.Context) (EngineID, error) {
 	if r.id != nil {
@@ -4885,6 +4996,196 @@ func (r *EngineCacheEntrySet) MarshalJSON() ([]byte, error) {
 	return json.Marshal(id)
 }
 
+// An input that differs between a call and an earlier call it was expected to reuse
+type EngineCacheMissCause struct {
+	query *querybuilder.Selection
+
+	current  *string
+	id       *EngineCacheMissCauseID
+	path     *string
+	previous *string
+	reason   *string
+}
+
+func (r *EngineCacheMissCause) WithGraphQLQuery(q *querybuilder.Selection) *EngineCacheMissCause {
+	return &EngineCacheMissCause{
+		query: q,
+	}
+}
+
+// The current value of the input, if applicable.
+func (r *EngineCacheMissCause) Current(ctx context.Context) (string, error) {
+	if r.current != nil {
+		return *r.current, nil
+	}
+	q := r.query.Select("current")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this EngineCacheMissCause.
+func (r *EngineCacheMissCause) ID(ctx context.Context) (EngineCacheMissCauseID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response EngineCacheMissCauseID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *EngineCacheMissCause) XXX_GraphQLType() string {
+	return "EngineCacheMissCause"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *EngineCacheMissCause) XXX_GraphQLIDType() string {
+	return "EngineCacheMissCauseID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *EngineCacheMissCause) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *EngineCacheMissCause) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The path of the call in which the differing input was found.
+func (r *EngineCacheMissCause) Path(ctx context.Context) (string, error) {
+	if r.path != nil {
+		return *r.path, nil
+	}
+	q := r.query.Select("path")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The previous value of the input, if applicable.
+func (r *EngineCacheMissCause) Previous(ctx context.Context) (string, error) {
+	if r.previous != nil {
+		return *r.previous, nil
+	}
+	q := r.query.Select("previous")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A description of how the input differs.
+func (r *EngineCacheMissCause) Reason(ctx context.Context) (string, error) {
+	if r.reason != nil {
+		return *r.reason, nil
+	}
+	q := r.query.Select("reason")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A client connected to the Dagger engine
+type EngineClient struct {
+	query *querybuilder.Selection
+
+	clientID *string
+	id       *EngineClientID
+	identity *string
+}
+
+func (r *EngineClient) WithGraphQLQuery(q *querybuilder.Selection) *EngineClient {
+	return &EngineClient{
+		query: q,
+	}
+}
+
+// The ID of the client.
+func (r *EngineClient) ClientID(ctx context.Context) (string, error) {
+	if r.clientID != nil {
+		return *r.clientID, nil
+	}
+	q := r.query.Select("clientID")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// A unique identifier for this EngineClient.
+func (r *EngineClient) ID(ctx context.Context) (EngineClientID, error) {
+	if r.id != nil {
+		return *r.id, nil
+	}
+	q := r.query.Select("id")
+
+	var response EngineClientID
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// XXX_GraphQLType is an internal function. It returns the native GraphQL type name
+func (r *EngineClient) XXX_GraphQLType() string {
+	return "EngineClient"
+}
+
+// XXX_GraphQLIDType is an internal function. It returns the native GraphQL type name for the ID of this object
+func (r *EngineClient) XXX_GraphQLIDType() string {
+	return "EngineClientID"
+}
+
+// XXX_GraphQLID is an internal function. It returns the underlying type ID
+func (r *EngineClient) XXX_GraphQLID(ctx context.Context) (string, error) {
+	id, err := r.ID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+func (r *EngineClient) MarshalJSON() ([]byte, error) {
+	id, err := r.ID(marshalCtx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(id)
+}
+
+// The subject of the certificate the client presented when connecting over mutual TLS, or empty if it did not present one.
+func (r *EngineClient) Identity(ctx context.Context) (string, error) {
+	if r.identity != nil {
+		return *r.identity, nil
+	}
+	q := r.query.Select("identity")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A definition of a custom enum defined in a Module.
 type EnumTypeDef struct {
 	query *querybuilder.Selection
@@ -5228,6 +5529,17 @@ func (r *Env) Checks(opts ...EnvChecksOpts) *CheckGroup {
 	}
 }
 
+// Serializes the environment to a JSON file, to be restored with loadEnv
+//
+// The file lists the bindings with their names, types and descriptions, and refers to objects by their IDs.
+func (r *Env) Encode() *File {
+	q := r.query.Select("encode")
+
+	return &File{
+		query: q,
+	}
+}
+
 // A unique identifier for this Env.
 func (r *Env) ID(ctx context.Context) (EnvID, error) {
 	if r.id != nil {
@@ -5557,6 +5869,54 @@ func (r *Env) WithDirectoryOutput(name string, description string) *Env {
 	}
 }
 
+// Create or update a binding of type EngineCacheMissCause in the environment
+func (r *Env) WithEngineCacheMissCauseInput(name string, value *EngineCacheMissCause, description string) *Env {
+	assertNotNil("value", value)
+	q := r.query.Select("withEngineCacheMissCauseInput")
+	q = q.Arg("name", name)
+	q = q.Arg("value", value)
+	q = q.Arg("description", description)
+
+	return &Env{
+		query: q,
+	}
+}
+
+// Declare a desired EngineCacheMissCause output to be assigned in the environment
+func (r *Env) WithEngineCacheMissCauseOutput(name string, description string) *Env {
+	q := r.query.Select("withEngineCacheMissCauseOutput")
+	q = q.Arg("name", name)
+	q = q.Arg("description", description)
+
+	return &Env{
+		query: q,
+	}
+}
+
+// Create or update a binding of type EngineClient in the environment
+func (r *Env) WithEngineClientInput(name string, value *EngineClient, description string) *Env {
+	assertNotNil("value", value)
+	q := r.query.Select("withEngineClientInput")
+	q = q.Arg("name", name)
+	q = q.Arg("value", value)
+	q = q.Arg("description", description)
+
+	return &Env{
+		query: q,
+	}
+}
+
+// Declare a desired EngineClient output to be assigned in the environment
+func (r *Env) WithEngineClientOutput(name string, description string) *Env {
+	q := r.query.Select("withEngineClientOutput")
+	q = q.Arg("name", name)
+	q = q.Arg("description", description)
+
+	return &Env{
+		query: q,
+	}
+}
+
 // Create or update a binding of type EnvFile in the environment
 func (r *Env) WithEnvFileInput(name string, value *EnvFile, description string) *Env {
 	assertNotNil("value", value)
@@ -6684,6 +7044,26 @@ func (r *File) AsJSON() *JSONValue {
 	}
 }
 
+// Parse the file contents as TOML.
+func (r *File) AsTOML() *JSONValue {
+	q := r.query.Select("asTOML")
+
+	return &JSONValue{
+		query: q,
+	}
+}
+
+// Parse the file contents as YAML.
+//
+// Files containing several documents are rejected.
+func (r *File) AsYAML() *JSONValue {
+	q := r.query.Select("asYAML")
+
+	return &JSONValue{
+		query: q,
+	}
+}
+
 // Change the owner of the file recursively.
 func (r *File) Chown(owner string) *File {
 	q := r.query.Select("chown")
@@ -8884,6 +9264,8 @@ type JSONValue struct {
 	asBoolean *bool
 	asInteger *int
 	asString  *string
+	asTOML    *string
+	asYAML    *string
 	contents  *JSON
 	id        *JSONValueID
 }
@@ -8974,6 +9356,36 @@ func (r *JSONValue) AsString(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// Return the value encoded as TOML
+//
+// Only objects can be encoded as TOML.
+func (r *JSONValue) AsTOML(ctx context.Context) (string, error) {
+	if r.asTOML != nil {
+		return *r.asTOML, nil
+	}
+	q := r.query.Select("asTOML")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// Return the value encoded as YAML
+//
+// If the value was parsed from YAML, comments and key order of the original document are preserved.
+func (r *JSONValue) AsYAML(ctx context.Context) (string, error) {
+	if r.asYAML != nil {
+		return *r.asYAML, nil
+	}
+	q := r.query.Select("asYAML")
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // JSONValueContentsOpts contains options for JSONValue.Contents
 type JSONValueContentsOpts struct {
 	// Pretty-print
@@ -9348,6 +9760,19 @@ func (r *LLM) Tools(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// Return a new LLM that asks the user before calling the specified function
+//
+// The user is shown the function and its arguments. If they deny the call, or can't be asked because the session isn't interactive, the call fails. Approvals and denials are recorded in the history.
+func (r *LLM) WithApprovalRequired(typeName string, function string) *LLM {
+	q := r.query.Select("withApprovalRequired")
+	q = q.Arg("typeName", typeName)
+	q = q.Arg("function", function)
+
+	return &LLM{
+		query: q,
+	}
+}
+
 // Return a new LLM with the specified function no longer exposed as a tool
 func (r *LLM) WithBlockedFunction(typeName string, function string) *LLM {
 	q := r.query.Select("withBlockedFunction")
@@ -9370,6 +9795,32 @@ func (r *LLM) WithEnv(env *Env) *LLM {
 	}
 }
 
+// LLMWithHistoryStrategyOpts contains options for LLM.WithHistoryStrategy
+type LLMWithHistoryStrategyOpts struct {
+	// The share of the context window that the history may fill before it's compacted, between 0 and 1
+	//
+	// Default: 0.8
+	Threshold float64
+}
+
+// Configure how the history is compacted as it approaches the model's context window
+//
+// By default, the results of the oldest tool calls are truncated once the history fills 80% of the context window.
+func (r *LLM) WithHistoryStrategy(strategy LLMHistoryStrategy, opts ...LLMWithHistoryStrategyOpts) *LLM {
+	q := r.query.Select("withHistoryStrategy")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `threshold` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Threshold) {
+			q = q.Arg("threshold", opts[i].Threshold)
+		}
+	}
+	q = q.Arg("strategy", strategy)
+
+	return &LLM{
+		query: q,
+	}
+}
+
 // Add an external MCP server to the LLM
 func (r *LLM) WithMCPServer(name string, service *Service) *LLM {
 	assertNotNil("service", service)
@@ -9413,6 +9864,16 @@ func (r *LLM) WithPromptFile(file *File) *LLM {
 	}
 }
 
+// Record the conversation to a replay fixture on the host. Only the main client can record.
+func (r *LLM) WithRecording(path string) *LLM {
+	q := r.query.Select("withRecording")
+	q = q.Arg("path", path)
+
+	return &LLM{
+		query: q,
+	}
+}
+
 // Use a static set of tools for method calls, e.g. for MCP clients that do not support dynamic tool registration
 func (r *LLM) WithStaticTools() *LLM {
 	q := r.query.Select("withStaticTools")
@@ -9422,6 +9883,18 @@ func (r *LLM) WithStaticTools() *LLM {
 	}
 }
 
+// Add another LLM as a tool that this LLM can delegate tasks to
+func (r *LLM) WithSubAgent(name string, llm *LLM) *LLM {
+	assertNotNil("llm", llm)
+	q := r.query.Select("withSubAgent")
+	q = q.Arg("name", name)
+	q = q.Arg("llm", llm)
+
+	return &LLM{
+		query: q,
+	}
+}
+
 // Add a system prompt to the LLM's environment
 func (r *LLM) WithSystemPrompt(prompt string) *LLM {
 	q := r.query.Select("withSystemPrompt")
@@ -9432,6 +9905,16 @@ func (r *LLM) WithSystemPrompt(prompt string) *LLM {
 	}
 }
 
+// Limit how many independent tool calls from a single response run at once
+func (r *LLM) WithToolConcurrency(limit int) *LLM {
+	q := r.query.Select("withToolConcurrency")
+	q = q.Arg("limit", limit)
+
+	return &LLM{
+		query: q,
+	}
+}
+
 // Disable the default system prompt
 func (r *LLM) WithoutDefaultSystemPrompt() *LLM {
 	q := r.query.Select("withoutDefaultSystemPrompt")
@@ -9464,6 +9947,8 @@ type LLMTokenUsage struct {
 
 	cachedTokenReads  *int
 	cachedTokenWrites *int
+	compactedTokens   *int
+	compactions       *int
 	id                *LLMTokenUsageID
 	inputTokens       *int
 	outputTokens      *int
@@ -9500,6 +9985,32 @@ func (r *LLMTokenUsage) CachedTokenWrites(ctx context.Context) (int, error) {
 	return response, q.Execute(ctx)
 }
 
+// The estimated number of tokens freed by compacting the history
+func (r *LLMTokenUsage) CompactedTokens(ctx context.Context) (int, error) {
+	if r.compactedTokens != nil {
+		return *r.compactedTokens, nil
+	}
+	q := r.query.Select("compactedTokens")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
+// The number of times the history was compacted to fit the context window
+func (r *LLMTokenUsage) Compactions(ctx context.Context) (int, error) {
+	if r.compactions != nil {
+		return *r.compactions, nil
+	}
+	q := r.query.Select("compactions")
+
+	var response int
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // A unique identifier for this LLMTokenUsage.
 func (r *LLMTokenUsage) ID(ctx context.Context) (LLMTokenUsageID, error) {
 	if r.id != nil {
@@ -10250,6 +10761,7 @@ type ModuleSource struct {
 	moduleOriginalName        *string
 	originalSubpath           *string
 	pin                       *string
+	publish                   *string
 	repoRootPath              *string
 	sourceRootSubpath         *string
 	sourceSubpath             *string
@@ -10540,7 +11052,7 @@ func (r *ModuleSource) IntrospectionSchemaJSON() *File {
 	}
 }
 
-// The kind of module source (currently local, git or dir).
+// The kind of module source (currently local, git, dir or oci).
 func (r *ModuleSource) Kind(ctx context.Context) (ModuleSourceKind, error) {
 	if r.kind != nil {
 		return *r.kind, nil
@@ -10618,6 +11130,22 @@ func (r *ModuleSource) Pin(ctx context.Context) (string, error) {
 	return response, q.Execute(ctx)
 }
 
+// Publish the module source to an OCI registry with its resolved dagger.json, returning the published ref pinned by digest.
+//
+// Every dependency, toolchain and blueprint must be a remote (git or OCI) module source.
+func (r *ModuleSource) Publish(ctx context.Context, address string) (string, error) {
+	if r.publish != nil {
+		return *r.publish, nil
+	}
+	q := r.query.Select("publish")
+	q = q.Arg("address", address)
+
+	var response string
+
+	q = q.Bind(&response)
+	return response, q.Execute(ctx)
+}
+
 // The import path corresponding to the root of the git repo this source points to. Only valid for git sources.
 func (r *ModuleSource) RepoRootPath(ctx context.Context) (string, error) {
 	if r.repoRootPath != nil {
@@ -10721,7 +11249,7 @@ func (r *ModuleSource) UserDefaults() *EnvFile {
 	}
 }
 
-// The specified version of the git repo this source points to.
+// The specified version of the git repo or OCI tag this source points to.
 func (r *ModuleSource) Version(ctx context.Context) (string, error) {
 	if r.version != nil {
 		return *r.version, nil
@@ -10875,6 +11403,27 @@ func (r *ModuleSource) WithUpdatedClients(clients []string) *ModuleSource {
 	}
 }
 
+// ModuleSourceWithVendorOpts contains options for ModuleSource.WithVendor
+type ModuleSourceWithVendorOpts struct {
+	// The path of the vendor directory, relative to the source root. Defaults to the one already configured, if any.
+	Path string
+}
+
+// Vendor the module's git dependencies, toolchains, blueprint and SDK in its generated context directory, so it can be loaded without network access.
+func (r *ModuleSource) WithVendor(opts ...ModuleSourceWithVendorOpts) *ModuleSource {
+	q := r.query.Select("withVendor")
+	for i := len(opts) - 1; i >= 0; i-- {
+		// `path` optional argument
+		if !querybuilder.IsZeroValue(opts[i].Path) {
+			q = q.Arg("path", opts[i].Path)
+		}
+	}
+
+	return &ModuleSource{
+		query: q,
+	}
+}
+
 // Remove the current blueprint from the module source.
 func (r *ModuleSource) WithoutBlueprint() *ModuleSource {
 	q := r.query.Select("withoutBlueprint")
@@ -11768,6 +12317,26 @@ func (r *Client) LoadEngineCacheFromID(id EngineCacheID) *EngineCache {
 	}
 }
 
+// Load a EngineCacheMissCause from its ID.
+func (r *Client) LoadEngineCacheMissCauseFromID(id EngineCacheMissCauseID) *EngineCacheMissCause {
+	q := r.query.Select("loadEngineCacheMissCauseFromID")
+	q = q.Arg("id", id)
+
+	return &EngineCacheMissCause{
+		query: q,
+	}
+}
+
+// Load a EngineClient from its ID.
+func (r *Client) LoadEngineClientFromID(id EngineClientID) *EngineClient {
+	q := r.query.Select("loadEngineClientFromID")
+	q = q.Arg("id", id)
+
+	return &EngineClient{
+		query: q,
+	}
+}
+
 // Load a Engine from its ID.
 func (r *Client) LoadEngineFromID(id EngineID) *Engine {
 	q := r.query.Select("loadEngineFromID")
@@ -11798,6 +12367,21 @@ func (r *Client) LoadEnumValueTypeDefFromID(id EnumValueTypeDefID) *EnumValueTyp
 	}
 }
 
+// Restores an environment encoded with Env.encode
+//
+// Objects bound in the environment are loaded from their IDs, so objects that refer to a host (e.g. host directories) are reloaded from the current client's host.
+//
+// Experimental: Environments are not yet stabilized
+func (r *Client) LoadEnv(file *File) *Env {
+	assertNotNil("file", file)
+	q := r.query.Select("loadEnv")
+	q = q.Arg("file", file)
+
+	return &Env{
+		query: q,
+	}
+}
+
 // Load a EnvFile from its ID.
 func (r *Client) LoadEnvFileFromID(id EnvFileID) *EnvFile {
 	q := r.query.Select("loadEnvFileFromID")
@@ -13922,6 +14506,70 @@ const (
 	CacheSharingModeLocked CacheSharingMode = "LOCKED"
 )
 
+// Strategy to use when exporting a changeset over host paths that changed since its before snapshot.
+type ChangesetExportConflict string
+
+func (ChangesetExportConflict) IsEnum() {}
+
+func (v ChangesetExportConflict) Name() string {
+	switch v {
+	case ChangesetExportConflictFail:
+		return "FAIL"
+	case ChangesetExportConflictSkip:
+		return "SKIP"
+	case ChangesetExportConflictBackup:
+		return "BACKUP"
+	default:
+		return ""
+	}
+}
+
+func (v ChangesetExportConflict) Value() string {
+	return string(v)
+}
+
+func (v *ChangesetExportConflict) MarshalJSON() ([]byte, error) {
+	if *v == "" {
+		return []byte(`""`), nil
+	}
+	name := v.Name()
+	if name == "" {
+		return nil, fmt.Errorf("invalid enum value %q", *v)
+	}
+	return json.Marshal(name)
+}
+
+func (v *ChangesetExportConflict) UnmarshalJSON(dt []byte) error {
+	var s string
+	if err := json.Unmarshal(dt, &s); err != nil {
+		return err
+	}
+	switch s {
+	case "":
+		*v = ""
+	case "BACKUP":
+		*v = ChangesetExportConflictBackup
+	case "FAIL":
+		*v = ChangesetExportConflictFail
+	case "SKIP":
+		*v = ChangesetExportConflictSkip
+	default:
+		return fmt.Errorf("invalid enum value %q", s)
+	}
+	return nil
+}
+
+const (
+	// Fail without writing anything to the host
+	ChangesetExportConflictFail ChangesetExportConflict = "FAIL"
+
+	// Leave the conflicting host paths untouched and apply all other changes
+	ChangesetExportConflictSkip ChangesetExportConflict = "SKIP"
+
+	// Copy the conflicting host paths next to themselves with a .orig suffix, then apply all changes
+	ChangesetExportConflictBackup ChangesetExportConflict = "BACKUP"
+)
+
 // Strategy to use when merging changesets with conflicting changes.
 type ChangesetMergeConflict string
 
@@ -14396,6 +15044,70 @@ const (
 	ImageMediaTypesDocker           ImageMediaTypes = ImageMediaTypesDockerMediaTypes
 )
 
+// How an LLM compacts its history when it approaches the model's context window.
+type LLMHistoryStrategy string
+
+func (LLMHistoryStrategy) IsEnum() {}
+
+func (v LLMHistoryStrategy) Name() string {
+	switch v {
+	case LLMHistoryStrategyNone:
+		return "NONE"
+	case LLMHistoryStrategyTruncate:
+		return "TRUNCATE"
+	case LLMHistoryStrategySummarize:
+		return "SUMMARIZE"
+	default:
+		return ""
+	}
+}
+
+func (v LLMHistoryStrategy) Value() string {
+	return string(v)
+}
+
+func (v *LLMHistoryStrategy) MarshalJSON() ([]byte, error) {
+	if *v == "" {
+		return []byte(`""`), nil
+	}
+	name := v.Name()
+	if name == "" {
+		return nil, fmt.Errorf("invalid enum value %q", *v)
+	}
+	return json.Marshal(name)
+}
+
+func (v *LLMHistoryStrategy) UnmarshalJSON(dt []byte) error {
+	var s string
+	if err := json.Unmarshal(dt, &s); err != nil {
+		return err
+	}
+	switch s {
+	case "":
+		*v = ""
+	case "NONE":
+		*v = LLMHistoryStrategyNone
+	case "SUMMARIZE":
+		*v = LLMHistoryStrategySummarize
+	case "TRUNCATE":
+		*v = LLMHistoryStrategyTruncate
+	default:
+		return fmt.Errorf("invalid enum value %q", s)
+	}
+	return nil
+}
+
+const (
+	// Never compact the history; requests fail once it outgrows the context window
+	LLMHistoryStrategyNone LLMHistoryStrategy = "NONE"
+
+	// Replace the results of the oldest tool calls with a placeholder
+	LLMHistoryStrategyTruncate LLMHistoryStrategy = "TRUNCATE"
+
+	// Replace the conversation so far with a summary written by the model, keeping the current turn
+	LLMHistoryStrategySummarize LLMHistoryStrategy = "SUMMARIZE"
+)
+
 // Experimental features of a module
 type ModuleSourceExperimentalFeature string
 
@@ -14459,6 +15171,8 @@ func (v ModuleSourceKind) Name() string {
 		return "GIT_SOURCE"
 	case ModuleSourceKindDirSource:
 		return "DIR_SOURCE"
+	case ModuleSourceKindOciSource:
+		return "OCI_SOURCE"
 	default:
 		return ""
 	}
@@ -14499,6 +15213,10 @@ func (v *ModuleSourceKind) UnmarshalJSON(dt []byte) error {
 		*v = ModuleSourceKindLocal
 	case "LOCAL_SOURCE":
 		*v = ModuleSourceKindLocalSource
+	case "OCI":
+		*v = ModuleSourceKindOci
+	case "OCI_SOURCE":
+		*v = ModuleSourceKindOciSource
 	default:
 		return fmt.Errorf("invalid enum value %q", s)
 	}
@@ -14514,6 +15232,9 @@ const (
 
 	ModuleSourceKindDirSource ModuleSourceKind = "DIR_SOURCE"
 	ModuleSourceKindDir       ModuleSourceKind = ModuleSourceKindDirSource
+
+	ModuleSourceKindOciSource ModuleSourceKind = "OCI_SOURCE"
+	ModuleSourceKindOci       ModuleSourceKind = ModuleSourceKindOciSource
 )
 
 // Transport layer network protocol associated to a port.